@@ -14,6 +14,11 @@ type Configuration struct {
 	Logging logger.Configuration
 	Sentry  sentry.Configuration
 	Metrics metrics.Configuration
+	Buckets []metrics.BucketsConfiguration
+	Samples []metrics.SampleConfiguration
+	Runtime *metrics.RuntimeConfiguration
+	Expiry  *metrics.ExpiryConfiguration
+	Series  *metrics.SeriesConfiguration
 	Prefix  string
 }
 
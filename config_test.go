@@ -2,6 +2,7 @@ package reporter
 
 import (
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
@@ -49,6 +50,105 @@ sentry:
 				Prefix: "aargau",
 			},
 		},
+		{
+			in: `
+metrics:
+  - expvar:
+      listen: :8123
+buckets:
+  - pattern: "*.latency"
+    exponential:
+      start: 1000000
+      factor: 2
+      count: 10
+`,
+			want: Configuration{
+				Logging: logger.DefaultConfiguration,
+				Metrics: metrics.Configuration([]metrics.ExporterConfiguration{
+					&metrics.ExpvarConfiguration{
+						Listen: config.Addr(":8123"),
+					},
+				}),
+				Buckets: []metrics.BucketsConfiguration{{
+					Pattern: "*.latency",
+					Exponential: &metrics.ExponentialBucketsConfiguration{
+						Start:  1000000,
+						Factor: 2,
+						Count:  10,
+					},
+				}},
+			},
+		},
+		{
+			in: `
+metrics:
+  - expvar:
+      listen: :8123
+samples:
+  - pattern: "*.latency"
+    type: window
+    window: 30s
+`,
+			want: Configuration{
+				Logging: logger.DefaultConfiguration,
+				Metrics: metrics.Configuration([]metrics.ExporterConfiguration{
+					&metrics.ExpvarConfiguration{
+						Listen: config.Addr(":8123"),
+					},
+				}),
+				Samples: []metrics.SampleConfiguration{{
+					Pattern:   "*.latency",
+					Type:      "window",
+					Size:      1028,
+					Alpha:     0.015,
+					Window:    config.Duration(30 * time.Second),
+					Precision: 3,
+				}},
+			},
+		},
+		{
+			in: `
+runtime:
+  goroutines: true
+  process: true
+`,
+			want: Configuration{
+				Logging: logger.DefaultConfiguration,
+				Runtime: &metrics.RuntimeConfiguration{
+					MemStats:   true,
+					Goroutines: true,
+					Process:    true,
+				},
+			},
+		},
+		{
+			in: `
+expiry:
+  ttl: 10m
+  pinned: ["*.static"]
+`,
+			want: Configuration{
+				Logging: logger.DefaultConfiguration,
+				Expiry: &metrics.ExpiryConfiguration{
+					TTL:    config.Duration(10 * time.Minute),
+					Pinned: []string{"*.static"},
+				},
+			},
+		},
+		{
+			in: `
+series:
+  max: 10000
+  overflow: alias
+`,
+			want: Configuration{
+				Logging: logger.DefaultConfiguration,
+				Series: &metrics.SeriesConfiguration{
+					Max:      10000,
+					Overflow: "alias",
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -3,7 +3,6 @@ module github.com/exoscale/go-reporter
 require (
 	collectd.org v0.3.0
 	github.com/certifi/gocertifi v0.0.0-20190105021004-abcd57078448 // indirect
-	github.com/deathowl/go-metrics-prometheus v0.0.0-20190530215645-35bace25558f
 	github.com/getsentry/raven-go v0.2.0
 	github.com/go-stack/stack v1.8.0
 	github.com/kylelemons/godebug v0.0.0-20170820004349-d65d576e9348
@@ -33,11 +33,20 @@ const (
 //
 // The ability to override log levels for some modules is currently
 // missing.
+//
+// InAppInclude and InAppExclude are lists of module prefixes used to
+// determine which stack frames belong to the application when
+// looking for the caller of a log record (see IncludeCaller) and the
+// in-app frames of the events sent to Sentry. The
+// reporter's own modules are always excluded, and InAppInclude takes
+// precedence over InAppExclude.
 type Configuration struct {
 	Level         Lvl
 	Console       bool
 	Syslog        bool
-	IncludeCaller bool `yaml:"include_caller,omitempty"`
+	IncludeCaller bool     `yaml:"include_caller,omitempty"`
+	InAppInclude  []string `yaml:"in_app_include,omitempty"`
+	InAppExclude  []string `yaml:"in_app_exclude,omitempty"`
 	Format        LogFormat
 	Files         []LogFile
 }
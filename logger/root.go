@@ -88,7 +88,8 @@ func New(config Configuration, additionalHandler log.Handler, prefix string) (lo
 	})
 
 	if config.IncludeCaller {
-		funcHandler = contextHandler(log.MultiHandler(handlers...), prefix)
+		funcHandler = contextHandler(log.MultiHandler(handlers...), prefix,
+			newInAppFilter(prefix, config.InAppInclude, config.InAppExclude))
 	}
 
 	logHandler := log.LvlFilterHandler(
@@ -109,27 +110,19 @@ func New(config Configuration, additionalHandler log.Handler, prefix string) (lo
 // log.CallerFileHandler and log.CallerFuncHandler but it's a bit
 // smarter on how the stack trace is inspected to avoid logging
 // modules. It adds a "caller" (qualified function + line number) and
-// a "module" (PROJECT package name).
-func contextHandler(h log.Handler, prefix string) log.Handler {
-	skipPrefixes := []string{
-		"github.com/exoscale/go-reporter",
-		"github.com/sirupsen/logrus",
-		fmt.Sprintf("%s/vendor/github.com/sirupsen/logrus", prefix),
-	}
+// a "module" (PROJECT package name). The caller is the first frame
+// belonging to the application according to the provided filter.
+func contextHandler(h log.Handler, prefix string, filter *inAppFilter) log.Handler {
 	return log.FuncHandler(func(r *log.Record) error {
 		callStack := stack.Trace().TrimBelow(r.Call)
 		callerFound := false
-	outer:
 		for _, call := range callStack {
 			if !callerFound {
 				// Searching for the first caller.
-				caller := fmt.Sprintf("%+v", call)
-				for _, prefix := range skipPrefixes {
-					if strings.HasPrefix(caller, prefix) {
-						continue outer
-					}
+				if !filter.inApp(call) {
+					continue
 				}
-				r.Ctx = append(r.Ctx, "caller", caller)
+				r.Ctx = append(r.Ctx, "caller", fmt.Sprintf("%+v", call))
 				callerFound = true
 			}
 			if callerFound {
@@ -152,6 +145,69 @@ func contextHandler(h log.Handler, prefix string) log.Handler {
 	})
 }
 
+// inAppFilter tells whether a stack frame belongs to the
+// application. Frames are matched against module prefixes using
+// both their qualified function name and their file path.
+type inAppFilter struct {
+	include []string
+	exclude []string
+}
+
+func newInAppFilter(prefix string, include, exclude []string) *inAppFilter {
+	return &inAppFilter{
+		include: include,
+		exclude: append([]string{
+			"github.com/exoscale/go-reporter",
+			"github.com/sirupsen/logrus",
+			fmt.Sprintf("%s/vendor/github.com/sirupsen/logrus", prefix),
+		}, exclude...),
+	}
+}
+
+func (f *inAppFilter) inApp(call stack.Call) bool {
+	return f.match(fmt.Sprintf("%+n", call), fmt.Sprintf("%+v", call))
+}
+
+// match tells whether a frame, identified by its qualified function
+// name and its file path, belongs to the application.
+func (f *inAppFilter) match(names ...string) bool {
+	match := func(prefixes []string) bool {
+		for _, prefix := range prefixes {
+			for _, name := range names {
+				if hasPathPrefix(name, prefix) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if match(f.include) {
+		return true
+	}
+	return !match(f.exclude)
+}
+
+// InApp tells whether a stack frame, identified by its qualified
+// function name and its file path, belongs to the application
+// according to the InAppInclude and InAppExclude settings of the
+// provided configuration.
+func InApp(config Configuration, prefix string, names ...string) bool {
+	return newInAppFilter(prefix, config.InAppInclude, config.InAppExclude).match(names...)
+}
+
+// hasPathPrefix tells if a qualified function name or a file path
+// belongs to the package path prefix: prefix must end on a path
+// component boundary, followed by a "/" or a ".".
+func hasPathPrefix(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) || strings.HasSuffix(prefix, "/") || strings.HasSuffix(prefix, ".") {
+		return true
+	}
+	return name[len(prefix)] == '/' || name[len(prefix)] == '.'
+}
+
 // JSONv1Format formats log records as JSONv1 objects separated by newlines.
 func JSONv1Format() log.Format {
 	return log.FormatFunc(func(r *log.Record) []byte {
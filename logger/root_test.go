@@ -2,6 +2,7 @@ package logger
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -162,3 +163,73 @@ func TestCaller(t *testing.T) {
 			"testing/testing.go", logline.Caller)
 	}
 }
+
+func TestIncludeCallerInApp(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory:\n%+v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cases := []struct {
+		description string
+		include     []string
+		exclude     []string
+		expected    string
+	}{
+		{"default", nil, nil, "testing/testing.go"},
+		{"included", []string{"github.com/exoscale/go-reporter/logger"}, nil,
+			"github.com/exoscale/go-reporter/logger/root_test.go"},
+		{"excluded", nil, []string{"testing"}, "runtime/"},
+	}
+	for i, tc := range cases {
+		name := filepath.Join(tempDir, fmt.Sprintf("out%d.json", i))
+		logger, err := New(Configuration{
+			IncludeCaller: true,
+			InAppInclude:  tc.include,
+			InAppExclude:  tc.exclude,
+			Level:         Lvl(log.LvlInfo),
+			Files:         []LogFile{LogFile{Name: name, Format: FormatJSON}},
+		}, nil, "project")
+		if err != nil {
+			t.Fatalf("Unable to initialize new logger:\n%+v", err)
+		}
+
+		logger.Info("hello")
+
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Unable to read JSON log %q:\n%+v", name, err)
+		}
+		var logline logLine
+		if err := json.Unmarshal(contents, &logline); err != nil {
+			t.Fatalf("Unable to decode JSON log line %q:\n%+v", contents, err)
+		}
+		if !strings.HasPrefix(logline.Caller, tc.expected) {
+			t.Errorf("%s: log caller should have prefix %q but got %q",
+				tc.description, tc.expected, logline.Caller)
+		}
+	}
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	cases := []struct {
+		name   string
+		prefix string
+		want   bool
+	}{
+		{"github.com/exoscale/go-reporter/logger/root.go:12", "github.com/exoscale/go-reporter", true},
+		{"github.com/exoscale/go-reporter.(*Reporter).Info", "github.com/exoscale/go-reporter", true},
+		{"github.com/exoscale/go-reporter", "github.com/exoscale/go-reporter", true},
+		{"github.com/exoscale/go-reporter-extra/main.go:3", "github.com/exoscale/go-reporter", false},
+		{"github.com/exoscale/go-reporterd.main", "github.com/exoscale/go-reporter", false},
+		{"github.com/exoscale/go-reporterd.main", "github.com/exoscale/", true},
+		{"testing.tRunner", "testing", true},
+		{"testingutil/util.go:3", "testing", false},
+	}
+	for _, tc := range cases {
+		if got := hasPathPrefix(tc.name, tc.prefix); got != tc.want {
+			t.Errorf("hasPathPrefix(%q, %q) == %v but expected %v", tc.name, tc.prefix, got, tc.want)
+		}
+	}
+}
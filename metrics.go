@@ -3,7 +3,14 @@
 // All the functions take a name and return an appropriate
 // metric. This metric is created if it doesn't exist. Unless the name
 // starts with ".", it will be prepended with the module name (guessed
-// from the stack trace).
+// from the stack trace). They also accept optional labels as a list of
+// alternating keys and values:
+//
+//     r.Counter("requests", "method", "GET", "code", "200").Inc(1)
+//
+// Invalid names and label keys are sanitized. Once the series limit
+// is reached, new series are rejected and the returned metric
+// discards its updates.
 
 package reporter
 
@@ -16,41 +23,62 @@ import (
 )
 
 // Counter returns a counter with the given name.
-func (r *Reporter) Counter(name string) metrics.Counter {
-	return metrics.GetOrRegisterCounter(expandName(name, r.prefix), r.metrics.Registry)
+func (r *Reporter) Counter(name string, labels ...string) metrics.Counter {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilCounter{}
+	}
+	return metrics.GetOrRegisterCounter(name, r.metrics.Registry)
 }
 
 // Gauge returns a gauge with the given name.
-func (r *Reporter) Gauge(name string) metrics.Gauge {
-	return metrics.GetOrRegisterGauge(expandName(name, r.prefix), r.metrics.Registry)
+func (r *Reporter) Gauge(name string, labels ...string) metrics.Gauge {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilGauge{}
+	}
+	return metrics.GetOrRegisterGauge(name, r.metrics.Registry)
 }
 
 // GaugeFloat64 returns a 64-bit float gauge with the given name.
-func (r *Reporter) GaugeFloat64(name string) metrics.GaugeFloat64 {
-	return metrics.GetOrRegisterGaugeFloat64(expandName(name, r.prefix), r.metrics.Registry)
+func (r *Reporter) GaugeFloat64(name string, labels ...string) metrics.GaugeFloat64 {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilGaugeFloat64{}
+	}
+	return metrics.GetOrRegisterGaugeFloat64(name, r.metrics.Registry)
 }
 
-// Histogram returns an histogram with the given name. This uses an
-// exponentially-decating sample with a forward-decaying priority
-// reservoir. It uses fixed values for the reservoir size and
-// alpha. Those values were scientifically obtained through random
-// Google searches.
-func (r *Reporter) Histogram(name string) metrics.Histogram {
-	reservoir := 100
-	alpha := 0.015
-	return metrics.GetOrRegisterHistogram(expandName(name, r.prefix),
-		r.metrics.Registry,
-		metrics.NewExpDecaySample(reservoir, alpha))
+// Histogram returns an histogram with the given name. This uses the
+// sample type matching the name, or an exponentially-decaying sample
+// with a forward-decaying priority reservoir. If the name matches a
+// buckets layout, the histogram also counts its values in buckets.
+func (r *Reporter) Histogram(name string, labels ...string) metrics.Histogram {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilHistogram{}
+	}
+	return r.metrics.GetOrRegisterHistogram(name)
 }
 
 // Meter returns a meter with the given name.
-func (r *Reporter) Meter(name string) metrics.Meter {
-	return metrics.GetOrRegisterMeter(expandName(name, r.prefix), r.metrics.Registry)
+func (r *Reporter) Meter(name string, labels ...string) metrics.Meter {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilMeter{}
+	}
+	return metrics.GetOrRegisterMeter(name, r.metrics.Registry)
 }
 
-// Timer returns a timer with the given name.
-func (r *Reporter) Timer(name string) metrics.Timer {
-	return metrics.GetOrRegisterTimer(expandName(name, r.prefix), r.metrics.Registry)
+// Timer returns a timer with the given name. This uses the sample
+// type matching the name, if any. If the name matches a buckets
+// layout, the timer also counts its values in buckets.
+func (r *Reporter) Timer(name string, labels ...string) metrics.Timer {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilTimer{}
+	}
+	return r.metrics.GetOrRegisterTimer(name)
 }
 
 // Push pushes registered metrics to a push gateway.
@@ -64,12 +92,28 @@ type Healthcheck struct {
 }
 
 // Healthcheck returns an healthcheck with the given name.
-func (r *Reporter) Healthcheck(name string, f func(h Healthcheck)) Healthcheck {
+func (r *Reporter) Healthcheck(name string, f func(h Healthcheck), labels ...string) Healthcheck {
 	check := func(h metrics.Healthcheck) {
 		f(Healthcheck{h})
 	}
-	return Healthcheck{metrics.GetOrRegisterHealthcheck(
-		expandName(name, r.prefix), r.metrics.Registry, check)}
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return Healthcheck{metrics.NilHealthcheck{}}
+	}
+	return Healthcheck{metrics.GetOrRegisterHealthcheck(name, r.metrics.Registry, check)}
+}
+
+// metricName returns the registry name of a metric, expanded with
+// the module name and followed by its labels. It returns false if
+// the series limit rejects the metric. Otherwise, the metric is
+// marked as updated for expiry.
+func (r *Reporter) metricName(name string, labels []string) (string, bool) {
+	name, ok := r.metrics.LimitSeries(r.metrics.LabeledName(expandName(name, r.prefix), labels...))
+	if !ok {
+		return "", false
+	}
+	r.metrics.Touch(name)
+	return name, true
 }
 
 const separator = "."
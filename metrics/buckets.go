@@ -0,0 +1,270 @@
+package metrics
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+)
+
+// BucketsConfiguration is the buckets layout of the histograms and
+// timers whose name (without labels) matches a pattern. Exactly one
+// of bounds, linear and exponential must be specified. Timers values
+// being durations in nanoseconds, so are their buckets bounds.
+type BucketsConfiguration struct {
+	Pattern     string
+	Bounds      []float64
+	Linear      *LinearBucketsConfiguration
+	Exponential *ExponentialBucketsConfiguration
+}
+
+// LinearBucketsConfiguration is a layout of count buckets, the
+// lowest upper bound being start and each following one being width
+// higher.
+type LinearBucketsConfiguration struct {
+	Start float64
+	Width float64
+	Count int
+}
+
+// ExponentialBucketsConfiguration is a layout of count buckets, the
+// lowest upper bound being start and each following one being factor
+// times higher.
+type ExponentialBucketsConfiguration struct {
+	Start  float64
+	Factor float64
+	Count  int
+}
+
+// UnmarshalYAML parses a buckets layout from YAML.
+func (c *BucketsConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawBucketsConfiguration BucketsConfiguration
+	raw := rawBucketsConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "unable to decode buckets configuration")
+	}
+	if raw.Pattern == "" {
+		return errors.Errorf("missing pattern value for buckets configuration")
+	}
+	if _, err := path.Match(raw.Pattern, ""); err != nil {
+		return errors.Wrapf(err, "invalid pattern %q for buckets configuration", raw.Pattern)
+	}
+	layouts := 0
+	if len(raw.Bounds) > 0 {
+		layouts++
+	}
+	if raw.Linear != nil {
+		layouts++
+		if raw.Linear.Width <= 0 || raw.Linear.Count <= 0 {
+			return errors.Errorf("linear buckets width and count should be positive")
+		}
+	}
+	if raw.Exponential != nil {
+		layouts++
+		if raw.Exponential.Start <= 0 || raw.Exponential.Factor <= 1 || raw.Exponential.Count <= 0 {
+			return errors.Errorf("exponential buckets start and count should be positive, factor greater than 1")
+		}
+	}
+	if layouts != 1 {
+		return errors.Errorf("exactly one of bounds, linear and exponential should be configured")
+	}
+	bounds := BucketsConfiguration(raw).bounds()
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			return errors.Errorf("buckets bounds should be sorted in strictly increasing order")
+		}
+	}
+	*c = BucketsConfiguration(raw)
+	return nil
+}
+
+// bounds returns the buckets upper bounds of the layout.
+func (c BucketsConfiguration) bounds() []float64 {
+	switch {
+	case c.Linear != nil:
+		bounds := make([]float64, c.Linear.Count)
+		for i := range bounds {
+			bounds[i] = c.Linear.Start + float64(i)*c.Linear.Width
+		}
+		return bounds
+	case c.Exponential != nil:
+		bounds := make([]float64, c.Exponential.Count)
+		bound := c.Exponential.Start
+		for i := range bounds {
+			bounds[i] = bound
+			bound *= c.Exponential.Factor
+		}
+		return bounds
+	}
+	return c.Bounds
+}
+
+// SetBuckets sets the buckets layouts of the histograms and timers
+// registered with GetOrRegisterHistogram and GetOrRegisterTimer. The
+// first layout whose pattern matches applies.
+func (m *Metrics) SetBuckets(layouts []BucketsConfiguration) {
+	m.buckets = layouts
+}
+
+// GetOrRegisterHistogram returns the histogram registered under the
+// given name, registering a new one if needed. Its sample is the one
+// of the first matching sample type, or an exponentially-decaying
+// sample of 100 values. If the name matches a buckets layout, the
+// histogram also counts its values in buckets.
+func (m *Metrics) GetOrRegisterHistogram(name string) metrics.Histogram {
+	return m.Registry.GetOrRegister(name, func() metrics.Histogram {
+		sample := m.sample(name)
+		if sample == nil {
+			sample = metrics.NewExpDecaySample(histogramReservoirSize, histogramAlpha)
+		}
+		if bounds := m.bucketsBounds(name); bounds != nil {
+			return &bucketHistogram{newHistogram(sample), newBucketCounts(bounds)}
+		}
+		return newHistogram(sample)
+	}).(metrics.Histogram)
+}
+
+// GetOrRegisterTimer returns the timer registered under the given
+// name, registering a new one if needed. Its sample is the one of
+// the first matching sample type, or an exponentially-decaying
+// sample of 1028 values. If the name matches a buckets layout, the
+// timer also counts its values in buckets.
+func (m *Metrics) GetOrRegisterTimer(name string) metrics.Timer {
+	return m.Registry.GetOrRegister(name, func() metrics.Timer {
+		var timer metrics.Timer
+		if sample := m.sample(name); sample != nil {
+			timer = newTimer(sample)
+		} else {
+			timer = metrics.NewTimer()
+		}
+		if bounds := m.bucketsBounds(name); bounds != nil {
+			return &bucketTimer{timer, newBucketCounts(bounds)}
+		}
+		return timer
+	}).(metrics.Timer)
+}
+
+// bucketsBounds returns the buckets upper bounds of the first layout
+// matching the name of a metric, or nil.
+func (m *Metrics) bucketsBounds(name string) []float64 {
+	name, _ = decodeLabels(name)
+	for _, layout := range m.buckets {
+		if matched, _ := path.Match(layout.Pattern, name); matched {
+			return layout.bounds()
+		}
+	}
+	return nil
+}
+
+// bucketed is implemented by histograms and timers counting their
+// values in buckets, and by their snapshots. Buckets returns the
+// upper bounds and the cumulative count of the values less than or
+// equal to each of them.
+type bucketed interface {
+	Buckets() ([]float64, []uint64)
+}
+
+// bucketName returns the name of the series of a bucket, as used by
+// the exporters not supporting native histograms.
+func bucketName(bound float64) string {
+	return "le_" + strconv.FormatFloat(bound, 'f', -1, 64)
+}
+
+// bucketCounts holds the (non-cumulative) counts of values in
+// buckets, updated atomically.
+type bucketCounts struct {
+	bounds []float64
+	counts []uint64
+}
+
+func newBucketCounts(bounds []float64) *bucketCounts {
+	return &bucketCounts{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// Buckets returns the upper bounds and the cumulative counts.
+func (c *bucketCounts) Buckets() ([]float64, []uint64) {
+	cumulative := make([]uint64, len(c.counts))
+	var total uint64
+	for i := range c.counts {
+		total += atomic.LoadUint64(&c.counts[i])
+		cumulative[i] = total
+	}
+	return c.bounds, cumulative
+}
+
+func (c *bucketCounts) observe(v float64) {
+	if i := sort.SearchFloat64s(c.bounds, v); i < len(c.bounds) {
+		atomic.AddUint64(&c.counts[i], 1)
+	}
+}
+
+// snapshot returns a copy of the counts. It should be taken before
+// the snapshot of the histogram or timer, for its count to never be
+// lower than the buckets counts.
+func (c *bucketCounts) snapshot() *bucketCounts {
+	s := newBucketCounts(c.bounds)
+	for i := range c.counts {
+		s.counts[i] = atomic.LoadUint64(&c.counts[i])
+	}
+	return s
+}
+
+// bucketHistogram is a histogram also counting its values in buckets.
+type bucketHistogram struct {
+	metrics.Histogram
+	*bucketCounts
+}
+
+// Clear clears the histogram and its buckets.
+func (h *bucketHistogram) Clear() {
+	h.Histogram.Clear()
+	for i := range h.counts {
+		atomic.StoreUint64(&h.counts[i], 0)
+	}
+}
+
+// Snapshot returns a read-only copy of the histogram.
+func (h *bucketHistogram) Snapshot() metrics.Histogram {
+	counts := h.bucketCounts.snapshot()
+	return &bucketHistogram{h.Histogram.Snapshot(), counts}
+}
+
+// Update samples a new value.
+func (h *bucketHistogram) Update(v int64) {
+	h.Histogram.Update(v)
+	h.observe(float64(v))
+}
+
+// bucketTimer is a timer also counting its values in buckets.
+type bucketTimer struct {
+	metrics.Timer
+	*bucketCounts
+}
+
+// Snapshot returns a read-only copy of the timer.
+func (t *bucketTimer) Snapshot() metrics.Timer {
+	counts := t.bucketCounts.snapshot()
+	return &bucketTimer{t.Timer.Snapshot(), counts}
+}
+
+// Time records the duration of the execution of f.
+func (t *bucketTimer) Time(f func()) {
+	ts := time.Now()
+	f()
+	t.UpdateSince(ts)
+}
+
+// Update records the duration of an event.
+func (t *bucketTimer) Update(d time.Duration) {
+	t.Timer.Update(d)
+	t.observe(float64(d))
+}
+
+// UpdateSince records the duration of an event that started at ts.
+func (t *bucketTimer) UpdateSince(ts time.Time) {
+	t.Update(time.Since(ts))
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalBucketsConfiguration(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []float64
+	}{
+		{`{pattern: "*.size", bounds: [1, 10, 100]}`, []float64{1, 10, 100}},
+		{`{pattern: "*", linear: {start: 0, width: 5, count: 3}}`, []float64{0, 5, 10}},
+		{`{pattern: "*", exponential: {start: 1000, factor: 10, count: 3}}`, []float64{1000, 10000, 100000}},
+	}
+	for _, c := range cases {
+		var got BucketsConfiguration
+		if err := yaml.Unmarshal([]byte(c.in), &got); err != nil {
+			t.Errorf("Unmarshal(%q) error:\n%+v", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.bounds(), c.want) {
+			t.Errorf("Unmarshal(%q).bounds() == %v but expected %v", c.in, got.bounds(), c.want)
+		}
+	}
+
+	for _, in := range []string{
+		`{bounds: [1]}`,
+		`{pattern: "[", bounds: [1]}`,
+		`{pattern: "*"}`,
+		`{pattern: "*", bounds: [10, 1]}`,
+		`{pattern: "*", bounds: [1], linear: {width: 1, count: 1}}`,
+		`{pattern: "*", linear: {width: 0, count: 1}}`,
+		`{pattern: "*", exponential: {start: 1, factor: 1, count: 1}}`,
+	} {
+		var got BucketsConfiguration
+		if err := yaml.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("Unmarshal(%q) == %+v but expected an error", in, got)
+		}
+	}
+}
+
+func TestGetOrRegisterBuckets(t *testing.T) {
+	m, err := New(nil, "project")
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	m.SetBuckets([]BucketsConfiguration{
+		{Pattern: "*.size", Bounds: []float64{1, 10}},
+		{Pattern: "*.latency", Bounds: []float64{float64(time.Millisecond)}},
+	})
+
+	h := m.GetOrRegisterHistogram(`request.size{code="200"}`)
+	for _, v := range []int64{1, 5, 50} {
+		h.Update(v)
+	}
+	snapshot := h.Snapshot()
+	h.Update(2)
+	if _, counts := snapshot.(bucketed).Buckets(); !reflect.DeepEqual(counts, []uint64{1, 2}) {
+		t.Errorf("Buckets() == %v but expected [1 2]", counts)
+	}
+	if m.GetOrRegisterHistogram(`request.size{code="200"}`) != h {
+		t.Errorf("GetOrRegisterHistogram() should have returned the existing histogram")
+	}
+
+	timer := m.GetOrRegisterTimer("request.latency")
+	defer timer.Stop()
+	timer.Update(time.Microsecond)
+	timer.Time(func() {})
+	if _, counts := timer.Snapshot().(bucketed).Buckets(); counts[0] != 2 {
+		t.Errorf("Buckets() == %v but expected [2]", counts)
+	}
+
+	if _, ok := m.GetOrRegisterHistogram("response.bytes").(bucketed); ok {
+		t.Errorf("GetOrRegisterHistogram() should not have counted values in buckets")
+	}
+}
+
+func TestBucketsExport(t *testing.T) {
+	r := metrics.NewRegistry()
+	h := &bucketHistogram{metrics.NewHistogram(metrics.NewUniformSample(10)), newBucketCounts([]float64{0.5, 10})}
+	if err := r.Register("size", h); err != nil {
+		t.Fatalf("Register() error:\n%+v", err)
+	}
+	h.Update(3)
+	h.Update(30)
+
+	// Prometheus
+	families, err := newPrometheusRegistry(t, &prometheusCollector{registry: r}).Gather()
+	if err != nil {
+		t.Fatalf("Gather() error:\n%+v", err)
+	}
+	for _, f := range families {
+		if f.GetName() != "size_histogram" {
+			continue
+		}
+		buckets := f.GetMetric()[0].GetHistogram().GetBucket()
+		if len(buckets) != 2 || buckets[0].GetCumulativeCount() != 0 || buckets[1].GetCumulativeCount() != 1 {
+			t.Errorf("Expected native buckets but got %v", buckets)
+		}
+	}
+
+	// Graphite
+	var paths []string
+	for _, dp := range graphiteDatapoints(r, "", nil) {
+		if strings.Contains(dp.path, "le_") {
+			paths = append(paths, dp.path)
+		}
+	}
+	sort.Strings(paths)
+	if want := []string{"size.le_0_5", "size.le_10"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("graphiteDatapoints() == %q but expected %q", paths, want)
+	}
+
+	// InfluxDB
+	s := &influxState{config: &InfluxConfiguration{}}
+	if lines := s.lines(r, time.Unix(0, 42)); len(lines) != 1 || !strings.HasSuffix(lines[0], ",le_0.5=0i,le_10=1i 42") {
+		t.Errorf("lines() == %q but expected buckets fields", lines)
+	}
+
+	// StatsD
+	statsd := newStatsdState(&StatsdConfiguration{Flavor: "statsd", Histograms: "mean"}, "")
+	if lines := statsd.lines(r); !reflect.DeepEqual(lines, []string{"size:16.5|ms|@0.5", "size.le_10:1|c"}) {
+		t.Errorf("lines() == %q but expected buckets counters", lines)
+	}
+}
@@ -148,8 +148,16 @@ func collectdReportOnce(r metrics.Registry, client *network.Client, interval tim
 				r).Mark(1)
 			return
 		}
-		plugin, pluginInstance := collectdGetPluginName(name)
-		plugin = strings.Join([]string{prefix, plugin}, ".")
+		var plugin, pluginInstance string
+		if base, labels := decodeLabels(name); labels != nil {
+			// Labeled metrics use their label values as plugin instance
+			plugin = strings.Join([]string{prefix, base}, ".")
+			pluginInstance = strings.Join(labelsValues(labels), "-")
+		} else {
+			plugin, pluginInstance = collectdGetPluginName(name)
+			plugin = strings.Join([]string{prefix, plugin}, ".")
+		}
+		plugin, pluginInstance = collectdName(plugin), collectdInstanceName(pluginInstance)
 		identifier := api.Identifier{
 			Host:           hostname,
 			Plugin:         plugin,
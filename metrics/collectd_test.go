@@ -54,6 +54,8 @@ func TestCollectd(t *testing.T) {
 	metrics.NewRegisteredHistogram("metrics.test.histogram", m.Registry,
 		metrics.NewUniformSample(10)).Update(1871)
 	metrics.NewRegisteredMeter("metrics.test.meter", m.Registry).Mark(19)
+	metrics.NewRegisteredCounter(m.LabeledName("metrics.test.labeled", "method", "GET", "code", "200"),
+		m.Registry).Inc(42)
 
 	// Create a metric that we'll exclude from reporting
 	metrics.NewRegisteredGauge("metrics.test.donotwant", m.Registry).Update(13)
@@ -140,6 +142,11 @@ L:
 			pluginInstance: "counter",
 			kind:           "counter",
 			values:         []api.Value{api.Counter(41)},
+		}, {
+			plugin:         "project.metrics.test.labeled",
+			pluginInstance: "200-GET",
+			kind:           "counter",
+			values:         []api.Value{api.Counter(42)},
 		}, {
 			plugin:         "project.metrics.test",
 			pluginInstance: "gauge",
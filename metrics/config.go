@@ -67,6 +67,24 @@ func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 				return errors.Wrapf(err, "incorrect collectd configuration for item %d", i+1)
 			}
 			finalConfiguration[i] = &exporterConfiguration
+		case "statsd":
+			var exporterConfiguration StatsdConfiguration
+			if err := yaml.Unmarshal(strExporterConfiguration, &exporterConfiguration); err != nil {
+				return errors.Wrapf(err, "incorrect statsd configuration for item %d", i+1)
+			}
+			finalConfiguration[i] = &exporterConfiguration
+		case "graphite":
+			var exporterConfiguration GraphiteConfiguration
+			if err := yaml.Unmarshal(strExporterConfiguration, &exporterConfiguration); err != nil {
+				return errors.Wrapf(err, "incorrect graphite configuration for item %d", i+1)
+			}
+			finalConfiguration[i] = &exporterConfiguration
+		case "influx":
+			var exporterConfiguration InfluxConfiguration
+			if err := yaml.Unmarshal(strExporterConfiguration, &exporterConfiguration); err != nil {
+				return errors.Wrapf(err, "incorrect influx configuration for item %d", i+1)
+			}
+			finalConfiguration[i] = &exporterConfiguration
 		case "prompushgw":
 			var exporterConfiguration PromPushGWConfiguration
 			if err := yaml.Unmarshal(strExporterConfiguration, &exporterConfiguration); err != nil {
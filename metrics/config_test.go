@@ -59,6 +59,17 @@ func TestUnmarshalConfiguration(t *testing.T) {
 				Subsystem: "bar",
 			},
 		},
+		{
+			in: `
+- prometheus:
+    listen: 127.0.0.1:7653
+    namespace: foo
+`,
+			want: PrometheusConfiguration{
+				Listen:    config.Addr("127.0.0.1:7653"),
+				Namespace: "foo",
+			},
+		},
 
 		{
 			in: `
@@ -83,6 +94,78 @@ func TestUnmarshalConfiguration(t *testing.T) {
 		},
 		{
 			in: `
+- statsd:
+    address: 127.0.0.1:8125
+    interval: 10s
+    flavor: dogstatsd
+    tags:
+      env: prod
+`,
+			want: StatsdConfiguration{
+				Network:       "udp",
+				Address:       "127.0.0.1:8125",
+				Flavor:        "dogstatsd",
+				Tags:          map[string]string{"env": "prod"},
+				Histograms:    "summary",
+				Interval:      config.Duration(10 * time.Second),
+				MaxPacketSize: 1432,
+			},
+		},
+		{
+			in: `
+- statsd:
+    address: 127.0.0.1:8125
+    interval: 10s
+    histograms: samples
+`,
+			want: StatsdConfiguration{
+				Network:       "udp",
+				Address:       "127.0.0.1:8125",
+				Flavor:        "statsd",
+				Histograms:    "mean",
+				Interval:      config.Duration(10 * time.Second),
+				MaxPacketSize: 1432,
+			},
+		},
+		{
+			in: `
+- graphite:
+    connect: 127.0.0.1:2003
+    interval: 1m
+    protocol: pickle
+`,
+			want: GraphiteConfiguration{
+				Connect:  config.Addr("127.0.0.1:2003"),
+				Interval: config.Duration(time.Minute),
+				Protocol: "pickle",
+				Prefix:   "{fqdn}",
+			},
+		},
+		{
+			in: `
+- influx:
+    url: https://influxdb.example.net:8086
+    apiversion: v2
+    org: exoscale
+    bucket: metrics
+    token: secret
+    interval: 10s
+    gzip: true
+`,
+			want: InfluxConfiguration{
+				URL:        "https://influxdb.example.net:8086",
+				APIVersion: "v2",
+				Org:        "exoscale",
+				Bucket:     "metrics",
+				Token:      "secret",
+				Interval:   config.Duration(10 * time.Second),
+				BatchSize:  5000,
+				Gzip:       true,
+				Retries:    3,
+			},
+		},
+		{
+			in: `
 - prompushgw:
     url: https://my.pushgateway.net
     job: bar
@@ -119,6 +202,19 @@ func TestUnmarshalIncompleteConfiguration(t *testing.T) {
 		`- file: {interval: 10m}`,
 		`- file: {path: /var/log/project...}`,
 		`- collectd: {}`,
+		`- graphite: {interval: 10s}`,
+		`- graphite: {connect: 127.0.0.1:2003}`,
+		`- graphite: {connect: 127.0.0.1:2003, interval: 10s, protocol: udp}`,
+		`- influx: {url: "http://127.0.0.1:8086", database: test}`,
+		`- influx: {url: "http://127.0.0.1:8086", interval: 10s}`,
+		`- influx: {url: "http://127.0.0.1:8086", interval: 10s, apiversion: v2, org: test}`,
+		`- influx: {url: "tcp://127.0.0.1:8086", interval: 10s, database: test}`,
+		`- influx: {url: "udp://127.0.0.1:8089", interval: 10s, token: a, username: b}`,
+		`- statsd: {interval: 10s}`,
+		`- statsd: {address: 127.0.0.1:8125}`,
+		`- statsd: {address: 127.0.0.1:8125, interval: 10s, network: tcp}`,
+		`- statsd: {address: 127.0.0.1:8125, interval: 10s, flavor: graphite}`,
+		`- statsd: {address: 127.0.0.1:8125, interval: 10s, histograms: values}`,
 		`
 - prometheus:
     listen: 127.0.0.1:7653
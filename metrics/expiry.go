@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+// ExpiryConfiguration is the expiry of the metrics created using the
+// reporter's helper methods: metrics not updated for TTL are
+// unregistered, unless their name (without labels) matches one of
+// the pinned patterns. A metric is updated when requested using a
+// helper method or when its value changes.
+type ExpiryConfiguration struct {
+	TTL    config.Duration
+	Pinned []string
+}
+
+// UnmarshalYAML parses an expiry configuration from YAML.
+func (c *ExpiryConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawExpiryConfiguration ExpiryConfiguration
+	raw := rawExpiryConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "unable to decode expiry configuration")
+	}
+	if raw.TTL <= 0 {
+		return errors.Errorf("missing or invalid ttl value for expiry configuration")
+	}
+	for _, pattern := range raw.Pinned {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid pinned pattern %q for expiry configuration", pattern)
+		}
+	}
+	*c = ExpiryConfiguration(raw)
+	return nil
+}
+
+// SetExpiry enables the expiry of the metrics marked as updated with
+// Touch. Expired metrics are checked every 5 seconds once started.
+func (m *Metrics) SetExpiry(c ExpiryConfiguration) {
+	m.expiry = &expirer{
+		config:  c,
+		tracked: make(map[string]*trackedMetric),
+		now:     time.Now,
+	}
+}
+
+// Touch marks the metric registered under the given name as updated.
+// This is a no-op unless expiry is enabled.
+func (m *Metrics) Touch(name string) {
+	e := m.expiry
+	if e == nil {
+		return
+	}
+	e.Lock()
+	defer e.Unlock()
+	if t, ok := e.tracked[name]; ok {
+		t.touched = true
+		return
+	}
+	unlabeled, _ := decodeLabels(name)
+	for _, pattern := range e.config.Pinned {
+		if matched, _ := path.Match(pattern, unlabeled); matched {
+			return
+		}
+	}
+	e.tracked[name] = &trackedMetric{updated: e.now(), touched: true}
+}
+
+// expirer keeps track of the metrics subject to expiry.
+type expirer struct {
+	sync.Mutex
+	config  ExpiryConfiguration
+	tracked map[string]*trackedMetric
+	now     func() time.Time
+}
+
+// trackedMetric is the state of a metric at the previous sweep.
+// go-metrics metrics don't record their update time: updates are
+// detected by comparing their count, value or sum.
+type trackedMetric struct {
+	count   int64
+	value   float64
+	updated time.Time
+	touched bool
+}
+
+// expire unregisters the metrics not updated for the TTL.
+func (m *Metrics) expire() {
+	e := m.expiry
+	e.Lock()
+	defer e.Unlock()
+	now := e.now()
+	for name, t := range e.tracked {
+		metric := m.Registry.Get(name)
+		if metric == nil {
+			delete(e.tracked, name)
+			continue
+		}
+		var count int64
+		var value float64
+		switch metric := metric.(type) {
+		case metrics.Counter:
+			count = metric.Count()
+		case metrics.Gauge:
+			count = metric.Value()
+		case metrics.GaugeFloat64:
+			value = metric.Value()
+		case metrics.Meter:
+			count = metric.Count()
+		case metrics.Histogram:
+			count, value = metric.Count(), float64(metric.Sum())
+		case metrics.Timer:
+			count, value = metric.Count(), float64(metric.Sum())
+		default:
+			// Other metrics never expire
+			t.touched = true
+		}
+		if t.touched || count != t.count || value != t.value {
+			t.count, t.value, t.updated, t.touched = count, value, now, false
+			continue
+		}
+		if now.Sub(t.updated) < time.Duration(e.config.TTL) {
+			continue
+		}
+		m.Registry.Unregister(name)
+		m.forgetLabels(name)
+		m.forgetSeries(name)
+		delete(e.tracked, name)
+		metrics.GetOrRegisterCounter(
+			"github.com/exoscale/go-reporter.metrics.expired",
+			m.Registry).Inc(1)
+	}
+}
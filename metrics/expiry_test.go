@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/yaml.v2"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+func TestUnmarshalExpiryConfiguration(t *testing.T) {
+	var got ExpiryConfiguration
+	if err := yaml.Unmarshal([]byte(`{ttl: 10m, pinned: ["*.static"]}`), &got); err != nil {
+		t.Fatalf("Unmarshal() error:\n%+v", err)
+	}
+	if got.TTL != config.Duration(10*time.Minute) || len(got.Pinned) != 1 {
+		t.Errorf("Unmarshal() == %+v", got)
+	}
+
+	for _, in := range []string{`{pinned: ["*"]}`, `{ttl: 1m, pinned: ["["]}`} {
+		var got ExpiryConfiguration
+		if err := yaml.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("Unmarshal(%q) == %+v but expected an error", in, got)
+		}
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	m, err := New(nil, "project")
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	m.SetExpiry(ExpiryConfiguration{TTL: config.Duration(time.Minute), Pinned: []string{"*.pinned"}})
+	now := time.Now()
+	m.expiry.now = func() time.Time { return now }
+	sweep := func(after time.Duration) {
+		now = now.Add(after)
+		m.expire()
+	}
+	get := func(name string) metrics.Counter {
+		m.Touch(name)
+		return metrics.GetOrRegisterCounter(name, m.Registry)
+	}
+
+	a := get(m.LabeledName("requests", "peer", "a"))
+	get(m.LabeledName("requests", "peer", "b")).Inc(1)
+	get("app.pinned").Inc(1)
+	sweep(0)
+
+	a.Inc(1)
+	sweep(time.Minute)
+	if m.Registry.Get(`requests{peer="a"}`) == nil {
+		t.Errorf("updated metric should not expire")
+	}
+	if m.Registry.Get(`requests{peer="b"}`) != nil {
+		t.Errorf("stale metric should expire")
+	}
+	sweep(time.Minute)
+	if m.Registry.Get(`requests{peer="a"}`) != nil {
+		t.Errorf("stale metric should expire")
+	}
+	if m.Registry.Get("app.pinned") == nil {
+		t.Errorf("pinned metric should not expire")
+	}
+	expired := m.Registry.Get("github.com/exoscale/go-reporter.metrics.expired").(metrics.Counter).Count()
+	if expired != 2 {
+		t.Errorf("expired == %d but expected 2", expired)
+	}
+	if _, ok := m.labels.sets["requests"]; ok {
+		t.Errorf("expired label sets should be forgotten")
+	}
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"encoding/json"
+	"io"
 	"os"
 	"time"
 
@@ -49,8 +51,8 @@ func (c *FileConfiguration) initExporter(m *Metrics) error {
 		for {
 			select {
 			case <-tick.C:
-				metrics.WriteJSONOnce(m.Registry, output)
-				output.Sync() // nolint: errcheck
+				fileWriteOnce(m.Registry, output) // nolint: errcheck
+				output.Sync()                     // nolint: errcheck
 			case <-m.t.Dying():
 				break L
 			}
@@ -60,3 +62,17 @@ func (c *FileConfiguration) initExporter(m *Metrics) error {
 
 	return nil
 }
+
+// fileWriteOnce writes the current metric values to w as a one-line
+// JSON object. Labeled metrics get additional "name" and "tags"
+// fields holding their name and labels.
+func fileWriteOnce(r metrics.Registry, w io.Writer) error {
+	all := r.GetAll()
+	for name, values := range all {
+		if base, labels := decodeLabels(name); labels != nil {
+			values["name"] = base
+			values["tags"] = labels
+		}
+	}
+	return json.NewEncoder(w).Encode(all)
+}
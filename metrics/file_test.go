@@ -43,6 +43,7 @@ func TestFile(t *testing.T) {
 		t.Fatal(err)
 	}
 	c.Inc(47)
+	metrics.NewRegisteredCounter(m.LabeledName("bar", "code", "200"), m.Registry).Inc(42)
 
 	if testing.Short() {
 		t.Skip("Skip logfile test in short mode")
@@ -70,4 +71,16 @@ func TestFile(t *testing.T) {
 	if got.Foo.Count != 47 {
 		t.Fatalf("Expected Foo == 47 but got %d instead", got.Foo)
 	}
+	var labeled map[string]struct {
+		Count int
+		Name  string
+		Tags  map[string]string
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &labeled); err != nil {
+		t.Fatalf("Unable to decode JSON body:\n%s\nError:\n%+v", lines[0], err)
+	}
+	bar := labeled[`bar{code="200"}`]
+	if bar.Count != 42 || bar.Name != "bar" || bar.Tags["code"] != "200" {
+		t.Fatalf("Expected labeled bar == 42 with tags but got %+v instead", bar)
+	}
 }
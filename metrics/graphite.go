@@ -0,0 +1,325 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+const (
+	// Bounds of the delay between reconnection attempts to graphite,
+	// doubled after each failed attempt.
+	graphiteMinBackoff = time.Second
+	graphiteMaxBackoff = time.Minute
+
+	// Maximum number of datapoints in a pickle payload.
+	graphiteMaxPickleDatapoints = 500
+
+	graphiteTimeout = 5 * time.Second
+)
+
+var (
+	graphitePercentiles      = []float64{0.5, 0.75, 0.95, 0.98, 0.99, 0.999}
+	graphitePercentilesNames = []string{"p50", "p75", "p95", "p98", "p99", "p999"}
+	errGraphiteDisconnected  = errors.New("not connected to graphite")
+)
+
+// GraphiteConfiguration represents the configuration for exporting
+// metrics to graphite (carbon).
+type GraphiteConfiguration struct {
+	Connect  config.Addr
+	Interval config.Duration
+	Protocol string
+	Prefix   string
+	Exclude  []string
+}
+
+// UnmarshalYAML parses a configuration for graphite from YAML.
+func (c *GraphiteConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawGraphiteConfiguration GraphiteConfiguration
+	raw := rawGraphiteConfiguration{
+		Protocol: "plaintext",
+		Prefix:   "{fqdn}",
+	}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "unable to decode graphite configuration")
+	}
+	if raw.Connect == "" {
+		return errors.Errorf("missing connect value for graphite configuration")
+	}
+	if raw.Interval == config.Duration(0) {
+		return errors.Errorf("missing interval value for graphite configuration")
+	}
+	if raw.Protocol != "plaintext" && raw.Protocol != "pickle" {
+		return errors.Errorf("invalid protocol %q for graphite configuration", raw.Protocol)
+	}
+	*c = GraphiteConfiguration(raw)
+	return nil
+}
+
+// initExporter initializes graphite reporter. The connection is
+// established at the first flush and re-established with an
+// exponential backoff when it fails.
+func (c *GraphiteConfiguration) initExporter(m *Metrics) error {
+	prefix := c.Prefix
+	if strings.Contains(prefix, "{fqdn}") {
+		hostname, err := config.GetFQDN()
+		if err != nil {
+			return errors.Wrap(err, "unable to get FQDN for graphite prefix")
+		}
+		prefix = strings.Replace(prefix, "{fqdn}", strings.Replace(hostname, ".", "_", -1), -1)
+	}
+	if m.prefix != "" {
+		prefix = strings.Trim(strings.Join([]string{prefix, m.prefix}, separator), separator)
+	}
+
+	client := &graphiteClient{address: c.Connect.String()}
+	m.t.Go(func() error {
+		tick := time.NewTicker(time.Duration(c.Interval))
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				graphiteReportOnce(m.Registry, client, c.Protocol, prefix, c.Exclude)
+			case <-m.t.Dying():
+				client.close()
+				return nil
+			}
+		}
+	})
+
+	return nil
+}
+
+// graphiteDatapoint is a value of a graphite metric.
+type graphiteDatapoint struct {
+	path  string
+	value float64
+}
+
+// graphiteReportOnce will export the current metrics to graphite.
+func graphiteReportOnce(r metrics.Registry, client *graphiteClient, protocol string,
+	prefix string, excluded []string) {
+	datapoints := graphiteDatapoints(r, prefix, excluded)
+	if len(datapoints) == 0 {
+		return
+	}
+	now := time.Now()
+	var payloads [][]byte
+	if protocol == "pickle" {
+		payloads = graphitePickle(datapoints, now)
+	} else {
+		payloads = [][]byte{graphitePlaintext(datapoints, now)}
+	}
+	for _, payload := range payloads {
+		if err := client.send(payload); err != nil {
+			metrics.GetOrRegisterMeter(
+				"github.com/exoscale/go-reporter.metrics.graphite.failed-writes",
+				r).Mark(int64(len(datapoints)))
+			return
+		}
+	}
+	metrics.GetOrRegisterMeter(
+		"github.com/exoscale/go-reporter.metrics.graphite.sent-metrics",
+		r).Mark(int64(len(datapoints)))
+}
+
+// graphiteDatapoints flattens the metrics of the registry into
+// graphite datapoints. Meters, histograms and timers get one series
+// per value, suffixed with its name, as for collectd.
+func graphiteDatapoints(r metrics.Registry, prefix string, excluded []string) []graphiteDatapoint {
+	var datapoints []graphiteDatapoint
+	r.Each(func(name string, i interface{}) {
+		// Filter metrics matching any configured pattern
+		// Any error parsing an exclusion pattern is silently ignored.
+		for _, pattern := range excluded {
+			if matched, _ := path.Match(pattern, name); matched {
+				return
+			}
+		}
+
+		metricPath := graphitePath(prefix, name)
+		add := func(suffix string, value float64) {
+			if suffix != "" {
+				suffix = separator + suffix
+			}
+			datapoints = append(datapoints, graphiteDatapoint{metricPath + suffix, value})
+		}
+		switch metric := i.(type) {
+		case metrics.Gauge:
+			add("", float64(metric.Value()))
+		case metrics.GaugeFloat64:
+			add("", metric.Value())
+		case metrics.Counter:
+			add("", float64(metric.Count()))
+		case metrics.Meter:
+			add("count", float64(metric.Count()))
+			add("m1_rate", metric.Rate1())
+			add("m5_rate", metric.Rate5())
+			add("m15_rate", metric.Rate15())
+			add("mean_rate", metric.RateMean())
+		case metrics.Histogram:
+			add("count", float64(metric.Count()))
+			add("max", float64(metric.Max()))
+			add("mean", metric.Mean())
+			add("min", float64(metric.Min()))
+			add("stddev", metric.StdDev())
+			for i, p := range metric.Percentiles(graphitePercentiles) {
+				add(graphitePercentilesNames[i], p)
+			}
+			graphiteBuckets(add, metric)
+		case metrics.Timer:
+			add("max", float64(metric.Max()))
+			add("mean", metric.Mean())
+			add("min", float64(metric.Min()))
+			add("stddev", metric.StdDev())
+			for i, p := range metric.Percentiles(graphitePercentiles) {
+				add(graphitePercentilesNames[i], p)
+			}
+			graphiteBuckets(add, metric)
+		}
+	})
+	return datapoints
+}
+
+// graphiteBuckets adds the series of the buckets of a histogram or
+// timer counting its values in buckets (if any): the cumulative count
+// of values for each bucket, dots of bounds replaced by underscores.
+func graphiteBuckets(add func(string, float64), metric interface{}) {
+	b, ok := metric.(bucketed)
+	if !ok {
+		return
+	}
+	bounds, counts := b.Buckets()
+	for i, bound := range bounds {
+		add(strings.Replace(bucketName(bound), ".", "_", -1), float64(counts[i]))
+	}
+}
+
+// graphitePath returns the graphite path of a metric. Label values
+// of labeled metrics are appended to the name.
+func graphitePath(prefix, name string) string {
+	parts := make([]string, 0, 2)
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	base, labels := decodeLabels(name)
+	parts = append(parts, base)
+	if labels != nil {
+		for _, v := range labelsValues(labels) {
+			parts = append(parts, strings.Replace(v, ".", "_", -1))
+		}
+	}
+	return graphiteName(strings.Join(parts, separator))
+}
+
+// graphitePlaintext encodes datapoints with the plaintext protocol.
+func graphitePlaintext(datapoints []graphiteDatapoint, now time.Time) []byte {
+	var buf bytes.Buffer
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	for _, dp := range datapoints {
+		buf.WriteString(dp.path)
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(dp.value, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(timestamp)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// graphitePickle encodes datapoints with the pickle protocol: each
+// payload is a length-prefixed pickle (protocol 2) of a list of
+// (path, (timestamp, value)) tuples.
+func graphitePickle(datapoints []graphiteDatapoint, now time.Time) [][]byte {
+	var payloads [][]byte
+	for len(datapoints) > 0 {
+		n := len(datapoints)
+		if n > graphiteMaxPickleDatapoints {
+			n = graphiteMaxPickleDatapoints
+		}
+		var buf bytes.Buffer
+		buf.Write([]byte{0, 0, 0, 0}) // length header
+		buf.Write([]byte{0x80, 2})    // PROTO 2
+		buf.WriteByte(']')            // EMPTY_LIST
+		buf.WriteByte('(')            // MARK
+		for _, dp := range datapoints[:n] {
+			var size [4]byte
+			binary.LittleEndian.PutUint32(size[:], uint32(len(dp.path)))
+			buf.WriteByte('X') // BINUNICODE
+			buf.Write(size[:])
+			buf.WriteString(dp.path)
+			for _, f := range []float64{float64(now.Unix()), dp.value} {
+				var value [8]byte
+				binary.BigEndian.PutUint64(value[:], math.Float64bits(f))
+				buf.WriteByte('G') // BINFLOAT
+				buf.Write(value[:])
+			}
+			buf.WriteByte(0x86) // TUPLE2
+			buf.WriteByte(0x86) // TUPLE2
+		}
+		buf.WriteByte('e') // APPENDS
+		buf.WriteByte('.') // STOP
+		payload := buf.Bytes()
+		binary.BigEndian.PutUint32(payload, uint32(len(payload)-4))
+		payloads = append(payloads, payload)
+		datapoints = datapoints[n:]
+	}
+	return payloads
+}
+
+// graphiteClient is a TCP connection to graphite, re-established
+// with an exponential backoff.
+type graphiteClient struct {
+	address string
+	conn    net.Conn
+	backoff time.Duration
+	retryAt time.Time
+}
+
+// send writes a payload to graphite, connecting if needed.
+func (c *graphiteClient) send(payload []byte) error {
+	if c.conn == nil {
+		now := time.Now()
+		if now.Before(c.retryAt) {
+			return errGraphiteDisconnected
+		}
+		conn, err := net.DialTimeout("tcp", c.address, graphiteTimeout)
+		if err != nil {
+			c.backoff *= 2
+			if c.backoff < graphiteMinBackoff {
+				c.backoff = graphiteMinBackoff
+			} else if c.backoff > graphiteMaxBackoff {
+				c.backoff = graphiteMaxBackoff
+			}
+			c.retryAt = now.Add(c.backoff)
+			return errors.Wrapf(err, "unable to connect to graphite (%v)", c.address)
+		}
+		c.conn = conn
+		c.backoff = 0
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(graphiteTimeout)) // nolint: errcheck
+	if _, err := c.conn.Write(payload); err != nil {
+		// Reconnect at next flush
+		c.close()
+		return errors.Wrap(err, "unable to write to graphite")
+	}
+	return nil
+}
+
+func (c *graphiteClient) close() {
+	if c.conn != nil {
+		c.conn.Close() // nolint: errcheck
+		c.conn = nil
+	}
+}
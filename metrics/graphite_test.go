@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+func TestGraphite(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen:\n%+v", err)
+	}
+	defer l.Close()
+
+	var configuration Configuration = make([]ExporterConfiguration, 1)
+	configuration[0] = &GraphiteConfiguration{
+		Connect:  config.Addr(l.Addr().String()),
+		Interval: config.Duration(500 * time.Millisecond),
+		Protocol: "plaintext",
+		Prefix:   "graphite",
+		Exclude:  []string{"go.*", "github.com/*"},
+	}
+	m, err := New(configuration, "project")
+	if err != nil {
+		t.Fatalf("New(%v) error:\n%+v", configuration, err)
+	}
+	m.MustStart()
+	defer m.Stop() // nolint: errcheck
+
+	metrics.NewRegisteredCounter(`foo{code="200"}`, m.Registry).Inc(47)
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Unable to accept connection:\n%+v", err)
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Unable to read from connection:\n%+v", err)
+	}
+	if !regexp.MustCompile(`^graphite\.project\.foo\.200 47 \d+\n$`).MatchString(line) {
+		t.Errorf("Received %q", line)
+	}
+}
+
+func TestGraphiteDatapoints(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredGauge(`gauge{code="2.0"}`, r).Update(3)
+	metrics.NewRegisteredMeter("meter", r).Mark(1)
+	metrics.NewRegisteredCounter("excluded", r).Inc(1)
+
+	var got []string
+	for _, dp := range graphiteDatapoints(r, "host_example_net", []string{"excluded"}) {
+		got = append(got, dp.path)
+	}
+	sort.Strings(got)
+	want := []string{
+		"host_example_net.gauge.2_0",
+		"host_example_net.meter.count",
+		"host_example_net.meter.m15_rate",
+		"host_example_net.meter.m1_rate",
+		"host_example_net.meter.m5_rate",
+		"host_example_net.meter.mean_rate",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("graphiteDatapoints() == %q but expected %q", got, want)
+	}
+}
+
+func TestGraphiteReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen:\n%+v", err)
+	}
+	address := l.Addr().String()
+	l.Close() // nolint: errcheck
+
+	client := &graphiteClient{address: address}
+	if err := client.send([]byte("foo 1 0\n")); err == nil {
+		t.Fatalf("send() should fail without server")
+	}
+	if client.backoff != graphiteMinBackoff {
+		t.Errorf("backoff == %v but expected %v", client.backoff, graphiteMinBackoff)
+	}
+	if err := client.send([]byte("foo 1 0\n")); err != errGraphiteDisconnected {
+		t.Errorf("send() == %v but expected %v", err, errGraphiteDisconnected)
+	}
+
+	l, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("Unable to listen:\n%+v", err)
+	}
+	defer l.Close()
+	client.retryAt = time.Time{}
+	if err := client.send([]byte("foo 1 0\n")); err != nil {
+		t.Errorf("send() error:\n%+v", err)
+	}
+	client.close()
+}
+
+func TestGraphitePickle(t *testing.T) {
+	payloads := graphitePickle([]graphiteDatapoint{{"a", 1}}, time.Unix(0, 0))
+	want := []byte{0, 0, 0, 32, 0x80, 2, ']', '(', 'X', 1, 0, 0, 0, 'a',
+		'G', 0, 0, 0, 0, 0, 0, 0, 0,
+		'G', 0x3f, 0xf0, 0, 0, 0, 0, 0, 0,
+		0x86, 0x86, 'e', '.'}
+	if len(payloads) != 1 || !reflect.DeepEqual(payloads[0], want) {
+		t.Errorf("graphitePickle() == %v but expected %v", payloads, want)
+	}
+}
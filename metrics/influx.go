@@ -0,0 +1,343 @@
+package metrics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+const (
+	influxRetryBackoff  = time.Second
+	influxTimeout       = 5 * time.Second
+	influxMaxPacketSize = 1432 // Ethernet MTU minus IPv6 and UDP headers
+)
+
+var (
+	influxPercentiles        = []float64{0.5, 0.75, 0.95, 0.98, 0.99, 0.999}
+	influxPercentilesNames   = []string{"p50", "p75", "p95", "p98", "p99", "p999"}
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	influxKeyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+// InfluxConfiguration represents the configuration for exporting
+// metrics to InfluxDB.
+type InfluxConfiguration struct {
+	URL             string
+	APIVersion      string
+	Database        string
+	RetentionPolicy string
+	Org             string
+	Bucket          string
+	Token           string
+	Username        string
+	Password        string
+	Tags            map[string]string
+	Interval        config.Duration
+	BatchSize       int
+	Gzip            bool
+	Retries         int
+	Exclude         []string
+}
+
+// UnmarshalYAML parses a configuration for InfluxDB from YAML.
+func (c *InfluxConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawInfluxConfiguration InfluxConfiguration
+	raw := rawInfluxConfiguration{
+		APIVersion: "v1",
+		BatchSize:  5000,
+		Retries:    3,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "unable to decode influx configuration")
+	}
+	if raw.Interval == config.Duration(0) {
+		return errors.Errorf("missing interval value for influx configuration")
+	}
+	u, err := url.Parse(raw.URL)
+	if err != nil || raw.URL == "" {
+		return errors.Errorf("missing or invalid url value for influx configuration")
+	}
+	switch u.Scheme {
+	case "udp":
+	case "http", "https":
+		switch raw.APIVersion {
+		case "v1":
+			if raw.Database == "" {
+				return errors.Errorf("missing database value for influx configuration")
+			}
+		case "v2":
+			if raw.Org == "" || raw.Bucket == "" {
+				return errors.Errorf("missing org or bucket value for influx configuration")
+			}
+		default:
+			return errors.Errorf("invalid api version %q for influx configuration", raw.APIVersion)
+		}
+	default:
+		return errors.Errorf("invalid url scheme %q for influx configuration", u.Scheme)
+	}
+	if raw.Token != "" && raw.Username != "" {
+		return errors.Errorf("token and username are mutually exclusive for influx configuration")
+	}
+	*c = InfluxConfiguration(raw)
+	return nil
+}
+
+// initExporter initializes InfluxDB reporter.
+func (c *InfluxConfiguration) initExporter(m *Metrics) error {
+	hostname, err := config.GetFQDN()
+	if err != nil {
+		return errors.Wrap(err, "unable to get FQDN for influx host tag")
+	}
+	s := &influxState{
+		config:  c,
+		tags:    map[string]string{"host": hostname},
+		backoff: influxRetryBackoff,
+		dying:   m.t.Dying(),
+	}
+	if m.prefix != "" {
+		s.tags["prefix"] = m.prefix
+	}
+	for k, v := range c.Tags {
+		s.tags[k] = v
+	}
+
+	u, _ := url.Parse(c.URL)
+	var conn net.Conn
+	if u.Scheme == "udp" {
+		if conn, err = net.Dial("udp", u.Host); err != nil {
+			return errors.Wrapf(err, "unable to connect to influx (%v)", u.Host)
+		}
+	} else {
+		query := url.Values{}
+		endpoint := "/write"
+		if c.APIVersion == "v2" {
+			endpoint = "/api/v2/write"
+			query.Set("org", c.Org)
+			query.Set("bucket", c.Bucket)
+		} else {
+			query.Set("db", c.Database)
+			if c.RetentionPolicy != "" {
+				query.Set("rp", c.RetentionPolicy)
+			}
+		}
+		s.writeURL = strings.TrimSuffix(c.URL, "/") + endpoint + "?" + query.Encode()
+		s.client = &http.Client{Timeout: influxTimeout}
+	}
+
+	m.t.Go(func() error {
+		tick := time.NewTicker(time.Duration(c.Interval))
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				lines := s.lines(m.Registry, time.Now())
+				var batches [][]byte
+				if conn != nil {
+					batches = statsdBatch(lines, influxMaxPacketSize)
+				} else {
+					for len(lines) > 0 {
+						n := len(lines)
+						if n > c.BatchSize {
+							n = c.BatchSize
+						}
+						batches = append(batches, []byte(strings.Join(lines[:n], "\n")))
+						lines = lines[n:]
+					}
+				}
+				failed := 0
+				for _, b := range batches {
+					if conn != nil {
+						_, err = conn.Write(b)
+					} else {
+						err = s.write(b)
+					}
+					if err != nil {
+						failed++
+					}
+				}
+				if failed > 0 {
+					metrics.GetOrRegisterMeter(
+						"github.com/exoscale/go-reporter.metrics.influx.failed-writes",
+						m.Registry).Mark(int64(failed))
+				}
+			case <-m.t.Dying():
+				if conn != nil {
+					conn.Close() // nolint: errcheck
+				}
+				return nil
+			}
+		}
+	})
+
+	return nil
+}
+
+// influxState holds the state of the InfluxDB exporter.
+type influxState struct {
+	config   *InfluxConfiguration
+	tags     map[string]string
+	writeURL string
+	client   *http.Client
+	backoff  time.Duration
+	dying    <-chan struct{}
+}
+
+// write writes a batch of lines to the HTTP write API. Network
+// errors, 429 and 5xx responses are retried with an exponential
+// backoff.
+func (s *influxState) write(body []byte) error {
+	if s.config.Gzip {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(body) // nolint: errcheck
+		if err := gz.Close(); err != nil {
+			return errors.Wrap(err, "unable to compress influx request")
+		}
+		body = buf.Bytes()
+	}
+	backoff := s.backoff
+	for retry := 0; ; retry++ {
+		retryable, err := s.post(body)
+		if err == nil || !retryable || retry >= s.config.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-s.dying:
+			return err
+		}
+	}
+}
+
+// post sends a write request, and tells if it can be retried.
+func (s *influxState) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.writeURL, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(err, "unable to build influx request")
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.config.Token)
+	} else if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return true, errors.Wrap(err, "unable to write to influx")
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 == 2 {
+		return false, nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500,
+		errors.Errorf("unexpected influx response status %q: %s", res.Status, bytes.TrimSpace(msg))
+}
+
+// lines returns the line protocol lines of the metrics of the
+// registry. Each metric is a single point, meters, histograms and
+// timers values being fields of the point. Labels are tags.
+func (s *influxState) lines(r metrics.Registry, now time.Time) []string {
+	var lines []string
+	timestamp := strconv.FormatInt(now.UnixNano(), 10)
+	r.Each(func(name string, i interface{}) {
+		// Any error parsing an exclusion pattern is silently ignored.
+		for _, pattern := range s.config.Exclude {
+			if matched, _ := path.Match(pattern, name); matched {
+				return
+			}
+		}
+
+		var fields []string
+		intField := func(key string, value int64) {
+			fields = append(fields, key+"="+strconv.FormatInt(value, 10)+"i")
+		}
+		floatField := func(key string, value float64) {
+			// NaN and infinite values are not supported by InfluxDB
+			if !math.IsNaN(value) && !math.IsInf(value, 0) {
+				fields = append(fields, key+"="+strconv.FormatFloat(value, 'f', -1, 64))
+			}
+		}
+		distribution := func(count, min, max int64, mean, stddev float64, ps []float64) {
+			intField("count", count)
+			intField("min", min)
+			intField("max", max)
+			floatField("mean", mean)
+			floatField("stddev", stddev)
+			for i, p := range ps {
+				floatField(influxPercentilesNames[i], p)
+			}
+		}
+		buckets := func(snapshot interface{}) {
+			if b, ok := snapshot.(bucketed); ok {
+				bounds, counts := b.Buckets()
+				for i, bound := range bounds {
+					intField(bucketName(bound), int64(counts[i]))
+				}
+			}
+		}
+		switch metric := i.(type) {
+		case metrics.Gauge:
+			intField("value", metric.Value())
+		case metrics.GaugeFloat64:
+			floatField("value", metric.Value())
+		case metrics.Counter:
+			intField("count", metric.Count())
+		case metrics.Meter:
+			intField("count", metric.Count())
+			floatField("m1_rate", metric.Rate1())
+			floatField("m5_rate", metric.Rate5())
+			floatField("m15_rate", metric.Rate15())
+			floatField("mean_rate", metric.RateMean())
+		case metrics.Histogram:
+			h := metric.Snapshot()
+			distribution(h.Count(), h.Min(), h.Max(), h.Mean(), h.StdDev(),
+				h.Percentiles(influxPercentiles))
+			buckets(h)
+		case metrics.Timer:
+			t := metric.Snapshot()
+			distribution(t.Count(), t.Min(), t.Max(), t.Mean(), t.StdDev(),
+				t.Percentiles(influxPercentiles))
+			buckets(t)
+		}
+		if len(fields) == 0 {
+			return
+		}
+
+		measurement, labels := decodeLabels(name)
+		// Duplicate tag keys are rejected: labels take precedence
+		tags := make(map[string]string, len(s.tags)+len(labels))
+		for k, v := range s.tags {
+			tags[k] = v
+		}
+		for k, v := range labels {
+			tags[k] = v
+		}
+		line := influxMeasurementEscaper.Replace(measurement)
+		for _, k := range labelsKeys(tags) {
+			if tags[k] != "" {
+				line += "," + influxKeyEscaper.Replace(k) + "=" + influxKeyEscaper.Replace(tags[k])
+			}
+		}
+		lines = append(lines, line+" "+strings.Join(fields, ",")+" "+timestamp)
+	})
+	return lines
+}
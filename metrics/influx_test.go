@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+func TestInflux(t *testing.T) {
+	bodies := make(chan string, 10)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/write" || r.URL.Query().Get("db") != "test" {
+			t.Errorf("Unexpected request %v", r.URL)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			t.Errorf("Unexpected credentials %q:%q", user, pass)
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("Unable to decompress body:\n%+v", err)
+		}
+		body, _ := ioutil.ReadAll(gz)
+		// Fail the first request to test retries
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		bodies <- string(body)
+	}))
+	defer server.Close()
+
+	var configuration Configuration = make([]ExporterConfiguration, 1)
+	configuration[0] = &InfluxConfiguration{
+		URL:        server.URL,
+		APIVersion: "v1",
+		Database:   "test",
+		Username:   "user",
+		Password:   "pass",
+		Interval:   config.Duration(500 * time.Millisecond),
+		BatchSize:  100,
+		Gzip:       true,
+		Retries:    3,
+		Exclude:    []string{"go.*", "github.com/*"},
+	}
+	m, err := New(configuration, "project")
+	if err != nil {
+		t.Fatalf("New(%v) error:\n%+v", configuration, err)
+	}
+	m.MustStart()
+	defer m.Stop() // nolint: errcheck
+
+	metrics.NewRegisteredCounter(`foo{code="200"}`, m.Registry).Inc(47)
+
+	select {
+	case body := <-bodies:
+		if !regexp.MustCompile(`^foo,code=200,host=[^ ]+,prefix=project count=47i \d+$`).MatchString(body) {
+			t.Errorf("Received %q", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timeout waiting for metrics")
+	}
+}
+
+func TestInfluxLines(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredGaugeFloat64(`gauge{host="other",path="/a b"}`, r).Update(1.5)
+	metrics.NewRegisteredHistogram("histogram", r, metrics.NewUniformSample(10)).Update(3)
+
+	s := &influxState{
+		config: &InfluxConfiguration{},
+		tags:   map[string]string{"host": "test"},
+	}
+	got := s.lines(r, time.Unix(0, 42))
+	sort.Strings(got)
+	want := []string{
+		`gauge,host=other,path=/a\ b value=1.5 42`,
+		"histogram,host=test count=1i,min=3i,max=3i,mean=3,stddev=0,p50=3,p75=3,p95=3,p98=3,p99=3,p999=3 42",
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("lines() == %q but expected %q", got, want)
+	}
+}
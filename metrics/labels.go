@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Labeled metrics are registered under their name followed by their
+// labels in a Prometheus-like notation, labels being sorted by key:
+//
+//     http.requests{code="200",method="GET"}
+//
+// Exporters decode such names to export the labels in the most
+// appropriate way for their destination.
+
+const (
+	// maxLabelSets is the maximum number of distinct label sets per
+	// metric name. Once reached, new label sets are folded into an
+	// overflow series.
+	maxLabelSets = 1000
+
+	// labelsOverflowValue is the label value of the overflow series.
+	labelsOverflowValue = "_overflow"
+)
+
+// labelsString returns the encoded representation of labels.
+func labelsString(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := labelsKeys(labels)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelsKeys returns the sorted keys of labels.
+func labelsKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelsValues returns the values of labels, sorted by key.
+func labelsValues(labels map[string]string) []string {
+	keys := labelsKeys(labels)
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = labels[k]
+	}
+	return values
+}
+
+// decodeLabels returns the metric name and the labels encoded in a
+// registry name. If the registry name doesn't contain labels (or if
+// they can't be decoded), it is returned unchanged with nil labels.
+func decodeLabels(s string) (string, map[string]string) {
+	i := strings.IndexByte(s, '{')
+	if i < 0 || !strings.HasSuffix(s, "}") {
+		return s, nil
+	}
+	name, rest := s[:i], s[i+1:len(s)-1]
+	labels := make(map[string]string)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return s, nil
+		}
+		quoted, err := strconv.QuotedPrefix(rest[eq+1:])
+		if err != nil {
+			return s, nil
+		}
+		labels[rest[:eq]], _ = strconv.Unquote(quoted)
+		rest = strings.TrimPrefix(rest[eq+1+len(quoted):], ",")
+	}
+	return name, labels
+}
+
+// labelLimiter keeps track of the label sets of each metric name to
+// bound their cardinality.
+type labelLimiter struct {
+	sync.Mutex
+	sets map[string]*labelSets
+}
+
+type labelSets struct {
+	keys   string
+	series map[string]struct{}
+}
+
+// LabeledName returns the registry name of the metric name with the
+// labels specified as a list of alternating keys and values. All the
+// label sets of a metric must have the same keys and there can be at
+// most 1000 of them: offending label sets are replaced by an overflow
+// label set whose values are all "_overflow". Invalid names and label
+// keys are sanitized.
+func (m *Metrics) LabeledName(name string, pairs ...string) string {
+	labels := make(map[string]string, (len(pairs)+1)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		var v string
+		if i+1 < len(pairs) {
+			v = pairs[i+1]
+		}
+		labels[pairs[i]] = v
+	}
+	name, labels = m.validName(name, labels)
+	if len(labels) == 0 {
+		return name
+	}
+	encoded := labelsString(labels)
+	keys := strings.Join(labelsKeys(labels), ",")
+
+	m.labels.Lock()
+	defer m.labels.Unlock()
+	if m.labels.sets == nil {
+		m.labels.sets = make(map[string]*labelSets)
+	}
+	sets, ok := m.labels.sets[name]
+	if !ok {
+		m.labels.sets[name] = &labelSets{
+			keys:   keys,
+			series: map[string]struct{}{encoded: {}},
+		}
+		return name + encoded
+	}
+	if _, ok := sets.series[encoded]; ok {
+		return name + encoded
+	}
+	if sets.keys == keys && len(sets.series) < maxLabelSets {
+		sets.series[encoded] = struct{}{}
+		return name + encoded
+	}
+
+	metrics.GetOrRegisterCounter(
+		"github.com/exoscale/go-reporter.metrics.labels.overflow",
+		m.Registry).Inc(1)
+	overflow := make(map[string]string)
+	for _, k := range strings.Split(sets.keys, ",") {
+		overflow[k] = labelsOverflowValue
+	}
+	return name + labelsString(overflow)
+}
+
+// forgetLabels removes the label set of an unregistered metric from
+// the label sets of its name, freeing room for new ones.
+func (m *Metrics) forgetLabels(name string) {
+	name, labels := decodeLabels(name)
+	if len(labels) == 0 {
+		return
+	}
+	m.labels.Lock()
+	defer m.labels.Unlock()
+	if sets, ok := m.labels.sets[name]; ok {
+		delete(sets.series, labelsString(labels))
+		if len(sets.series) == 0 {
+			delete(m.labels.sets, name)
+		}
+	}
+}
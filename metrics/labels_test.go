@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+func TestDecodeLabels(t *testing.T) {
+	cases := []struct {
+		in     string
+		name   string
+		labels map[string]string
+	}{
+		{"foo", "foo", nil},
+		{`foo{code="200",method="GET"}`, "foo", map[string]string{"code": "200", "method": "GET"}},
+		{`foo{path="/a\"b,c={d}"}`, "foo", map[string]string{"path": `/a"b,c={d}`}},
+		{`foo{method=GET}`, `foo{method=GET}`, nil},
+		{`foo{method}`, `foo{method}`, nil},
+	}
+	for _, c := range cases {
+		name, labels := decodeLabels(c.in)
+		if name != c.name || !reflect.DeepEqual(labels, c.labels) {
+			t.Errorf("decodeLabels(%q) == %q, %v but expected %q, %v",
+				c.in, name, labels, c.name, c.labels)
+		}
+	}
+}
+
+func TestLabeledName(t *testing.T) {
+	m, err := New(nil, "project")
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	cases := []struct {
+		pairs []string
+		want  string
+	}{
+		{nil, "foo"},
+		{[]string{"method", "GET", "code", "200"}, `foo{code="200",method="GET"}`},
+		{[]string{"code", "200", "method", "GET"}, `foo{code="200",method="GET"}`},
+		// Inconsistent label keys
+		{[]string{"method", "GET"}, `foo{code="_overflow",method="_overflow"}`},
+	}
+	for _, c := range cases {
+		got := m.LabeledName("foo", c.pairs...)
+		if got != c.want {
+			t.Errorf("LabeledName(%q, %q) == %q but expected %q", "foo", c.pairs, got, c.want)
+		}
+	}
+
+	// Too many label sets
+	for i := 0; i < maxLabelSets; i++ {
+		m.LabeledName("bar", "id", strconv.Itoa(i))
+	}
+	if got := m.LabeledName("bar", "id", "last"); got != `bar{id="_overflow"}` {
+		t.Errorf("LabeledName() == %q but expected overflow", got)
+	}
+	overflow := m.Registry.Get("github.com/exoscale/go-reporter.metrics.labels.overflow")
+	if overflow == nil || overflow.(metrics.Counter).Count() != 2 {
+		t.Errorf("Expected 2 label sets overflows, got %v", overflow)
+	}
+}
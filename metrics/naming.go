@@ -0,0 +1,290 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+	log "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Names of the metrics created using the reporter's helper methods
+// are validated when registered: they are dot-separated paths of
+// printable, non-space characters, braces and double quotes being
+// reserved for the labels encoding, while label keys are made of
+// letters, digits and underscores and don't start with a digit.
+// Invalid names and label keys are sanitized by replacing the
+// offending characters by underscores and dropping empty path
+// components.
+//
+// Exporters then sanitize names and label values with their own
+// rules, defined below, so that a name is mangled the same way
+// wherever it is exported to the same kind of destination.
+
+// maxOffenders is the maximum number of offending metric names
+// logged. Further offenders are only counted.
+const maxOffenders = 1000
+
+// sanitize replaces the runes of s for which valid returns false by
+// underscores. valid is also given the byte offset of the rune.
+// Invalid UTF-8 sequences are replaced as well.
+func sanitize(s string, valid func(i int, r rune) bool) string {
+	clean := true
+	for i, r := range s {
+		if r == utf8.RuneError || !valid(i, r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i, r := range s {
+		if r == utf8.RuneError || !valid(i, r) {
+			b.WriteByte('_')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// validNameRune reports whether r is allowed in a registered metric
+// name.
+func validNameRune(_ int, r rune) bool {
+	return unicode.IsPrint(r) && !unicode.IsSpace(r) && r != '{' && r != '}' && r != '"'
+}
+
+// validLabelKeyRune reports whether r is allowed at offset i in a
+// label key.
+func validLabelKeyRune(i int, r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9'
+}
+
+// sanitizeName returns the valid metric name closest to name.
+func sanitizeName(name string) string {
+	parts := strings.Split(sanitize(name, validNameRune), separator)
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	if len(kept) == 0 {
+		return "_"
+	}
+	return strings.Join(kept, separator)
+}
+
+// sanitizeLabelKey returns the valid label key closest to key.
+func sanitizeLabelKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return sanitize(key, validLabelKeyRune)
+}
+
+// prometheusName replaces characters invalid in Prometheus metric
+// names by underscores.
+func prometheusName(name string) string {
+	return sanitize(name, func(i int, r rune) bool {
+		return r == ':' || validLabelKeyRune(i, r)
+	})
+}
+
+// prometheusLabelName replaces characters invalid in Prometheus label
+// names by underscores.
+func prometheusLabelName(key string) string {
+	return sanitize(key, validLabelKeyRune)
+}
+
+// graphiteName replaces characters invalid in graphite paths by
+// underscores.
+func graphiteName(name string) string {
+	return sanitize(name, func(_ int, r rune) bool {
+		return !unicode.IsSpace(r) && !unicode.IsControl(r) && r != ';'
+	})
+}
+
+// statsdName replaces characters invalid in StatsD metric names by
+// underscores.
+func statsdName(name string) string {
+	return sanitize(name, func(_ int, r rune) bool {
+		return !unicode.IsSpace(r) && !unicode.IsControl(r) && !strings.ContainsRune(":|@,#", r)
+	})
+}
+
+// statsdTag returns a DogStatsD tag from a key and a value, replacing
+// characters invalid in tags by underscores.
+func statsdTag(key, value string) string {
+	return sanitize(key+":"+value, func(_ int, r rune) bool {
+		return !unicode.IsControl(r) && !strings.ContainsRune(",|#", r)
+	})
+}
+
+// collectdName replaces characters invalid in collectd plugin names
+// by underscores: dashes separate the plugin instance and slashes
+// separate the identifier parts.
+func collectdName(name string) string {
+	return sanitize(name, func(_ int, r rune) bool {
+		return !unicode.IsControl(r) && r != '-' && r != '/'
+	})
+}
+
+// collectdInstanceName replaces characters invalid in collectd plugin
+// instances by underscores.
+func collectdInstanceName(name string) string {
+	return sanitize(name, func(_ int, r rune) bool {
+		return !unicode.IsControl(r) && r != '/'
+	})
+}
+
+// SeriesConfiguration is the limit of the number of distinct series
+// created using the reporter's helper methods. Once Max series exist,
+// new series are rejected: the helper methods return metrics
+// discarding their updates. When Overflow is "alias", new labeled
+// series are instead folded into the overflow series of their name,
+// whose label values are all "_overflow".
+type SeriesConfiguration struct {
+	Max      int
+	Overflow string
+}
+
+// UnmarshalYAML parses a series configuration from YAML.
+func (c *SeriesConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawSeriesConfiguration SeriesConfiguration
+	raw := rawSeriesConfiguration{Overflow: "reject"}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "unable to decode series configuration")
+	}
+	if raw.Max <= 0 {
+		return errors.Errorf("missing or invalid max value for series configuration")
+	}
+	switch raw.Overflow {
+	case "reject", "alias":
+	default:
+		return errors.Errorf("unknown overflow %q for series configuration", raw.Overflow)
+	}
+	*c = SeriesConfiguration(raw)
+	return nil
+}
+
+// SetSeries limits the number of distinct series admitted by
+// LimitSeries.
+func (m *Metrics) SetSeries(c SeriesConfiguration) {
+	m.naming.Lock()
+	defer m.naming.Unlock()
+	m.naming.series = &c
+	m.naming.names = make(map[string]struct{})
+}
+
+// SetLogger sets the logger used to report offending metric names,
+// once per name.
+func (m *Metrics) SetLogger(logger log.Logger) {
+	m.naming.Lock()
+	defer m.naming.Unlock()
+	m.naming.logger = logger
+}
+
+// LimitSeries returns the registry name of a series subject to the
+// series limit: either the given name, or the name of the overflow
+// series it is aliased to. It returns false if the series is
+// rejected.
+func (m *Metrics) LimitSeries(name string) (string, bool) {
+	m.naming.Lock()
+	defer m.naming.Unlock()
+	c := m.naming.series
+	if c == nil {
+		return name, true
+	}
+	if _, ok := m.naming.names[name]; ok {
+		return name, true
+	}
+	if len(m.naming.names) < c.Max {
+		m.naming.names[name] = struct{}{}
+		return name, true
+	}
+
+	metrics.GetOrRegisterCounter(
+		"github.com/exoscale/go-reporter.metrics.series.overflow",
+		m.Registry).Inc(1)
+	base, labels := decodeLabels(name)
+	if c.Overflow == "alias" && len(labels) > 0 {
+		for k := range labels {
+			labels[k] = labelsOverflowValue
+		}
+		alias := base + labelsString(labels)
+		m.warnOnce(base, "too many metric series, aliasing to the overflow series",
+			"max", c.Max, "alias", alias)
+		return alias, true
+	}
+	m.warnOnce(base, "too many metric series, rejecting new ones", "max", c.Max)
+	return "", false
+}
+
+// forgetSeries removes an unregistered metric from the series
+// admitted by LimitSeries, freeing room for new ones.
+func (m *Metrics) forgetSeries(name string) {
+	m.naming.Lock()
+	defer m.naming.Unlock()
+	delete(m.naming.names, name)
+}
+
+// validName returns the sanitized metric name and label keys,
+// reporting the offending ones.
+func (m *Metrics) validName(name string, labels map[string]string) (string, map[string]string) {
+	if valid := sanitizeName(name); valid != name {
+		m.naming.Lock()
+		m.warnOnce(name, "invalid metric name", "sanitized", valid)
+		m.naming.Unlock()
+		name = valid
+	}
+	for k, v := range labels {
+		if valid := sanitizeLabelKey(k); valid != k {
+			m.naming.Lock()
+			m.warnOnce(name, "invalid label key", "key", k, "sanitized", valid)
+			m.naming.Unlock()
+			delete(labels, k)
+			labels[valid] = v
+		}
+	}
+	return name, labels
+}
+
+// warnOnce logs a warning about an offending metric name, the first
+// time only. The naming lock must be held.
+func (m *Metrics) warnOnce(name, msg string, ctx ...interface{}) {
+	if m.naming.logger == nil {
+		return
+	}
+	if _, ok := m.naming.warned[name]; ok || len(m.naming.warned) >= maxOffenders {
+		return
+	}
+	if m.naming.warned == nil {
+		m.naming.warned = make(map[string]struct{})
+	}
+	m.naming.warned[name] = struct{}{}
+	m.naming.logger.Warn(msg, append([]interface{}{"name", name}, ctx...)...)
+}
+
+// warnLocked is warnOnce for callers not holding the naming lock.
+func (m *Metrics) warnLocked(name, msg string, ctx ...interface{}) {
+	m.naming.Lock()
+	defer m.naming.Unlock()
+	m.warnOnce(name, msg, ctx...)
+}
+
+// namingState holds the state of the series limit and the offending
+// names already logged.
+type namingState struct {
+	sync.Mutex
+	logger log.Logger
+	series *SeriesConfiguration
+	names  map[string]struct{}
+	warned map[string]struct{}
+}
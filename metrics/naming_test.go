@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/rcrowley/go-metrics"
+	log "gopkg.in/inconshreveable/log15.v2"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSanitizeNames(t *testing.T) {
+	cases := []struct {
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{sanitizeName, "foo.bar", "foo.bar"},
+		{sanitizeName, "foo bar\n{baz}", "foo_bar__baz_"},
+		{sanitizeName, ".foo..bar.", "foo.bar"},
+		{sanitizeName, "foo\xffbar", "foo_bar"},
+		{sanitizeName, "..", "_"},
+		{sanitizeLabelKey, "status-code", "status_code"},
+		{sanitizeLabelKey, "2xx", "_xx"},
+		{sanitizeLabelKey, "", "_"},
+		{prometheusName, "foo.bar-baz:qux", "foo_bar_baz:qux"},
+		{prometheusName, "github.com/exoscale/foo", "github_com_exoscale_foo"},
+		{prometheusLabelName, "a:b", "a_b"},
+		{graphiteName, "foo bar;baz\tqux", "foo_bar_baz_qux"},
+		{statsdName, "foo:bar|c@0.1#a,b", "foo_bar_c_0.1_a_b"},
+		{collectdName, "foo-bar/baz", "foo_bar_baz"},
+		{collectdInstanceName, "GET-/api", "GET-_api"},
+	}
+	for _, c := range cases {
+		if got := c.fn(c.in); got != c.want {
+			t.Errorf("sanitize(%q) == %q but expected %q", c.in, got, c.want)
+		}
+	}
+	if got := statsdTag("path", "/a,b"); got != "path:/a_b" {
+		t.Errorf("statsdTag() == %q but expected %q", got, "path:/a_b")
+	}
+}
+
+func TestLabeledNameSanitized(t *testing.T) {
+	m, err := New(nil, "project")
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	var logged int
+	m.SetLogger(log.New())
+	m.naming.logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		logged++
+		return nil
+	}))
+
+	for i := 0; i < 3; i++ {
+		got := m.LabeledName("foo bar", "status-code", "200")
+		if want := `foo_bar{status_code="200"}`; got != want {
+			t.Errorf("LabeledName() == %q but expected %q", got, want)
+		}
+	}
+	if logged != 2 {
+		t.Errorf("Expected 2 offenders logged, got %d", logged)
+	}
+}
+
+func TestUnmarshalSeriesConfiguration(t *testing.T) {
+	var got SeriesConfiguration
+	if err := yaml.Unmarshal([]byte("max: 100"), &got); err != nil {
+		t.Fatalf("Unmarshal() error:\n%+v", err)
+	}
+	if want := (SeriesConfiguration{Max: 100, Overflow: "reject"}); got != want {
+		t.Errorf("Unmarshal() == %+v but expected %+v", got, want)
+	}
+	for _, in := range []string{"overflow: alias", "max: 100\noverflow: drop"} {
+		if err := yaml.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("Unmarshal(%q) expected an error", in)
+		}
+	}
+}
+
+func TestLimitSeries(t *testing.T) {
+	cases := []struct {
+		overflow string
+		name     string
+		want     string
+		ok       bool
+	}{
+		{"reject", "foo", "foo", true},
+		{"reject", `bar{id="1"}`, `bar{id="1"}`, true},
+		{"reject", "foo", "foo", true},
+		{"reject", `bar{id="2"}`, "", false},
+		{"reject", "baz", "", false},
+		{"alias", `bar{id="1"}`, `bar{id="1"}`, true},
+		{"alias", `bar{id="2"}`, `bar{id="_overflow"}`, true},
+		{"alias", "baz", "", false},
+	}
+	var m *Metrics
+	for _, c := range cases {
+		if m == nil || m.naming.series.Overflow != c.overflow {
+			var err error
+			m, err = New(nil, "project")
+			if err != nil {
+				t.Fatalf("New() error:\n%+v", err)
+			}
+			m.SetSeries(SeriesConfiguration{Max: 2, Overflow: c.overflow})
+			m.LimitSeries("foo")
+		}
+		got, ok := m.LimitSeries(c.name)
+		if got != c.want || ok != c.ok {
+			t.Errorf("LimitSeries(%q) == %q, %v but expected %q, %v (%s)",
+				c.name, got, ok, c.want, c.ok, c.overflow)
+		}
+	}
+	overflow := m.Registry.Get("github.com/exoscale/go-reporter.metrics.series.overflow")
+	if overflow == nil || overflow.(metrics.Counter).Count() != 2 {
+		t.Errorf("Expected 2 series overflows, got %v", overflow)
+	}
+
+	// Expired series free room for new ones
+	m.forgetSeries("foo")
+	if _, ok := m.LimitSeries("baz"); !ok {
+		t.Errorf("LimitSeries(%q) rejected after forgetting a series", "baz")
+	}
+}
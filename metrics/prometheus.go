@@ -7,12 +7,14 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
-	prometheusmetrics "github.com/deathowl/go-metrics-prometheus"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rcrowley/go-metrics"
 
 	"github.com/exoscale/go-reporter/config"
 )
@@ -42,10 +44,6 @@ func (c *PrometheusConfiguration) UnmarshalYAML(unmarshal func(interface{}) erro
 	if raw.Namespace == "" {
 		return errors.Errorf("missing namespace value")
 	}
-	if raw.Interval == config.Duration(0) {
-		return errors.Errorf("missing interval value for prometheus configuration")
-	}
-
 	if (raw.CertFile != "" || raw.KeyFile != "" || raw.CacertFile != "") &&
 		(raw.CertFile == "" || raw.KeyFile == "" || raw.CacertFile == "") {
 		return errors.Errorf("certfile, keyfile and cacertfile should be configured")
@@ -56,24 +54,30 @@ func (c *PrometheusConfiguration) UnmarshalYAML(unmarshal func(interface{}) erro
 
 // initExporter initialize the Prometheus exporter
 func (c *PrometheusConfiguration) initExporter(metrics *Metrics) error {
-	prometheusRegistry := prometheus.DefaultRegisterer
-	pClient := prometheusmetrics.NewPrometheusProvider(
-		metrics.Registry,
-		c.Namespace,
-		c.Subsystem,
-		prometheusRegistry,
-		time.Duration(c.Interval))
-	metrics.t.Go(func() error {
-		tick := time.NewTicker(time.Duration(c.Interval))
-		for {
-			select {
-			case <-tick.C:
-				pClient.UpdatePrometheusMetricsOnce() // nolint: errcheck
-			case <-metrics.t.Dying():
-				return nil
+	collector := &prometheusCollector{
+		registry:  metrics.Registry,
+		namespace: c.Namespace,
+		subsystem: c.Subsystem,
+		periodic:  c.Interval != config.Duration(0),
+		warn:      metrics.warnLocked,
+	}
+	if err := prometheus.DefaultRegisterer.Register(collector); err != nil {
+		return errors.Wrap(err, "unable to register metrics collector")
+	}
+	if collector.periodic {
+		metrics.t.Go(func() error {
+			tick := time.NewTicker(time.Duration(c.Interval))
+			defer tick.Stop()
+			for {
+				select {
+				case <-tick.C:
+					collector.flush()
+				case <-metrics.t.Dying():
+					return nil
+				}
 			}
-		}
-	})
+		})
+	}
 
 	address := c.Listen
 	listener, err := net.Listen("tcp", address.String())
@@ -115,3 +119,175 @@ func (c *PrometheusConfiguration) initExporter(metrics *Metrics) error {
 	})
 	return nil
 }
+
+var (
+	prometheusHistogramBuckets = []float64{0.05, 0.1, 0.25, 0.50, 0.75, 0.9, 0.95, 0.99}
+	prometheusTimerBuckets     = []float64{0.50, 0.95, 0.99, 0.999}
+)
+
+// prometheusCollector is a Prometheus collector exporting the metrics
+// of a registry, reading them at scrape time. Counters and gauges are
+// exported as gauges, meters as gauges of their 1-minute rate,
+// histograms as a gauge of their last sample and a "_histogram"
+// histogram, timers as a gauge of their 1-minute rate and a "_timer"
+// histogram. Labels are exported as Prometheus labels. The buckets of
+// histograms and timers counting their values in buckets are native
+// Prometheus buckets, the others are the percentiles of their sample.
+//
+// Distinct registry names may be sanitized into the same Prometheus
+// name (e.g. "a.b" and "a_b"). As the Prometheus registry rejects
+// the whole scrape when a family is inconsistent, the metrics
+// colliding with the ones of another registry name are skipped
+// instead (the first name in lexical order wins) and logged.
+//
+// When periodic, scrapes get the metrics read during the latest
+// flush instead.
+type prometheusCollector struct {
+	registry  metrics.Registry
+	namespace string
+	subsystem string
+	periodic  bool
+	warn      func(name, msg string, ctx ...interface{})
+
+	mu      sync.Mutex
+	flushed []prometheus.Metric
+}
+
+// Describe sends no descriptor: metrics are not known in advance,
+// this is an unchecked collector.
+func (c *prometheusCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect sends the current value of the metrics.
+func (c *prometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	if !c.periodic {
+		c.collect(func(m prometheus.Metric) { ch <- m })
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range c.flushed {
+		ch <- m
+	}
+}
+
+// flush reads the metrics to be sent by the next scrapes.
+func (c *prometheusCollector) flush() {
+	var flushed []prometheus.Metric
+	c.collect(func(m prometheus.Metric) { flushed = append(flushed, m) })
+	c.mu.Lock()
+	c.flushed = flushed
+	c.mu.Unlock()
+}
+
+// prometheusBuckets returns the buckets of a histogram or timer
+// snapshot: its own buckets if it counts its values in buckets,
+// otherwise the percentiles ps of its sample.
+func prometheusBuckets(snapshot interface{}, ps []float64, percentiles func([]float64) []float64) map[float64]uint64 {
+	counts := make(map[float64]uint64)
+	if b, ok := snapshot.(bucketed); ok {
+		bounds, cumulative := b.Buckets()
+		for i, bound := range bounds {
+			counts[bound] = cumulative[i]
+		}
+		return counts
+	}
+	for i, v := range percentiles(ps) {
+		counts[ps[i]] = uint64(v)
+	}
+	return counts
+}
+
+// collect reads the metrics of the registry.
+func (c *prometheusCollector) collect(send func(prometheus.Metric)) {
+	registered := make(map[string]interface{})
+	c.registry.Each(func(name string, i interface{}) {
+		registered[name] = i
+	})
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	owners := make(map[string]string)   // registry name per Prometheus name
+	series := make(map[string]struct{}) // Prometheus series sent
+	for _, key := range names {
+		name, labels := decodeLabels(key)
+		keys := labelsKeys(labels)
+		sanitized := make(map[string]string, len(keys))
+		for i, k := range keys {
+			keys[i] = prometheusLabelName(k)
+			sanitized[keys[i]] = labels[k]
+		}
+		values := labelsValues(labels)
+		desc := func(name string) *prometheus.Desc {
+			fqName := prometheusName(prometheus.BuildFQName(c.namespace, c.subsystem, name))
+			if owner, ok := owners[fqName]; ok && owner != name {
+				c.warnCollision(fqName, "skipping metric colliding with another one once sanitized",
+					"metric", name, "colliding", owner)
+				return nil
+			}
+			s := fqName + labelsString(sanitized)
+			if _, ok := series[s]; ok {
+				c.warnCollision(s, "skipping metric series colliding with another one once sanitized",
+					"metric", key)
+				return nil
+			}
+			owners[fqName] = name
+			series[s] = struct{}{}
+			return prometheus.NewDesc(fqName, fqName, keys, nil)
+		}
+		gauge := func(value float64) {
+			d := desc(name)
+			if d == nil {
+				return
+			}
+			m, err := prometheus.NewConstMetric(d, prometheus.GaugeValue, value, values...)
+			if err != nil {
+				m = prometheus.NewInvalidMetric(d, err)
+			}
+			send(m)
+		}
+		histogram := func(suffix string, count, sum int64, counts map[float64]uint64) {
+			d := desc(name + suffix)
+			if d == nil {
+				return
+			}
+			m, err := prometheus.NewConstHistogram(d, uint64(count), float64(sum), counts, values...)
+			if err != nil {
+				m = prometheus.NewInvalidMetric(d, err)
+			}
+			send(m)
+		}
+		switch metric := registered[key].(type) {
+		case metrics.Counter:
+			gauge(float64(metric.Count()))
+		case metrics.Gauge:
+			gauge(float64(metric.Value()))
+		case metrics.GaugeFloat64:
+			gauge(metric.Value())
+		case metrics.Histogram:
+			h := metric.Snapshot()
+			if samples := h.Sample().Values(); len(samples) > 0 {
+				gauge(float64(samples[len(samples)-1]))
+			}
+			histogram("_histogram", h.Count(), h.Sum(),
+				prometheusBuckets(h, prometheusHistogramBuckets, h.Percentiles))
+		case metrics.Meter:
+			gauge(metric.Rate1())
+		case metrics.Timer:
+			t := metric.Snapshot()
+			gauge(t.Rate1())
+			histogram("_timer", t.Count(), t.Sum(),
+				prometheusBuckets(t, prometheusTimerBuckets, t.Percentiles))
+		}
+	}
+}
+
+// warnCollision logs a metric skipped because of a name collision,
+// if a logging function is set.
+func (c *prometheusCollector) warnCollision(name, msg string, ctx ...interface{}) {
+	if c.warn != nil {
+		c.warn(name, msg, ctx...)
+	}
+}
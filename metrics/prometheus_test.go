@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rcrowley/go-metrics"
+)
+
+// gatherPrometheus returns the value of the metrics collected by c,
+// indexed by name and labels. Histograms values are their count.
+func gatherPrometheus(t *testing.T, c prometheus.Collector) map[string]float64 {
+	families, err := newPrometheusRegistry(t, c).Gather()
+	if err != nil {
+		t.Fatalf("Gather() error:\n%+v", err)
+	}
+	got := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			name := f.GetName() + labelsString(labels)
+			if m.GetHistogram() != nil {
+				got[name] = float64(m.GetHistogram().GetSampleCount())
+			} else {
+				got[name] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	return got
+}
+
+// newPrometheusRegistry returns a Prometheus registry with c
+// registered.
+func newPrometheusRegistry(t *testing.T, c prometheus.Collector) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(c); err != nil {
+		t.Fatalf("Register() error:\n%+v", err)
+	}
+	return registry
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("foo", r).Inc(1)
+	metrics.NewRegisteredCounter(`foo{code="200",http.method="GET"}`, r).Inc(47)
+	h := metrics.NewRegisteredHistogram("bar", r, metrics.NewUniformSample(10))
+	h.Update(3)
+
+	c := &prometheusCollector{registry: r, namespace: "project"}
+	got := gatherPrometheus(t, c)
+	want := map[string]float64{
+		"project_foo": 1,
+		`project_foo{code="200",http_method="GET"}`: 47,
+		"project_bar":           3,
+		"project_bar_histogram": 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect() == %v but expected %v", got, want)
+	}
+
+	// Registry is read at scrape time
+	h.Update(5)
+	got = gatherPrometheus(t, c)
+	if got["project_bar"] != 5 || got["project_bar_histogram"] != 2 {
+		t.Errorf("Collect() == %v but expected updated histogram", got)
+	}
+}
+
+func TestPrometheusCollectorPeriodic(t *testing.T) {
+	r := metrics.NewRegistry()
+	counter := metrics.NewRegisteredCounter("foo", r)
+
+	c := &prometheusCollector{registry: r, namespace: "project", periodic: true}
+	if got := gatherPrometheus(t, c); len(got) != 0 {
+		t.Errorf("Collect() == %v before flush but expected nothing", got)
+	}
+
+	counter.Inc(1)
+	c.flush()
+	counter.Inc(1)
+	if got := gatherPrometheus(t, c); !reflect.DeepEqual(got, map[string]float64{"project_foo": 1}) {
+		t.Errorf("Collect() == %v but expected flushed value", got)
+	}
+}
+
+func TestPrometheusCollectorCollisions(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("a.b", r).Inc(1)
+	metrics.NewRegisteredCounter("a_b", r).Inc(2)
+	metrics.NewRegisteredHistogram("c", r, metrics.NewUniformSample(10)).Update(3)
+	metrics.NewRegisteredCounter("c_histogram", r).Inc(4)
+	var warned []string
+	c := &prometheusCollector{
+		registry: r,
+		warn: func(name, msg string, ctx ...interface{}) {
+			warned = append(warned, name)
+		},
+	}
+	got := gatherPrometheus(t, c)
+	want := map[string]float64{
+		"a_b":         1,
+		"c":           3,
+		"c_histogram": 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect() == %v but expected %v", got, want)
+	}
+	if want := []string{"a_b", "c_histogram"}; !reflect.DeepEqual(warned, want) {
+		t.Errorf("Collect() warned about %v but expected %v", warned, want)
+	}
+
+	families, err := newPrometheusRegistry(t, c).Gather()
+	if err != nil {
+		t.Fatalf("Gather() error:\n%+v", err)
+	}
+	for _, f := range families {
+		if f.GetHelp() != f.GetName() {
+			t.Errorf("Gather() help of %q == %q but expected its name", f.GetName(), f.GetHelp())
+		}
+	}
+}
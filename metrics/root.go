@@ -22,7 +22,13 @@ type Metrics struct {
 	prefix   string
 	Registry metrics.Registry
 
-	t tomb.Tomb
+	labels  labelLimiter
+	naming  namingState
+	buckets []BucketsConfiguration
+	samples []SampleConfiguration
+	runtime *RuntimeConfiguration
+	expiry  *expirer
+	t       tomb.Tomb
 }
 
 // New creates a new metric registry and setup the appropriate
@@ -41,8 +47,11 @@ func New(configuration Configuration, prefix string) (*Metrics, error) {
 // Start starts the metric collection and the exporters.
 func (m *Metrics) Start() error {
 	// Register runtime metrics
-	runtimeRegistry := metrics.NewPrefixedChildRegistry(m.Registry, "go.")
-	metrics.RegisterRuntimeMemStats(runtimeRegistry)
+	runtimeConfiguration := DefaultRuntimeConfiguration
+	if m.runtime != nil {
+		runtimeConfiguration = *m.runtime
+	}
+	collector := newRuntimeCollector(m.Registry, runtimeConfiguration)
 	m.t.Go(func() error {
 		for {
 			timeout := time.After(runtimeMetricsInterval)
@@ -52,7 +61,14 @@ func (m *Metrics) Start() error {
 			case <-timeout:
 				break
 			}
-			metrics.CaptureRuntimeMemStatsOnce(runtimeRegistry)
+			if err := collector.capture(); err != nil {
+				metrics.GetOrRegisterMeter(
+					"github.com/exoscale/go-reporter.metrics.runtime.failed-captures",
+					m.Registry).Mark(1)
+			}
+			if m.expiry != nil {
+				m.expire()
+			}
 		}
 	})
 
@@ -74,25 +90,32 @@ func (m *Metrics) MustStart() {
 	}
 }
 
-// Push pushes registered metrics to a Prometheus push gateway.
+// Push pushes registered metrics to a Prometheus push gateway. Unlike
+// the prometheus exporter, names are lowercased before being
+// sanitized.
 func (m *Metrics) Push() error {
 	registry := prometheus.NewRegistry()
 	m.Registry.Each(func(name string, metric interface{}) {
-		name = strings.Replace(strings.ToLower(name), ".", "_", -1)
+		name, labels := decodeLabels(name)
+		name = prometheusName(strings.ToLower(name))
+		constLabels := make(prometheus.Labels, len(labels))
+		for k, v := range labels {
+			constLabels[prometheusLabelName(k)] = v
+		}
 
 		switch metric := metric.(type) {
 		case *metrics.StandardGauge:
-			g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name})
+			g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, ConstLabels: constLabels})
 			g.Set(float64(metric.Value()))
 			registry.Register(g) // nolint: errcheck
 
 		case *metrics.StandardGaugeFloat64:
-			g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name})
+			g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, ConstLabels: constLabels})
 			g.Set(float64(metric.Value()))
 			registry.Register(g) // nolint: errcheck
 
 		case *metrics.StandardCounter:
-			c := prometheus.NewCounter(prometheus.CounterOpts{Name: name})
+			c := prometheus.NewCounter(prometheus.CounterOpts{Name: name, ConstLabels: constLabels})
 			c.Add(float64(metric.Count()))
 			registry.Register(c) // nolint: errcheck
 		}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+)
+
+// RuntimeConfiguration is the set of Go runtime and process metrics
+// groups to collect, each one being enabled independently:
+//
+//   - memstats: runtime.MemStats statistics (go.runtime.MemStats.*)
+//   - goroutines: goroutines and OS threads counts
+//     (go.runtime.goroutines, go.runtime.threads)
+//   - gc: garbage collections count and pauses distribution in
+//     nanoseconds (go.gc.count, go.gc.pause)
+//   - scheduler: scheduler latency quantiles in seconds since the
+//     previous capture (go.sched.latency.*), requires Go 1.17
+//   - process: resident memory, open file descriptors and their
+//     limit, CPU seconds, start time and context switches read from
+//     /proc/self (process.*), Linux only
+type RuntimeConfiguration struct {
+	MemStats   bool
+	Goroutines bool
+	GC         bool
+	Scheduler  bool
+	Process    bool
+}
+
+// DefaultRuntimeConfiguration is the default runtime metrics
+// configuration.
+var DefaultRuntimeConfiguration = RuntimeConfiguration{
+	MemStats: true,
+}
+
+// UnmarshalYAML parses a runtime metrics configuration from YAML.
+func (c *RuntimeConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawRuntimeConfiguration RuntimeConfiguration
+	raw := rawRuntimeConfiguration(DefaultRuntimeConfiguration)
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "unable to decode runtime configuration")
+	}
+	*c = RuntimeConfiguration(raw)
+	return nil
+}
+
+// SetRuntime sets the runtime metrics groups collected once started.
+// Otherwise, DefaultRuntimeConfiguration is used.
+func (m *Metrics) SetRuntime(c RuntimeConfiguration) {
+	m.runtime = &c
+}
+
+// runtimeCollector collects the enabled runtime metrics groups.
+type runtimeCollector struct {
+	config          RuntimeConfiguration
+	runtimeRegistry metrics.Registry
+
+	goroutines metrics.Gauge
+	threads    metrics.Gauge
+	threadsPrf *pprof.Profile
+
+	gcCount  metrics.Gauge
+	gcPauses metrics.Histogram
+	gcStats  debug.GCStats
+
+	sched   *schedLatency
+	process *processStats
+}
+
+// newRuntimeCollector registers the metrics of the enabled groups
+// under the go. (runtime) and process. (process) prefixes.
+func newRuntimeCollector(r metrics.Registry, c RuntimeConfiguration) *runtimeCollector {
+	rc := runtimeCollector{
+		config:          c,
+		runtimeRegistry: metrics.NewPrefixedChildRegistry(r, "go."),
+	}
+	if c.MemStats {
+		metrics.RegisterRuntimeMemStats(rc.runtimeRegistry)
+	}
+	if c.Goroutines {
+		rc.goroutines = metrics.NewRegisteredGauge("runtime.goroutines", rc.runtimeRegistry)
+		rc.threads = metrics.NewRegisteredGauge("runtime.threads", rc.runtimeRegistry)
+		rc.threadsPrf = pprof.Lookup("threadcreate")
+	}
+	if c.GC {
+		rc.gcCount = metrics.NewRegisteredGauge("gc.count", rc.runtimeRegistry)
+		rc.gcPauses = metrics.NewRegisteredHistogram("gc.pause", rc.runtimeRegistry,
+			metrics.NewExpDecaySample(histogramReservoirSize, histogramAlpha))
+	}
+	if c.Scheduler {
+		rc.sched = newSchedLatency(rc.runtimeRegistry)
+	}
+	if c.Process {
+		rc.process = newProcessStats(metrics.NewPrefixedChildRegistry(r, "process."))
+	}
+	return &rc
+}
+
+// capture updates the metrics of the enabled groups. Groups not
+// supported by the Go version or the platform are ignored.
+func (rc *runtimeCollector) capture() error {
+	if rc.config.MemStats {
+		metrics.CaptureRuntimeMemStatsOnce(rc.runtimeRegistry)
+	}
+	if rc.config.Goroutines {
+		rc.goroutines.Update(int64(runtime.NumGoroutine()))
+		rc.threads.Update(int64(rc.threadsPrf.Count()))
+	}
+	if rc.config.GC {
+		// Pauses are sorted from the most recent one and only the
+		// latest ones are kept.
+		lastNumGC := rc.gcStats.NumGC
+		debug.ReadGCStats(&rc.gcStats)
+		rc.gcCount.Update(rc.gcStats.NumGC)
+		pauses := rc.gcStats.Pause
+		if n := rc.gcStats.NumGC - lastNumGC; n < int64(len(pauses)) {
+			pauses = pauses[:n]
+		}
+		for i := len(pauses) - 1; i >= 0; i-- {
+			rc.gcPauses.Update(int64(pauses[i]))
+		}
+	}
+	if rc.sched != nil {
+		rc.sched.capture()
+	}
+	if rc.process != nil {
+		return rc.process.capture()
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+)
+
+// procClockTicks is the number of clock ticks per second (USER_HZ)
+// used by /proc files, 100 on all supported architectures.
+const procClockTicks = 100
+
+// processStats collects the process metrics read from /proc/self.
+type processStats struct {
+	root string
+
+	rss                 metrics.Gauge
+	openFDs             metrics.Gauge
+	maxFDs              metrics.Gauge
+	cpuSeconds          metrics.GaugeFloat64
+	startTime           metrics.GaugeFloat64
+	voluntarySwitches   metrics.Gauge
+	involuntarySwitches metrics.Gauge
+}
+
+func newProcessStats(r metrics.Registry) *processStats {
+	return &processStats{
+		root:                "/proc",
+		rss:                 metrics.NewRegisteredGauge("memory.rss", r),
+		openFDs:             metrics.NewRegisteredGauge("fds.open", r),
+		maxFDs:              metrics.NewRegisteredGauge("fds.max", r),
+		cpuSeconds:          metrics.NewRegisteredGaugeFloat64("cpu.seconds", r),
+		startTime:           metrics.NewRegisteredGaugeFloat64("start_time", r),
+		voluntarySwitches:   metrics.NewRegisteredGauge("context_switches.voluntary", r),
+		involuntarySwitches: metrics.NewRegisteredGauge("context_switches.involuntary", r),
+	}
+}
+
+// capture updates the process metrics.
+func (p *processStats) capture() error {
+	if err := p.captureStat(); err != nil {
+		return err
+	}
+	if err := p.captureFDs(); err != nil {
+		return err
+	}
+	return p.readLines("self/status", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return
+		}
+		v, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return
+		}
+		switch fields[0] {
+		case "voluntary_ctxt_switches:":
+			p.voluntarySwitches.Update(v)
+		case "nonvoluntary_ctxt_switches:":
+			p.involuntarySwitches.Update(v)
+		}
+	})
+}
+
+// captureStat updates the metrics read from /proc/self/stat (see
+// proc(5)).
+func (p *processStats) captureStat() error {
+	data, err := ioutil.ReadFile(filepath.Join(p.root, "self", "stat"))
+	if err != nil {
+		return errors.Wrap(err, "unable to read process stat")
+	}
+	// The executable name (2nd field) may contain spaces and
+	// parentheses, the following fields start with the 3rd one.
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return errors.Errorf("invalid process stat format")
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 22 {
+		return errors.Errorf("invalid process stat format")
+	}
+	field := func(n int) float64 {
+		v, _ := strconv.ParseFloat(fields[n-3], 64)
+		return v
+	}
+	p.cpuSeconds.Update((field(14) + field(15)) / procClockTicks)
+	p.rss.Update(int64(field(24)) * int64(os.Getpagesize()))
+
+	var bootTime float64
+	if err := p.readLines("stat", func(line string) {
+		if strings.HasPrefix(line, "btime ") {
+			bootTime, _ = strconv.ParseFloat(strings.TrimPrefix(line, "btime "), 64)
+		}
+	}); err != nil {
+		return err
+	}
+	p.startTime.Update(bootTime + field(22)/procClockTicks)
+	return nil
+}
+
+// captureFDs updates the number of open file descriptors and their
+// limit (-1 if unlimited).
+func (p *processStats) captureFDs() error {
+	d, err := os.Open(filepath.Join(p.root, "self", "fd"))
+	if err != nil {
+		return errors.Wrap(err, "unable to list process file descriptors")
+	}
+	defer d.Close()
+	fds, err := d.Readdirnames(-1)
+	if err != nil {
+		return errors.Wrap(err, "unable to list process file descriptors")
+	}
+	p.openFDs.Update(int64(len(fds)))
+
+	return p.readLines("self/limits", func(line string) {
+		// Max open files            1024                 1048576              files
+		if !strings.HasPrefix(line, "Max open files") {
+			return
+		}
+		soft := strings.Fields(strings.TrimPrefix(line, "Max open files"))[0]
+		if soft == "unlimited" {
+			p.maxFDs.Update(-1)
+		} else if v, err := strconv.ParseInt(soft, 10, 64); err == nil {
+			p.maxFDs.Update(v)
+		}
+	})
+}
+
+// readLines calls fn for each line of a file relative to the procfs
+// mount point.
+func (p *processStats) readLines(name string, fn func(line string)) error {
+	f, err := os.Open(filepath.Join(p.root, name))
+	if err != nil {
+		return errors.Wrapf(err, "unable to open %s", name)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+func TestProcessStats(t *testing.T) {
+	root, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatalf("TempDir() error:\n%+v", err)
+	}
+	defer os.RemoveAll(root)
+	for name, content := range map[string]string{
+		"stat": "cpu  1 2 3\nbtime 1600000000\n",
+		"self/stat": "42 (my (app)) S 1 42 42 0 -1 4194560 1000 0 0 0 250 150 0 0 20 0 12 0 5000 " +
+			"1000000 25 18446744073709551615\n",
+		"self/limits": "Limit                     Soft Limit           Hard Limit           Units\n" +
+			"Max open files            unlimited            unlimited            files\n",
+		"self/status": "Name:\tapp\nvoluntary_ctxt_switches:\t120\nnonvoluntary_ctxt_switches:\t7\n",
+		"self/fd/0":   "",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0700); err != nil {
+			t.Fatalf("MkdirAll() error:\n%+v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0600); err != nil {
+			t.Fatalf("WriteFile() error:\n%+v", err)
+		}
+	}
+
+	r := metrics.NewRegistry()
+	p := newProcessStats(r)
+	p.root = root
+	if err := p.capture(); err != nil {
+		t.Fatalf("capture() error:\n%+v", err)
+	}
+	gauges := map[string]int64{
+		"memory.rss":                   int64(25 * os.Getpagesize()),
+		"fds.open":                     1,
+		"fds.max":                      -1,
+		"context_switches.voluntary":   120,
+		"context_switches.involuntary": 7,
+	}
+	for name, want := range gauges {
+		if got := r.Get(name).(metrics.Gauge).Value(); got != want {
+			t.Errorf("%s == %d but expected %d", name, got, want)
+		}
+	}
+	if got := r.Get("cpu.seconds").(metrics.GaugeFloat64).Value(); got != 4 {
+		t.Errorf("cpu.seconds == %v but expected 4", got)
+	}
+	if got := r.Get("start_time").(metrics.GaugeFloat64).Value(); got != 1600000050 {
+		t.Errorf("start_time == %v but expected 1600000050", got)
+	}
+
+	p.root = filepath.Join(root, "missing")
+	if err := p.capture(); err == nil {
+		t.Errorf("capture() should fail without procfs")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package metrics
+
+import (
+	"github.com/rcrowley/go-metrics"
+)
+
+// processStats collects the process metrics, which are only
+// supported on Linux.
+type processStats struct{}
+
+func newProcessStats(metrics.Registry) *processStats { return nil }
+
+func (*processStats) capture() error { return nil }
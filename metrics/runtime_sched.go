@@ -0,0 +1,89 @@
+//go:build go1.17
+// +build go1.17
+
+package metrics
+
+import (
+	"math"
+	runtimemetrics "runtime/metrics"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// schedLatenciesMetric is the distribution of the time goroutines
+// spent runnable before running.
+const schedLatenciesMetric = "/sched/latencies:seconds"
+
+var schedLatencyQuantiles = []struct {
+	name string
+	q    float64
+}{
+	{"sched.latency.p50", 0.5},
+	{"sched.latency.p90", 0.9},
+	{"sched.latency.p99", 0.99},
+	{"sched.latency.max", 1},
+}
+
+// schedLatency collects the quantiles (in seconds) of the scheduler
+// latency observed since the previous capture.
+type schedLatency struct {
+	samples   []runtimemetrics.Sample
+	previous  []uint64
+	quantiles []metrics.GaugeFloat64
+}
+
+// newSchedLatency returns a scheduler latency collector, or nil if
+// the runtime doesn't support the scheduler latency metric.
+func newSchedLatency(r metrics.Registry) *schedLatency {
+	s := schedLatency{samples: []runtimemetrics.Sample{{Name: schedLatenciesMetric}}}
+	runtimemetrics.Read(s.samples)
+	if s.samples[0].Value.Kind() != runtimemetrics.KindFloat64Histogram {
+		return nil
+	}
+	for _, q := range schedLatencyQuantiles {
+		s.quantiles = append(s.quantiles, metrics.NewRegisteredGaugeFloat64(q.name, r))
+	}
+	return &s
+}
+
+// capture updates the scheduler latency quantiles.
+func (s *schedLatency) capture() {
+	runtimemetrics.Read(s.samples)
+	h := s.samples[0].Value.Float64Histogram()
+	counts := make([]uint64, len(h.Counts))
+	var total uint64
+	for i, c := range h.Counts {
+		counts[i] = c
+		if i < len(s.previous) {
+			counts[i] -= s.previous[i]
+		}
+		total += counts[i]
+	}
+	s.previous = append(s.previous[:0], h.Counts...)
+	for i, q := range schedLatencyQuantiles {
+		s.quantiles[i].Update(histogramQuantile(h.Buckets, counts, total, q.q))
+	}
+}
+
+// histogramQuantile estimates a quantile of the values counted in a
+// runtime/metrics histogram as the upper bound of the bucket holding
+// it (or the lower bound for the unbounded highest bucket).
+func histogramQuantile(buckets []float64, counts []uint64, total uint64, q float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var cumulative uint64
+	for i, c := range counts {
+		if cumulative += c; cumulative >= rank {
+			if math.IsInf(buckets[i+1], 1) {
+				return buckets[i]
+			}
+			return buckets[i+1]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,16 @@
+//go:build !go1.17
+// +build !go1.17
+
+package metrics
+
+import (
+	"github.com/rcrowley/go-metrics"
+)
+
+// schedLatency collects the scheduler latency, which requires the
+// runtime/metrics package of Go 1.17 or later.
+type schedLatency struct{}
+
+func newSchedLatency(metrics.Registry) *schedLatency { return nil }
+
+func (*schedLatency) capture() {}
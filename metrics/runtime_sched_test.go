@@ -0,0 +1,31 @@
+//go:build go1.17
+// +build go1.17
+
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := []float64{0, 1, 2, 4, math.Inf(1)}
+	counts := []uint64{50, 40, 9, 1}
+	cases := []struct {
+		q    float64
+		want float64
+	}{
+		{0.5, 1},
+		{0.9, 2},
+		{0.99, 4},
+		{1, 4},
+	}
+	for _, c := range cases {
+		if got := histogramQuantile(buckets, counts, 100, c.q); got != c.want {
+			t.Errorf("histogramQuantile(%v) == %v but expected %v", c.q, got, c.want)
+		}
+	}
+	if got := histogramQuantile(buckets, make([]uint64, 4), 0, 0.5); got != 0 {
+		t.Errorf("histogramQuantile() of an empty histogram == %v", got)
+	}
+}
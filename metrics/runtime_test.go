@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalRuntimeConfiguration(t *testing.T) {
+	var got RuntimeConfiguration
+	if err := yaml.Unmarshal([]byte(`{gc: true, process: true}`), &got); err != nil {
+		t.Fatalf("Unmarshal() error:\n%+v", err)
+	}
+	want := RuntimeConfiguration{MemStats: true, GC: true, Process: true}
+	if got != want {
+		t.Errorf("Unmarshal() == %+v but expected %+v", got, want)
+	}
+}
+
+func TestRuntimeCollector(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := newRuntimeCollector(r, RuntimeConfiguration{
+		Goroutines: true,
+		GC:         true,
+		Scheduler:  true,
+		Process:    true,
+	})
+	if err := c.capture(); err != nil {
+		t.Fatalf("capture() error:\n%+v", err)
+	}
+	runtime.GC()
+	runtime.GC()
+	if err := c.capture(); err != nil {
+		t.Fatalf("capture() error:\n%+v", err)
+	}
+
+	if r.Get("go.runtime.MemStats.Alloc") != nil {
+		t.Errorf("memstats should not be registered")
+	}
+	if v := r.Get("go.runtime.goroutines").(metrics.Gauge).Value(); v <= 0 {
+		t.Errorf("go.runtime.goroutines == %d", v)
+	}
+	if v := r.Get("go.runtime.threads").(metrics.Gauge).Value(); v <= 0 {
+		t.Errorf("go.runtime.threads == %d", v)
+	}
+	if v := r.Get("go.gc.pause").(metrics.Histogram).Count(); v < 2 {
+		t.Errorf("go.gc.pause count == %d but expected at least 2", v)
+	}
+	if c.sched != nil && r.Get("go.sched.latency.p99") == nil {
+		t.Errorf("go.sched.latency.p99 should be registered")
+	}
+	if c.process != nil {
+		if v := r.Get("process.memory.rss").(metrics.Gauge).Value(); v <= 0 {
+			t.Errorf("process.memory.rss == %d", v)
+		}
+	}
+}
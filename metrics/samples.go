@@ -0,0 +1,451 @@
+package metrics
+
+import (
+	"math"
+	"math/bits"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+// Histograms use by default an exponentially-decaying sample with a
+// forward-decaying priority reservoir.
+const (
+	histogramReservoirSize = 100
+	histogramAlpha         = 0.015
+)
+
+// SampleConfiguration is the sample type of the histograms and
+// timers whose name (without labels) matches a pattern:
+//
+//   - uniform: a uniform random sample of size values
+//   - expdecay: an exponentially-decaying random sample of size
+//     values, biased towards the recent ones by alpha
+//   - window: the last (at most size) values recorded during a
+//     sliding time window
+//   - hdr: a high dynamic range histogram counting all the values,
+//     whose percentiles have precision significant decimal digits
+type SampleConfiguration struct {
+	Pattern   string
+	Type      string
+	Size      int
+	Alpha     float64
+	Window    config.Duration
+	Precision int
+}
+
+// UnmarshalYAML parses a sample type from YAML.
+func (c *SampleConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawSampleConfiguration SampleConfiguration
+	raw := rawSampleConfiguration{
+		Size:      1028,
+		Alpha:     0.015,
+		Window:    config.Duration(time.Minute),
+		Precision: 3,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "unable to decode sample configuration")
+	}
+	if raw.Pattern == "" {
+		return errors.Errorf("missing pattern value for sample configuration")
+	}
+	if _, err := path.Match(raw.Pattern, ""); err != nil {
+		return errors.Wrapf(err, "invalid pattern %q for sample configuration", raw.Pattern)
+	}
+	switch raw.Type {
+	case "uniform", "expdecay", "window", "hdr":
+	default:
+		return errors.Errorf("invalid type %q for sample configuration", raw.Type)
+	}
+	if raw.Size <= 0 || raw.Alpha <= 0 || raw.Window <= 0 {
+		return errors.Errorf("sample size, alpha and window should be positive")
+	}
+	if raw.Precision < 1 || raw.Precision > 5 {
+		return errors.Errorf("sample precision should be between 1 and 5")
+	}
+	*c = SampleConfiguration(raw)
+	return nil
+}
+
+// sample returns a new sample of the configured type.
+func (c SampleConfiguration) sample() metrics.Sample {
+	switch c.Type {
+	case "uniform":
+		return metrics.NewUniformSample(c.Size)
+	case "window":
+		return newWindowSample(time.Duration(c.Window), c.Size)
+	case "hdr":
+		return newHDRSample(c.Precision)
+	}
+	return metrics.NewExpDecaySample(c.Size, c.Alpha)
+}
+
+// SetSamples sets the sample types of the histograms and timers
+// registered with GetOrRegisterHistogram and GetOrRegisterTimer. The
+// first sample type whose pattern matches applies.
+func (m *Metrics) SetSamples(samples []SampleConfiguration) {
+	m.samples = samples
+}
+
+// sample returns a new sample of the type of the first sample
+// configuration matching the name of a metric, or nil.
+func (m *Metrics) sample(name string) metrics.Sample {
+	name, _ = decodeLabels(name)
+	for _, s := range m.samples {
+		if matched, _ := path.Match(s.Pattern, name); matched {
+			return s.sample()
+		}
+	}
+	return nil
+}
+
+// newHistogram returns a new histogram using the given sample. The
+// standard histogram only supports samples whose snapshot is a
+// *metrics.SampleSnapshot.
+func newHistogram(s metrics.Sample) metrics.Histogram {
+	if _, ok := s.Snapshot().(*metrics.SampleSnapshot); ok {
+		return metrics.NewHistogram(s)
+	}
+	return &sampleHistogram{s}
+}
+
+// newTimer returns a new timer using the given sample. As for
+// histograms, the standard timer only supports some samples.
+func newTimer(s metrics.Sample) metrics.Timer {
+	if _, ok := s.Snapshot().(*metrics.SampleSnapshot); ok {
+		return metrics.NewCustomTimer(metrics.NewHistogram(s), metrics.NewMeter())
+	}
+	return &sampleTimer{sampleHistogram: &sampleHistogram{s}, meter: metrics.NewMeter()}
+}
+
+// sampleHistogram is a histogram supporting any sample.
+type sampleHistogram struct {
+	sample metrics.Sample
+}
+
+func (h *sampleHistogram) Clear()                             { h.sample.Clear() }
+func (h *sampleHistogram) Count() int64                       { return h.sample.Count() }
+func (h *sampleHistogram) Max() int64                         { return h.sample.Max() }
+func (h *sampleHistogram) Mean() float64                      { return h.sample.Mean() }
+func (h *sampleHistogram) Min() int64                         { return h.sample.Min() }
+func (h *sampleHistogram) Percentile(p float64) float64       { return h.sample.Percentile(p) }
+func (h *sampleHistogram) Percentiles(ps []float64) []float64 { return h.sample.Percentiles(ps) }
+func (h *sampleHistogram) Sample() metrics.Sample             { return h.sample }
+func (h *sampleHistogram) Snapshot() metrics.Histogram        { return &sampleHistogram{h.sample.Snapshot()} }
+func (h *sampleHistogram) StdDev() float64                    { return h.sample.StdDev() }
+func (h *sampleHistogram) Sum() int64                         { return h.sample.Sum() }
+func (h *sampleHistogram) Update(v int64)                     { h.sample.Update(v) }
+func (h *sampleHistogram) Variance() float64                  { return h.sample.Variance() }
+
+// sampleTimer is a timer supporting any sample.
+type sampleTimer struct {
+	*sampleHistogram
+	mu    sync.Mutex
+	meter metrics.Meter
+}
+
+func (t *sampleTimer) Rate1() float64    { return t.meter.Rate1() }
+func (t *sampleTimer) Rate5() float64    { return t.meter.Rate5() }
+func (t *sampleTimer) Rate15() float64   { return t.meter.Rate15() }
+func (t *sampleTimer) RateMean() float64 { return t.meter.RateMean() }
+func (t *sampleTimer) Stop()             { t.meter.Stop() }
+
+// Snapshot returns a read-only copy of the timer.
+func (t *sampleTimer) Snapshot() metrics.Timer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &sampleTimer{
+		sampleHistogram: t.sampleHistogram.Snapshot().(*sampleHistogram),
+		meter:           t.meter.Snapshot(),
+	}
+}
+
+// Time records the duration of the execution of f.
+func (t *sampleTimer) Time(f func()) {
+	ts := time.Now()
+	f()
+	t.Update(time.Since(ts))
+}
+
+// Update records the duration of an event.
+func (t *sampleTimer) Update(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sampleHistogram.Update(int64(d))
+	t.meter.Mark(1)
+}
+
+// UpdateSince records the duration of an event that started at ts.
+func (t *sampleTimer) UpdateSince(ts time.Time) {
+	t.Update(time.Since(ts))
+}
+
+// windowSample is a sample of the values recorded during a sliding
+// time window, keeping at most size of the most recent ones.
+type windowSample struct {
+	mu     sync.Mutex
+	window time.Duration
+	size   int
+	count  int64
+	times  []time.Time
+	values []int64
+	now    func() time.Time
+}
+
+func newWindowSample(window time.Duration, size int) *windowSample {
+	return &windowSample{window: window, size: size, now: time.Now}
+}
+
+// Clear clears all values.
+func (s *windowSample) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count = 0
+	s.times, s.values = s.times[:0], s.values[:0]
+}
+
+// Count returns the number of values recorded, which may exceed
+// the size of the sample.
+func (s *windowSample) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (s *windowSample) Max() int64                   { return metrics.SampleMax(s.Values()) }
+func (s *windowSample) Mean() float64                { return metrics.SampleMean(s.Values()) }
+func (s *windowSample) Min() int64                   { return metrics.SampleMin(s.Values()) }
+func (s *windowSample) Percentile(p float64) float64 { return metrics.SamplePercentile(s.Values(), p) }
+func (s *windowSample) Percentiles(ps []float64) []float64 {
+	return metrics.SamplePercentiles(s.Values(), ps)
+}
+func (s *windowSample) Size() int         { return len(s.Values()) }
+func (s *windowSample) StdDev() float64   { return metrics.SampleStdDev(s.Values()) }
+func (s *windowSample) Sum() int64        { return metrics.SampleSum(s.Values()) }
+func (s *windowSample) Variance() float64 { return metrics.SampleVariance(s.Values()) }
+
+// Snapshot returns a read-only copy of the sample.
+func (s *windowSample) Snapshot() metrics.Sample {
+	values := s.Values()
+	return metrics.NewSampleSnapshot(s.Count(), values)
+}
+
+// Update records a new value.
+func (s *windowSample) Update(v int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.expire(now)
+	if len(s.values) == s.size {
+		s.times, s.values = append(s.times[:0], s.times[1:]...), append(s.values[:0], s.values[1:]...)
+	}
+	s.count++
+	s.times, s.values = append(s.times, now), append(s.values, v)
+}
+
+// Values returns a copy of the values in the sample.
+func (s *windowSample) Values() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(s.now())
+	return append([]int64(nil), s.values...)
+}
+
+// expire removes the values recorded before the window.
+func (s *windowSample) expire(now time.Time) {
+	i := sort.Search(len(s.times), func(i int) bool { return now.Sub(s.times[i]) <= s.window })
+	if i > 0 {
+		s.times, s.values = append(s.times[:0], s.times[i:]...), append(s.values[:0], s.values[i:]...)
+	}
+}
+
+// hdrSample is a high dynamic range sample: all values are counted
+// in buckets whose width is proportional to their magnitude,
+// bounding the relative error of the percentiles. Count, sum,
+// minimum, maximum, mean and variance are exact. Values are not
+// kept, so Values returns nil.
+type hdrSample struct {
+	mu        sync.Mutex
+	precision uint             // bits of the values kept exact
+	counts    map[int64]uint64 // by bucket index, negative for negative values
+	count     int64
+	sum       int64
+	min, max  int64
+	mean, m2  float64 // Welford's algorithm
+	snapshot  bool
+}
+
+func newHDRSample(digits int) *hdrSample {
+	return &hdrSample{
+		precision: uint(math.Ceil(float64(digits) * math.Log2(10))),
+		counts:    make(map[int64]uint64),
+	}
+}
+
+// Clear clears all values.
+func (s *hdrSample) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts = make(map[int64]uint64)
+	s.count, s.sum, s.min, s.max, s.mean, s.m2 = 0, 0, 0, 0, 0, 0
+}
+
+func (s *hdrSample) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (s *hdrSample) Max() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.max
+}
+
+func (s *hdrSample) Mean() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mean
+}
+
+func (s *hdrSample) Min() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.min
+}
+
+func (s *hdrSample) Sum() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sum
+}
+
+func (s *hdrSample) Variance() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return 0
+	}
+	return s.m2 / float64(s.count)
+}
+
+func (s *hdrSample) Percentile(p float64) float64 { return s.Percentiles([]float64{p})[0] }
+func (s *hdrSample) Size() int                    { return int(s.Count()) }
+func (s *hdrSample) StdDev() float64              { return math.Sqrt(s.Variance()) }
+func (s *hdrSample) Values() []int64              { return nil }
+
+// Percentiles returns a slice of arbitrary percentiles of the
+// values recorded.
+func (s *hdrSample) Percentiles(ps []float64) []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make([]float64, len(ps))
+	if s.count == 0 {
+		return values
+	}
+	indexes := make([]int64, 0, len(s.counts))
+	for i := range s.counts {
+		indexes = append(indexes, i)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	for i, p := range ps {
+		// The extreme values are known exactly
+		rank := uint64(math.Ceil(p * float64(s.count)))
+		if rank <= 1 {
+			values[i] = float64(s.min)
+			continue
+		}
+		if rank >= uint64(s.count) {
+			values[i] = float64(s.max)
+			continue
+		}
+		var cumulative uint64
+		for _, index := range indexes {
+			if cumulative += s.counts[index]; cumulative >= rank {
+				values[i] = float64(s.value(index))
+				break
+			}
+		}
+		values[i] = math.Max(float64(s.min), math.Min(float64(s.max), values[i]))
+	}
+	return values
+}
+
+// Snapshot returns a read-only copy of the sample.
+func (s *hdrSample) Snapshot() metrics.Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := &hdrSample{
+		precision: s.precision,
+		counts:    make(map[int64]uint64, len(s.counts)),
+		count:     s.count,
+		sum:       s.sum,
+		min:       s.min,
+		max:       s.max,
+		mean:      s.mean,
+		m2:        s.m2,
+		snapshot:  true,
+	}
+	for i, c := range s.counts {
+		snapshot.counts[i] = c
+	}
+	return snapshot
+}
+
+// Update records a new value.
+func (s *hdrSample) Update(v int64) {
+	if s.snapshot {
+		panic("Update called on a HDR sample snapshot")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[s.index(v)]++
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+	s.sum += v
+	delta := float64(v) - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (float64(v) - s.mean)
+}
+
+// index returns the bucket index of a value. Values lower than
+// 2^(precision+1) have their own bucket, higher ones share buckets
+// with the values having the same precision+1 most significant bits.
+func (s *hdrSample) index(v int64) int64 {
+	if v < 0 {
+		if v == math.MinInt64 {
+			v++
+		}
+		return -s.index(-v) - 1
+	}
+	u := uint64(v)
+	if u < 1<<(s.precision+1) {
+		return v
+	}
+	shift := uint(bits.Len64(u)) - (s.precision + 1)
+	return int64(uint64(shift)<<s.precision + u>>shift)
+}
+
+// value returns the middle of the bucket of an index.
+func (s *hdrSample) value(index int64) int64 {
+	if index < 0 {
+		return -s.value(-index - 1)
+	}
+	if index < 1<<(s.precision+1) {
+		return index
+	}
+	shift := uint(index>>s.precision) - 1
+	lowest := (index - int64(shift)<<s.precision) << shift
+	return lowest + (1<<shift-1)/2
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalSampleConfiguration(t *testing.T) {
+	var got SampleConfiguration
+	if err := yaml.Unmarshal([]byte(`{pattern: "*", type: hdr, precision: 4}`), &got); err != nil {
+		t.Fatalf("Unmarshal() error:\n%+v", err)
+	}
+	if got.Precision != 4 || got.Size != 1028 {
+		t.Errorf("Unmarshal() == %+v", got)
+	}
+
+	for _, in := range []string{
+		`{type: uniform}`,
+		`{pattern: "[", type: uniform}`,
+		`{pattern: "*"}`,
+		`{pattern: "*", type: reservoir}`,
+		`{pattern: "*", type: uniform, size: -1}`,
+		`{pattern: "*", type: hdr, precision: 6}`,
+	} {
+		var got SampleConfiguration
+		if err := yaml.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("Unmarshal(%q) == %+v but expected an error", in, got)
+		}
+	}
+}
+
+func TestGetOrRegisterSamples(t *testing.T) {
+	m, err := New(nil, "project")
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	m.SetSamples([]SampleConfiguration{
+		{Pattern: "*.latency", Type: "hdr", Precision: 3},
+		{Pattern: "*.size", Type: "uniform", Size: 10},
+	})
+	m.SetBuckets([]BucketsConfiguration{
+		{Pattern: "*.latency", Bounds: []float64{float64(time.Millisecond)}},
+	})
+
+	timer := m.GetOrRegisterTimer(`request.latency{code="200"}`)
+	defer timer.Stop()
+	for i := 1; i <= 1000; i++ {
+		timer.Update(time.Duration(i) * time.Microsecond)
+	}
+	s := timer.Snapshot()
+	if s.Count() != 1000 {
+		t.Errorf("Count() == %d but expected 1000", s.Count())
+	}
+	if p := s.Percentile(0.999); math.Abs(p-float64(999*time.Microsecond)) > float64(time.Microsecond) {
+		t.Errorf("Percentile(0.999) == %v but expected ~999µs", p)
+	}
+	if _, counts := s.(bucketed).Buckets(); counts[0] != 1000 {
+		t.Errorf("Buckets() counts == %v but expected [1000]", counts)
+	}
+
+	h := m.GetOrRegisterHistogram("response.size")
+	for i := int64(0); i < 100; i++ {
+		h.Update(i)
+	}
+	if h.Sample().Size() != 10 {
+		t.Errorf("Sample().Size() == %d but expected 10", h.Sample().Size())
+	}
+
+	// Unmatched names use the default samples
+	if _, ok := m.GetOrRegisterHistogram("response.bytes").Sample().(*metrics.ExpDecaySample); !ok {
+		t.Errorf("GetOrRegisterHistogram() should use an exponentially-decaying sample")
+	}
+	other := m.GetOrRegisterTimer("request.duration")
+	defer other.Stop()
+	if _, ok := other.(*metrics.StandardTimer); !ok {
+		t.Errorf("GetOrRegisterTimer() == %T but expected a standard timer", other)
+	}
+}
+
+func TestWindowSample(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := newWindowSample(time.Minute, 3)
+	s.now = func() time.Time { return now }
+	for i := int64(1); i <= 4; i++ {
+		s.Update(i)
+		now = now.Add(10 * time.Second)
+	}
+	if got := s.Values(); len(got) != 3 || got[0] != 2 {
+		t.Errorf("Values() == %v but expected [2 3 4]", got)
+	}
+	now = now.Add(45 * time.Second)
+	if got := s.Values(); len(got) != 1 || got[0] != 4 {
+		t.Errorf("Values() == %v but expected [4]", got)
+	}
+	if s.Count() != 4 {
+		t.Errorf("Count() == %d but expected 4", s.Count())
+	}
+}
+
+func TestHDRSample(t *testing.T) {
+	s := newHDRSample(3)
+	rnd := rand.New(rand.NewSource(42))
+	values := make([]int64, 100000)
+	for i := range values {
+		values[i] = int64(math.Exp(rnd.NormFloat64()*2 + 12))
+		s.Update(values[i])
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	if s.Count() != int64(len(values)) || s.Min() != values[0] || s.Max() != values[len(values)-1] ||
+		s.Sum() != metrics.SampleSum(values) {
+		t.Errorf("Count(), Min(), Max(), Sum() should be exact")
+	}
+	for _, p := range []float64{0.5, 0.99, 0.999, 0.9999} {
+		exact := float64(values[int(math.Ceil(p*float64(len(values))))-1])
+		if got := s.Percentile(p); math.Abs(got-exact) > exact*1e-3+1 {
+			t.Errorf("Percentile(%v) == %v but expected %v", p, got, exact)
+		}
+	}
+
+	h := newHistogram(s)
+	snapshot := h.Snapshot()
+	h.Update(1)
+	if snapshot.Count() != int64(len(values)) {
+		t.Errorf("Snapshot().Count() == %d but expected %d", snapshot.Count(), len(values))
+	}
+}
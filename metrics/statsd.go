@@ -0,0 +1,284 @@
+package metrics
+
+import (
+	"bytes"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+// StatsdConfiguration represents the configuration for exporting
+// metrics to a StatsD or DogStatsD agent.
+type StatsdConfiguration struct {
+	Network       string
+	Address       string
+	Flavor        string
+	Tags          map[string]string
+	Histograms    string
+	Interval      config.Duration
+	MaxPacketSize int
+	Exclude       []string
+}
+
+// UnmarshalYAML parses a configuration for StatsD from YAML.
+func (c *StatsdConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawStatsdConfiguration StatsdConfiguration
+	raw := rawStatsdConfiguration{
+		Network:       "udp",
+		Flavor:        "statsd",
+		Histograms:    "summary",
+		MaxPacketSize: 1432, // Ethernet MTU minus IPv6 and UDP headers
+	}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "unable to decode statsd configuration")
+	}
+	if raw.Interval == config.Duration(0) {
+		return errors.Errorf("missing interval value for statsd configuration")
+	}
+	if raw.Address == "" {
+		return errors.Errorf("missing address value for statsd configuration")
+	}
+	if raw.Network != "udp" && raw.Network != "unixgram" {
+		return errors.Errorf("invalid network %q for statsd configuration", raw.Network)
+	}
+	if raw.Flavor != "statsd" && raw.Flavor != "dogstatsd" {
+		return errors.Errorf("invalid flavor %q for statsd configuration", raw.Flavor)
+	}
+	if raw.Histograms == "samples" {
+		// Deprecated alias of "mean"
+		raw.Histograms = "mean"
+	}
+	if raw.Histograms != "summary" && raw.Histograms != "mean" {
+		return errors.Errorf("invalid histograms %q for statsd configuration", raw.Histograms)
+	}
+	*c = StatsdConfiguration(raw)
+	return nil
+}
+
+// initExporter initializes the StatsD exporter.
+func (c *StatsdConfiguration) initExporter(m *Metrics) error {
+	conn, err := net.Dial(c.Network, c.Address)
+	if err != nil {
+		return errors.Wrapf(err, "unable to connect to statsd (%v)", c.Address)
+	}
+
+	s := newStatsdState(c, m.prefix)
+	m.t.Go(func() error {
+		tick := time.NewTicker(time.Duration(c.Interval))
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				failed := 0
+				for _, packet := range statsdBatch(s.lines(m.Registry), c.MaxPacketSize) {
+					if _, err := conn.Write(packet); err != nil {
+						failed++
+					}
+				}
+				if failed > 0 {
+					metrics.GetOrRegisterMeter(
+						"github.com/exoscale/go-reporter.metrics.statsd.failed-writes",
+						m.Registry).Mark(int64(failed))
+				}
+			case <-m.t.Dying():
+				return conn.Close()
+			}
+		}
+	})
+
+	return nil
+}
+
+var (
+	statsdPercentiles      = []float64{0.5, 0.75, 0.95, 0.99}
+	statsdPercentilesNames = []string{"p50", "p75", "p95", "p99"}
+)
+
+// statsdState holds the state of the StatsD exporter: cumulative
+// metrics are sent as deltas since the previous flush. Values of
+// metrics unregistered since the previous flush are discarded.
+type statsdState struct {
+	config   *StatsdConfiguration
+	prefix   string
+	tags     []string
+	previous map[string]int64
+	current  map[string]int64
+}
+
+func newStatsdState(c *StatsdConfiguration, prefix string) *statsdState {
+	s := &statsdState{
+		config:   c,
+		prefix:   prefix,
+		previous: make(map[string]int64),
+	}
+	for k, v := range c.Tags {
+		s.tags = append(s.tags, statsdTag(k, v))
+	}
+	sort.Strings(s.tags)
+	return s
+}
+
+// lines returns the StatsD protocol lines of the metrics of the
+// registry. Counters and meters are sent as counter deltas, gauges as
+// gauges. Histograms and timers (in milliseconds) are sent either as
+// a counter and gauges of their statistics ("summary"), or as the
+// mean of the values recorded since the previous flush with a sample
+// rate accounting for all of them ("mean"), the agent aggregating
+// those means only.
+func (s *statsdState) lines(r metrics.Registry) []string {
+	var lines []string
+	s.current = make(map[string]int64, len(s.previous))
+	defer func() { s.previous = s.current }()
+	r.Each(func(key string, i interface{}) {
+		// Any error parsing an exclusion pattern is silently ignored.
+		for _, pattern := range s.config.Exclude {
+			if matched, _ := path.Match(pattern, key); matched {
+				return
+			}
+		}
+
+		name, tags := s.nameAndTags(key)
+		line := func(suffix, value, typ string) {
+			l := name + suffix + ":" + value + "|" + typ
+			if len(tags) > 0 {
+				l += "|#" + strings.Join(tags, ",")
+			}
+			lines = append(lines, l)
+		}
+		gauge := func(value float64) {
+			// A signed value is a relative change for StatsD
+			if value < 0 {
+				line("", "0", "g")
+			}
+			line("", statsdFloat(value), "g")
+		}
+		distribution := func(count, sum, min, max int64, mean, stddev float64, ps []float64, unit float64) {
+			countDelta := s.delta(key+".count", count)
+			sumDelta := s.delta(key+".sum", sum)
+			if s.config.Histograms == "mean" {
+				if countDelta <= 0 {
+					return
+				}
+				typ := "ms"
+				if s.config.Flavor == "dogstatsd" && unit == 1 {
+					typ = "h"
+				}
+				if countDelta > 1 {
+					typ += "|@" + statsdFloat(1/float64(countDelta))
+				}
+				line("", statsdFloat(float64(sumDelta)/float64(countDelta)/unit), typ)
+				return
+			}
+			line(".count", strconv.FormatInt(countDelta, 10), "c")
+			line(".min", statsdFloat(float64(min)/unit), "g")
+			line(".max", statsdFloat(float64(max)/unit), "g")
+			line(".mean", statsdFloat(mean/unit), "g")
+			line(".stddev", statsdFloat(stddev/unit), "g")
+			for i, p := range ps {
+				line("."+statsdPercentilesNames[i], statsdFloat(p/unit), "g")
+			}
+		}
+
+		buckets := func(snapshot interface{}) {
+			if b, ok := snapshot.(bucketed); ok {
+				bounds, counts := b.Buckets()
+				for i, bound := range bounds {
+					suffix := "." + strings.Replace(bucketName(bound), ".", "_", -1)
+					if delta := s.delta(key+suffix, int64(counts[i])); delta != 0 {
+						line(suffix, strconv.FormatInt(delta, 10), "c")
+					}
+				}
+			}
+		}
+
+		switch metric := i.(type) {
+		case metrics.Counter:
+			if delta := s.delta(key, metric.Count()); delta != 0 {
+				line("", strconv.FormatInt(delta, 10), "c")
+			}
+		case metrics.Gauge:
+			gauge(float64(metric.Value()))
+		case metrics.GaugeFloat64:
+			gauge(metric.Value())
+		case metrics.Meter:
+			if delta := s.delta(key, metric.Count()); delta != 0 {
+				line("", strconv.FormatInt(delta, 10), "c")
+			}
+		case metrics.Histogram:
+			h := metric.Snapshot()
+			distribution(h.Count(), h.Sum(), h.Min(), h.Max(), h.Mean(), h.StdDev(),
+				h.Percentiles(statsdPercentiles), 1)
+			buckets(h)
+		case metrics.Timer:
+			t := metric.Snapshot()
+			distribution(t.Count(), t.Sum(), t.Min(), t.Max(), t.Mean(), t.StdDev(),
+				t.Percentiles(statsdPercentiles), float64(time.Millisecond))
+			buckets(t)
+		}
+	})
+	return lines
+}
+
+// delta returns the difference between the current value of a
+// cumulative metric and its value at the previous flush.
+func (s *statsdState) delta(key string, value int64) int64 {
+	delta := value - s.previous[key]
+	s.current[key] = value
+	return delta
+}
+
+// nameAndTags returns the name and the tags of a metric. Labels are
+// sent as tags with DogStatsD, or appended to the name otherwise.
+func (s *statsdState) nameAndTags(key string) (string, []string) {
+	name, labels := decodeLabels(key)
+	if s.prefix != "" {
+		name = strings.Join([]string{s.prefix, name}, separator)
+	}
+	if s.config.Flavor != "dogstatsd" {
+		if labels != nil {
+			name = strings.Join(append([]string{name}, labelsValues(labels)...), separator)
+		}
+		return statsdName(name), nil
+	}
+	tags := s.tags
+	if labels != nil {
+		tags = append([]string(nil), s.tags...)
+		for _, k := range labelsKeys(labels) {
+			tags = append(tags, statsdTag(k, labels[k]))
+		}
+	}
+	return statsdName(name), tags
+}
+
+// statsdBatch batches lines in newline-separated packets of at most
+// max bytes. Larger lines are sent in their own packet.
+func statsdBatch(lines []string, max int) [][]byte {
+	var packets [][]byte
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > max {
+			packets = append(packets, append([]byte(nil), buf.Bytes()...))
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		packets = append(packets, buf.Bytes())
+	}
+	return packets
+}
+
+func statsdFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+func TestStatsd(t *testing.T) {
+	sock, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Unable to listen:\n%+v", err)
+	}
+	defer sock.Close()
+
+	var configuration Configuration = make([]ExporterConfiguration, 1)
+	configuration[0] = &StatsdConfiguration{
+		Network:       "udp",
+		Address:       sock.LocalAddr().String(),
+		Flavor:        "dogstatsd",
+		Histograms:    "summary",
+		Interval:      config.Duration(500 * time.Millisecond),
+		MaxPacketSize: 1432,
+		Exclude:       []string{"go.*"},
+	}
+	m, err := New(configuration, "project")
+	if err != nil {
+		t.Fatalf("New(%v) error:\n%+v", configuration, err)
+	}
+	m.MustStart()
+	defer m.Stop() // nolint: errcheck
+
+	metrics.NewRegisteredCounter(`foo{code="200"}`, m.Registry).Inc(47)
+
+	if err := sock.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1432)
+	n, err := sock.Read(buf)
+	if err != nil {
+		t.Fatalf("Unable to read from socket:\n%+v", err)
+	}
+	if got, want := string(buf[:n]), "project.foo:47|c|#code:200"; got != want {
+		t.Errorf("Received %q but expected %q", got, want)
+	}
+}
+
+func TestStatsdLines(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := metrics.NewRegisteredCounter("counter", r)
+	c.Inc(5)
+	metrics.NewRegisteredGauge(`gauge{code="200"}`, r).Update(-3)
+	timer := metrics.NewRegisteredTimer("timer", r)
+	timer.Update(10 * time.Millisecond)
+	timer.Update(30 * time.Millisecond)
+
+	s := newStatsdState(&StatsdConfiguration{Flavor: "statsd", Histograms: "mean"}, "project")
+	got := s.lines(r)
+	sort.Strings(got)
+	want := []string{
+		"project.counter:5|c",
+		"project.gauge.200:-3|g",
+		"project.gauge.200:0|g",
+		"project.timer:20|ms|@0.5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lines() == %q but expected %q", got, want)
+	}
+
+	// Deltas since previous flush
+	c.Inc(1)
+	got = s.lines(r)
+	sort.Strings(got)
+	want = []string{
+		"project.counter:1|c",
+		"project.gauge.200:-3|g",
+		"project.gauge.200:0|g",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lines() == %q but expected %q", got, want)
+	}
+
+	// Counters unregistered then registered again start over
+	r.Unregister("counter")
+	s.lines(r)
+	metrics.NewRegisteredCounter("counter", r).Inc(2)
+	got = s.lines(r)
+	sort.Strings(got)
+	if got[0] != "project.counter:2|c" {
+		t.Errorf("lines() == %q but expected a 2 counter delta", got)
+	}
+}
+
+func TestStatsdBatch(t *testing.T) {
+	packets := statsdBatch([]string{"a:1|c", "b:1|c", "c:1|c", "looooooooong:1|c"}, 12)
+	if len(packets) != 3 || string(packets[0]) != "a:1|c\nb:1|c" || string(packets[2]) != "looooooooong:1|c" {
+		t.Errorf("statsdBatch() == %q", packets)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/exoscale/go-reporter/metrics"
 )
 
 func TestMetricsExpandName(t *testing.T) {
@@ -131,3 +133,32 @@ func TestMetricsHealthcheck(t *testing.T) {
 		t.Errorf("Expected healthcheck error %q, got %q", "nope", h.Error())
 	}
 }
+
+func TestMetricsLabeledCounter(t *testing.T) {
+	r := NewMock()
+	r.Counter(".requests", "method", "GET").Inc(2)
+	r.Counter(".requests", "method", "POST").Inc(1)
+	got := r.Counter(".requests", "method", "GET").Count()
+	if got != 2 {
+		t.Errorf("Expected labeled counter value == 2, got %d", got)
+	}
+	if r.metrics.Registry.Get(`requests{method="POST"}`) == nil {
+		t.Errorf("Expected labeled counter to be registered, got %v", r.metrics.Registry.GetAll())
+	}
+}
+
+func TestMetricsSeriesLimit(t *testing.T) {
+	r := NewSilentMock()
+	r.metrics.SetSeries(metrics.SeriesConfiguration{Max: 1, Overflow: "reject"})
+	r.Counter(".accepted").Inc(1)
+	r.Counter(".rejected").Inc(1)
+	if got := r.Counter(".rejected").Count(); got != 0 {
+		t.Errorf("Expected rejected counter value == 0, got %d", got)
+	}
+	if r.metrics.Registry.Get("rejected") != nil {
+		t.Errorf("Expected rejected counter not to be registered")
+	}
+	if got := r.Counter(".accepted").Count(); got != 1 {
+		t.Errorf("Expected accepted counter value == 1, got %d", got)
+	}
+}
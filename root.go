@@ -29,8 +29,20 @@ func New(config Configuration) (*Reporter, error) {
 		return nil, err
 	}
 
+	// Stack frames considered as part of the application must be the
+	// same for Sentry and for the caller added to the logs.
+	if s != nil {
+		s.SetIncludePaths(config.Logging.InAppInclude)
+		s.Transport = &inAppTransport{
+			Transport: s.Transport,
+			config:    config.Logging,
+			prefix:    config.Prefix,
+		}
+	}
+
 	// Initialize logger
-	l, err := logger.New(config.Logging, sentryHandler(s, config.Sentry.Wait), config.Prefix)
+	limiter := sentry.NewRateLimiter(config.Sentry)
+	l, err := logger.New(config.Logging, sentryHandler(s, config.Sentry.Wait, limiter), config.Prefix)
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +52,26 @@ func New(config Configuration) (*Reporter, error) {
 	if err != nil {
 		return nil, err
 	}
+	m.SetLogger(l)
+	m.SetBuckets(config.Buckets)
+	m.SetSamples(config.Samples)
+	if config.Runtime != nil {
+		m.SetRuntime(*config.Runtime)
+	}
+	if config.Expiry != nil {
+		m.SetExpiry(*config.Expiry)
+	}
+	if config.Series != nil {
+		m.SetSeries(*config.Series)
+	}
+	if s != nil {
+		if err := m.Registry.Register("github.com/exoscale/go-reporter.sentry.events.sent", limiter.Sent); err != nil {
+			return nil, err
+		}
+		if err := m.Registry.Register("github.com/exoscale/go-reporter.sentry.events.suppressed", limiter.Suppressed); err != nil {
+			return nil, err
+		}
+	}
 
 	return &Reporter{
 		config:  &config,
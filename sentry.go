@@ -6,13 +6,17 @@ import (
 	"github.com/getsentry/raven-go"
 	"github.com/pkg/errors"
 	log "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/exoscale/go-reporter/logger"
+	"github.com/exoscale/go-reporter/sentry"
 )
 
 // Interface Sentry with the logging subsytem. The idea is to register
 // an additional handler that will forward errors to Sentry. If wait is
 // true, calls to Sentry API will be done synchronously (and thus will
-// block the caller).
-func sentryHandler(client *raven.Client, wait bool) log.Handler {
+// block the caller). Events exceeding the limits of the provided rate
+// limiter are dropped.
+func sentryHandler(client *raven.Client, wait bool, limiter *sentry.RateLimiter) log.Handler {
 	if client == nil {
 		// No sentry configured
 		return nil
@@ -44,6 +48,18 @@ func sentryHandler(client *raven.Client, wait bool) log.Handler {
 			return nil
 		}
 
+		fingerprint := fmt.Sprintf("%T\x00%s", errors.Cause(err), err.Error())
+		ok, suppressed, suppressedTotal := limiter.Allow(fingerprint)
+		if !ok {
+			return nil
+		}
+		if suppressed > 0 || suppressedTotal > 0 {
+			err = raven.WrapWithExtra(err, map[string]interface{}{
+				"suppressed_events":       suppressed,
+				"suppressed_events_total": suppressedTotal,
+			})
+		}
+
 		client.CaptureError(err, tags)
 		if wait {
 			client.Wait()
@@ -52,3 +68,40 @@ func sentryHandler(client *raven.Client, wait bool) log.Handler {
 		return nil
 	})
 }
+
+// inAppTransport clears the in-app flag of the stack frames that the
+// logging configuration excludes from the application before handing
+// the packet over to the wrapped transport. Raven only knows about
+// include paths.
+type inAppTransport struct {
+	raven.Transport
+	config logger.Configuration
+	prefix string
+}
+
+func (t *inAppTransport) Send(url, authHeader string, packet *raven.Packet) error {
+	for _, inter := range packet.Interfaces {
+		var st *raven.Stacktrace
+		switch v := inter.(type) {
+		case *raven.Exception:
+			st = v.Stacktrace
+		case *raven.Stacktrace:
+			st = v
+		}
+		if st == nil {
+			continue
+		}
+		culprit := st.Culprit()
+		for _, frame := range st.Frames {
+			if frame.InApp && !logger.InApp(t.config, t.prefix,
+				fmt.Sprintf("%s.%s", frame.Module, frame.Function),
+				frame.AbsolutePath) {
+				frame.InApp = false
+			}
+		}
+		if packet.Culprit == culprit {
+			packet.Culprit = st.Culprit()
+		}
+	}
+	return t.Transport.Send(url, authHeader, packet)
+}
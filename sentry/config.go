@@ -1,9 +1,19 @@
 package sentry
 
+import (
+	"github.com/exoscale/go-reporter/config"
+)
+
 // Configuration is the configuration for Sentry.
+//
+// MaxEventsPerSecond limits the number of events sent to Sentry
+// while DedupCooldown suppresses identical events sent during the
+// given period. Both are disabled when zero.
 type Configuration struct {
-	DSN     string
-	Tags    map[string]string
-	Version string
-	Wait    bool
+	DSN                string
+	Tags               map[string]string
+	Version            string
+	Wait               bool
+	MaxEventsPerSecond float64         `yaml:"max_events_per_second"`
+	DedupCooldown      config.Duration `yaml:"dedup_cooldown"`
 }
@@ -0,0 +1,123 @@
+package sentry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Number of tracked fingerprints above which expired ones are
+// purged. Sweeps are amortized: once above the threshold, the
+// fingerprints are swept at most once every as many events as there
+// are tracked fingerprints.
+const rateLimiterSweepThreshold = 1000
+
+// RateLimiter limits the events sent to Sentry. It combines a global
+// events-per-second budget (token bucket) and a per-fingerprint
+// cooldown. Suppressed and sent events are accounted in the Sent and
+// Suppressed counters.
+type RateLimiter struct {
+	Sent       metrics.Counter
+	Suppressed metrics.Counter
+
+	mu              sync.Mutex
+	rate            float64
+	tokens          float64
+	last            time.Time
+	cooldown        time.Duration
+	fingerprints    map[string]*fingerprint
+	suppressedTotal int64
+	sinceSweep      int // events since the latest sweep
+	now             func() time.Time
+}
+
+type fingerprint struct {
+	lastSent   time.Time
+	suppressed int64
+}
+
+// NewRateLimiter creates a new rate limiter from configuration. When
+// no limit is configured, all events are allowed but still accounted.
+func NewRateLimiter(config Configuration) *RateLimiter {
+	return &RateLimiter{
+		Sent:         metrics.NewCounter(),
+		Suppressed:   metrics.NewCounter(),
+		rate:         config.MaxEventsPerSecond,
+		tokens:       burst(config.MaxEventsPerSecond),
+		cooldown:     time.Duration(config.DedupCooldown),
+		fingerprints: make(map[string]*fingerprint),
+		now:          time.Now,
+	}
+}
+
+func burst(rate float64) float64 {
+	if rate < 1 {
+		return 1
+	}
+	return rate
+}
+
+// Allow tells if an event with the given fingerprint can be sent. If
+// so, it also returns the number of events with the same fingerprint
+// and the total number of events suppressed since the last sent
+// event.
+func (l *RateLimiter) Allow(key string) (ok bool, suppressed, suppressedTotal int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sinceSweep++
+	if len(l.fingerprints) > rateLimiterSweepThreshold && l.sinceSweep >= len(l.fingerprints) {
+		l.sweep(now)
+	}
+
+	f, found := l.fingerprints[key]
+	if !found {
+		f = &fingerprint{}
+		l.fingerprints[key] = f
+	}
+
+	if l.cooldown > 0 && !f.lastSent.IsZero() && now.Sub(f.lastSent) < l.cooldown {
+		l.suppress(f)
+		return false, 0, 0
+	}
+	if l.rate > 0 {
+		if !l.last.IsZero() {
+			l.tokens += now.Sub(l.last).Seconds() * l.rate
+			if max := burst(l.rate); l.tokens > max {
+				l.tokens = max
+			}
+		}
+		l.last = now
+		if l.tokens < 1 {
+			l.suppress(f)
+			return false, 0, 0
+		}
+		l.tokens--
+	}
+
+	suppressed, suppressedTotal = f.suppressed, l.suppressedTotal
+	f.suppressed = 0
+	f.lastSent = now
+	l.suppressedTotal = 0
+	l.Sent.Inc(1)
+	return true, suppressed, suppressedTotal
+}
+
+// sweep purges the fingerprints whose cooldown has expired and that
+// have no pending suppressed events.
+func (l *RateLimiter) sweep(now time.Time) {
+	l.sinceSweep = 0
+	for k, f := range l.fingerprints {
+		if f.suppressed == 0 && now.Sub(f.lastSent) >= l.cooldown {
+			delete(l.fingerprints, k)
+		}
+	}
+}
+
+func (l *RateLimiter) suppress(f *fingerprint) {
+	f.suppressed++
+	l.suppressedTotal++
+	l.Suppressed.Inc(1)
+}
@@ -0,0 +1,76 @@
+package sentry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/exoscale/go-reporter/config"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewRateLimiter(Configuration{
+		MaxEventsPerSecond: 2,
+		DedupCooldown:      config.Duration(10 * time.Second),
+	})
+	l.now = func() time.Time { return now }
+
+	cases := []struct {
+		advance         time.Duration
+		fingerprint     string
+		ok              bool
+		suppressed      int64
+		suppressedTotal int64
+	}{
+		{0, "a", true, 0, 0},
+		{0, "a", false, 0, 0}, // cooldown
+		{0, "b", true, 0, 1},
+		{0, "c", false, 0, 0}, // budget exhausted
+		{time.Second, "c", true, 1, 1},
+		{10 * time.Second, "a", true, 1, 0},
+	}
+	for i, tc := range cases {
+		now = now.Add(tc.advance)
+		ok, suppressed, suppressedTotal := l.Allow(tc.fingerprint)
+		if ok != tc.ok || suppressed != tc.suppressed || suppressedTotal != tc.suppressedTotal {
+			t.Errorf("Allow(%q) #%d == %v, %d, %d but expected %v, %d, %d",
+				tc.fingerprint, i+1, ok, suppressed, suppressedTotal,
+				tc.ok, tc.suppressed, tc.suppressedTotal)
+		}
+	}
+	if got := l.Sent.Count(); got != 4 {
+		t.Errorf("Sent.Count() == %d but expected %d", got, 4)
+	}
+	if got := l.Suppressed.Count(); got != 2 {
+		t.Errorf("Suppressed.Count() == %d but expected %d", got, 2)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	now := time.Now()
+	l := NewRateLimiter(Configuration{DedupCooldown: config.Duration(time.Minute)})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2*rateLimiterSweepThreshold; i++ {
+		l.Allow(fmt.Sprint(i))
+	}
+	if got := len(l.fingerprints); got != 2*rateLimiterSweepThreshold {
+		t.Fatalf("len(fingerprints) == %d but expected %d", got, 2*rateLimiterSweepThreshold)
+	}
+
+	// Expired fingerprints are not swept on every event, but once
+	// every as many events as there are fingerprints.
+	now = now.Add(time.Minute)
+	l.sinceSweep = 0
+	l.Allow("a")
+	if got := len(l.fingerprints); got != 2*rateLimiterSweepThreshold+1 {
+		t.Errorf("len(fingerprints) == %d but expected no sweep", got)
+	}
+	for i := 1; i < 2*rateLimiterSweepThreshold+1; i++ {
+		l.Allow("a")
+	}
+	if got := len(l.fingerprints); got != 1 {
+		t.Errorf("len(fingerprints) == %d but expected %d after a sweep", got, 1)
+	}
+}
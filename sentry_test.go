@@ -14,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/getsentry/raven-go"
 	"github.com/pkg/errors"
 	log "gopkg.in/inconshreveable/log15.v2"
 
@@ -125,3 +126,50 @@ func TestSentryReporting(t *testing.T) {
 		}
 	}
 }
+
+type packetsTransport struct {
+	packets []*raven.Packet
+}
+
+func (t *packetsTransport) Send(url, authHeader string, packet *raven.Packet) error {
+	t.packets = append(t.packets, packet)
+	return nil
+}
+
+func TestSentryInAppExclude(t *testing.T) {
+	next := &packetsTransport{}
+	transport := &inAppTransport{
+		Transport: next,
+		config: logger.Configuration{
+			InAppInclude: []string{"github.com/exoscale/app/vendor/github.com/exoscale/lib"},
+			InAppExclude: []string{"github.com/exoscale/app/internal/wrap"},
+		},
+		prefix: "github.com/exoscale/app",
+	}
+	st := &raven.Stacktrace{Frames: []*raven.StacktraceFrame{
+		{Module: "main", Function: "main", InApp: true},
+		{Module: "github.com/exoscale/app/vendor/github.com/exoscale/lib", Function: "Do", InApp: true},
+		{Module: "github.com/exoscale/app/internal/wrap", Function: "Error", InApp: true},
+		{Module: "net/http", Function: "Serve", InApp: false},
+	}}
+	packet := &raven.Packet{
+		Culprit:    st.Culprit(),
+		Interfaces: []raven.Interface{&raven.Exception{Stacktrace: st}},
+	}
+	if err := transport.Send("", "", packet); err != nil {
+		t.Fatalf("Send() error:\n%+v", err)
+	}
+	if len(next.packets) != 1 {
+		t.Fatalf("Send() forwarded %d packets but expected 1", len(next.packets))
+	}
+	expected := []bool{true, true, false, false}
+	for i, frame := range st.Frames {
+		if frame.InApp != expected[i] {
+			t.Errorf("frames[%d].InApp == %v but expected %v", i, frame.InApp, expected[i])
+		}
+	}
+	culprit := "github.com/exoscale/app/vendor/github.com/exoscale/lib.Do"
+	if packet.Culprit != culprit {
+		t.Errorf("packet.Culprit == %q but expected %q", packet.Culprit, culprit)
+	}
+}
@@ -2,20 +2,28 @@ package v2
 
 import (
 	"github.com/exoscale/go-reporter/v2/errors"
+	"github.com/exoscale/go-reporter/v2/health"
 	"github.com/exoscale/go-reporter/v2/logging"
 	"github.com/exoscale/go-reporter/v2/metrics"
+	"github.com/exoscale/go-reporter/v2/tracing"
 )
 
 type Config struct {
 	// Errors represents the errors reporter configuration.
 	Errors *errors.Config `yaml:"errors"`
 
+	// Health represents the health reporter configuration.
+	Health *health.Config `yaml:"health"`
+
 	// Logging represents the logging reporter configuration.
 	Logging *logging.Config `yaml:"logging"`
 
 	// Metrics represents the metrics reporter configuration.
 	Metrics *metrics.Config `yaml:"metrics"`
 
+	// Tracing represents the tracing reporter configuration.
+	Tracing *tracing.Config `yaml:"tracing"`
+
 	// Debug represents a flags indicating whether to enable internal reporter activity logging.
 	// This is mainly for debug purposes.
 	Debug bool `yaml:"debug"`
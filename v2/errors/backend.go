@@ -0,0 +1,218 @@
+package errors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"gopkg.in/tomb.v2"
+)
+
+// deliveryBufferSize represents the maximum number of events queued by a delivery backend in asynchronous mode.
+// Events sent while the queue is full are dropped.
+const deliveryBufferSize = 30
+
+// Backend represents an errors reporter backend, i.e. the destination of the events produced by the errors reporter.
+// Events are built by the Sentry SDK regardless of the backend, which makes any sentry.Transport implementation a
+// valid backend.
+type Backend interface {
+	sentry.Transport
+}
+
+// newBackend returns the backend specified in the errors reporter configuration. A nil backend means that the
+// Sentry SDK default transport must be used.
+func newBackend(config *Config) (Backend, error) {
+	var (
+		s   *spool
+		err error
+	)
+
+	if config.Spool != nil && config.Backend != "file" {
+		if s, err = newSpool(config.Spool); err != nil {
+			return nil, err
+		}
+	}
+
+	switch config.Backend {
+	case "file":
+		b, err := newFileBackend(config.File)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+
+	case "webhook":
+		return newDeliveryBackend(newWebhookDeliverer(config.Webhook), s, config.Wait), nil
+
+	default:
+		// The Sentry SDK transports don't report delivery failures, so we have to use our own
+		// to be able to spool the events that couldn't be delivered.
+		if s != nil {
+			d, err := newSentryDeliverer(config.DSN)
+			if err != nil {
+				return nil, err
+			}
+			return newDeliveryBackend(d, s, config.Wait), nil
+		}
+
+		if config.Wait {
+			return &sentry.HTTPSyncTransport{Timeout: sentryFlushTimeout}, nil
+		}
+		return nil, nil
+	}
+}
+
+// deliverer is the interface implemented by the backends endpoints able to report event delivery failures.
+type deliverer interface {
+	deliver(event *sentry.Event) error
+}
+
+// deliveryBackend represents an errors reporter backend delivering events to a remote endpoint, either
+// synchronously or asynchronously. If a spool is set, the events that couldn't be delivered are stored in it.
+type deliveryBackend struct {
+	d     deliverer
+	spool *spool
+	wait  bool
+
+	queue chan *sentry.Event
+	start sync.Once
+
+	mu     sync.RWMutex // Held for reading while queueing an event, for writing while closing the queue
+	closed bool
+
+	pendingMu sync.Mutex
+	pending   int           // Number of queued events not delivered yet
+	idle      chan struct{} // Closed when no event is pending
+}
+
+// newDeliveryBackend returns a new delivery backend. If wait is true, events are delivered synchronously.
+func newDeliveryBackend(d deliverer, s *spool, wait bool) *deliveryBackend {
+	idle := make(chan struct{})
+	close(idle)
+
+	return &deliveryBackend{
+		d:     d,
+		spool: s,
+		wait:  wait,
+		queue: make(chan *sentry.Event, deliveryBufferSize),
+		idle:  idle,
+	}
+}
+
+// Configure starts the delivery backend worker in asynchronous mode.
+func (b *deliveryBackend) Configure(_ sentry.ClientOptions) {
+	if !b.wait {
+		b.start.Do(func() {
+			go b.worker()
+		})
+	}
+}
+
+// SendEvent delivers the event to the backend endpoint. Events sent after the backend has been closed are dropped.
+func (b *deliveryBackend) SendEvent(event *sentry.Event) {
+	if b.wait {
+		b.send(event)
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		sentry.Logger.Println("delivery backend is closed, dropping event")
+		return
+	}
+
+	b.addPending(1)
+	select {
+	case b.queue <- event:
+	default:
+		b.addPending(-1)
+		sentry.Logger.Println("delivery queue is full, dropping event")
+	}
+}
+
+// Flush waits until the queued events have been delivered, blocking for at most the given timeout. It returns false
+// if the timeout was reached.
+func (b *deliveryBackend) Flush(timeout time.Duration) bool {
+	b.pendingMu.Lock()
+	idle := b.idle
+	b.pendingMu.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Close stops the delivery backend worker once the queued events have been delivered.
+func (b *deliveryBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+
+	return nil
+}
+
+func (b *deliveryBackend) worker() {
+	for event := range b.queue {
+		b.send(event)
+		b.addPending(-1)
+	}
+}
+
+// addPending adds delta to the number of pending events, signaling flushers once none is pending.
+func (b *deliveryBackend) addPending(delta int) {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+
+	if b.pending == 0 && delta > 0 {
+		b.idle = make(chan struct{})
+	}
+
+	b.pending += delta
+	if b.pending == 0 {
+		close(b.idle)
+	}
+}
+
+// send delivers the event, and stores it in the spool (if any) in case of failure.
+func (b *deliveryBackend) send(event *sentry.Event) {
+	err := b.d.deliver(event)
+	if err == nil {
+		return
+	}
+
+	sentry.Logger.Printf("unable to deliver event: %s", err)
+
+	if b.spool != nil {
+		if err := b.spool.store(event); err != nil {
+			sentry.Logger.Printf("unable to spool event: %s", err)
+		}
+	}
+}
+
+// replayLoop periodically attempts to deliver the spooled events. This method blocks the caller until the tomb
+// dies.
+func (b *deliveryBackend) replayLoop(t *tomb.Tomb) error {
+	tick := time.NewTicker(b.spool.replayInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			if n, err := b.spool.replay(b.d.deliver); err != nil {
+				sentry.Logger.Printf("unable to replay spooled events (%d replayed): %s", n, err)
+			}
+
+		case <-t.Dying():
+			return nil
+		}
+	}
+}
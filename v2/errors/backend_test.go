@@ -0,0 +1,108 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBackend(t *testing.T) {
+	var (
+		testErrorMessage = "oh noes!"
+		testFile         = path.Join(os.TempDir(), "go-reporter-errors.json")
+	)
+
+	defer os.Remove(testFile)
+
+	testReporter, err := New(&Config{
+		Backend: "file",
+		File:    &FileBackendConfig{Path: testFile},
+	})
+	require.NoError(t, err)
+	require.IsType(t, &fileBackend{}, testReporter.backend)
+
+	testReporter.SendError(errors.New(testErrorMessage), nil)
+	require.NoError(t, testReporter.Stop(context.Background()))
+
+	data, err := ioutil.ReadFile(testFile)
+	require.NoError(t, err)
+
+	var event sentry.Event
+	require.NoError(t, json.Unmarshal(data, &event))
+	require.Equal(t, testErrorMessage, event.Exception[0].Value)
+	require.NotNil(t, event.Exception[0].Stacktrace)
+}
+
+func TestWebhookBackend(t *testing.T) {
+	var (
+		testErrorMessage = "oh noes!"
+		events           = make(chan *sentry.Event, 1)
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var event sentry.Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- &event
+	}))
+	defer ts.Close()
+
+	testReporter, err := New(&Config{
+		Backend: "webhook",
+		Webhook: &WebhookBackendConfig{
+			URL:     ts.URL,
+			Headers: map[string]string{"X-Token": "secret"},
+		},
+	})
+	require.NoError(t, err)
+	require.IsType(t, &deliveryBackend{}, testReporter.backend)
+
+	testReporter.SendError(errors.New(testErrorMessage), nil)
+	require.True(t, testReporter.backend.Flush(sentryFlushTimeout))
+	require.NoError(t, testReporter.Stop(context.Background()))
+
+	event := <-events
+	require.Equal(t, testErrorMessage, event.Exception[0].Value)
+}
+
+func TestDeliveryBackend_SendAfterClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	testReporter, err := New(&Config{
+		Backend: "webhook",
+		Webhook: &WebhookBackendConfig{URL: ts.URL},
+	})
+	require.NoError(t, err)
+	require.NoError(t, testReporter.Start(context.Background()))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			testReporter.SendError(errors.New("oh noes!"), nil)
+		}
+	}()
+	require.NoError(t, testReporter.Stop(context.Background()))
+	wg.Wait()
+
+	require.NotPanics(t, func() {
+		testReporter.SendError(errors.New("oh noes!"), nil)
+		testReporter.sentry.CaptureException(errors.New("oh noes!"), nil, nil)
+	})
+	require.NoError(t, testReporter.backend.(*deliveryBackend).Close(), "closing twice should be a no-op")
+}
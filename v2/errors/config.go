@@ -1,22 +1,169 @@
 package errors
 
-import validation "github.com/go-ozzo/ozzo-validation/v4"
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultBackend = "sentry"
+
+	defaultWebhookTimeoutSec = 5
+
+	defaultSpoolMaxSize           = 10 * 1024 * 1024
+	defaultSpoolMaxAgeSec         = 24 * 60 * 60
+	defaultSpoolReplayIntervalSec = 30
+)
+
+// FileBackendConfig represents a file errors backend configuration.
+type FileBackendConfig struct {
+	// Path represents the filesystem path of the file to which the events are appended, one JSON document per line.
+	Path string `yaml:"path"`
+}
+
+func (c *FileBackendConfig) validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Path, validation.Required))
+}
+
+// WebhookBackendConfig represents a webhook errors backend configuration.
+type WebhookBackendConfig struct {
+	// URL represents the URL to which the events are sent as JSON documents using HTTP POST requests.
+	URL string `yaml:"url"`
+
+	// Headers represents user-defined HTTP headers to add to the webhook requests (e.g. for authentication).
+	Headers map[string]string `yaml:"headers"`
+
+	// Timeout represents the webhook requests timeout in seconds.
+	Timeout int `yaml:"timeout"`
+}
+
+func (c *WebhookBackendConfig) validate() error {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultWebhookTimeoutSec
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.URL, validation.Required, is.URL))
+}
+
+// SpoolConfig represents an errors reporter spool configuration.
+type SpoolConfig struct {
+	// Directory represents the filesystem path of the directory in which the events that couldn't be delivered to
+	// the backend are stored.
+	Directory string `yaml:"directory"`
+
+	// MaxSize represents the maximum total size in bytes of the spooled events. When exceeded, the oldest events are
+	// discarded. If not specified, defaults to 10MB.
+	MaxSize int64 `yaml:"max_size"`
+
+	// MaxAge represents the maximum age in seconds of the spooled events, older events are discarded.
+	// If not specified, defaults to 24 hours.
+	MaxAge int `yaml:"max_age"`
+
+	// ReplayInterval represents the time interval in seconds at which the delivery of the spooled events is
+	// attempted again. If not specified, defaults to 30 seconds.
+	ReplayInterval int `yaml:"replay_interval"`
+}
+
+func (c *SpoolConfig) validate() error {
+	if c.MaxSize <= 0 {
+		c.MaxSize = defaultSpoolMaxSize
+	}
+
+	if c.MaxAge <= 0 {
+		c.MaxAge = defaultSpoolMaxAgeSec
+	}
+
+	if c.ReplayInterval <= 0 {
+		c.ReplayInterval = defaultSpoolReplayIntervalSec
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Directory, validation.Required))
+}
 
 // Config represents an errors reporter configuration.
 type Config struct {
-	// DSN represents the Sentry DSN.
+	// Backend represents the backend to which the errors events are sent (sentry|file|webhook).
+	// If not specified, defaults to "sentry".
+	Backend string `yaml:"backend"`
+
+	// DSN represents the Sentry DSN. Required if the backend is "sentry".
 	DSN string `yaml:"dsn"`
 
-	// Wait represents a flag indicating if the calls to Sentry should be done synchronously
+	// File represents the file backend configuration. Required if the backend is "file".
+	File *FileBackendConfig `yaml:"file"`
+
+	// Webhook represents the webhook backend configuration. Required if the backend is "webhook".
+	Webhook *WebhookBackendConfig `yaml:"webhook"`
+
+	// Spool represents the spool configuration. If specified, the events that couldn't be delivered to the backend
+	// (e.g. during network partitions) are stored on disk and replayed in the background. The spool doesn't apply
+	// to the "file" backend.
+	Spool *SpoolConfig `yaml:"spool"`
+
+	// Wait represents a flag indicating if the calls to the backend should be done synchronously
 	// (effectively blocking the caller).
 	Wait bool `yaml:"wait"`
 
+	// MaxEventsPerSecond represents the maximum number of events per second sent to the backend. Events exceeding
+	// this budget are suppressed. If not specified, events are not rate limited.
+	MaxEventsPerSecond float64 `yaml:"max_events_per_second"`
+
+	// DedupCooldown represents the time interval in seconds during which events similar to a previously sent one
+	// (i.e. sharing the same fingerprint) are suppressed. If not specified, events are not deduplicated.
+	DedupCooldown int `yaml:"dedup_cooldown"`
+
+	// InAppInclude represents a list of module prefixes (e.g. "github.com/exoscale/myapp") whose stack trace frames
+	// are considered as part of the application. It takes precedence over InAppExclude.
+	InAppInclude []string `yaml:"in_app_include"`
+
+	// InAppExclude represents a list of module prefixes whose stack trace frames are considered as not part of the
+	// application, in addition to the reporter's internal modules. Frames are not removed from the stack traces.
+	InAppExclude []string `yaml:"in_app_exclude"`
+
 	// Debug represents a flags indicating whether to enable internal reporter activity logging.
 	// This is mainly for debug purposes.
 	Debug bool `yaml:"debug"`
 }
 
 func (c *Config) validate() error {
+	if c.Backend == "" {
+		c.Backend = defaultBackend
+	}
+
+	if c.File != nil {
+		if err := c.File.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Webhook != nil {
+		if err := c.Webhook.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Spool != nil {
+		if err := c.Spool.validate(); err != nil {
+			return err
+		}
+	}
+
 	return validation.ValidateStruct(c,
-		validation.Field(&c.DSN, validation.Required))
+		validation.Field(&c.Backend,
+			validation.In(
+				"sentry",
+				"file",
+				"webhook",
+			)),
+		validation.Field(&c.DSN,
+			validation.When(c.Backend == "sentry", validation.Required)),
+		validation.Field(&c.File,
+			validation.When(c.Backend == "file", validation.Required)),
+		validation.Field(&c.Webhook,
+			validation.When(c.Backend == "webhook", validation.Required)),
+		validation.Field(&c.MaxEventsPerSecond, validation.Min(0.0)),
+		validation.Field(&c.DedupCooldown, validation.Min(0)))
 }
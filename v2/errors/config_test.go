@@ -14,4 +14,43 @@ func TestConfig_Validate(t *testing.T) {
 
 	config = &Config{DSN: testSentryDSN}
 	require.NoError(t, config.validate())
+	require.Equal(t, defaultBackend, config.Backend, "should have been set to default value")
+
+	config = &Config{Backend: "lolnope", DSN: testSentryDSN}
+	require.Error(t, config.validate())
+
+	config = &Config{Backend: "file"}
+	require.Error(t, config.validate())
+
+	config = &Config{Backend: "file", File: &FileBackendConfig{}}
+	require.Error(t, config.validate())
+
+	config = &Config{Backend: "file", File: &FileBackendConfig{Path: "/tmp/errors.json"}}
+	require.NoError(t, config.validate())
+
+	config = &Config{Backend: "webhook", Webhook: &WebhookBackendConfig{URL: "lolnope"}}
+	require.Error(t, config.validate())
+
+	config = &Config{Backend: "webhook", Webhook: &WebhookBackendConfig{URL: "https://example.net/events"}}
+	require.NoError(t, config.validate())
+	require.Equal(t, defaultWebhookTimeoutSec, config.Webhook.Timeout, "should have been set to default value")
+
+	config = &Config{DSN: testSentryDSN, Spool: &SpoolConfig{}}
+	require.Error(t, config.validate())
+
+	config = &Config{DSN: testSentryDSN, Spool: &SpoolConfig{Directory: "/tmp/spool"}}
+	require.NoError(t, config.validate())
+	require.Equal(t, int64(defaultSpoolMaxSize), config.Spool.MaxSize, "should have been set to default value")
+	require.Equal(t, defaultSpoolMaxAgeSec, config.Spool.MaxAge, "should have been set to default value")
+	require.Equal(t, defaultSpoolReplayIntervalSec, config.Spool.ReplayInterval,
+		"should have been set to default value")
+
+	config = &Config{DSN: testSentryDSN, MaxEventsPerSecond: -1}
+	require.Error(t, config.validate())
+
+	config = &Config{DSN: testSentryDSN, DedupCooldown: -1}
+	require.Error(t, config.validate())
+
+	config = &Config{DSN: testSentryDSN, MaxEventsPerSecond: 0.5, DedupCooldown: 60}
+	require.NoError(t, config.validate())
 }
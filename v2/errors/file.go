@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// fileBackend represents an errors reporter backend writing events to a file as JSON lines, including their full
+// stack traces. This is typically useful in air-gapped environments where no Sentry server is reachable.
+type fileBackend struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// newFileBackend returns a new file backend writing to the file specified in the configuration.
+func newFileBackend(config *FileBackendConfig) (*fileBackend, error) {
+	f, err := os.OpenFile(config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileBackend{file: f}, nil
+}
+
+// Configure is a no-op for fileBackend.
+func (b *fileBackend) Configure(_ sentry.ClientOptions) {}
+
+// SendEvent appends the event to the backend file.
+func (b *fileBackend) SendEvent(event *sentry.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		sentry.Logger.Printf("unable to encode event: %s", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.file.Write(append(data, '\n')); err != nil {
+		sentry.Logger.Printf("unable to write event: %s", err)
+	}
+}
+
+// Flush commits the backend file content to stable storage. It always returns true immediately.
+func (b *fileBackend) Flush(_ time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_ = b.file.Sync()
+
+	return true
+}
+
+// Close closes the backend file.
+func (b *fileBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.file.Close()
+}
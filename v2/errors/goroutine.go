@@ -0,0 +1,192 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultRestartMinBackoff = time.Second
+	defaultRestartMaxBackoff = time.Minute
+)
+
+// RestartOptions represents the options controlling the restart of a goroutine after a panic.
+type RestartOptions struct {
+	// MinBackoff represents the delay before restarting the goroutine after its first panic. The delay is doubled
+	// after each consecutive panic. If not specified, defaults to 1 second.
+	MinBackoff time.Duration
+
+	// MaxBackoff represents the maximum delay before restarting the goroutine. If the goroutine runs for longer than
+	// this delay before panicking again, the delay is reset to MinBackoff. If not specified, defaults to 1 minute.
+	MaxBackoff time.Duration
+
+	// MaxRestarts represents the maximum number of restarts after which the goroutine is given up. If not specified,
+	// the goroutine is restarted until the context is canceled.
+	MaxRestarts int
+}
+
+// PanicError represents an error resulting from a panic recovered in a goroutine.
+type PanicError struct {
+	// Goroutine represents the name of the goroutine in which the panic occurred.
+	Goroutine string
+
+	// Value represents the recovered panic value.
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in goroutine %q: %v", e.Goroutine, e.Value)
+}
+
+// Go runs the function fn in a new goroutine identified by name. If fn panics, the panic is recovered, sent to Sentry
+// with the goroutine name as "goroutine" tag, and logged if a logger has been set using SetLogger().
+func (r *Reporter) Go(ctx context.Context, name string, fn func(context.Context)) {
+	go func() {
+		_ = r.run(ctx, name, fn)
+	}()
+}
+
+// GoWithRestart works like Go, except that the function fn is restarted with an exponential backoff delay each time
+// it panics, until ctx is canceled. If opts is nil, default options are used.
+func (r *Reporter) GoWithRestart(ctx context.Context, name string, fn func(context.Context), opts *RestartOptions) {
+	if opts == nil {
+		opts = new(RestartOptions)
+	}
+
+	minBackoff, maxBackoff := opts.MinBackoff, opts.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultRestartMinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = defaultRestartMaxBackoff
+		if maxBackoff < minBackoff {
+			maxBackoff = minBackoff
+		}
+	}
+
+	go func() {
+		backoff := minBackoff
+
+		for restarts := 0; ; restarts++ {
+			started := time.Now()
+			if r.run(ctx, name, fn) == nil {
+				return
+			}
+
+			if opts.MaxRestarts > 0 && restarts >= opts.MaxRestarts {
+				r.Debug("giving up goroutine", "goroutine", name, "restarts", restarts)
+				return
+			}
+
+			if time.Since(started) > maxBackoff {
+				backoff = minBackoff
+			}
+
+			r.Debug("restarting goroutine", "goroutine", name, "backoff", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}()
+}
+
+// run executes the function fn and returns a *PanicError if it panics, after reporting the panic.
+func (r *Reporter) run(ctx context.Context, name string, fn func(context.Context)) (err error) {
+	defer func() {
+		if re := recover(); re != nil {
+			err = &PanicError{Goroutine: name, Value: re}
+			r.reportGoroutinePanic(ctx, name, re)
+		}
+	}()
+
+	fn(ctx)
+
+	return nil
+}
+
+// reportGoroutinePanic sends the panic value re recovered in the goroutine name to Sentry, and logs it. The scope
+// and the trace context of the span carried by ctx (if any) are attached to the event and the log record.
+func (r *Reporter) reportGoroutinePanic(ctx context.Context, name string, re interface{}) {
+	scope := sentry.NewScope()
+	if s := r.FromContext(ctx).sentryScope(); s != nil {
+		scope = s.Clone()
+	}
+	scope.SetTag("goroutine", name)
+
+	// The Sentry event ID is always set in the log record context, even if empty (i.e. the event has been
+	// suppressed), so that LogHandler() doesn't send the record to Sentry.
+	var eventID string
+	if id := r.reportPanic(re, scope); id != nil {
+		eventID = string(*id)
+	}
+
+	if r.logger != nil {
+		kv := []interface{}{"goroutine", name, "panic", re, sentryEventIDLogKey, eventID}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			kv = append(kv, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+		}
+		r.logger.Error("panic recovered in goroutine", kv...)
+	}
+}
+
+// Group represents a collection of goroutines working on subtasks of a common task, similarly to
+// golang.org/x/sync/errgroup: the first goroutine to return an error (including a recovered panic, reported as a
+// *PanicError) cancels the group context.
+type Group struct {
+	r      *Reporter
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel func()
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup returns a new goroutines Group and an associated context derived from ctx.
+func (r *Reporter) NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Group{r: r, ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go runs the function fn in a new goroutine identified by name. If fn panics, the panic is reported as with
+// Reporter.Go() and returned as a *PanicError by the Wait method.
+func (g *Group) Go(name string, fn func(context.Context) error) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		var fnErr error
+		if err := g.r.run(g.ctx, name, func(ctx context.Context) { fnErr = fn(ctx) }); err != nil {
+			fnErr = err
+		}
+
+		if fnErr != nil {
+			g.errOnce.Do(func() {
+				g.err = fnErr
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait blocks until all the goroutines of the group have returned, then returns the first non-nil error (if any)
+// returned by one of them.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	return g.err
+}
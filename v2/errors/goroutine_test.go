@@ -0,0 +1,158 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+type testLogger struct {
+	sync.Mutex
+	records []*log15.Record
+}
+
+func (l *testLogger) Error(msg string, ctx ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.records = append(l.records, &log15.Record{Lvl: log15.LvlError, Msg: msg, Ctx: ctx})
+}
+
+func (l *testLogger) Records() []*log15.Record {
+	l.Lock()
+	defer l.Unlock()
+	return l.records
+}
+
+func TestReporter_Go(t *testing.T) {
+	var (
+		testErrorMessage    = "oh noes!"
+		sentryTestTransport = new(SentryTestTransport)
+		logger              = new(testLogger)
+	)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+	testReporter.SetLogger(logger)
+
+	testReporter.Go(context.Background(), "worker", func(_ context.Context) {
+		panic(errors.New(testErrorMessage))
+	})
+
+	require.Eventually(t,
+		func() bool { return len(logger.Records()) == 1 },
+		time.Second*3,
+		10*time.Millisecond)
+	require.Len(t, sentryTestTransport.Events(), 1)
+	require.Equal(t, testErrorMessage, sentryTestTransport.Events()[0].Exception[0].Value)
+	require.Equal(t, "worker", sentryTestTransport.Events()[0].Tags["goroutine"])
+
+	// The log record must reference the Sentry event, and must not be sent again by the log handler
+	rec := logger.Records()[0]
+	require.Equal(t, []interface{}{
+		"goroutine", "worker",
+		"panic", errors.New(testErrorMessage),
+		sentryEventIDLogKey, string(sentryTestTransport.Events()[0].EventID),
+	}, rec.Ctx)
+	require.NoError(t, testReporter.LogHandler().Log(rec))
+	require.Len(t, sentryTestTransport.Events(), 1)
+}
+
+func TestReporter_Go_Trace(t *testing.T) {
+	var (
+		sentryTestTransport = new(SentryTestTransport)
+		logger              = new(testLogger)
+		testTraceID, _      = trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		testSpanID, _       = trace.SpanIDFromHex("00f067aa0ba902b7")
+	)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+	testReporter.SetLogger(logger)
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: testTraceID,
+		SpanID:  testSpanID,
+	}))
+	testReporter.Go(ctx, "worker", func(_ context.Context) {
+		panic(errors.New("oh noes!"))
+	})
+
+	require.Eventually(t,
+		func() bool { return len(logger.Records()) == 1 },
+		time.Second*3,
+		10*time.Millisecond)
+	require.Equal(t, testTraceID.String(), sentryTestTransport.Events()[0].Tags["trace_id"])
+	require.Equal(t, []interface{}{"trace_id", testTraceID.String(), "span_id", testSpanID.String()},
+		logger.Records()[0].Ctx[6:])
+}
+
+func TestReporter_GoWithRestart(t *testing.T) {
+	var (
+		sentryTestTransport = new(SentryTestTransport)
+		runs                = make(chan struct{}, 10)
+	)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	testReporter.GoWithRestart(context.Background(), "worker", func(_ context.Context) {
+		runs <- struct{}{}
+		panic("oh noes!")
+	}, &RestartOptions{
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+		MaxRestarts: 2,
+	})
+
+	require.Eventually(t,
+		func() bool { return len(sentryTestTransport.Events()) == 3 },
+		time.Second*3,
+		10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, runs, 3)
+}
+
+func TestGroup(t *testing.T) {
+	var (
+		testError           = errors.New("oh noes!")
+		sentryTestTransport = new(SentryTestTransport)
+	)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	g, ctx := testReporter.NewGroup(context.Background())
+	g.Go("ok", func(_ context.Context) error { return nil })
+	g.Go("ko", func(_ context.Context) error { return testError })
+	require.Equal(t, testError, g.Wait())
+	require.Error(t, ctx.Err())
+	require.Len(t, sentryTestTransport.Events(), 0)
+
+	g, ctx = testReporter.NewGroup(context.Background())
+	g.Go("waiter", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	g.Go("panicker", func(_ context.Context) error { panic("oh noes!") })
+	err = g.Wait()
+	require.Error(t, err)
+	require.IsType(t, &PanicError{}, err)
+	require.Equal(t, "panicker", err.(*PanicError).Goroutine)
+	require.Error(t, ctx.Err())
+	require.Len(t, sentryTestTransport.Events(), 1)
+	require.Equal(t, "panicker", sentryTestTransport.Events()[0].Tags["goroutine"])
+}
@@ -0,0 +1,208 @@
+package errors
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scrubbedValue represents the value replacing sensitive data in events sent to Sentry.
+const scrubbedValue = "[Filtered]"
+
+// defaultHTTPHeaders represents the list of HTTP request headers attached to Sentry events by default.
+var defaultHTTPHeaders = []string{
+	"Accept",
+	"Content-Length",
+	"Content-Type",
+	"Referer",
+	"User-Agent",
+	"X-Forwarded-For",
+	"X-Request-Id",
+}
+
+// HTTPMiddlewareOptions represents the options of the errors reporter HTTP middleware.
+type HTTPMiddlewareOptions struct {
+	// Headers represents the list of HTTP request headers to attach to Sentry events, all other headers are left out.
+	// If not specified, a default list of non-sensitive headers is used.
+	Headers []string
+
+	// ReportServerErrors represents a flag indicating whether to send an event to Sentry when a request handler
+	// replies with a 5xx status code.
+	ReportServerErrors bool
+
+	// OnPanic represents a function executed after a panic in a request handler has been recovered and reported,
+	// typically to reply to the client. If not specified, a "500 Internal Server Error" response is sent.
+	OnPanic func(w http.ResponseWriter, req *http.Request, recovered interface{})
+}
+
+// HTTPMiddleware returns a net/http middleware recovering panics occurring in the wrapped handler and sending them to
+// Sentry. Information about the incoming request is attached to a per-request scope available to the wrapped handler
+// using ScopeFromContext() or Reporter.FromContext() (as well as sentry.GetHubFromContext()), and added to the events
+// sent to Sentry. If opts is nil, default options are used.
+func (r *Reporter) HTTPMiddleware(opts *HTTPMiddlewareOptions) func(http.Handler) http.Handler {
+	if opts == nil {
+		opts = new(HTTPMiddlewareOptions)
+	}
+
+	headers := opts.Headers
+	if headers == nil {
+		headers = defaultHTTPHeaders
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			scope := NewScope()
+			if r.scope != nil {
+				scope = r.scope.Clone()
+			}
+			scope.scope.SetRequest(sentryRequestFromHTTPRequest(req, headers))
+			if sc := trace.SpanContextFromContext(req.Context()); sc.IsValid() {
+				scope.setTraceContext(sc)
+			}
+
+			// The request scope is made available to the wrapped handler both as errors reporter scope
+			// and as Sentry hub, for compatibility with third-party integrations.
+			hub := sentry.NewHub(r.sentry, scope.scope)
+			ctx := context.WithValue(req.Context(), scopeContextKey{}, scope)
+			req = req.WithContext(sentry.SetHubOnContext(ctx, hub))
+
+			rw := &statusRecorder{ResponseWriter: w}
+
+			defer func() {
+				if re := recover(); re != nil {
+					// http.ErrAbortHandler is the sentinel panic value used to abort a response on purpose:
+					// it is propagated to the HTTP server, which suppresses it, instead of being reported.
+					if re == http.ErrAbortHandler {
+						panic(re)
+					}
+
+					r.reportPanic(re, scope.scope)
+
+					if opts.OnPanic != nil {
+						opts.OnPanic(rw, req, re)
+						return
+					}
+					if !rw.wroteHeader && !rw.hijacked {
+						http.Error(rw, http.StatusText(http.StatusInternalServerError),
+							http.StatusInternalServerError)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rw, req)
+
+			if opts.ReportServerErrors && rw.status >= http.StatusInternalServerError {
+				r.sentry.CaptureException(
+					fmt.Errorf("%s %s: %d %s", req.Method, req.URL.Path, rw.status, http.StatusText(rw.status)),
+					nil,
+					sentryEventWithTags(map[string]string{"status_code": fmt.Sprint(rw.status)}).
+						withScope(scope.scope))
+			}
+		})
+	}
+}
+
+// sentryRequestFromHTTPRequest returns a sentry.Request containing the HTTP request method, URL (with query
+// parameters values scrubbed), the allowed headers and the client remote address.
+func sentryRequestFromHTTPRequest(req *http.Request, headers []string) sentry.Request {
+	u := *req.URL
+	if u.Host == "" {
+		u.Host = req.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		if req.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+	u.User = nil
+	u.RawQuery = scrubQuery(u.Query()).Encode()
+
+	request := sentry.Request{
+		URL:         u.String(),
+		Method:      req.Method,
+		QueryString: u.RawQuery,
+		Headers:     make(map[string]string),
+		Env:         map[string]string{"REMOTE_ADDR": req.RemoteAddr},
+	}
+	for _, h := range headers {
+		if v := req.Header.Get(h); v != "" {
+			request.Headers[http.CanonicalHeaderKey(h)] = v
+		}
+	}
+
+	return request
+}
+
+// scrubQuery returns a copy of the URL query values where all values have been replaced by a placeholder.
+func scrubQuery(query url.Values) url.Values {
+	scrubbed := make(url.Values, len(query))
+	for k := range query {
+		scrubbed.Set(k, scrubbedValue)
+	}
+
+	return scrubbed
+}
+
+// statusRecorder is a http.ResponseWriter recording the status code replied by a request handler. It implements the
+// http.Flusher, http.Hijacker and http.Pusher interfaces if the underlying http.ResponseWriter does.
+type statusRecorder struct {
+	http.ResponseWriter
+
+	status      int
+	wroteHeader bool
+	hijacked    bool
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements the http.Flusher interface if the underlying http.ResponseWriter supports it.
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface if the underlying http.ResponseWriter supports it.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying http.ResponseWriter doesn't implement http.Hijacker")
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+
+	return conn, rw, err
+}
+
+// Push implements the http.Pusher interface if the underlying http.ResponseWriter supports it.
+func (w *statusRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
@@ -0,0 +1,128 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReporter_HTTPMiddleware(t *testing.T) {
+	var (
+		testErrorMessage    = "oh noes!"
+		sentryTestTransport = new(SentryTestTransport)
+	)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	handler := testReporter.HTTPMiddleware(nil)(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		assert.True(t, sentry.HasHubOnContext(req.Context()))
+		panic(errors.New(testErrorMessage))
+	}))
+
+	req := httptest.NewRequest("GET", "http://example.net/test?token=secret", nil)
+	req.Header.Set("User-Agent", "go-reporter")
+	req.Header.Set("Authorization", "Bearer secret")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusInternalServerError, res.Code)
+	require.Len(t, sentryTestTransport.Events(), 1)
+	event := sentryTestTransport.Events()[0]
+	require.Equal(t, testErrorMessage, event.Exception[0].Value)
+	require.Equal(t, "GET", event.Request.Method)
+	require.Equal(t, "http://example.net/test?token=%5BFiltered%5D", event.Request.URL)
+	require.Equal(t, map[string]string{"User-Agent": "go-reporter"}, event.Request.Headers)
+	require.Equal(t, req.RemoteAddr, event.Request.Env["REMOTE_ADDR"])
+}
+
+func TestReporter_HTTPMiddleware_ReportServerErrors(t *testing.T) {
+	var sentryTestTransport = new(SentryTestTransport)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	handler := testReporter.HTTPMiddleware(&HTTPMiddlewareOptions{
+		ReportServerErrors: true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte("ok"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/ok", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sentryTestTransport.Events(), 0)
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("POST", "/ko", nil))
+	require.Equal(t, http.StatusBadGateway, res.Code)
+	require.Len(t, sentryTestTransport.Events(), 1)
+	require.Equal(t, "POST /ko: 502 Bad Gateway", sentryTestTransport.Events()[0].Exception[0].Value)
+	require.Equal(t, "502", sentryTestTransport.Events()[0].Tags["status_code"])
+}
+
+func TestReporter_HTTPMiddleware_ErrAbortHandler(t *testing.T) {
+	var sentryTestTransport = new(SentryTestTransport)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	handler := testReporter.HTTPMiddleware(nil)(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+	require.Len(t, sentryTestTransport.Events(), 0)
+}
+
+func TestReporter_HTTPMiddleware_Hijack(t *testing.T) {
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(new(SentryTestTransport))
+
+	ts := httptest.NewServer(testReporter.HTTPMiddleware(nil)(http.HandlerFunc(
+		func(w http.ResponseWriter, _ *http.Request) {
+			// Assertions are not fatal here, since the handler runs in the server goroutine
+			if pusher, ok := w.(http.Pusher); assert.True(t, ok) {
+				assert.Equal(t, http.ErrNotSupported, pusher.Push("/style.css", nil))
+			}
+
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+
+			_, _ = buf.WriteString("HTTP/1.1 418 I'm a teapot\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+			_ = buf.Flush()
+		})))
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusTeapot, res.StatusCode)
+
+	// Hijacking is reported as unsupported if the underlying http.ResponseWriter doesn't support it
+	_, _, err = (&statusRecorder{ResponseWriter: httptest.NewRecorder()}).Hijack()
+	require.Error(t, err)
+}
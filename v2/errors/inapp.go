@@ -0,0 +1,88 @@
+package errors
+
+import (
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// defaultInAppExclude represents the list of modules always considered as not part of the application, unless
+// explicitly included.
+var defaultInAppExclude = []string{
+	"github.com/exoscale/go-reporter",
+	"gopkg.in/inconshreveable/log15.v2",
+}
+
+// inAppFilter marks the stack trace frames as being part of the application ("in-app") or not, based on their
+// module. Frames are never removed from the stack traces: Sentry uses the in-app flag to determine the culprit frame
+// of an event and to collapse the irrelevant frames.
+type inAppFilter struct {
+	include []string
+	exclude []string
+}
+
+// newInAppFilter returns a new in-app filter. The include list takes precedence over the exclude list, which is
+// extended with the reporter's internal modules.
+func newInAppFilter(include, exclude []string) *inAppFilter {
+	return &inAppFilter{
+		include: include,
+		exclude: append(append([]string{}, defaultInAppExclude...), exclude...),
+	}
+}
+
+// inApp returns whether the frame of the specified module is part of the application. If the module doesn't match
+// any of the include or exclude prefixes, the frame keeps the in-app flag determined by the Sentry SDK (i.e. false
+// for standard library and vendored modules).
+func (f *inAppFilter) inApp(module string, inApp bool) bool {
+	// Note: the Sentry SDK encodes dots in packages' import path into "%2e".
+	module = strings.Replace(module, "%2e", ".", -1)
+
+	for _, prefix := range f.include {
+		if hasModulePrefix(module, prefix) {
+			return true
+		}
+	}
+
+	for _, prefix := range f.exclude {
+		if hasModulePrefix(module, prefix) {
+			return false
+		}
+	}
+
+	return inApp
+}
+
+// markFrames sets the in-app flag of the stack trace frames.
+func (f *inAppFilter) markFrames(frames []sentry.Frame) {
+	for i := range frames {
+		frames[i].InApp = f.inApp(frames[i].Module, frames[i].InApp)
+	}
+}
+
+// eventProcessor returns a Sentry event processor marking the in-app frames of the event stack traces.
+func (f *inAppFilter) eventProcessor() sentry.EventProcessor {
+	return func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		for i := range event.Exception {
+			if event.Exception[i].Stacktrace != nil {
+				f.markFrames(event.Exception[i].Stacktrace.Frames)
+			}
+		}
+		for i := range event.Threads {
+			if event.Threads[i].Stacktrace != nil {
+				f.markFrames(event.Threads[i].Stacktrace.Frames)
+			}
+		}
+
+		return event
+	}
+}
+
+// hasModulePrefix returns whether the module belongs to the module or package path prefix, i.e. if it is equal to
+// prefix or is a sub-package of prefix.
+func hasModulePrefix(module, prefix string) bool {
+	if !strings.HasPrefix(module, prefix) {
+		return false
+	}
+
+	return len(module) == len(prefix) || strings.HasSuffix(prefix, "/") || module[len(prefix)] == '/'
+}
@@ -0,0 +1,105 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInAppFilter_MarkFrames(t *testing.T) {
+	var testStacktrace = []sentry.Frame{
+		sentry.Frame{Function: "main", Symbol: "", Module: "main", Package: "", Filename: "goreporter.go", AbsPath: "/Users/marc/Documents/Exoscale/git/go-reporter/v2/test/goreporter.go", Lineno: 60, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "doSomething", Symbol: "", Module: "main", Package: "", Filename: "goreporter.go", AbsPath: "/Users/marc/Documents/Exoscale/git/go-reporter/v2/test/goreporter.go", Lineno: 81, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "(*Reporter).Error", Symbol: "", Module: "github.com/exoscale/go-reporter/v2", Package: "", Filename: "logging.go", AbsPath: "/Users/marc/Documents/Exoscale/git/go-reporter/v2/logging.go", Lineno: 15, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "(*Reporter).Error", Symbol: "", Module: "github.com/exoscale/go-reporter/v2/logging", Package: "", Filename: "reporter.go", AbsPath: "/Users/marc/Documents/Exoscale/git/go-reporter/v2/logging/reporter.go", Lineno: 83, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "(*logger).Error", Symbol: "", Module: "gopkg.in/inconshreveable/log15%2ev2", Package: "", Filename: "logger.go", AbsPath: "/Users/marc/.go/pkg/mod/gopkg.in/inconshreveable/log15.v2@v2.0.0-20200109203555-b30bc20e4fd1/logger.go", Lineno: 153, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "(*logger).write", Symbol: "", Module: "gopkg.in/inconshreveable/log15%2ev2", Package: "", Filename: "logger.go", AbsPath: "/Users/marc/.go/pkg/mod/gopkg.in/inconshreveable/log15.v2@v2.0.0-20200109203555-b30bc20e4fd1/logger.go", Lineno: 112, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "(*swapHandler).Log", Symbol: "", Module: "gopkg.in/inconshreveable/log15%2ev2", Package: "", Filename: "handler_go14.go", AbsPath: "/Users/marc/.go/pkg/mod/gopkg.in/inconshreveable/log15.v2@v2.0.0-20200109203555-b30bc20e4fd1/handler_go14.go", Lineno: 14, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "funcHandler.Log", Symbol: "", Module: "gopkg.in/inconshreveable/log15%2ev2", Package: "", Filename: "handler.go", AbsPath: "/Users/marc/.go/pkg/mod/gopkg.in/inconshreveable/log15.v2@v2.0.0-20200109203555-b30bc20e4fd1/handler.go", Lineno: 31, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "MultiHandler.func1", Symbol: "", Module: "gopkg.in/inconshreveable/log15%2ev2", Package: "", Filename: "handler.go", AbsPath: "/Users/marc/.go/pkg/mod/gopkg.in/inconshreveable/log15.v2@v2.0.0-20200109203555-b30bc20e4fd1/handler.go", Lineno: 204, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "funcHandler.Log", Symbol: "", Module: "gopkg.in/inconshreveable/log15%2ev2", Package: "", Filename: "handler.go", AbsPath: "/Users/marc/.go/pkg/mod/gopkg.in/inconshreveable/log15.v2@v2.0.0-20200109203555-b30bc20e4fd1/handler.go", Lineno: 31, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+		sentry.Frame{Function: "(*Reporter).LogHandler.func1", Symbol: "", Module: "github.com/exoscale/go-reporter/v2/errors", Package: "", Filename: "reporter.go", AbsPath: "/Users/marc/Documents/Exoscale/git/go-reporter/v2/errors/reporter.go", Lineno: 57, Colno: 0, PreContext: []string(nil), ContextLine: "", PostContext: []string(nil), InApp: true, Vars: map[string]interface{}(nil)},
+	}
+
+	newInAppFilter(nil, nil).markFrames(testStacktrace)
+	require.Len(t, testStacktrace, 11)
+	for _, frame := range testStacktrace {
+		require.Equal(t, frame.Module == "main", frame.InApp, frame.Module)
+	}
+}
+
+func TestInAppFilter_InApp(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		include  []string
+		exclude  []string
+		module   string
+		inApp    bool
+		expected bool
+	}{
+		{name: "unmatched keeps SDK value", module: "github.com/exoscale/myapp", inApp: true, expected: true},
+		{name: "unmatched keeps SDK value (stdlib)", module: "net/http", inApp: false, expected: false},
+		{name: "internal module", module: "github.com/exoscale/go-reporter/v2/errors", inApp: true},
+		{name: "encoded internal module", module: "gopkg.in/inconshreveable/log15%2ev2", inApp: true},
+		{
+			name:    "excluded module",
+			exclude: []string{"github.com/exoscale/myapp/internal"},
+			module:  "github.com/exoscale/myapp/internal/db",
+			inApp:   true,
+		},
+		{
+			name:     "module prefix boundary",
+			exclude:  []string{"github.com/exoscale/myapp"},
+			module:   "github.com/exoscale/myapplication",
+			inApp:    true,
+			expected: true,
+		},
+		{
+			name:     "included module",
+			include:  []string{"github.com/acme/lib"},
+			module:   "github.com/acme/lib",
+			expected: true,
+		},
+		{
+			name:     "include takes precedence",
+			include:  []string{"github.com/exoscale/go-reporter/v2/examples"},
+			module:   "github.com/exoscale/go-reporter/v2/examples/http",
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, newInAppFilter(tc.include, tc.exclude).inApp(tc.module, tc.inApp))
+		})
+	}
+}
+
+func TestReporter_InApp(t *testing.T) {
+	var sentryTestTransport = new(SentryTestTransport)
+
+	testReporter, err := New(&Config{
+		DSN:          testSentryDSN,
+		InAppExclude: []string{"testing"},
+	})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+	testReporter.SendError(errors.New("oh noes!"), nil)
+
+	require.Len(t, sentryTestTransport.Events(), 1)
+	frames := sentryTestTransport.Events()[0].Exception[0].Stacktrace.Frames
+	require.NotEmpty(t, frames)
+
+	// Frames are marked, never removed
+	var modules []string
+	for _, frame := range frames {
+		modules = append(modules, frame.Module)
+		if frame.Module == "github.com/exoscale/go-reporter/v2/errors" || frame.Module == "testing" {
+			require.False(t, frame.InApp, frame.Function)
+		}
+	}
+	require.Contains(t, modules, "github.com/exoscale/go-reporter/v2/errors")
+}
@@ -0,0 +1,180 @@
+package errors
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/rcrowley/go-metrics"
+)
+
+const (
+	// rateLimiterSweepThreshold represents the number of tracked fingerprints above which the rate limiter
+	// purges its expired entries. Sweeps are amortized: once above the threshold, the fingerprints are swept at most
+	// once every as many events as there are tracked fingerprints.
+	rateLimiterSweepThreshold = 1000
+
+	// suppressedEventsExtraKey represents the Sentry event extra data key reporting the number of events sharing the
+	// same fingerprint that have been suppressed since the last one sent.
+	suppressedEventsExtraKey = "suppressed_events"
+
+	// suppressedEventsTotalExtraKey represents the Sentry event extra data key reporting the total number of events
+	// suppressed since the last event sent.
+	suppressedEventsTotalExtraKey = "suppressed_events_total"
+)
+
+// fingerprintState represents the rate limiter state for a given event fingerprint.
+type fingerprintState struct {
+	lastSent   time.Time
+	suppressed int64
+}
+
+// rateLimiter implements a client-side rate limiter for error events, combining a global events-per-second budget
+// (token bucket) and a per-fingerprint cooldown period.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	rate     float64 // Events per second, 0 means unlimited
+	tokens   float64
+	last     time.Time
+	cooldown time.Duration
+
+	fingerprints    map[string]*fingerprintState
+	suppressedTotal int64
+	sinceSweep      int // Events since the latest sweep
+
+	sent       metrics.Counter
+	suppressed metrics.Counter
+
+	now func() time.Time
+}
+
+// newRateLimiter returns a new rate limiter allowing up to rate events per second globally, and at most one event
+// per fingerprint every cooldown period. Zero values disable the respective limit.
+func newRateLimiter(rate float64, cooldown time.Duration) *rateLimiter {
+	return &rateLimiter{
+		rate:         rate,
+		tokens:       burst(rate),
+		cooldown:     cooldown,
+		fingerprints: make(map[string]*fingerprintState),
+		sent:         metrics.NewCounter(),
+		suppressed:   metrics.NewCounter(),
+		now:          time.Now,
+	}
+}
+
+// burst returns the maximum number of tokens the global bucket can hold for the specified rate.
+func burst(rate float64) float64 {
+	if rate < 1 {
+		return 1
+	}
+	return rate
+}
+
+// allow reports whether an event with the specified fingerprint can be sent. If it can, it also returns the number
+// of events sharing this fingerprint and the total number of events suppressed since the last time an event was
+// sent, and resets those counters.
+func (l *rateLimiter) allow(fingerprint string) (ok bool, suppressed, suppressedTotal int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	l.sinceSweep++
+	if len(l.fingerprints) > rateLimiterSweepThreshold && l.sinceSweep >= len(l.fingerprints) {
+		l.sweep(now)
+	}
+
+	state, found := l.fingerprints[fingerprint]
+	if !found {
+		state = &fingerprintState{}
+		l.fingerprints[fingerprint] = state
+	}
+
+	if l.cooldown > 0 && !state.lastSent.IsZero() && now.Sub(state.lastSent) < l.cooldown {
+		l.suppress(state)
+		return false, 0, 0
+	}
+
+	if l.rate > 0 {
+		if !l.last.IsZero() {
+			l.tokens += now.Sub(l.last).Seconds() * l.rate
+			if max := burst(l.rate); l.tokens > max {
+				l.tokens = max
+			}
+		}
+		l.last = now
+
+		if l.tokens < 1 {
+			l.suppress(state)
+			return false, 0, 0
+		}
+		l.tokens--
+	}
+
+	suppressed, suppressedTotal = state.suppressed, l.suppressedTotal
+	state.suppressed = 0
+	state.lastSent = now
+	l.suppressedTotal = 0
+	l.sent.Inc(1)
+
+	return true, suppressed, suppressedTotal
+}
+
+// suppress records the suppression of an event.
+func (l *rateLimiter) suppress(state *fingerprintState) {
+	state.suppressed++
+	l.suppressedTotal++
+	l.suppressed.Inc(1)
+}
+
+// sweep purges the fingerprints whose cooldown period has expired and that have no pending suppressed events.
+func (l *rateLimiter) sweep(now time.Time) {
+	l.sinceSweep = 0
+
+	for fingerprint, state := range l.fingerprints {
+		if state.suppressed == 0 && now.Sub(state.lastSent) >= l.cooldown {
+			delete(l.fingerprints, fingerprint)
+		}
+	}
+}
+
+// eventProcessor returns a sentry.EventProcessor dropping the events exceeding the rate limiter's limits, and
+// annotating the sent events with the number of events suppressed in the meantime.
+func (l *rateLimiter) eventProcessor() sentry.EventProcessor {
+	return func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		ok, suppressed, suppressedTotal := l.allow(eventFingerprint(event))
+		if !ok {
+			return nil
+		}
+
+		if suppressed > 0 || suppressedTotal > 0 {
+			if event.Extra == nil {
+				event.Extra = make(map[string]interface{})
+			}
+			event.Extra[suppressedEventsExtraKey] = suppressed
+			event.Extra[suppressedEventsTotalExtraKey] = suppressedTotal
+		}
+
+		return event
+	}
+}
+
+// eventFingerprint returns a string identifying similar Sentry events: if the event has an explicit fingerprint it
+// is used, otherwise it is computed from the event exceptions type and value, or from the event message.
+func eventFingerprint(event *sentry.Event) string {
+	if len(event.Fingerprint) > 0 {
+		return strings.Join(event.Fingerprint, "\x00")
+	}
+
+	if len(event.Exception) > 0 {
+		parts := make([]string, 0, len(event.Exception)*2)
+		for _, e := range event.Exception {
+			parts = append(parts, e.Type, e.Value)
+		}
+		return strings.Join(parts, "\x00")
+	}
+
+	return event.Message
+}
@@ -0,0 +1,138 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	var (
+		now     = time.Now()
+		limiter = newRateLimiter(2, 10*time.Second)
+	)
+
+	limiter.now = func() time.Time { return now }
+
+	ok, _, _ := limiter.allow("a")
+	require.True(t, ok)
+
+	// Same fingerprint within the cooldown period
+	ok, _, _ = limiter.allow("a")
+	require.False(t, ok)
+
+	ok, suppressed, suppressedTotal := limiter.allow("b")
+	require.True(t, ok)
+	require.Equal(t, int64(0), suppressed)
+	require.Equal(t, int64(1), suppressedTotal)
+
+	// Global budget exhausted
+	ok, _, _ = limiter.allow("c")
+	require.False(t, ok)
+
+	now = now.Add(time.Second)
+	ok, suppressed, suppressedTotal = limiter.allow("c")
+	require.True(t, ok)
+	require.Equal(t, int64(1), suppressed)
+	require.Equal(t, int64(1), suppressedTotal)
+
+	now = now.Add(10 * time.Second)
+	ok, suppressed, suppressedTotal = limiter.allow("a")
+	require.True(t, ok)
+	require.Equal(t, int64(1), suppressed)
+	require.Equal(t, int64(0), suppressedTotal)
+
+	require.Equal(t, int64(4), limiter.sent.Count())
+	require.Equal(t, int64(2), limiter.suppressed.Count())
+}
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	limiter := newRateLimiter(0, 0)
+
+	for i := 0; i < 100; i++ {
+		ok, _, _ := limiter.allow("a")
+		require.True(t, ok)
+	}
+	require.Equal(t, int64(100), limiter.sent.Count())
+	require.Equal(t, int64(0), limiter.suppressed.Count())
+}
+
+func TestRateLimiter_Sweep(t *testing.T) {
+	var (
+		now     = time.Now()
+		limiter = newRateLimiter(0, time.Minute)
+	)
+
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2*rateLimiterSweepThreshold; i++ {
+		limiter.allow(fmt.Sprint(i))
+	}
+	require.Len(t, limiter.fingerprints, 2*rateLimiterSweepThreshold)
+
+	// Expired fingerprints are not swept on every event...
+	now = now.Add(time.Minute)
+	limiter.sinceSweep = 0
+	limiter.allow("a")
+	require.Len(t, limiter.fingerprints, 2*rateLimiterSweepThreshold+1)
+
+	// ...but once every as many events as there are fingerprints
+	for i := 1; i < 2*rateLimiterSweepThreshold+1; i++ {
+		limiter.allow("a")
+	}
+	require.Len(t, limiter.fingerprints, 1)
+}
+
+func TestEventFingerprint(t *testing.T) {
+	require.Equal(t, "a\x00b", eventFingerprint(&sentry.Event{
+		Fingerprint: []string{"a", "b"},
+		Message:     "oh noes!",
+	}))
+
+	require.Equal(t, "*errors.errorString\x00oh noes!", eventFingerprint(&sentry.Event{
+		Exception: []sentry.Exception{{Type: "*errors.errorString", Value: "oh noes!"}},
+	}))
+
+	require.Equal(t, "oh noes!", eventFingerprint(&sentry.Event{Message: "oh noes!"}))
+}
+
+func TestReporter_RateLimiting(t *testing.T) {
+	var (
+		testError           = errors.New("oh noes!")
+		sentryTestTransport = new(SentryTestTransport)
+	)
+
+	testReporter, err := New(&Config{
+		DSN:           testSentryDSN,
+		DedupCooldown: 60,
+	})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	for i := 0; i < 5; i++ {
+		testReporter.SendError(testError, nil)
+	}
+	require.Len(t, sentryTestTransport.Events(), 1)
+
+	// Simulate the cooldown period expiration
+	testReporter.limiter.fingerprints[eventFingerprint(sentryTestTransport.Events()[0])].lastSent = time.Time{}
+
+	testReporter.SendError(testError, nil)
+	require.Len(t, sentryTestTransport.Events(), 2)
+	require.Equal(t, int64(4), sentryTestTransport.Events()[1].Extra[suppressedEventsExtraKey])
+	require.Equal(t, int64(4), sentryTestTransport.Events()[1].Extra[suppressedEventsTotalExtraKey])
+
+	registered := make(map[string]interface{})
+	require.NoError(t, testReporter.RegisterMetrics(func(name string, metric interface{}) error {
+		registered[name] = metric
+		return nil
+	}))
+	require.Equal(t, int64(2), registered["errors.events.sent"].(gometrics.Counter).Count())
+	require.Equal(t, int64(4), registered["errors.events.suppressed"].(gometrics.Counter).Count())
+}
@@ -4,17 +4,30 @@ package errors
 import (
 	"context"
 	"errors"
+	"io"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"gopkg.in/inconshreveable/log15.v2"
+	"gopkg.in/tomb.v2"
 
 	"github.com/exoscale/go-reporter/v2/internal/debug"
 )
 
+// Logger represents the interface of the logger used by the errors reporter to log the panics it recovers.
+type Logger interface {
+	Error(msg string, ctx ...interface{})
+}
+
 // Reporter represents an errors reporter instance.
 type Reporter struct {
-	sentry *sentry.Client
+	sentry  *sentry.Client
+	backend Backend
+	limiter *rateLimiter
+	logger  Logger
+	scope   *Scope
 
+	t      *tomb.Tomb // Goroutines manager
 	config *Config
 
 	*debug.D
@@ -41,15 +54,21 @@ func New(config *Config) (*Reporter, error) {
 		reporter.D.On()
 	}
 
-	reporter.Debug("initializing Sentry client")
+	reporter.Debug("initializing Sentry client", "backend", config.Backend)
 
 	sentryOpts := sentry.ClientOptions{
-		Dsn:              config.DSN,
 		AttachStacktrace: true,
 	}
 
-	if config.Wait {
-		sentryOpts.Transport = &sentry.HTTPSyncTransport{Timeout: sentryFlushTimeout}
+	if config.Backend == "sentry" {
+		sentryOpts.Dsn = config.DSN
+	}
+
+	if reporter.backend, err = newBackend(config); err != nil {
+		return nil, err
+	}
+	if reporter.backend != nil {
+		sentryOpts.Transport = reporter.backend
 	}
 
 	if config.Debug {
@@ -62,16 +81,54 @@ func New(config *Config) (*Reporter, error) {
 		return nil, err
 	}
 
+	reporter.sentry.AddEventProcessor(newInAppFilter(config.InAppInclude, config.InAppExclude).eventProcessor())
+
+	// The rate limiter is always installed as it also accounts for the events sent, even if no limits are set.
+	if config.MaxEventsPerSecond > 0 || config.DedupCooldown > 0 {
+		reporter.Debug("enabling events rate limiting",
+			"max_events_per_second", config.MaxEventsPerSecond,
+			"dedup_cooldown", config.DedupCooldown)
+	}
+	reporter.limiter = newRateLimiter(config.MaxEventsPerSecond, time.Duration(config.DedupCooldown)*time.Second)
+	reporter.sentry.AddEventProcessor(reporter.limiter.eventProcessor())
+
 	return &reporter, nil
 }
 
-// Start is a no-op operation.
-func (r *Reporter) Start(_ context.Context) error {
+// Start starts the errors reporter. If a spool is configured, the spooled events delivery is periodically attempted
+// until the reporter is stopped.
+func (r *Reporter) Start(ctx context.Context) error {
+	if b, ok := r.backend.(*deliveryBackend); ok && b.spool != nil {
+		r.Debug("starting spooled events replay loop", "directory", b.spool.dir)
+
+		r.t, _ = tomb.WithContext(ctx)
+		r.t.Go(func() error {
+			return b.replayLoop(r.t)
+		})
+	}
+
 	return nil
 }
 
-// Stop is a no-op operation.
+// Stop waits for the pending events to be sent to the backend, then releases the backend resources.
 func (r *Reporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if r.t != nil {
+		r.t.Kill(nil)
+		if err := r.t.Wait(); err != nil {
+			return err
+		}
+	}
+
+	if !r.sentry.Flush(sentryFlushTimeout) {
+		r.Debug("timeout reached while flushing pending events")
+	}
+
+	if c, ok := r.sentry.Transport.(io.Closer); ok {
+		return c.Close()
+	}
+
 	return nil
 }
 
@@ -82,7 +139,14 @@ func (r *Reporter) LogHandler() log15.Handler {
 			return nil
 		}
 
-		r.sentry.CaptureException(errors.New(rec.Msg), nil, sentryEventFromLogRecord(rec))
+		// Skip records related to an event already sent to Sentry
+		for i := 0; i < len(rec.Ctx); i += 2 {
+			if rec.Ctx[i] == sentryEventIDLogKey {
+				return nil
+			}
+		}
+
+		r.sentry.CaptureException(errors.New(rec.Msg), nil, sentryEventFromLogRecord(rec).withScope(r.sentryScope()))
 
 		return nil
 	})
@@ -90,7 +154,13 @@ func (r *Reporter) LogHandler() log15.Handler {
 
 // SendError sends the specified error to Sentry. If tags is not nil, they will be added to the event.
 func (r *Reporter) SendError(err error, tags map[string]string) {
-	r.sentry.CaptureException(err, nil, sentryEventWithTags(tags))
+	r.sentry.CaptureException(err, nil, sentryEventWithTags(tags).withScope(r.sentryScope()))
+}
+
+// SendErrorContext sends the specified error to Sentry like SendError(), attaching the data of the scope and the
+// trace context of the span carried by ctx (if any) to the event.
+func (r *Reporter) SendErrorContext(ctx context.Context, err error, tags map[string]string) {
+	r.FromContext(ctx).SendError(err, tags)
 }
 
 // PanicHandler is a function that recovers from a panic and sends an event to Sentry. If a fn function is provided it
@@ -99,7 +169,7 @@ func (r *Reporter) SendError(err error, tags map[string]string) {
 // for.
 func (r *Reporter) PanicHandler(fn func(interface{})) {
 	if re := recover(); re != nil {
-		r.sentry.Recover(re, nil, sentryEventFromPanic(re))
+		r.reportPanic(re, r.sentryScope())
 
 		if fn != nil {
 			fn(re)
@@ -107,6 +177,35 @@ func (r *Reporter) PanicHandler(fn func(interface{})) {
 	}
 }
 
+// RegisterMetrics registers the errors reporter's internal metrics (number of events sent and suppressed) using the
+// provided register function – typically the metrics reporter's Register() method.
+func (r *Reporter) RegisterMetrics(register func(name string, metric interface{}) error) error {
+	if err := register("errors.events.sent", r.limiter.sent); err != nil {
+		return err
+	}
+
+	return register("errors.events.suppressed", r.limiter.suppressed)
+}
+
+// SetLogger sets the logger used to log the panics recovered by the errors reporter in goroutines started with the
+// Go(), GoWithRestart() and Group.Go() methods. Log records are tagged with the ID of the corresponding Sentry event
+// under the "sentry_event_id" key, and are not sent again to Sentry by LogHandler().
+func (r *Reporter) SetLogger(logger Logger) {
+	r.logger = logger
+}
+
+// reportPanic sends an event to Sentry for the recovered panic value re, and returns the ID of the event sent (or nil
+// if no event has been sent). If scope is not nil, its data is added to the event.
+func (r *Reporter) reportPanic(re interface{}, scope *sentry.Scope) *sentry.EventID {
+	return r.sentry.Recover(re, &sentry.EventHint{RecoveredException: re}, sentryEventFromPanic(re).withScope(scope))
+}
+
+// SetBackend sets the errors reporter's backend, overriding the one specified in the configuration.
+func (r *Reporter) SetBackend(b Backend) {
+	b.Configure(r.sentry.Options())
+	r.sentry.Transport = b
+}
+
 // SetSentryTransport sets the errors reporter's Sentry client transport. This is mainly for testing purposes.
 func (r *Reporter) SetSentryTransport(t sentry.Transport) {
 	r.sentry.Transport = t
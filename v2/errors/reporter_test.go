@@ -1,10 +1,18 @@
 package errors
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/getsentry/sentry-go"
 	"github.com/stretchr/testify/require"
+
+	gtesting "github.com/exoscale/go-reporter/v2/testing"
 )
 
 func TestNew(t *testing.T) {
@@ -46,3 +54,54 @@ func TestReporter_SendError(t *testing.T) {
 	require.Equal(t, testErrorMessage.Error(), sentryTestTransport.Events()[0].Exception[0].Value)
 	require.Equal(t, testTags, sentryTestTransport.Events()[0].Tags)
 }
+
+func TestReporter_SentryServer(t *testing.T) {
+	var testErrorMessage = "oh noes!"
+
+	for _, wait := range []bool{true, false} {
+		server := gtesting.NewSentryServer(t)
+
+		testReporter, err := New(&Config{DSN: server.DSN(), Wait: wait})
+		require.NoError(t, err)
+		require.NoError(t, testReporter.Start(context.Background()))
+
+		testReporter.SendError(errors.New(testErrorMessage), map[string]string{"k": "v"})
+		event := server.ExpectEvent(func(e *sentry.Event) bool {
+			return len(e.Exception) > 0 && e.Exception[0].Value == testErrorMessage
+		}, time.Second*3)
+		require.Equal(t, "v", event.Tags["k"])
+
+		require.NoError(t, testReporter.Stop(context.Background()))
+		server.Close()
+	}
+}
+
+func TestReporter_SentryServer_Spool(t *testing.T) {
+	server := gtesting.NewSentryServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "go-reporter-spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testReporter, err := New(&Config{
+		DSN:   server.DSN(),
+		Spool: &SpoolConfig{Directory: dir, ReplayInterval: 1},
+		Wait:  true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, testReporter.Start(context.Background()))
+
+	// Events rejected by Sentry, either failing or rate limited, are spooled and replayed later on
+	server.FailRequests(1, http.StatusInternalServerError)
+	testReporter.SendError(errors.New("first"), nil)
+	server.RateLimitRequests(1, time.Second)
+	testReporter.SendError(errors.New("second"), nil)
+	require.Empty(t, server.Events())
+
+	events := server.WaitForEvents(2, time.Second*5)
+	require.Equal(t, "first", events[0].Exception[0].Value)
+	require.Equal(t, "second", events[1].Exception[0].Value)
+
+	require.NoError(t, testReporter.Stop(context.Background()))
+}
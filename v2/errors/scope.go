@@ -0,0 +1,189 @@
+package errors
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Level represents an event severity level.
+type Level string
+
+// Supported event severity levels.
+const (
+	LevelDebug   Level = Level(sentry.LevelDebug)
+	LevelInfo    Level = Level(sentry.LevelInfo)
+	LevelWarning Level = Level(sentry.LevelWarning)
+	LevelError   Level = Level(sentry.LevelError)
+	LevelFatal   Level = Level(sentry.LevelFatal)
+)
+
+// User represents the user affected by an event.
+type User struct {
+	ID        string
+	Username  string
+	Email     string
+	IPAddress string
+}
+
+// Scope represents contextual data (user, tags, extra data, request information) attached to the events sent by the
+// errors reporter. Scope methods are safe for concurrent use.
+type Scope struct {
+	scope *sentry.Scope
+}
+
+// NewScope returns a new empty scope.
+func NewScope() *Scope {
+	return &Scope{scope: sentry.NewScope()}
+}
+
+// Clone returns a copy of the scope.
+func (s *Scope) Clone() *Scope {
+	return &Scope{scope: s.scope.Clone()}
+}
+
+// SetUser sets the user affected by the events.
+func (s *Scope) SetUser(user User) {
+	s.scope.SetUser(sentry.User{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		IPAddress: user.IPAddress,
+	})
+}
+
+// SetTag sets a tag, overriding any existing tag with the same key.
+func (s *Scope) SetTag(key, value string) {
+	s.scope.SetTag(key, value)
+}
+
+// SetTags sets multiple tags at once.
+func (s *Scope) SetTags(tags map[string]string) {
+	s.scope.SetTags(tags)
+}
+
+// SetExtra sets structured extra data, overriding any existing data with the same key.
+func (s *Scope) SetExtra(key string, value interface{}) {
+	s.scope.SetExtra(key, value)
+}
+
+// SetExtras sets multiple structured extra data at once.
+func (s *Scope) SetExtras(extra map[string]interface{}) {
+	s.scope.SetExtras(extra)
+}
+
+// SetRequest sets the HTTP request information (method, URL with scrubbed query parameters values, non-sensitive
+// headers and client remote address).
+func (s *Scope) SetRequest(req *http.Request) {
+	s.scope.SetRequest(sentryRequestFromHTTPRequest(req, defaultHTTPHeaders))
+}
+
+// setTraceContext attaches the trace context of a span to the events, so that they can be correlated with the
+// traces recorded by the tracing reporter.
+func (s *Scope) setTraceContext(sc trace.SpanContext) {
+	s.scope.SetContext("trace", map[string]interface{}{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	})
+	s.scope.SetTag("trace_id", sc.TraceID().String())
+}
+
+// scopeContextKey represents the context key under which the errors reporter scope is stored.
+type scopeContextKey struct{}
+
+// ContextWithScope returns a copy of ctx carrying a copy of the scope found in ctx (or a new one if none), modified
+// by the function fn.
+func ContextWithScope(ctx context.Context, fn func(*Scope)) context.Context {
+	scope := NewScope()
+	if parent := ScopeFromContext(ctx); parent != nil {
+		scope = parent.Clone()
+	}
+
+	if fn != nil {
+		fn(scope)
+	}
+
+	return context.WithValue(ctx, scopeContextKey{}, scope)
+}
+
+// ScopeFromContext returns the scope carried by ctx, or nil if none. If ctx doesn't carry a scope but carries a
+// Sentry hub (e.g. set by the HTTPMiddleware() or a third-party integration), the hub's scope is returned.
+func ScopeFromContext(ctx context.Context) *Scope {
+	if scope, ok := ctx.Value(scopeContextKey{}).(*Scope); ok {
+		return scope
+	}
+
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		return &Scope{scope: hub.Scope()}
+	}
+
+	return nil
+}
+
+// WithScope returns a copy of the errors reporter whose events are attached the data of its current scope (if any)
+// modified by the function fn. The returned reporter shares the resources of its parent, and must not be started or
+// stopped.
+func (r *Reporter) WithScope(fn func(*Scope)) *Reporter {
+	child := *r
+
+	child.scope = NewScope()
+	if r.scope != nil {
+		child.scope = r.scope.Clone()
+	}
+
+	if fn != nil {
+		fn(child.scope)
+	}
+
+	return &child
+}
+
+// FromContext returns a copy of the errors reporter whose events are attached the data of the scope carried by ctx
+// (see ContextWithScope()), as well as the trace context of the span carried by ctx (if any). If ctx carries neither
+// a scope nor a valid span, the reporter is returned unchanged.
+func (r *Reporter) FromContext(ctx context.Context) *Reporter {
+	scope := ScopeFromContext(ctx)
+	sc := trace.SpanContextFromContext(ctx)
+	if scope == nil && !sc.IsValid() {
+		return r
+	}
+
+	if sc.IsValid() {
+		switch {
+		case scope != nil:
+			scope = scope.Clone()
+		case r.scope != nil:
+			scope = r.scope.Clone()
+		default:
+			scope = NewScope()
+		}
+		scope.setTraceContext(sc)
+	}
+
+	child := *r
+	child.scope = scope
+
+	return &child
+}
+
+// CaptureMessage sends a non-error event with the specified severity level and message.
+func (r *Reporter) CaptureMessage(level Level, msg string) {
+	r.sentry.CaptureMessage(msg, nil, sentryEventWithLevel(sentry.Level(level)).withScope(r.sentryScope()))
+}
+
+// CaptureMessageContext sends a non-error event like CaptureMessage(), attaching the data of the scope and the trace
+// context of the span carried by ctx (if any) to the event.
+func (r *Reporter) CaptureMessageContext(ctx context.Context, level Level, msg string) {
+	r.FromContext(ctx).CaptureMessage(level, msg)
+}
+
+// sentryScope returns the reporter's Sentry scope, or nil if it doesn't have any.
+func (r *Reporter) sentryScope() *sentry.Scope {
+	if r.scope == nil {
+		return nil
+	}
+
+	return r.scope.scope
+}
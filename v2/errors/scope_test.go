@@ -0,0 +1,130 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestReporter_WithScope(t *testing.T) {
+	var sentryTestTransport = new(SentryTestTransport)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	scoped := testReporter.WithScope(func(s *Scope) {
+		s.SetUser(User{ID: "42", Email: "user@example.net"})
+		s.SetTag("component", "api")
+		s.SetExtra("attempt", 3)
+	})
+	child := scoped.WithScope(func(s *Scope) { s.SetTag("component", "worker") })
+
+	scoped.SendError(errors.New("oh noes!"), map[string]string{"k": "v"})
+	child.SendError(errors.New("oh noes!"), nil)
+	testReporter.SendError(errors.New("oh noes!"), nil)
+
+	events := sentryTestTransport.Events()
+	require.Len(t, events, 3)
+
+	require.Equal(t, sentry.User{ID: "42", Email: "user@example.net"}, events[0].User)
+	require.Equal(t, map[string]string{"component": "api", "k": "v"}, events[0].Tags)
+	require.Equal(t, 3, events[0].Extra["attempt"])
+
+	// Modifying a child scope must not affect its parent
+	require.Equal(t, "worker", events[1].Tags["component"])
+	require.Equal(t, "42", events[1].User.ID)
+
+	require.Empty(t, events[2].User)
+	require.Empty(t, events[2].Tags)
+}
+
+func TestReporter_FromContext(t *testing.T) {
+	var sentryTestTransport = new(SentryTestTransport)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	require.Equal(t, testReporter, testReporter.FromContext(context.Background()))
+
+	ctx := ContextWithScope(context.Background(), func(s *Scope) { s.SetTag("request_id", "abc") })
+	ctx = ContextWithScope(ctx, func(s *Scope) { s.SetTag("tenant", "acme") })
+
+	testReporter.FromContext(ctx).SendError(errors.New("oh noes!"), nil)
+	require.Len(t, sentryTestTransport.Events(), 1)
+	require.Equal(t, map[string]string{"request_id": "abc", "tenant": "acme"}, sentryTestTransport.Events()[0].Tags)
+
+	// Goroutines launched with a scoped context attach the scope to the reported panics
+	done := make(chan struct{})
+	testReporter.Go(ctx, "worker", func(_ context.Context) {
+		defer close(done)
+		panic("oh noes!")
+	})
+	<-done
+	require.Eventually(t,
+		func() bool { return len(sentryTestTransport.Events()) == 2 },
+		time.Second*3,
+		10*time.Millisecond)
+	require.Equal(t, "acme", sentryTestTransport.Events()[1].Tags["tenant"])
+	require.Equal(t, "worker", sentryTestTransport.Events()[1].Tags["goroutine"])
+}
+
+func TestReporter_CaptureMessage(t *testing.T) {
+	var sentryTestTransport = new(SentryTestTransport)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	testReporter.
+		WithScope(func(s *Scope) { s.SetTag("k", "v") }).
+		CaptureMessage(LevelWarning, "disk almost full")
+
+	require.Len(t, sentryTestTransport.Events(), 1)
+	require.Equal(t, "disk almost full", sentryTestTransport.Events()[0].Message)
+	require.Equal(t, sentry.LevelWarning, sentryTestTransport.Events()[0].Level)
+	require.Equal(t, map[string]string{"k": "v"}, sentryTestTransport.Events()[0].Tags)
+}
+
+func TestReporter_FromContext_Trace(t *testing.T) {
+	var (
+		sentryTestTransport = new(SentryTestTransport)
+		testTraceID, _      = trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		testSpanID, _       = trace.SpanIDFromHex("00f067aa0ba902b7")
+	)
+
+	testReporter, err := New(&Config{DSN: testSentryDSN})
+	require.NoError(t, err)
+
+	testReporter.SetSentryTransport(sentryTestTransport)
+
+	parent := ContextWithScope(context.Background(), func(s *Scope) { s.SetTag("k", "v") })
+	ctx := trace.ContextWithSpanContext(parent, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: testTraceID,
+		SpanID:  testSpanID,
+	}))
+
+	testReporter.FromContext(ctx).SendError(errors.New("oh noes!"), nil)
+	testReporter.FromContext(parent).SendError(errors.New("oh noes!"), nil)
+
+	events := sentryTestTransport.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, map[string]interface{}{
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":  "00f067aa0ba902b7",
+	}, events[0].Contexts["trace"])
+	require.Equal(t, map[string]string{"k": "v", "trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"}, events[0].Tags)
+
+	// The scope carried by the parent context must not have been modified
+	require.NotContains(t, events[1].Contexts, "trace")
+	require.Equal(t, map[string]string{"k": "v"}, events[1].Tags)
+}
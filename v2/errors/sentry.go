@@ -1,8 +1,10 @@
 package errors
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"net/http"
 	"sync"
 	"time"
 
@@ -12,34 +14,43 @@ import (
 	"github.com/exoscale/go-reporter/v2/internal/debug"
 )
 
-const sentryFlushTimeout = 5 * time.Second
+const (
+	sentryFlushTimeout = 5 * time.Second
 
-var (
-	// internalPackages represents a list of packages to be excluded from the errors stack trace sent to Sentry.
-	// Note: the Sentry SDK encodes dots in packages' import path into "%2e".
-	internalPackages = []string{
-		"github.com/exoscale/go-reporter",
-		"gopkg.in/inconshreveable/log15%2ev2",
-	}
+	// sentryEventIDLogKey represents the log record context key referencing a Sentry event ID.
+	sentryEventIDLogKey = "sentry_event_id"
 )
 
 // sentryEventModifier implements the sentry.EventModifier interface.
 type sentryEventModifier struct {
 	tags  map[string]string
+	level sentry.Level
 	panic bool
+	scope *sentry.Scope
 }
 
-func (m *sentryEventModifier) ApplyToEvent(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
-	// Clean stack traces by filtering non-relevant frames
-	for i := range event.Exception {
-		event.Exception[i].Stacktrace.Frames = filterStackFrames(event.Exception[i].Stacktrace.Frames)
-	}
-	for i := range event.Threads {
-		event.Threads[i].Stacktrace.Frames = filterStackFrames(event.Threads[i].Stacktrace.Frames)
+func (m *sentryEventModifier) ApplyToEvent(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+	// Apply the Sentry scope data (e.g. HTTP request information) if any
+	if m.scope != nil {
+		if event = m.scope.ApplyToEvent(event, hint); event == nil {
+			return nil
+		}
 	}
 
 	// Add tags extracted from the original log record context
-	event.Tags = m.tags
+	if len(m.tags) > 0 {
+		if event.Tags == nil {
+			event.Tags = make(map[string]string)
+		}
+		for k, v := range m.tags {
+			event.Tags[k] = v
+		}
+	}
+
+	// Override the event severity level if specified
+	if m.level != "" {
+		event.Level = m.level
+	}
 
 	// If the event has been created following a panic, flag it as crashed
 	if m.panic && len(event.Threads) == 1 {
@@ -49,6 +60,12 @@ func (m *sentryEventModifier) ApplyToEvent(event *sentry.Event, _ *sentry.EventH
 	return event
 }
 
+// withScope sets the Sentry scope to apply to the event before the modifier's own changes.
+func (m *sentryEventModifier) withScope(scope *sentry.Scope) *sentryEventModifier {
+	m.scope = scope
+	return m
+}
+
 // sentryEventFromLogRecord returns a sentryEventModifier instance containing tags extracted from a log record's
 // context.
 func sentryEventFromLogRecord(rec *log15.Record) *sentryEventModifier {
@@ -82,21 +99,9 @@ func sentryEventWithTags(tags map[string]string) *sentryEventModifier {
 	return &sentryEventModifier{tags: tags}
 }
 
-// filterStackFrames filters out all frames related to internal packages.
-func filterStackFrames(frames []sentry.Frame) []sentry.Frame {
-	var filteredFrames = make([]sentry.Frame, 0)
-
-nextFrame:
-	for _, frame := range frames {
-		for _, pkg := range internalPackages {
-			if strings.HasPrefix(frame.Module, pkg) {
-				continue nextFrame
-			}
-		}
-		filteredFrames = append(filteredFrames, frame)
-	}
-
-	return filteredFrames
+// sentryEventWithLevel returns a sentryEventModifier instance setting the event severity level.
+func sentryEventWithLevel(level sentry.Level) *sentryEventModifier {
+	return &sentryEventModifier{level: level}
 }
 
 // SentryTestTransport is an implementation of the sentry.Transport interface for testing purposes.
@@ -129,6 +134,42 @@ func (t *SentryTestTransport) Events() []*sentry.Event {
 	return t.events
 }
 
+// sentryDeliverer represents an endpoint delivering events to the Sentry store API, reporting delivery failures.
+type sentryDeliverer struct {
+	dsn    *sentry.Dsn
+	client *http.Client
+}
+
+// newSentryDeliverer returns a new Sentry deliverer for the specified DSN.
+func newSentryDeliverer(dsn string) (*sentryDeliverer, error) {
+	d, err := sentry.NewDsn(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sentryDeliverer{
+		dsn:    d,
+		client: &http.Client{Timeout: sentryFlushTimeout},
+	}, nil
+}
+
+func (d *sentryDeliverer) deliver(event *sentry.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.dsn.StoreAPIURL().String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, v := range d.dsn.RequestHeaders() {
+		req.Header.Set(k, v)
+	}
+
+	return doDeliveryRequest(d.client, req)
+}
+
 type sentryDebugWriter struct {
 	d *debug.D
 }
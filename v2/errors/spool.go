@@ -0,0 +1,175 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// spoolFileSuffix represents the file name suffix of the spooled events.
+const spoolFileSuffix = ".event.json"
+
+// spool represents a durable on-disk storage for the events that couldn't be delivered to the errors backend.
+// Events are stored one per file, named after the time they have been spooled so that the lexical order of the
+// files reflects their chronological order.
+type spool struct {
+	mu sync.Mutex
+
+	dir            string
+	maxSize        int64
+	maxAge         time.Duration
+	replayInterval time.Duration
+
+	now func() time.Time
+}
+
+// newSpool returns a new spool, creating its directory if needed.
+func newSpool(config *SpoolConfig) (*spool, error) {
+	if err := os.MkdirAll(config.Directory, 0700); err != nil {
+		return nil, err
+	}
+
+	return &spool{
+		dir:            config.Directory,
+		maxSize:        config.MaxSize,
+		maxAge:         time.Duration(config.MaxAge) * time.Second,
+		replayInterval: time.Duration(config.ReplayInterval) * time.Second,
+		now:            time.Now,
+	}, nil
+}
+
+// store writes the event to the spool, then prunes the spool to enforce its size and age limits.
+func (s *spool) store(event *sentry.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := fmt.Sprintf("%020d-%s%s", s.now().UnixNano(), event.EventID, spoolFileSuffix)
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		return err
+	}
+
+	return s.prune()
+}
+
+// replay attempts to deliver the spooled events using the deliver function, from the oldest to the newest, and
+// removes them from the spool once delivered. It stops at the first delivery failure, and returns the number of
+// events delivered. The spool lock is not held while delivering, so that events can be stored in the meantime.
+func (s *spool) replay(deliver func(*sentry.Event) error) (int, error) {
+	s.mu.Lock()
+	err := s.prune()
+	var files []os.FileInfo
+	if err == nil {
+		files, err = s.files()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, f := range files {
+		path := filepath.Join(s.dir, f.Name())
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Pruned in the meantime
+				continue
+			}
+			return replayed, err
+		}
+
+		var event sentry.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			// Corrupted event, there is no point keeping it around.
+			sentry.Logger.Printf("discarding corrupted spooled event %s: %s", path, err)
+			_ = s.remove(path)
+			continue
+		}
+
+		if err := deliver(&event); err != nil {
+			return replayed, err
+		}
+
+		if err := s.remove(path); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// remove removes a spooled event file, unless already removed.
+func (s *spool) remove(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// prune removes the spooled events older than the spool maximum age, then the oldest events until the spool size
+// is under its maximum size. The caller must hold the spool lock.
+func (s *spool) prune() error {
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, f := range files {
+		size += f.Size()
+	}
+
+	for _, f := range files {
+		if (s.maxAge <= 0 || s.now().Sub(f.ModTime()) < s.maxAge) && (s.maxSize <= 0 || size <= s.maxSize) {
+			break
+		}
+
+		if err := os.Remove(filepath.Join(s.dir, f.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		size -= f.Size()
+	}
+
+	return nil
+}
+
+// files returns the spooled events files, sorted from the oldest to the newest.
+func (s *spool) files() ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.Mode().IsRegular() && !strings.HasPrefix(e.Name(), ".") && strings.HasSuffix(e.Name(), spoolFileSuffix) {
+			files = append(files, e)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	return files, nil
+}
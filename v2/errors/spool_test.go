@@ -0,0 +1,176 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/require"
+)
+
+func testNewSpool(t *testing.T, config *SpoolConfig) *spool {
+	dir, err := ioutil.TempDir("", "go-reporter-spool")
+	require.NoError(t, err)
+
+	config.Directory = dir
+	require.NoError(t, config.validate())
+
+	s, err := newSpool(config)
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestSpool_Replay(t *testing.T) {
+	var (
+		s         = testNewSpool(t, &SpoolConfig{})
+		delivered = make([]sentry.EventID, 0)
+		fail      = true
+		deliver   = func(event *sentry.Event) error {
+			if fail {
+				return errors.New("oh noes!")
+			}
+			delivered = append(delivered, event.EventID)
+			return nil
+		}
+	)
+	defer os.RemoveAll(s.dir)
+
+	require.NoError(t, s.store(&sentry.Event{EventID: "1"}))
+	require.NoError(t, s.store(&sentry.Event{EventID: "2"}))
+
+	n, err := s.replay(deliver)
+	require.Error(t, err)
+	require.Equal(t, 0, n)
+
+	fail = false
+	n, err = s.replay(deliver)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []sentry.EventID{"1", "2"}, delivered)
+
+	files, err := s.files()
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+}
+
+func TestSpool_Replay_Store(t *testing.T) {
+	s := testNewSpool(t, &SpoolConfig{})
+	defer os.RemoveAll(s.dir)
+
+	require.NoError(t, s.store(&sentry.Event{EventID: "1"}))
+
+	// Storing events while delivering spooled ones must not block
+	stored := make(chan error, 1)
+	n, err := s.replay(func(*sentry.Event) error {
+		go func() { stored <- s.store(&sentry.Event{EventID: "2"}) }()
+		select {
+		case err := <-stored:
+			return err
+		case <-time.After(time.Second):
+			return errors.New("store blocked by replay")
+		}
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	files, err := s.files()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Contains(t, files[0].Name(), "-2"+spoolFileSuffix)
+}
+
+func TestSpool_Prune(t *testing.T) {
+	s := testNewSpool(t, &SpoolConfig{MaxSize: 100})
+	defer os.RemoveAll(s.dir)
+
+	for _, id := range []sentry.EventID{"1", "2", "3"} {
+		require.NoError(t, s.store(&sentry.Event{EventID: id, Message: "oh noes!"}))
+	}
+
+	// The oldest events are discarded to honor the spool maximum size
+	files, err := s.files()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Contains(t, files[0].Name(), "-3"+spoolFileSuffix)
+
+	// Events older than the spool maximum age are discarded
+	s.now = func() time.Time { return time.Now().Add(s.maxAge) }
+	require.NoError(t, s.prune())
+	files, err = s.files()
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+}
+
+func TestReporter_Spool(t *testing.T) {
+	var (
+		testErrorMessage = "oh noes!"
+		reachable        int32
+		received         int32
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&reachable) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		atomic.AddInt32(&received, 1)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "go-reporter-spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testReporter, err := New(&Config{
+		Backend: "webhook",
+		Webhook: &WebhookBackendConfig{URL: ts.URL},
+		Spool: &SpoolConfig{
+			Directory:      dir,
+			ReplayInterval: 1,
+		},
+		Wait: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, testReporter.Start(context.Background()))
+
+	testReporter.SendError(errors.New(testErrorMessage), nil)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	atomic.StoreInt32(&reachable, 1)
+	require.Eventually(t,
+		func() bool { return atomic.LoadInt32(&received) == 1 },
+		time.Second*3,
+		100*time.Millisecond)
+
+	require.NoError(t, testReporter.Stop(context.Background()))
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+}
+
+func TestNewSentryDeliverer(t *testing.T) {
+	var received = make(chan *http.Request, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer ts.Close()
+
+	d, err := newSentryDeliverer("http://public:secret@" + ts.Listener.Addr().String() + "/42")
+	require.NoError(t, err)
+	require.NoError(t, d.deliver(&sentry.Event{Message: "oh noes!"}))
+
+	req := <-received
+	require.Equal(t, "/api/42/store/", req.URL.Path)
+	require.Contains(t, req.Header.Get("X-Sentry-Auth"), "sentry_key=public")
+}
@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// webhookDeliverer represents an endpoint delivering events as JSON documents to a generic HTTP endpoint.
+type webhookDeliverer struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// newWebhookDeliverer returns a new webhook deliverer.
+func newWebhookDeliverer(config *WebhookBackendConfig) *webhookDeliverer {
+	return &webhookDeliverer{
+		url:     config.URL,
+		headers: config.Headers,
+		client:  &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+	}
+}
+
+func (d *webhookDeliverer) deliver(event *sentry.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range d.headers {
+		req.Header.Set(k, v)
+	}
+
+	return doDeliveryRequest(d.client, req)
+}
+
+// doDeliveryRequest performs an event delivery HTTP request, and returns an error if the request fails or the
+// server doesn't reply with a 2xx status code.
+func doDeliveryRequest(client *http.Client, req *http.Request) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %q", res.Status)
+	}
+
+	return nil
+}
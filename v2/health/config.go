@@ -0,0 +1,45 @@
+package health
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultTimeoutSec  = 5
+	defaultIntervalSec = 10
+)
+
+// Config represents a health reporter configuration.
+type Config struct {
+	// Listen represents a net.Dial compatible string indicating the network address to bind the HTTP endpoint server
+	// serving the "/livez", "/readyz" and "/healthz" endpoints. If not specified, no server is started: the endpoints
+	// can still be served using the reporter's HTTPHandler() method.
+	Listen string `yaml:"listen"`
+
+	// Timeout represents the default time in seconds after which a check is considered failed. It can be overridden
+	// per check when registering it. If not specified, defaults to 5 seconds.
+	Timeout int `yaml:"timeout"`
+
+	// Interval represents the time interval in seconds at which the checks are executed in the background. Check
+	// results are cached for this duration: the endpoints only execute the checks whose result is older. If not
+	// specified, defaults to 10 seconds.
+	Interval int `yaml:"interval"`
+
+	// Debug represents a flags indicating whether to enable internal reporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeoutSec
+	}
+
+	if c.Interval <= 0 {
+		c.Interval = defaultIntervalSec
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Listen, is.DialString))
+}
@@ -0,0 +1,17 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testConfig := new(Config)
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultTimeoutSec, testConfig.Timeout)
+	require.Equal(t, defaultIntervalSec, testConfig.Interval)
+
+	require.NoError(t, (&Config{Listen: "127.0.0.1:8080"}).validate())
+	require.Error(t, (&Config{Listen: "nope"}).validate())
+}
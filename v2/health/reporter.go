@@ -0,0 +1,393 @@
+// health implements a health reporter executing named liveness and readiness checks.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+)
+
+// Group represents a group of checks.
+type Group string
+
+const (
+	// Liveness represents the group of the checks failing when the process needs to be restarted, served at the
+	// "/livez" path.
+	Liveness Group = "liveness"
+
+	// Readiness represents the group of the checks failing when the process cannot serve requests (e.g. a database is
+	// unreachable), served at the "/readyz" path.
+	Readiness Group = "readiness"
+)
+
+const (
+	// StatusOK represents the status of a passing check, or of a group of passing checks.
+	StatusOK = "ok"
+
+	// StatusFail represents the status of a failing check, or of a group of checks of which at least one fails.
+	StatusFail = "fail"
+
+	// checkStatusMetricName represents the name of the gauges exporting the checks status: 1 if a check passes, 0 if
+	// it fails or hasn't been executed yet.
+	checkStatusMetricName = "health.check.status"
+)
+
+// CheckFunc represents a check function: it returns a non-nil error if the check fails. The context is canceled once
+// the check times out.
+type CheckFunc func(ctx context.Context) error
+
+// CheckOption represents a check registration option.
+type CheckOption func(*check)
+
+// WithTimeout returns a check registration option overriding the configured checks timeout.
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// Result represents the result of the latest execution of a check.
+type Result struct {
+	Group     Group     `json:"group"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report represents the status of a set of checks: its status is "ok" if all the checks pass, "fail" otherwise.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// check represents a registered check.
+type check struct {
+	mu sync.Mutex // Held while executing the check, so that concurrent requests share the same execution
+
+	name    string
+	group   Group
+	fn      CheckFunc
+	timeout time.Duration
+	last    Result
+	gauge   metrics.Gauge
+}
+
+// Reporter represents a health reporter instance.
+type Reporter struct {
+	mu       sync.RWMutex
+	checks   map[string]*check
+	register func(name string, metric interface{}) error
+
+	listener net.Listener
+	now      func() time.Time
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new health reporter instance.
+func New(config *Config) (*Reporter, error) {
+	var reporter Reporter
+
+	if config == nil {
+		return nil, nil
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	reporter.config = config
+	reporter.checks = make(map[string]*check)
+	reporter.now = time.Now
+
+	reporter.D = debug.New("reporter/health")
+	if config.Debug {
+		reporter.D.On()
+	}
+
+	return &reporter, nil
+}
+
+// AddLivenessCheck registers a named liveness check. Check names must be unique across groups.
+func (r *Reporter) AddLivenessCheck(name string, fn CheckFunc, opts ...CheckOption) error {
+	return r.addCheck(Liveness, name, fn, opts...)
+}
+
+// AddReadinessCheck registers a named readiness check. Check names must be unique across groups.
+func (r *Reporter) AddReadinessCheck(name string, fn CheckFunc, opts ...CheckOption) error {
+	return r.addCheck(Readiness, name, fn, opts...)
+}
+
+func (r *Reporter) addCheck(group Group, name string, fn CheckFunc, opts ...CheckOption) error {
+	c := &check{
+		name:    name,
+		group:   group,
+		fn:      fn,
+		timeout: time.Duration(r.config.Timeout) * time.Second,
+		gauge:   metrics.NewGauge(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.checks[name]; ok {
+		return fmt.Errorf("check %q already registered", name)
+	}
+
+	if r.register != nil {
+		if err := r.register(c.metricName(), c.gauge); err != nil {
+			return err
+		}
+	}
+
+	r.checks[name] = c
+	r.Debug("registered check", "check", name, "group", group, "timeout", c.timeout)
+
+	return nil
+}
+
+// RegisterMetrics registers the checks status gauges using the provided register function – typically the metrics
+// reporter's Register() method. The gauges of the checks registered afterwards are registered as well.
+func (r *Reporter) RegisterMetrics(register func(name string, metric interface{}) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.checks {
+		if err := register(c.metricName(), c.gauge); err != nil {
+			return err
+		}
+	}
+	r.register = register
+
+	return nil
+}
+
+// Report returns the status of the checks of the specified groups (all the checks if none is specified). The checks
+// whose cached result is older than the configured interval are executed concurrently.
+func (r *Reporter) Report(groups ...Group) Report {
+	return r.report(time.Duration(r.config.Interval)*time.Second, groups...)
+}
+
+// HTTPHandler returns an http.Handler serving the status of the liveness checks at the "/livez" path, of the
+// readiness checks at the "/readyz" path and of all the checks at the "/healthz" path, as JSON reports. The HTTP
+// status code is 200 if all the checks pass, 503 otherwise.
+func (r *Reporter) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/livez", r.handler(Liveness))
+	mux.Handle("/readyz", r.handler(Readiness))
+	mux.Handle("/healthz", r.handler())
+
+	return mux
+}
+
+// Start starts the health reporter: the checks are executed in the background at the configured interval, and the
+// HTTP endpoint server is started if a listen address is configured. The HTTP endpoint network address is bound
+// before returning: binding errors are returned to the caller.
+func (r *Reporter) Start(ctx context.Context) error {
+	var err error
+
+	if r.config.Listen != "" {
+		if r.listener, err = net.Listen("tcp", r.config.Listen); err != nil {
+			return fmt.Errorf("unable to bind health endpoint server: %s", err)
+		}
+	}
+
+	r.t, _ = tomb.WithContext(ctx)
+
+	if r.listener != nil {
+		r.t.Go(func() error {
+			return r.serveHTTP(r.listener, &http.Server{Handler: r.HTTPHandler()})
+		})
+	}
+
+	r.t.Go(func() error {
+		ticker := time.NewTicker(time.Duration(r.config.Interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			r.report(0)
+
+			select {
+			case <-ticker.C:
+			case <-r.t.Dying():
+				return nil
+			}
+		}
+	})
+
+	return nil
+}
+
+// Stop stops the health reporter.
+func (r *Reporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if r.t == nil {
+		return nil
+	}
+
+	r.t.Kill(nil)
+
+	return r.t.Wait()
+}
+
+// report returns the status of the checks of the specified groups (all the checks if none is specified), executing
+// concurrently the checks whose cached result is at least maxAge old.
+func (r *Reporter) report(maxAge time.Duration, groups ...Group) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if c.in(groups) {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = r.result(c, maxAge)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+		report.Checks[c.name] = results[i]
+	}
+
+	return report
+}
+
+// result returns the result of the check c, executing it if its cached result is at least maxAge old. Concurrent
+// callers wait for the ongoing execution and share its result.
+func (r *Reporter) result(c *check, maxAge time.Duration) Result {
+	requested := r.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.last.CheckedAt.IsZero() && (requested.Sub(c.last.CheckedAt) < maxAge ||
+		!c.last.CheckedAt.Before(requested)) {
+		return c.last
+	}
+
+	start := r.now()
+	err := c.execute()
+
+	c.last = Result{
+		Group:     c.group,
+		Status:    StatusOK,
+		Duration:  r.now().Sub(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		c.last.Status = StatusFail
+		c.last.Error = err.Error()
+		c.gauge.Update(0)
+		r.Debug("check failed", "check", c.name, "group", c.group, "err", err)
+	} else {
+		c.gauge.Update(1)
+	}
+
+	return c.last
+}
+
+// handler returns an http.Handler serving the report of the checks of the specified groups.
+func (r *Reporter) handler(groups ...Group) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		report := r.Report(groups...)
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// serveHTTP runs an HTTP server on the listener l to serve the health endpoints. This method blocks the caller until
+// the reporter's tomb dies.
+func (r *Reporter) serveHTTP(l net.Listener, server *http.Server) error {
+	r.Debug("starting endpoint server", "address", l.Addr())
+
+	r.t.Go(func() error {
+		if err := server.Serve(l); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+
+	_ = <-r.t.Dying()
+	r.Debug("terminating endpoint server")
+	return server.Shutdown(context.Background())
+}
+
+// execute executes the check function, returning an error if it fails, panics or times out. A timed out check
+// function keeps running in the background until it returns.
+func (c *check) execute() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if re := recover(); re != nil {
+				done <- fmt.Errorf("check panicked: %v", re)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out after %s", c.timeout)
+	}
+}
+
+// in returns true if the check belongs to one of the groups, or if no group is specified.
+func (c *check) in(groups []Group) bool {
+	if len(groups) == 0 {
+		return true
+	}
+
+	for _, g := range groups {
+		if c.group == g {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metricName returns the registry name of the check status gauge.
+func (c *check) metricName() string {
+	return labels.Encode(checkStatusMetricName, labels.Labels{"check": c.name, "group": string(c.group)})
+}
@@ -0,0 +1,199 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	reporter, err := New(nil)
+	require.NoError(t, err)
+	require.Nil(t, reporter)
+
+	reporter, err = New(&Config{})
+	require.NoError(t, err)
+	require.NotNil(t, reporter)
+}
+
+func TestReporter_AddCheck(t *testing.T) {
+	reporter, err := New(&Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, reporter.AddLivenessCheck("deadlock", func(context.Context) error { return nil }))
+	require.NoError(t, reporter.AddReadinessCheck("database", func(context.Context) error { return nil }))
+	require.Error(t, reporter.AddReadinessCheck("deadlock", func(context.Context) error { return nil }),
+		"check names should be unique across groups")
+}
+
+func TestReporter_Report(t *testing.T) {
+	reporter, err := New(&Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, reporter.AddLivenessCheck("deadlock", func(context.Context) error { return nil }))
+	require.NoError(t, reporter.AddReadinessCheck("database", func(context.Context) error {
+		return errors.New("connection refused")
+	}))
+	require.NoError(t, reporter.AddReadinessCheck("cache", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, WithTimeout(10*time.Millisecond)))
+	require.NoError(t, reporter.AddReadinessCheck("queue", func(context.Context) error {
+		panic("oh noes!")
+	}))
+
+	report := reporter.Report(Liveness)
+	require.Equal(t, StatusOK, report.Status)
+	require.Len(t, report.Checks, 1)
+	require.Equal(t, Liveness, report.Checks["deadlock"].Group)
+
+	report = reporter.Report(Readiness)
+	require.Equal(t, StatusFail, report.Status)
+	require.Len(t, report.Checks, 3)
+	require.Equal(t, "connection refused", report.Checks["database"].Error)
+	require.Equal(t, "check timed out after 10ms", report.Checks["cache"].Error)
+	require.Equal(t, "check panicked: oh noes!", report.Checks["queue"].Error)
+
+	require.Len(t, reporter.Report().Checks, 4)
+}
+
+func TestReporter_Report_Cache(t *testing.T) {
+	var executions int32
+
+	reporter, err := New(&Config{Interval: 60})
+	require.NoError(t, err)
+
+	require.NoError(t, reporter.AddReadinessCheck("database", func(context.Context) error {
+		atomic.AddInt32(&executions, 1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}))
+
+	done := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func() {
+			reporter.Report()
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&executions), "concurrent reports should share the same execution")
+
+	reporter.Report()
+	require.Equal(t, int32(1), atomic.LoadInt32(&executions), "cached result should have been used")
+
+	reporter.now = func() time.Time { return time.Now().Add(time.Minute) }
+	reporter.Report()
+	require.Equal(t, int32(2), atomic.LoadInt32(&executions), "stale result should have been refreshed")
+}
+
+func TestReporter_HTTPHandler(t *testing.T) {
+	var ready int32
+
+	reporter, err := New(&Config{Interval: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, reporter.AddLivenessCheck("deadlock", func(context.Context) error { return nil }))
+	require.NoError(t, reporter.AddReadinessCheck("database", func(context.Context) error {
+		if atomic.LoadInt32(&ready) == 0 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}))
+
+	server := httptest.NewServer(reporter.HTTPHandler())
+	defer server.Close()
+
+	get := func(path string) (int, Report) {
+		res, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+		var report Report
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+		return res.StatusCode, report
+	}
+
+	status, report := get("/livez")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, StatusOK, report.Status)
+
+	status, report = get("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, "connection refused", report.Checks["database"].Error)
+
+	status, report = get("/healthz")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Len(t, report.Checks, 2)
+
+	atomic.StoreInt32(&ready, 1)
+	reporter.now = func() time.Time { return time.Now().Add(time.Second) }
+	status, _ = get("/readyz")
+	require.Equal(t, http.StatusOK, status)
+}
+
+func TestReporter_RegisterMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	reporter, err := New(&Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, reporter.AddLivenessCheck("deadlock", func(context.Context) error { return nil }))
+	require.NoError(t, reporter.RegisterMetrics(registry.Register))
+	require.NoError(t, reporter.AddReadinessCheck("database", func(context.Context) error {
+		return errors.New("connection refused")
+	}))
+
+	reporter.Report()
+	require.Equal(t, int64(1),
+		registry.Get(`health.check.status{check="deadlock",group="liveness"}`).(metrics.Gauge).Value())
+	require.Equal(t, int64(0),
+		registry.Get(`health.check.status{check="database",group="readiness"}`).(metrics.Gauge).Value())
+}
+
+func TestReporter_StartStop(t *testing.T) {
+	var executions int32
+
+	reporter, err := New(&Config{Listen: testFreeAddr(t), Interval: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, reporter.AddLivenessCheck("deadlock", func(context.Context) error {
+		atomic.AddInt32(&executions, 1)
+		return nil
+	}))
+
+	require.NoError(t, reporter.Start(context.Background()))
+	require.Eventually(t,
+		func() bool { return atomic.LoadInt32(&executions) >= 2 },
+		3*time.Second,
+		100*time.Millisecond,
+		"checks should have been executed in the background")
+
+	res, err := http.Get("http://" + reporter.listener.Addr().String() + "/livez")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	require.NoError(t, reporter.Stop(context.Background()))
+}
+
+// testFreeAddr returns a local network address available for binding.
+func testFreeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	return l.Addr().String()
+}
@@ -0,0 +1,186 @@
+// buckets implements go-metrics histograms and timers additionally counting their values in buckets.
+//
+// Unlike the quantiles computed from the histograms samples, bucket counts can be aggregated across instances:
+// exporters supporting them export bucketed histograms and timers as native histograms or as bucket series, the
+// other ones export them as regular histograms and timers.
+package buckets
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Bucketed represents a histogram or timer (or a snapshot of it) counting its values in buckets.
+type Bucketed interface {
+	// Buckets returns the buckets upper bounds, sorted in increasing order, and the cumulative count of the values
+	// less than or equal to each of them. The implicit +Inf bucket is not returned, its count being the total count
+	// of values.
+	Buckets() ([]float64, []uint64)
+}
+
+// Linear returns count buckets upper bounds, the lowest one being start and each following one being width higher.
+func Linear(start, width float64, count int) []float64 {
+	bounds := make([]float64, count)
+	for i := range bounds {
+		bounds[i] = start + float64(i)*width
+	}
+
+	return bounds
+}
+
+// Exponential returns count buckets upper bounds, the lowest one being start and each following one being factor
+// times higher.
+func Exponential(start, factor float64, count int) []float64 {
+	bounds := make([]float64, count)
+	for i := range bounds {
+		bounds[i] = start
+		start *= factor
+	}
+
+	return bounds
+}
+
+// Validate returns an error if bounds are not valid buckets upper bounds.
+func Validate(bounds []float64) error {
+	if len(bounds) == 0 {
+		return errors.New("at least one bucket is required")
+	}
+
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			return errors.New("buckets upper bounds must be sorted in strictly increasing order")
+		}
+	}
+
+	return nil
+}
+
+// Format returns the string representation of a bucket upper bound, used by exporters to name bucket series.
+func Format(bound float64) string {
+	return strconv.FormatFloat(bound, 'f', -1, 64)
+}
+
+// counts represents the values counts of a set of buckets.
+type counts struct {
+	bounds []float64
+	counts []uint64 // Non-cumulative counts, updated atomically
+}
+
+func newCounts(bounds []float64) *counts {
+	return &counts{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// Buckets implements the Bucketed interface.
+func (c *counts) Buckets() ([]float64, []uint64) {
+	cumulative := make([]uint64, len(c.counts))
+
+	var total uint64
+	for i := range c.counts {
+		total += atomic.LoadUint64(&c.counts[i])
+		cumulative[i] = total
+	}
+
+	return c.bounds, cumulative
+}
+
+func (c *counts) observe(v float64) {
+	if i := sort.SearchFloat64s(c.bounds, v); i < len(c.bounds) {
+		atomic.AddUint64(&c.counts[i], 1)
+	}
+}
+
+func (c *counts) clear() {
+	for i := range c.counts {
+		atomic.StoreUint64(&c.counts[i], 0)
+	}
+}
+
+// snapshot returns a copy of the counts. Snapshots must be taken before the ones of the histogram or timer counting
+// the same values, so that their count of values is always greater than or equal to the buckets counts.
+func (c *counts) snapshot() *counts {
+	s := newCounts(c.bounds)
+	for i := range c.counts {
+		s.counts[i] = atomic.LoadUint64(&c.counts[i])
+	}
+
+	return s
+}
+
+// Histogram represents a go-metrics histogram additionally counting its values in buckets.
+type Histogram struct {
+	metrics.Histogram
+	*counts
+}
+
+// NewHistogram returns a new histogram wrapping h and additionally counting its values in buckets of the specified
+// upper bounds.
+func NewHistogram(bounds []float64, h metrics.Histogram) *Histogram {
+	return &Histogram{
+		Histogram: h,
+		counts:    newCounts(bounds),
+	}
+}
+
+// Clear clears the histogram and its buckets.
+func (h *Histogram) Clear() {
+	h.Histogram.Clear()
+	h.counts.clear()
+}
+
+// Snapshot returns a read-only copy of the histogram and its buckets.
+func (h *Histogram) Snapshot() metrics.Histogram {
+	counts := h.counts.snapshot()
+
+	return &Histogram{Histogram: h.Histogram.Snapshot(), counts: counts}
+}
+
+// Update samples a new value.
+func (h *Histogram) Update(v int64) {
+	h.Histogram.Update(v)
+	h.observe(float64(v))
+}
+
+// Timer represents a go-metrics timer additionally counting its values (durations in nanoseconds) in buckets.
+type Timer struct {
+	metrics.Timer
+	*counts
+}
+
+// NewTimer returns a new timer wrapping t and additionally counting its values in buckets of the specified upper
+// bounds, expressed in nanoseconds.
+func NewTimer(bounds []float64, t metrics.Timer) *Timer {
+	return &Timer{
+		Timer:  t,
+		counts: newCounts(bounds),
+	}
+}
+
+// Snapshot returns a read-only copy of the timer and its buckets.
+func (t *Timer) Snapshot() metrics.Timer {
+	counts := t.counts.snapshot()
+
+	return &Timer{Timer: t.Timer.Snapshot(), counts: counts}
+}
+
+// Time records the duration of the execution of the function f.
+func (t *Timer) Time(f func()) {
+	ts := time.Now()
+	f()
+	t.UpdateSince(ts)
+}
+
+// Update records the duration of an event.
+func (t *Timer) Update(d time.Duration) {
+	t.Timer.Update(d)
+	t.observe(float64(d))
+}
+
+// UpdateSince records the duration of an event that started at ts and ends now.
+func (t *Timer) UpdateSince(ts time.Time) {
+	t.Update(time.Since(ts))
+}
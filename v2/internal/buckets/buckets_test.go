@@ -0,0 +1,63 @@
+package buckets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayouts(t *testing.T) {
+	require.Equal(t, []float64{1, 3, 5}, Linear(1, 2, 3))
+	require.Equal(t, []float64{1, 10, 100, 1000}, Exponential(1, 10, 4))
+
+	require.NoError(t, Validate([]float64{0.5, 1}))
+	require.Error(t, Validate(nil))
+	require.Error(t, Validate([]float64{1, 1}))
+
+	require.Equal(t, "0.005", Format(0.005))
+	require.Equal(t, "1000000", Format(1e6))
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 5, 10}, metrics.NewHistogram(metrics.NewUniformSample(100)))
+	for _, v := range []int64{0, 1, 2, 5, 7, 42} {
+		h.Update(v)
+	}
+
+	var (
+		_        metrics.Histogram = h
+		snapshot                   = h.Snapshot()
+	)
+	h.Update(3)
+
+	bounds, counts := snapshot.(Bucketed).Buckets()
+	require.Equal(t, []float64{1, 5, 10}, bounds)
+	require.Equal(t, []uint64{2, 4, 5}, counts)
+	require.Equal(t, int64(6), snapshot.Count())
+	require.Panics(t, func() { snapshot.Update(1) })
+
+	h.Clear()
+	_, counts = h.Buckets()
+	require.Equal(t, []uint64{0, 0, 0}, counts)
+	require.Zero(t, h.Count())
+}
+
+func TestTimer(t *testing.T) {
+	timer := NewTimer([]float64{float64(time.Millisecond), float64(time.Second)}, metrics.NewTimer())
+	defer timer.Stop()
+
+	timer.Update(time.Microsecond)
+	timer.Update(time.Minute)
+	timer.Time(func() {})
+
+	var (
+		_        metrics.Timer = timer
+		snapshot               = timer.Snapshot()
+	)
+
+	_, counts := snapshot.(Bucketed).Buckets()
+	require.Equal(t, []uint64{2, 2}, counts)
+	require.Equal(t, int64(3), snapshot.Count())
+}
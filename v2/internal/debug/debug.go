@@ -40,6 +40,15 @@ func (d *D) Debug(msg string, ctx ...interface{}) {
 	d.logger.Debug(msg, ctx...)
 }
 
+// Warn logs a warning-level message.
+func (d *D) Warn(msg string, ctx ...interface{}) {
+	if d.prefix != "" {
+		msg = d.prefix + ": " + msg
+	}
+
+	d.logger.Warn(msg, ctx...)
+}
+
 // Error logs an error-level message.
 func (d *D) Error(msg string, ctx ...interface{}) {
 	if d.prefix != "" {
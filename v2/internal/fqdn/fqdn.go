@@ -0,0 +1,35 @@
+// fqdn implements the resolution of the host fully qualified domain name.
+package fqdn
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// Get returns the host fully qualified domain name, resolved by looking up the reverse DNS record of the first IPv4
+// address of the hostname. If it can't be resolved, the hostname is returned.
+func Get() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	addrs, err := net.LookupIP(hostname)
+	if err != nil {
+		return hostname, nil
+	}
+
+	for _, addr := range addrs {
+		if ipv4 := addr.To4(); ipv4 != nil {
+			hosts, err := net.LookupAddr(ipv4.String())
+			if err != nil || len(hosts) == 0 {
+				return hostname, nil
+			}
+
+			return strings.TrimSuffix(hosts[0], "."), nil
+		}
+	}
+
+	return hostname, nil
+}
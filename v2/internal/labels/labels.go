@@ -0,0 +1,113 @@
+// labels implements the encoding of metrics labels into go-metrics registry names.
+//
+// go-metrics registries identify metrics by a flat name: labeled metrics are registered under their name followed by
+// their labels in a Prometheus-like notation, e.g. `http_requests{code="200",method="GET"}`, labels being sorted by
+// key. Exporters decode such names to export the labels in the most appropriate way for their destination.
+package labels
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Labels represents a set of metric labels.
+type Labels map[string]string
+
+// FromPairs returns the labels specified as a list of alternating keys and values. If the list has an odd number of
+// elements, the last key is given an empty value.
+func FromPairs(pairs ...string) Labels {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	l := make(Labels, (len(pairs)+1)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		var v string
+		if i+1 < len(pairs) {
+			v = pairs[i+1]
+		}
+		l[pairs[i]] = v
+	}
+
+	return l
+}
+
+// Keys returns the sorted label keys.
+func (l Labels) Keys() []string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Values returns the label values, sorted by key.
+func (l Labels) Values() []string {
+	keys := l.Keys()
+
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = l[k]
+	}
+
+	return values
+}
+
+// String returns the encoded representation of the labels, e.g. `{code="200",method="GET"}`.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range l.Keys() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l[k]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// Encode returns the registry name of the metric name with the labels l.
+func Encode(name string, l Labels) string {
+	return name + l.String()
+}
+
+// Decode returns the metric name and labels encoded in a registry name. If the registry name doesn't contain labels
+// (or if they can't be decoded), it is returned unchanged with nil labels.
+func Decode(s string) (string, Labels) {
+	i := strings.IndexByte(s, '{')
+	if i < 0 || !strings.HasSuffix(s, "}") {
+		return s, nil
+	}
+
+	name, rest := s[:i], s[i+1:len(s)-1]
+	l := make(Labels)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return s, nil
+		}
+		k := rest[:eq]
+
+		quoted, err := strconv.QuotedPrefix(rest[eq+1:])
+		if err != nil {
+			return s, nil
+		}
+		v, _ := strconv.Unquote(quoted)
+		l[k] = v
+
+		rest = strings.TrimPrefix(rest[eq+1+len(quoted):], ",")
+	}
+
+	return name, l
+}
@@ -0,0 +1,48 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromPairs(t *testing.T) {
+	require.Nil(t, FromPairs())
+	require.Equal(t, Labels{"method": "GET", "code": "200"}, FromPairs("method", "GET", "code", "200"))
+	require.Equal(t, Labels{"method": "GET", "code": ""}, FromPairs("method", "GET", "code"))
+}
+
+func TestLabels_String(t *testing.T) {
+	require.Equal(t, "", Labels{}.String())
+	require.Equal(t, `{code="200",method="GET"}`, Labels{"method": "GET", "code": "200"}.String())
+	require.Equal(t, `{path="/a\"b,c={d}"}`, Labels{"path": `/a"b,c={d}`}.String())
+}
+
+func TestEncodeDecode(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		labels Labels
+	}{
+		{name: "requests"},
+		{name: "requests", labels: Labels{"method": "GET", "code": "200"}},
+		{name: "requests", labels: Labels{"path": `/a"b,c={d}`, "empty": ""}},
+	}
+
+	for _, tc := range testCases {
+		name, l := Decode(Encode(tc.name, tc.labels))
+		require.Equal(t, tc.name, name)
+		if len(tc.labels) == 0 {
+			require.Nil(t, l)
+		} else {
+			require.Equal(t, tc.labels, l)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, s := range []string{"requests{", "requests{method}", `requests{method=GET}`, `requests{method="GET}`} {
+		name, l := Decode(s)
+		require.Equal(t, s, name)
+		require.Nil(t, l)
+	}
+}
@@ -0,0 +1,132 @@
+// naming implements the naming rules of metrics, shared by the reporter and the exporters.
+//
+// The names of the metrics created using the reporter's helper methods are validated when registered: they are
+// dot-separated paths of printable, non-space characters, braces and double quotes being reserved for the labels
+// encoding, and label keys are made of letters, digits and underscores and don't start with a digit. Invalid names
+// and label keys are sanitized by replacing the offending characters with underscores and dropping empty path
+// components.
+//
+// Exporters then sanitize names and label values using the destination-specific functions of this package, so that a
+// metric is mangled the same way by all the exporters to the same kind of destination.
+package naming
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// nameSeparator represents the separator of the metrics names components.
+const nameSeparator = "."
+
+// Sanitize returns s with the runes for which valid returns false replaced with underscores, valid being also given
+// the byte offset of the rune. Invalid UTF-8 sequences are replaced as well.
+func Sanitize(s string, valid func(i int, r rune) bool) string {
+	clean := true
+	for i, r := range s {
+		if r == utf8.RuneError || !valid(i, r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i, r := range s {
+		if r == utf8.RuneError || !valid(i, r) {
+			b.WriteByte('_')
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Name returns the valid metric name closest to name.
+func Name(name string) string {
+	parts := strings.Split(Sanitize(name, validNameRune), nameSeparator)
+
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	if len(kept) == 0 {
+		return "_"
+	}
+
+	return strings.Join(kept, nameSeparator)
+}
+
+// LabelKey returns the valid label key closest to key.
+func LabelKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+
+	return Sanitize(key, validLabelKeyRune)
+}
+
+// Prometheus returns name with the characters invalid in Prometheus metric names replaced with underscores.
+func Prometheus(name string) string {
+	return Sanitize(name, func(i int, r rune) bool {
+		return r == ':' || validLabelKeyRune(i, r)
+	})
+}
+
+// PrometheusLabel returns key with the characters invalid in Prometheus label names replaced with underscores.
+func PrometheusLabel(key string) string {
+	return Sanitize(key, validLabelKeyRune)
+}
+
+// Graphite returns path with the characters invalid in Graphite metric paths replaced with underscores.
+func Graphite(path string) string {
+	return Sanitize(path, func(_ int, r rune) bool {
+		return !unicode.IsSpace(r) && !unicode.IsControl(r) && r != ';'
+	})
+}
+
+// Statsd returns name with the characters having a special meaning in the StatsD protocol replaced with underscores.
+func Statsd(name string) string {
+	return Sanitize(name, func(_ int, r rune) bool {
+		return !unicode.IsSpace(r) && !unicode.IsControl(r) && !strings.ContainsRune(":|@,#", r)
+	})
+}
+
+// StatsdTag returns the DogStatsD tag of a key and a value, with the characters having a special meaning in the
+// DogStatsD protocol replaced with underscores.
+func StatsdTag(key, value string) string {
+	return Sanitize(key+":"+value, func(_ int, r rune) bool {
+		return !unicode.IsControl(r) && !strings.ContainsRune(",|#", r)
+	})
+}
+
+// Collectd returns name with the characters invalid in collectd plugin names replaced with underscores: dashes
+// separate the plugin instance and slashes separate the identifier parts.
+func Collectd(name string) string {
+	return Sanitize(name, func(_ int, r rune) bool {
+		return !unicode.IsControl(r) && r != '-' && r != '/'
+	})
+}
+
+// CollectdInstance returns name with the characters invalid in collectd plugin instances replaced with underscores.
+func CollectdInstance(name string) string {
+	return Sanitize(name, func(_ int, r rune) bool {
+		return !unicode.IsControl(r) && r != '/'
+	})
+}
+
+// validNameRune reports whether r is allowed in a registered metric name.
+func validNameRune(_ int, r rune) bool {
+	return unicode.IsPrint(r) && !unicode.IsSpace(r) && r != '{' && r != '}' && r != '"'
+}
+
+// validLabelKeyRune reports whether r is allowed at offset i in a label key.
+func validLabelKeyRune(i int, r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9'
+}
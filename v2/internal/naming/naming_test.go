@@ -0,0 +1,44 @@
+package naming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestName(t *testing.T) {
+	require.Equal(t, "api.requests", Name("api.requests"))
+	require.Equal(t, "api_requests__total_", Name("api requests\n{total}"))
+	require.Equal(t, "api.requests", Name(".api..requests."))
+	require.Equal(t, "api_requests", Name("api\xffrequests"))
+	require.Equal(t, "_", Name(".."))
+}
+
+func TestLabelKey(t *testing.T) {
+	require.Equal(t, "status_code", LabelKey("status_code"))
+	require.Equal(t, "status_code", LabelKey("status-code"))
+	require.Equal(t, "_xx", LabelKey("2xx"))
+	require.Equal(t, "_", LabelKey(""))
+}
+
+func TestExporters(t *testing.T) {
+	var testCases = []struct {
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{fn: Prometheus, in: "api.requests-total:sum", want: "api_requests_total:sum"},
+		{fn: Prometheus, in: "github.com/exoscale/api", want: "github_com_exoscale_api"},
+		{fn: PrometheusLabel, in: "a:b", want: "a_b"},
+		{fn: Graphite, in: "api requests;total\tsum", want: "api_requests_total_sum"},
+		{fn: Statsd, in: "api:requests|c@0.1#a,b", want: "api_requests_c_0.1_a_b"},
+		{fn: Collectd, in: "api-requests/total", want: "api_requests_total"},
+		{fn: CollectdInstance, in: "GET-/api", want: "GET-_api"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, tc.fn(tc.in), tc.in)
+	}
+
+	require.Equal(t, "path:/a_b", StatsdTag("path", "/a,b"))
+}
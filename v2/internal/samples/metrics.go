@@ -0,0 +1,128 @@
+package samples
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// NewHistogram returns a new histogram using the specified sample. The go-metrics standard histogram only supports
+// samples whose snapshot is a *metrics.SampleSnapshot, so a histogram supporting any sample is returned for the
+// other ones (e.g. HDR samples).
+func NewHistogram(s metrics.Sample) metrics.Histogram {
+	if _, ok := s.Snapshot().(*metrics.SampleSnapshot); ok {
+		return metrics.NewHistogram(s)
+	}
+
+	return &histogram{sample: s}
+}
+
+// NewTimer returns a new timer using the specified sample. As for NewHistogram, a timer supporting any sample is
+// returned for the samples not supported by the go-metrics standard timer.
+func NewTimer(s metrics.Sample) metrics.Timer {
+	if _, ok := s.Snapshot().(*metrics.SampleSnapshot); ok {
+		return metrics.NewCustomTimer(metrics.NewHistogram(s), metrics.NewMeter())
+	}
+
+	return &timer{
+		histogram: &histogram{sample: s},
+		meter:     metrics.NewMeter(),
+	}
+}
+
+// histogram represents a go-metrics histogram supporting any sample type.
+type histogram struct {
+	sample metrics.Sample
+}
+
+// Clear clears the histogram and its sample.
+func (h *histogram) Clear() { h.sample.Clear() }
+
+// Count returns the number of values recorded since the histogram was last cleared.
+func (h *histogram) Count() int64 { return h.sample.Count() }
+
+// Max returns the maximum value in the sample.
+func (h *histogram) Max() int64 { return h.sample.Max() }
+
+// Mean returns the mean of the values in the sample.
+func (h *histogram) Mean() float64 { return h.sample.Mean() }
+
+// Min returns the minimum value in the sample.
+func (h *histogram) Min() int64 { return h.sample.Min() }
+
+// Percentile returns an arbitrary percentile of the values in the sample.
+func (h *histogram) Percentile(p float64) float64 { return h.sample.Percentile(p) }
+
+// Percentiles returns a slice of arbitrary percentiles of the values in the sample.
+func (h *histogram) Percentiles(ps []float64) []float64 { return h.sample.Percentiles(ps) }
+
+// Sample returns the histogram's sample.
+func (h *histogram) Sample() metrics.Sample { return h.sample }
+
+// Snapshot returns a read-only copy of the histogram.
+func (h *histogram) Snapshot() metrics.Histogram { return &histogram{sample: h.sample.Snapshot()} }
+
+// StdDev returns the standard deviation of the values in the sample.
+func (h *histogram) StdDev() float64 { return h.sample.StdDev() }
+
+// Sum returns the sum of the values in the sample.
+func (h *histogram) Sum() int64 { return h.sample.Sum() }
+
+// Update samples a new value. It panics if called on a snapshot.
+func (h *histogram) Update(v int64) { h.sample.Update(v) }
+
+// Variance returns the variance of the values in the sample.
+func (h *histogram) Variance() float64 { return h.sample.Variance() }
+
+// timer represents a go-metrics timer supporting any sample type.
+type timer struct {
+	*histogram
+	mu    sync.Mutex
+	meter metrics.Meter
+}
+
+// Rate1 returns the one-minute moving average rate of events per second.
+func (t *timer) Rate1() float64 { return t.meter.Rate1() }
+
+// Rate5 returns the five-minute moving average rate of events per second.
+func (t *timer) Rate5() float64 { return t.meter.Rate5() }
+
+// Rate15 returns the fifteen-minute moving average rate of events per second.
+func (t *timer) Rate15() float64 { return t.meter.Rate15() }
+
+// RateMean returns the meter's mean rate of events per second.
+func (t *timer) RateMean() float64 { return t.meter.RateMean() }
+
+// Snapshot returns a read-only copy of the timer.
+func (t *timer) Snapshot() metrics.Timer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return &timer{
+		histogram: t.histogram.Snapshot().(*histogram),
+		meter:     t.meter.Snapshot(),
+	}
+}
+
+// Stop stops the meter.
+func (t *timer) Stop() { t.meter.Stop() }
+
+// Time records the duration of the execution of the function f.
+func (t *timer) Time(f func()) {
+	ts := time.Now()
+	f()
+	t.Update(time.Since(ts))
+}
+
+// Update records the duration of an event. It panics if called on a snapshot.
+func (t *timer) Update(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.histogram.Update(int64(d))
+	t.meter.Mark(1)
+}
+
+// UpdateSince records the duration of an event that started at ts and ends now.
+func (t *timer) UpdateSince(ts time.Time) { t.Update(time.Since(ts)) }
@@ -0,0 +1,356 @@
+// samples implements go-metrics samples complementing the uniform and exponentially-decaying ones provided by
+// go-metrics: a sliding time window sample, and a high dynamic range (HDR) sample.
+package samples
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// timedValue represents a value of a sliding time window sample.
+type timedValue struct {
+	t     time.Time
+	value int64
+}
+
+// Window represents a sample of the values recorded during a sliding time window, keeping at most a fixed number of
+// the most recent values.
+type Window struct {
+	mu     sync.Mutex
+	window time.Duration
+	size   int
+	count  int64
+	values []timedValue // Sorted by recording time
+	now    func() time.Time
+}
+
+// NewWindow returns a new sample of the values recorded during the last window, keeping at most the size most
+// recent ones.
+func NewWindow(window time.Duration, size int) *Window {
+	return &Window{
+		window: window,
+		size:   size,
+		values: make([]timedValue, 0, size),
+		now:    time.Now,
+	}
+}
+
+// Clear clears all values.
+func (s *Window) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count = 0
+	s.values = s.values[:0]
+}
+
+// Count returns the number of values recorded, which may exceed the size of the sample.
+func (s *Window) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.count
+}
+
+// Max returns the maximum value in the sample.
+func (s *Window) Max() int64 { return metrics.SampleMax(s.Values()) }
+
+// Mean returns the mean of the values in the sample.
+func (s *Window) Mean() float64 { return metrics.SampleMean(s.Values()) }
+
+// Min returns the minimum value in the sample.
+func (s *Window) Min() int64 { return metrics.SampleMin(s.Values()) }
+
+// Percentile returns an arbitrary percentile of the values in the sample.
+func (s *Window) Percentile(p float64) float64 { return metrics.SamplePercentile(s.Values(), p) }
+
+// Percentiles returns a slice of arbitrary percentiles of the values in the sample.
+func (s *Window) Percentiles(ps []float64) []float64 {
+	return metrics.SamplePercentiles(s.Values(), ps)
+}
+
+// Size returns the number of values in the sample.
+func (s *Window) Size() int { return len(s.Values()) }
+
+// Snapshot returns a read-only copy of the sample.
+func (s *Window) Snapshot() metrics.Sample {
+	values := s.Values()
+
+	return metrics.NewSampleSnapshot(s.Count(), values)
+}
+
+// StdDev returns the standard deviation of the values in the sample.
+func (s *Window) StdDev() float64 { return metrics.SampleStdDev(s.Values()) }
+
+// Sum returns the sum of the values in the sample.
+func (s *Window) Sum() int64 { return metrics.SampleSum(s.Values()) }
+
+// Update records a new value.
+func (s *Window) Update(v int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+	if len(s.values) == s.size {
+		s.values = append(s.values[:0], s.values[1:]...)
+	}
+
+	s.count++
+	s.values = append(s.values, timedValue{t: now, value: v})
+}
+
+// Values returns a copy of the values in the sample.
+func (s *Window) Values() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(s.now())
+	values := make([]int64, len(s.values))
+	for i, v := range s.values {
+		values[i] = v.value
+	}
+
+	return values
+}
+
+// Variance returns the variance of the values in the sample.
+func (s *Window) Variance() float64 { return metrics.SampleVariance(s.Values()) }
+
+// expire removes the values recorded before the sliding window.
+func (s *Window) expire(now time.Time) {
+	i := sort.Search(len(s.values), func(i int) bool { return now.Sub(s.values[i].t) <= s.window })
+	if i > 0 {
+		s.values = append(s.values[:0], s.values[i:]...)
+	}
+}
+
+// HDR represents a high dynamic range sample: instead of keeping a subset of the values, it counts all the values
+// recorded in buckets whose width is proportional to their magnitude, bounding the relative error of the percentiles
+// regardless of the values range. Count, sum, minimum, maximum, mean and variance are exact. Since individual values
+// are not kept, Values returns nil.
+type HDR struct {
+	mu        sync.Mutex
+	precision uint             // Number of bits of the values kept exact
+	counts    map[int64]uint64 // Buckets counts by index, negative indexes being the buckets of negative values
+	count     int64
+	sum       int64
+	min, max  int64
+	mean, m2  float64 // Running mean and sum of squared differences from the mean (Welford's algorithm)
+	snapshot  bool
+}
+
+// NewHDR returns a new high dynamic range sample, computing percentiles with the specified number of significant
+// decimal digits.
+func NewHDR(digits int) *HDR {
+	return &HDR{
+		precision: uint(math.Ceil(float64(digits) * math.Log2(10))),
+		counts:    make(map[int64]uint64),
+	}
+}
+
+// Clear clears all values.
+func (s *HDR) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts = make(map[int64]uint64)
+	s.count, s.sum, s.min, s.max, s.mean, s.m2 = 0, 0, 0, 0, 0, 0
+}
+
+// Count returns the number of values recorded.
+func (s *HDR) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.count
+}
+
+// Max returns the maximum value recorded.
+func (s *HDR) Max() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.max
+}
+
+// Mean returns the mean of the values recorded.
+func (s *HDR) Mean() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.mean
+}
+
+// Min returns the minimum value recorded.
+func (s *HDR) Min() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.min
+}
+
+// Percentile returns an arbitrary percentile of the values recorded.
+func (s *HDR) Percentile(p float64) float64 {
+	return s.Percentiles([]float64{p})[0]
+}
+
+// Percentiles returns a slice of arbitrary percentiles of the values recorded.
+func (s *HDR) Percentiles(ps []float64) []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := make([]float64, len(ps))
+	if s.count == 0 {
+		return values
+	}
+
+	indexes := make([]int64, 0, len(s.counts))
+	for i := range s.counts {
+		indexes = append(indexes, i)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	for i, p := range ps {
+		// The extreme values are known exactly
+		rank := uint64(math.Ceil(p * float64(s.count)))
+		if rank <= 1 {
+			values[i] = float64(s.min)
+			continue
+		}
+		if rank >= uint64(s.count) {
+			values[i] = float64(s.max)
+			continue
+		}
+
+		var cumulative uint64
+		for _, index := range indexes {
+			if cumulative += s.counts[index]; cumulative >= rank {
+				values[i] = float64(s.value(index))
+				break
+			}
+		}
+
+		values[i] = math.Max(float64(s.min), math.Min(float64(s.max), values[i]))
+	}
+
+	return values
+}
+
+// Size returns the number of values recorded.
+func (s *HDR) Size() int { return int(s.Count()) }
+
+// Snapshot returns a read-only copy of the sample.
+func (s *HDR) Snapshot() metrics.Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := &HDR{
+		precision: s.precision,
+		counts:    make(map[int64]uint64, len(s.counts)),
+		count:     s.count,
+		sum:       s.sum,
+		min:       s.min,
+		max:       s.max,
+		mean:      s.mean,
+		m2:        s.m2,
+		snapshot:  true,
+	}
+	for i, c := range s.counts {
+		snapshot.counts[i] = c
+	}
+
+	return snapshot
+}
+
+// StdDev returns the standard deviation of the values recorded.
+func (s *HDR) StdDev() float64 { return math.Sqrt(s.Variance()) }
+
+// Sum returns the sum of the values recorded.
+func (s *HDR) Sum() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sum
+}
+
+// Update records a new value. It panics if called on a snapshot.
+func (s *HDR) Update(v int64) {
+	if s.snapshot {
+		panic("Update called on a HDR sample snapshot")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[s.index(v)]++
+
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+	s.sum += v
+
+	delta := float64(v) - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (float64(v) - s.mean)
+}
+
+// Values returns nil, since the sample doesn't keep individual values.
+func (s *HDR) Values() []int64 { return nil }
+
+// Variance returns the variance of the values recorded.
+func (s *HDR) Variance() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.count == 0 {
+		return 0
+	}
+
+	return s.m2 / float64(s.count)
+}
+
+// index returns the index of the bucket of a value. Values lower than 2^(precision+1) have their own bucket, higher
+// ones share buckets of 2^shift values, shift being such that the values share their precision+1 most significant
+// bits.
+func (s *HDR) index(v int64) int64 {
+	if v < 0 {
+		if v == math.MinInt64 {
+			v++
+		}
+		return -s.index(-v) - 1
+	}
+
+	u := uint64(v)
+	if u < 1<<(s.precision+1) {
+		return v
+	}
+
+	shift := uint(bits.Len64(u)) - (s.precision + 1)
+	return int64(uint64(shift)<<s.precision + u>>shift)
+}
+
+// value returns the value representing the values of a bucket, i.e. the middle of the bucket.
+func (s *HDR) value(index int64) int64 {
+	if index < 0 {
+		return -s.value(-index - 1)
+	}
+
+	if index < 1<<(s.precision+1) {
+		return index
+	}
+
+	shift := uint(index>>s.precision) - 1
+	lowest := (index - int64(shift)<<s.precision) << shift
+
+	return lowest + (1<<shift-1)/2
+}
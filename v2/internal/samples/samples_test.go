@@ -0,0 +1,120 @@
+package samples
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow(t *testing.T) {
+	var (
+		now    = time.Unix(0, 0)
+		window = NewWindow(time.Minute, 3)
+	)
+	window.now = func() time.Time { return now }
+
+	for i := int64(1); i <= 4; i++ {
+		window.Update(i)
+		now = now.Add(10 * time.Second)
+	}
+	require.Equal(t, []int64{2, 3, 4}, window.Values(), "should have kept the most recent values")
+	require.Equal(t, int64(4), window.Count())
+
+	now = now.Add(45 * time.Second)
+	require.Equal(t, []int64{4}, window.Values(), "should have expired the values out of the window")
+	require.Equal(t, int64(4), window.Max())
+
+	snapshot := window.Snapshot()
+	require.IsType(t, &metrics.SampleSnapshot{}, snapshot)
+	require.Equal(t, int64(4), snapshot.Count())
+
+	window.Clear()
+	require.Zero(t, window.Count())
+	require.Zero(t, window.Size())
+}
+
+func TestHDR(t *testing.T) {
+	var (
+		hdr    = NewHDR(3)
+		values = make([]int64, 0, 100000)
+		rnd    = rand.New(rand.NewSource(42))
+	)
+
+	for i := 0; i < cap(values); i++ {
+		// Log-normally distributed latencies from microseconds to seconds
+		v := int64(math.Exp(rnd.NormFloat64()*2+12)) - 50000
+		values = append(values, v)
+		hdr.Update(v)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	require.Equal(t, int64(len(values)), hdr.Count())
+	require.Equal(t, values[0], hdr.Min())
+	require.Equal(t, values[len(values)-1], hdr.Max())
+	require.Equal(t, metrics.SampleSum(values), hdr.Sum())
+	require.InDelta(t, metrics.SampleMean(values), hdr.Mean(), 1e-3)
+	require.InEpsilon(t, metrics.SampleStdDev(values), hdr.StdDev(), 1e-9)
+	require.Nil(t, hdr.Values())
+
+	for _, p := range []float64{0.001, 0.5, 0.9, 0.99, 0.999, 0.9999} {
+		exact := float64(values[int(math.Ceil(p*float64(len(values))))-1])
+		require.InDelta(t, exact, hdr.Percentile(p), math.Abs(exact)*1e-3+1, "p%v", p*100)
+	}
+	require.Equal(t, float64(hdr.Max()), hdr.Percentile(1))
+
+	snapshot := hdr.Snapshot()
+	hdr.Clear()
+	require.Zero(t, hdr.Count())
+	require.Equal(t, []float64{0}, hdr.Percentiles([]float64{0.5}))
+	require.Equal(t, int64(len(values)), snapshot.Count())
+	require.Panics(t, func() { snapshot.Update(1) })
+}
+
+func TestHDR_index(t *testing.T) {
+	hdr := NewHDR(2) // 7 bits
+	require.Equal(t, uint(7), hdr.precision)
+
+	for _, v := range []int64{0, 1, 255, 256, 1000, 123456789, math.MaxInt64, -1, -1000, math.MinInt64} {
+		value := hdr.value(hdr.index(v))
+		require.InDelta(t, float64(v), float64(value), math.Abs(float64(v))/(1<<7), "%d", v)
+	}
+
+	for v := int64(0); v < 100000; v++ {
+		require.LessOrEqual(t, hdr.index(v), hdr.index(v+1), "indexes should be monotonic")
+	}
+}
+
+func TestNewHistogram(t *testing.T) {
+	require.IsType(t, &metrics.StandardHistogram{}, NewHistogram(metrics.NewUniformSample(10)))
+	require.IsType(t, &metrics.StandardHistogram{}, NewHistogram(NewWindow(time.Minute, 10)))
+
+	histogram := NewHistogram(NewHDR(3))
+	histogram.Update(42)
+	snapshot := histogram.Snapshot()
+	histogram.Update(43)
+	require.Equal(t, int64(1), snapshot.Count())
+	require.Equal(t, float64(42), snapshot.Percentile(0.99))
+}
+
+func TestNewTimer(t *testing.T) {
+	timer := NewTimer(NewHDR(3))
+	defer timer.Stop()
+
+	timer.Update(time.Millisecond)
+	timer.Time(func() {})
+
+	snapshot := timer.Snapshot()
+	timer.Update(time.Second)
+	require.Equal(t, int64(2), snapshot.Count())
+	require.InDelta(t, float64(time.Millisecond), snapshot.Percentile(1), float64(time.Millisecond)/1000)
+	require.Panics(t, func() { snapshot.Update(time.Second) })
+
+	defaultTimer := NewTimer(metrics.NewExpDecaySample(1028, 0.015))
+	defer defaultTimer.Stop()
+	require.IsType(t, &metrics.StandardTimer{}, defaultTimer)
+}
@@ -1,5 +1,9 @@
 package v2
 
+import (
+	"context"
+)
+
 // Crit is a convenience wrapper around Reporter.Logging.Crit().
 // It is effective only if the reporter has its logging reporter configured.
 func (r *Reporter) Crit(msg string, ctx ...interface{}) {
@@ -39,3 +43,48 @@ func (r *Reporter) Debug(msg string, ctx ...interface{}) {
 		r.Logging.Debug(msg, ctx...)
 	}
 }
+
+// CritContext is a convenience wrapper around Reporter.Logging.FromContext(ctx).Crit(): the record is added the
+// "trace_id" and "span_id" of the span carried by ctx (if any). It is effective only if the reporter has its logging
+// reporter configured.
+func (r *Reporter) CritContext(ctx context.Context, msg string, kv ...interface{}) {
+	if r.Logging != nil {
+		r.Logging.FromContext(ctx).Crit(msg, kv...)
+	}
+}
+
+// ErrorContext is a convenience wrapper around Reporter.Logging.FromContext(ctx).Error(): the record is added the
+// "trace_id" and "span_id" of the span carried by ctx (if any). It is effective only if the reporter has its logging
+// reporter configured.
+func (r *Reporter) ErrorContext(ctx context.Context, msg string, kv ...interface{}) {
+	if r.Logging != nil {
+		r.Logging.FromContext(ctx).Error(msg, kv...)
+	}
+}
+
+// WarnContext is a convenience wrapper around Reporter.Logging.FromContext(ctx).Warn(): the record is added the
+// "trace_id" and "span_id" of the span carried by ctx (if any). It is effective only if the reporter has its logging
+// reporter configured.
+func (r *Reporter) WarnContext(ctx context.Context, msg string, kv ...interface{}) {
+	if r.Logging != nil {
+		r.Logging.FromContext(ctx).Warn(msg, kv...)
+	}
+}
+
+// InfoContext is a convenience wrapper around Reporter.Logging.FromContext(ctx).Info(): the record is added the
+// "trace_id" and "span_id" of the span carried by ctx (if any). It is effective only if the reporter has its logging
+// reporter configured.
+func (r *Reporter) InfoContext(ctx context.Context, msg string, kv ...interface{}) {
+	if r.Logging != nil {
+		r.Logging.FromContext(ctx).Info(msg, kv...)
+	}
+}
+
+// DebugContext is a convenience wrapper around Reporter.Logging.FromContext(ctx).Debug(): the record is added the
+// "trace_id" and "span_id" of the span carried by ctx (if any). It is effective only if the reporter has its logging
+// reporter configured.
+func (r *Reporter) DebugContext(ctx context.Context, msg string, kv ...interface{}) {
+	if r.Logging != nil {
+		r.Logging.FromContext(ctx).Debug(msg, kv...)
+	}
+}
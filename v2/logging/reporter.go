@@ -5,6 +5,7 @@ import (
 	"context"
 	"sort"
 
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/inconshreveable/log15.v2"
 
 	"github.com/exoscale/go-reporter/v2/internal/debug"
@@ -96,6 +97,20 @@ func (r *Reporter) SetHandler(h log15.Handler) {
 	r.logger.SetHandler(h)
 }
 
+// FromContext returns a copy of the logging reporter adding the "trace_id" and "span_id" of the span carried by ctx
+// to its log records. If ctx doesn't carry a valid span, the reporter is returned unchanged.
+func (r *Reporter) FromContext(ctx context.Context) *Reporter {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return r
+	}
+
+	child := *r
+	child.logger = r.logger.New("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+
+	return &child
+}
+
 // Crit logs a message with a "critical" severity level.
 func (r *Reporter) Crit(msg string, ctx ...interface{}) {
 	r.logger.Crit(msg, ctx...)
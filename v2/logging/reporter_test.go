@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/inconshreveable/log15.v2"
 
 	gtesting "github.com/exoscale/go-reporter/v2/testing"
@@ -153,3 +155,34 @@ func TestReporter_Handler(t *testing.T) {
 
 	require.Equal(t, testHandler, reporter.Handler())
 }
+
+func TestReporter_FromContext(t *testing.T) {
+	var (
+		testTraceID, _ = trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		testSpanID, _  = trace.SpanIDFromHex("00f067aa0ba902b7")
+	)
+
+	testHandler := newTestLogHandler()
+
+	reporter, err := New(&Config{Destinations: []*LogDestinationConfig{
+		{Type: "console"},
+	}})
+	require.NoError(t, err)
+
+	reporter.SetHandler(testHandler)
+
+	require.Equal(t, reporter, reporter.FromContext(context.Background()))
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: testTraceID,
+		SpanID:  testSpanID,
+	}))
+	reporter.FromContext(ctx).Info("hello", "k", "v")
+
+	require.Len(t, testHandler.records, 1)
+	require.Equal(t, []interface{}{
+		"trace_id", "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id", "00f067aa0ba902b7",
+		"k", "v",
+	}, testHandler.records[0].Ctx)
+}
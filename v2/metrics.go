@@ -0,0 +1,68 @@
+package v2
+
+import (
+	"github.com/rcrowley/go-metrics"
+)
+
+// Counter is a convenience wrapper around Reporter.Metrics.Counter().
+// If the reporter doesn't have its metrics reporter configured, a no-op counter is returned.
+func (r *Reporter) Counter(name string, labels ...string) metrics.Counter {
+	if r.Metrics != nil {
+		return r.Metrics.Counter(name, labels...)
+	}
+	return metrics.NilCounter{}
+}
+
+// Gauge is a convenience wrapper around Reporter.Metrics.Gauge().
+// If the reporter doesn't have its metrics reporter configured, a no-op gauge is returned.
+func (r *Reporter) Gauge(name string, labels ...string) metrics.Gauge {
+	if r.Metrics != nil {
+		return r.Metrics.Gauge(name, labels...)
+	}
+	return metrics.NilGauge{}
+}
+
+// GaugeFloat64 is a convenience wrapper around Reporter.Metrics.GaugeFloat64().
+// If the reporter doesn't have its metrics reporter configured, a no-op gauge is returned.
+func (r *Reporter) GaugeFloat64(name string, labels ...string) metrics.GaugeFloat64 {
+	if r.Metrics != nil {
+		return r.Metrics.GaugeFloat64(name, labels...)
+	}
+	return metrics.NilGaugeFloat64{}
+}
+
+// Histogram is a convenience wrapper around Reporter.Metrics.Histogram().
+// If the reporter doesn't have its metrics reporter configured, a no-op histogram is returned.
+func (r *Reporter) Histogram(name string, labels ...string) metrics.Histogram {
+	if r.Metrics != nil {
+		return r.Metrics.Histogram(name, labels...)
+	}
+	return metrics.NilHistogram{}
+}
+
+// Meter is a convenience wrapper around Reporter.Metrics.Meter().
+// If the reporter doesn't have its metrics reporter configured, a no-op meter is returned.
+func (r *Reporter) Meter(name string, labels ...string) metrics.Meter {
+	if r.Metrics != nil {
+		return r.Metrics.Meter(name, labels...)
+	}
+	return metrics.NilMeter{}
+}
+
+// Timer is a convenience wrapper around Reporter.Metrics.Timer().
+// If the reporter doesn't have its metrics reporter configured, a no-op timer is returned.
+func (r *Reporter) Timer(name string, labels ...string) metrics.Timer {
+	if r.Metrics != nil {
+		return r.Metrics.Timer(name, labels...)
+	}
+	return metrics.NilTimer{}
+}
+
+// Healthcheck is a convenience wrapper around Reporter.Metrics.Healthcheck().
+// If the reporter doesn't have its metrics reporter configured, a no-op healthcheck is returned.
+func (r *Reporter) Healthcheck(name string, fn func(metrics.Healthcheck), labels ...string) metrics.Healthcheck {
+	if r.Metrics != nil {
+		return r.Metrics.Healthcheck(name, fn, labels...)
+	}
+	return metrics.NilHealthcheck{}
+}
@@ -0,0 +1,256 @@
+// collectd implements a collectd metrics exporter.
+package collectd
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"collectd.org/api"
+	"collectd.org/network"
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/fqdn"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+	"github.com/exoscale/go-reporter/v2/internal/naming"
+)
+
+// Percentiles reported for histograms and timers.
+var percentiles = []float64{0.5, 0.75, 0.95, 0.98, 0.99, 0.999}
+
+// Exporter represents a metrics exporter to a collectd server.
+type Exporter struct {
+	registry metrics.Registry
+	client   *network.Client
+	hostname string
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new collectd metrics exporter based on provided configuration, periodically sending the metrics of
+// the go-metrics registry to the collectd server.
+//
+// For histograms, meters and timers to be accepted by the collectd server, the following types must be appended to
+// its types.db file:
+//
+//	histogram count:COUNTER:0:U, max:GAUGE:U:U, mean:GAUGE:U:U, min:GAUGE:U:U, stddev:GAUGE:0:U, p50:GAUGE:U:U, p75:GAUGE:U:U, p95:GAUGE:U:U, p98:GAUGE:U:U, p99:GAUGE:U:U, p999:GAUGE:U:U
+//	meter     count:COUNTER:0:U, m1_rate:GAUGE:0:U, m5_rate:GAUGE:0:U, m15_rate:GAUGE:0:U, mean_rate:GAUGE:0:U
+//	timer     max:GAUGE:U:U, mean:GAUGE:U:U, min:GAUGE:U:U, stddev:GAUGE:0:U, p50:GAUGE:U:U, p75:GAUGE:U:U, p95:GAUGE:U:U, p98:GAUGE:U:U, p99:GAUGE:U:U, p999:GAUGE:U:U
+func New(config *Config, registry metrics.Registry) (*Exporter, error) {
+	var exporter Exporter
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	exporter.config = config
+	exporter.registry = registry
+
+	exporter.D = debug.New("reporter/metrics/collectd")
+	if config.Debug {
+		exporter.D.On()
+	}
+
+	exporter.hostname = config.Hostname
+	if exporter.hostname == "" {
+		var err error
+		if exporter.hostname, err = fqdn.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	exporter.Debug("enabling exporter",
+		"connect", config.Connect,
+		"hostname", exporter.hostname,
+		"flush_interval", config.FlushInterval)
+
+	return &exporter, nil
+}
+
+// Start starts the metrics exporter.
+func (e *Exporter) Start(ctx context.Context) error {
+	var err error
+
+	// The client uses UDP, there is no need to reconnect.
+	if e.client, err = network.Dial(e.config.Connect, network.ClientOptions{}); err != nil {
+		return err
+	}
+
+	e.t, _ = tomb.WithContext(ctx)
+	e.t.Go(e.flushLoop)
+
+	return nil
+}
+
+// Stop stops the metrics exporter.
+func (e *Exporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if e.t == nil {
+		return nil
+	}
+
+	e.t.Kill(nil)
+
+	return e.t.Wait()
+}
+
+// flushLoop periodically sends the go-metrics registry metrics to the collectd server. This method blocks the
+// caller until the exporter's tomb dies.
+func (e *Exporter) flushLoop() error {
+	tick := time.NewTicker(time.Duration(e.config.FlushInterval) * time.Second)
+	defer tick.Stop()
+
+	e.Debug("starting flush loop")
+
+	for {
+		select {
+		case <-tick.C:
+			e.flush()
+
+		case <-e.t.Dying():
+			e.Debug("terminating flush loop")
+			// Closing the client flushes its buffer: since metrics are sent over UDP, errors are not fatal.
+			if err := e.client.Close(); err != nil {
+				e.Error("unable to flush metrics", "err", err)
+			}
+			return nil
+		}
+	}
+}
+
+// flush sends the current go-metrics registry metrics to the collectd server.
+func (e *Exporter) flush() {
+	var (
+		ctx    = context.Background()
+		now    = time.Now()
+		failed int
+	)
+
+	for _, vl := range e.valueLists(now) {
+		if err := e.client.Write(ctx, vl); err != nil {
+			failed++
+		}
+	}
+
+	// The client buffers the value lists until its buffer is full
+	if err := e.client.Flush(); err != nil {
+		e.Error("unable to flush metrics", "err", err)
+	}
+
+	if failed > 0 {
+		e.Error("unable to send some metrics", "failed", failed)
+	}
+}
+
+// valueLists returns the collectd value lists of the go-metrics registry metrics.
+func (e *Exporter) valueLists(now time.Time) []*api.ValueList {
+	var vls []*api.ValueList
+
+	e.registry.Each(func(name string, i interface{}) {
+		// Any error parsing an exclusion pattern is silently ignored.
+		for _, pattern := range e.config.Exclude {
+			if matched, _ := path.Match(pattern, name); matched {
+				return
+			}
+		}
+
+		var (
+			identifierType string
+			values         []api.Value
+		)
+
+		switch metric := i.(type) {
+		case metrics.Gauge:
+			identifierType = "gauge"
+			values = []api.Value{api.Gauge(metric.Value())}
+
+		case metrics.GaugeFloat64:
+			identifierType = "gauge"
+			values = []api.Value{api.Gauge(metric.Value())}
+
+		case metrics.Counter:
+			identifierType = "counter"
+			values = []api.Value{api.Counter(metric.Count())}
+
+		case metrics.Meter:
+			snapshot := metric.Snapshot()
+			identifierType = "meter"
+			values = []api.Value{
+				api.Counter(snapshot.Count()),
+				api.Gauge(snapshot.Rate1()),
+				api.Gauge(snapshot.Rate5()),
+				api.Gauge(snapshot.Rate15()),
+				api.Gauge(snapshot.RateMean()),
+			}
+
+		case metrics.Histogram:
+			snapshot := metric.Snapshot()
+			identifierType = "histogram"
+			values = append([]api.Value{
+				api.Counter(snapshot.Count()),
+				api.Gauge(snapshot.Max()),
+				api.Gauge(snapshot.Mean()),
+				api.Gauge(snapshot.Min()),
+				api.Gauge(snapshot.StdDev()),
+			}, gauges(snapshot.Percentiles(percentiles))...)
+
+		case metrics.Timer:
+			snapshot := metric.Snapshot()
+			identifierType = "timer"
+			values = append([]api.Value{
+				api.Gauge(snapshot.Max()),
+				api.Gauge(snapshot.Mean()),
+				api.Gauge(snapshot.Min()),
+				api.Gauge(snapshot.StdDev()),
+			}, gauges(snapshot.Percentiles(percentiles))...)
+
+		default:
+			return
+		}
+
+		plugin, pluginInstance := pluginName(name)
+		vls = append(vls, &api.ValueList{
+			Identifier: api.Identifier{
+				Host:           e.hostname,
+				Plugin:         plugin,
+				PluginInstance: pluginInstance,
+				Type:           identifierType,
+			},
+			Time:     now,
+			Interval: time.Duration(e.config.FlushInterval) * time.Second,
+			Values:   values,
+		})
+	})
+
+	return vls
+}
+
+// pluginName returns the collectd plugin and plugin instance of a metric: the plugin instance is either the label
+// values (sorted by key and joined with "-") of a labeled metric, or the last component of the metric name.
+// Characters invalid in collectd identifiers are replaced with underscores.
+func pluginName(name string) (string, string) {
+	if name, l := labels.Decode(name); l != nil {
+		return naming.Collectd(name), naming.CollectdInstance(strings.Join(l.Values(), "-"))
+	}
+
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return naming.Collectd(name[:i]), naming.CollectdInstance(name[i+1:])
+	}
+
+	return naming.Collectd(name), ""
+}
+
+func gauges(values []float64) []api.Value {
+	gauges := make([]api.Value, len(values))
+	for i, v := range values {
+		gauges[i] = api.Gauge(v)
+	}
+
+	return gauges
+}
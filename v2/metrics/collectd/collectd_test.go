@@ -0,0 +1,102 @@
+package collectd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"collectd.org/api"
+	"collectd.org/network"
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var (
+		testConfig = &Config{Hostname: "test"}
+		metrics    = gometrics.NewRegistry()
+	)
+
+	exporter, err := New(testConfig, metrics)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	require.Equal(t, metrics, exporter.registry)
+	require.Equal(t, "test", exporter.hostname)
+	require.Equal(t, testConfig, exporter.config)
+}
+
+func TestExporter_Start(t *testing.T) {
+	sock, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer sock.Close()
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test.counter", registry).Inc(42)
+	gometrics.NewRegisteredGauge("test.excluded", registry).Update(1)
+
+	exporter, err := New(&Config{
+		Connect:       sock.LocalAddr().String(),
+		FlushInterval: 1,
+		Hostname:      "test",
+		Exclude:       []string{"*.excluded"},
+	}, registry)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	require.NoError(t, sock.SetReadDeadline(time.Now().Add(3*time.Second)))
+	buf := make([]byte, network.DefaultBufferSize)
+	n, err := sock.Read(buf)
+	require.NoError(t, err)
+
+	vls, err := network.Parse(buf[:n], network.ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, vls, 1)
+	require.Equal(t, api.Identifier{Host: "test", Plugin: "test", PluginInstance: "counter", Type: "counter"},
+		vls[0].Identifier)
+	require.Equal(t, []api.Value{api.Counter(42)}, vls[0].Values)
+}
+
+func TestExporter_Stop(t *testing.T) {
+	var testCtx = context.Background()
+
+	exporter, err := New(&Config{Hostname: "test"}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Stop(testCtx), "stopping a non-started exporter should be a no-op")
+
+	require.NoError(t, exporter.Start(testCtx))
+	require.NotNil(t, exporter.t)
+	require.True(t, exporter.t.Alive())
+
+	require.Eventually(t,
+		func() bool { return exporter.Stop(testCtx) == nil },
+		time.Second*3,
+		500*time.Millisecond,
+	)
+}
+
+func TestExporter_valueLists(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredGaugeFloat64(`test.gauge{code="200",method="GET"}`, registry).Update(1.5)
+	histogram := gometrics.NewRegisteredHistogram("test.histogram", registry, gometrics.NewUniformSample(10))
+	histogram.Update(10)
+	gometrics.NewRegisteredTimer("test.timer", registry).Update(time.Second)
+	gometrics.NewRegisteredMeter("test.meter", registry).Mark(1)
+
+	exporter, err := New(&Config{Hostname: "test"}, registry)
+	require.NoError(t, err)
+
+	identifiers := make(map[api.Identifier]int)
+	for _, vl := range exporter.valueLists(time.Now()) {
+		identifiers[vl.Identifier] = len(vl.Values)
+	}
+	require.Equal(t, map[api.Identifier]int{
+		{Host: "test", Plugin: "test.gauge", PluginInstance: "200-GET", Type: "gauge"}: 1,
+		{Host: "test", Plugin: "test", PluginInstance: "histogram", Type: "histogram"}: 11,
+		{Host: "test", Plugin: "test", PluginInstance: "timer", Type: "timer"}:         10,
+		{Host: "test", Plugin: "test", PluginInstance: "meter", Type: "meter"}:         5,
+	}, identifiers)
+}
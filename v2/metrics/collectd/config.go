@@ -0,0 +1,46 @@
+package collectd
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultConnect          = "127.0.0.1:25826"
+	defaultFlushIntervalSec = 10
+)
+
+// Config represents a collectd metrics export configuration.
+type Config struct {
+	// Connect represents the network address ("host:port") of the collectd server to send the metrics to using
+	// the collectd binary network protocol over UDP. If not specified, defaults to "127.0.0.1:25826".
+	Connect string `yaml:"connect"`
+
+	// FlushInterval represents the time interval in seconds at which the metrics are sent to the collectd server.
+	// If not specified, defaults to 10 seconds.
+	FlushInterval int `yaml:"flush_interval"`
+
+	// Hostname represents the host name of the sent metrics. If not specified, defaults to the host FQDN.
+	Hostname string `yaml:"hostname"`
+
+	// Exclude represents a list of metric names patterns (shell globbing) to exclude from the export.
+	Exclude []string `yaml:"exclude"`
+
+	// Debug represents a flags indicating whether to enable internal exporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	if c.Connect == "" {
+		c.Connect = defaultConnect
+	}
+
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushIntervalSec
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Connect, is.DialString),
+	)
+}
@@ -0,0 +1,16 @@
+package collectd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testConfig := new(Config)
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultConnect, testConfig.Connect, "should have been set to default value")
+	require.Equal(t, defaultFlushIntervalSec, testConfig.FlushInterval, "should have been set to default value")
+
+	require.Error(t, (&Config{Connect: "nope"}).validate())
+}
@@ -1,25 +1,336 @@
 package metrics
 
 import (
+	"errors"
+	"path"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+	"github.com/exoscale/go-reporter/v2/internal/samples"
+	"github.com/exoscale/go-reporter/v2/metrics/collectd"
+	"github.com/exoscale/go-reporter/v2/metrics/expvar"
+	"github.com/exoscale/go-reporter/v2/metrics/file"
+	"github.com/exoscale/go-reporter/v2/metrics/graphite"
+	"github.com/exoscale/go-reporter/v2/metrics/influx"
+	"github.com/exoscale/go-reporter/v2/metrics/otlp"
 	"github.com/exoscale/go-reporter/v2/metrics/prometheus"
+	"github.com/exoscale/go-reporter/v2/metrics/pushgateway"
+	"github.com/exoscale/go-reporter/v2/metrics/statsd"
 )
 
 const (
 	defaultFlushIntervalSec = 5
+	defaultMaxLabelSets     = 1000
+	defaultSampleSize       = 1028
+	defaultSampleAlpha      = 0.015
+	defaultSampleWindowSec  = 60
+	defaultSamplePrecision  = 3
 )
 
+// LinearBucketsConfig represents a linear buckets layout configuration.
+type LinearBucketsConfig struct {
+	// Start represents the upper bound of the lowest bucket.
+	Start float64 `yaml:"start"`
+
+	// Width represents the difference between the upper bounds of two consecutive buckets.
+	Width float64 `yaml:"width"`
+
+	// Count represents the number of buckets.
+	Count int `yaml:"count"`
+}
+
+// ExponentialBucketsConfig represents an exponential buckets layout configuration.
+type ExponentialBucketsConfig struct {
+	// Start represents the upper bound of the lowest bucket.
+	Start float64 `yaml:"start"`
+
+	// Factor represents the ratio between the upper bounds of two consecutive buckets.
+	Factor float64 `yaml:"factor"`
+
+	// Count represents the number of buckets.
+	Count int `yaml:"count"`
+}
+
+// BucketsConfig represents the buckets layout of the histograms and timers whose name matches a pattern. Exactly one
+// of Bounds, Linear and Exponential must be specified. Timers values being durations in nanoseconds, so are the
+// upper bounds of their buckets.
+type BucketsConfig struct {
+	// Pattern represents a metric name pattern (shell globbing), matched against the names of the histograms and
+	// timers without their labels.
+	Pattern string `yaml:"pattern"`
+
+	// Bounds represents the explicit buckets upper bounds, sorted in increasing order.
+	Bounds []float64 `yaml:"bounds"`
+
+	// Linear represents a linear buckets layout.
+	Linear *LinearBucketsConfig `yaml:"linear"`
+
+	// Exponential represents an exponential buckets layout.
+	Exponential *ExponentialBucketsConfig `yaml:"exponential"`
+}
+
+// bounds returns the buckets upper bounds of the layout.
+func (c *BucketsConfig) bounds() []float64 {
+	switch {
+	case c.Linear != nil:
+		return buckets.Linear(c.Linear.Start, c.Linear.Width, c.Linear.Count)
+
+	case c.Exponential != nil:
+		return buckets.Exponential(c.Exponential.Start, c.Exponential.Factor, c.Exponential.Count)
+	}
+
+	return c.Bounds
+}
+
+func (c *BucketsConfig) validate() error {
+	layouts := 0
+	if len(c.Bounds) > 0 {
+		layouts++
+	}
+	if c.Linear != nil {
+		layouts++
+	}
+	if c.Exponential != nil {
+		layouts++
+	}
+	if layouts != 1 {
+		return errors.New("exactly one of bounds, linear and exponential must be specified")
+	}
+
+	if c.Linear != nil {
+		if err := validation.ValidateStruct(c.Linear,
+			validation.Field(&c.Linear.Width, validation.Required, validation.Min(0.0)),
+			validation.Field(&c.Linear.Count, validation.Required, validation.Min(1)),
+		); err != nil {
+			return err
+		}
+	}
+
+	if c.Exponential != nil {
+		if err := validation.ValidateStruct(c.Exponential,
+			validation.Field(&c.Exponential.Start, validation.Required, validation.Min(0.0).Exclusive()),
+			validation.Field(&c.Exponential.Factor, validation.Required, validation.Min(1.0).Exclusive()),
+			validation.Field(&c.Exponential.Count, validation.Required, validation.Min(1)),
+		); err != nil {
+			return err
+		}
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Pattern,
+			validation.Required,
+			validation.By(func(v interface{}) error {
+				_, err := path.Match(v.(string), "")
+				return err
+			})),
+		validation.Field(&c.Bounds,
+			validation.By(func(v interface{}) error {
+				return buckets.Validate(c.bounds())
+			})),
+	)
+}
+
+// SampleConfig represents the sample type of the histograms and timers whose name matches a pattern, i.e. the way
+// their values are retained to compute statistics (e.g. percentiles).
+type SampleConfig struct {
+	// Pattern represents the pattern (shell globbing) matched against the histograms and timers names, excluding
+	// their labels.
+	Pattern string `yaml:"pattern"`
+
+	// Type represents the sample type (uniform|expdecay|window|hdr):
+	//   - "uniform": a uniform random sample of Size values (Vitter's algorithm R)
+	//   - "expdecay": an exponentially-decaying random sample of Size values, biased towards the recent ones by
+	//     Alpha
+	//   - "window": the last (at most Size) values recorded during a sliding time window of Window seconds
+	//   - "hdr": a high dynamic range histogram counting all the values, whose percentiles have Precision
+	//     significant decimal digits
+	Type string `yaml:"type"`
+
+	// Size represents the maximum number of values of "uniform", "expdecay" and "window" samples. If not specified,
+	// defaults to 1028.
+	Size int `yaml:"size"`
+
+	// Alpha represents the decay factor of "expdecay" samples. If not specified, defaults to 0.015.
+	Alpha float64 `yaml:"alpha"`
+
+	// Window represents the time window in seconds of "window" samples. If not specified, defaults to 60 seconds.
+	Window int `yaml:"window"`
+
+	// Precision represents the number of significant decimal digits (1 to 5) of the percentiles of "hdr" samples.
+	// If not specified, defaults to 3.
+	Precision int `yaml:"precision"`
+}
+
+// sample returns a new sample of the configured type.
+func (c *SampleConfig) sample() metrics.Sample {
+	switch c.Type {
+	case "uniform":
+		return metrics.NewUniformSample(c.Size)
+	case "window":
+		return samples.NewWindow(time.Duration(c.Window)*time.Second, c.Size)
+	case "hdr":
+		return samples.NewHDR(c.Precision)
+	default:
+		return metrics.NewExpDecaySample(c.Size, c.Alpha)
+	}
+}
+
+func (c *SampleConfig) validate() error {
+	if c.Size <= 0 {
+		c.Size = defaultSampleSize
+	}
+
+	if c.Alpha <= 0 {
+		c.Alpha = defaultSampleAlpha
+	}
+
+	if c.Window <= 0 {
+		c.Window = defaultSampleWindowSec
+	}
+
+	if c.Precision <= 0 {
+		c.Precision = defaultSamplePrecision
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Pattern,
+			validation.Required,
+			validation.By(func(v interface{}) error {
+				_, err := path.Match(v.(string), "")
+				return err
+			})),
+		validation.Field(&c.Type,
+			validation.Required,
+			validation.In(
+				"uniform",
+				"expdecay",
+				"window",
+				"hdr",
+			)),
+		validation.Field(&c.Precision, validation.Max(5)),
+	)
+}
+
+// RuntimeMetricsConfig represents the Go runtime and process metrics groups to collect, each one being enabled
+// independently. The metrics are updated at the reporter's flush interval.
+type RuntimeMetricsConfig struct {
+	// MemStats represents a flag indicating whether to collect the runtime.MemStats statistics ("go.runtime.MemStats.*"
+	// metrics). Reading them briefly stops the world.
+	MemStats bool `yaml:"memstats"`
+
+	// Goroutines represents a flag indicating whether to collect the number of goroutines and OS threads
+	// ("go.runtime.goroutines" and "go.runtime.threads" metrics).
+	Goroutines bool `yaml:"goroutines"`
+
+	// GC represents a flag indicating whether to collect the number of garbage collections and the distribution of
+	// their pauses in nanoseconds ("go.gc.count" and "go.gc.pause" metrics).
+	GC bool `yaml:"gc"`
+
+	// Scheduler represents a flag indicating whether to collect quantiles of the scheduler latency in seconds, i.e.
+	// the time goroutines spent runnable before running, since the previous flush ("go.sched.latency.*" metrics).
+	// This requires Go 1.17 or later.
+	Scheduler bool `yaml:"scheduler"`
+
+	// Process represents a flag indicating whether to collect the process metrics read from /proc/self: resident
+	// memory in bytes, open file descriptors and their limit, CPU time in seconds, start time in seconds since the
+	// Unix epoch and context switches ("process.*" metrics). This is only supported on Linux.
+	Process bool `yaml:"process"`
+}
+
 // Config represents a metrics reporter configuration.
 type Config struct {
 	// Prometheus represents a Prometheus metrics exporter configuration.
 	Prometheus *prometheus.Config `yaml:"prometheus"`
 
+	// Pushgateway represents a Prometheus Pushgateway metrics exporter configuration. The pushed metrics are the
+	// ones exposed by the Prometheus metrics exporter (if not configured, the default Prometheus exporter
+	// configuration is used).
+	Pushgateway *pushgateway.Config `yaml:"pushgateway"`
+
+	// Collectd represents a collectd metrics exporter configuration.
+	Collectd *collectd.Config `yaml:"collectd"`
+
+	// Expvar represents an expvar metrics exporter configuration.
+	Expvar *expvar.Config `yaml:"expvar"`
+
+	// File represents a file metrics exporter configuration.
+	File *file.Config `yaml:"file"`
+
+	// Statsd represents a StatsD/DogStatsD metrics exporter configuration.
+	Statsd *statsd.Config `yaml:"statsd"`
+
+	// Graphite represents a Graphite metrics exporter configuration.
+	Graphite *graphite.Config `yaml:"graphite"`
+
+	// Influx represents an InfluxDB metrics exporter configuration.
+	Influx *influx.Config `yaml:"influx"`
+
+	// OTLP represents an OTLP/HTTP metrics exporter configuration.
+	OTLP *otlp.Config `yaml:"otlp"`
+
+	// Prefix represents a prefix prepended to the names of the metrics created using the reporter's helper methods
+	// (e.g. Counter(), Timer()...).
+	Prefix string `yaml:"prefix"`
+
+	// PackagePrefix represents the import path of the project (e.g. "github.com/exoscale/project"). If specified,
+	// the names of the metrics created using the reporter's helper methods are automatically prefixed with the path
+	// of the calling package relative to it (e.g. "api.handlers." for metrics created in package
+	// "github.com/exoscale/project/api/handlers"), unless they start with a ".".
+	PackagePrefix string `yaml:"package_prefix"`
+
+	// MaxLabelSets represents the maximum number of distinct label sets per labeled metric name. Once reached, new
+	// label sets are folded into a single overflow series whose label values are all "_overflow". If not specified,
+	// defaults to 1000.
+	MaxLabelSets int `yaml:"max_label_sets"`
+
+	// MaxSeries represents the maximum number of distinct series (metric names and label sets) created using the
+	// reporter's helper methods. Once reached, new series are handled according to SeriesOverflow and counted by the
+	// "metrics.series.overflow" counter. Expired series (see TTL) free room for new ones. If not specified, the number
+	// of series is not limited.
+	MaxSeries int `yaml:"max_series"`
+
+	// SeriesOverflow represents the policy applied to the series exceeding MaxSeries: either "reject", the helper
+	// methods returning metrics discarding their updates, or "alias", new labeled series being folded into the
+	// overflow series of their name whose label values are all "_overflow" (unlabeled series are still rejected).
+	// If not specified, defaults to "reject".
+	SeriesOverflow string `yaml:"series_overflow"`
+
+	// Buckets represents the buckets layouts of the histograms and timers created using the reporter's helper
+	// methods, by metric name pattern (the first matching pattern applies). Such histograms and timers additionally
+	// count their values in buckets, which are exported as native histograms or bucket series by the exporters
+	// supporting them (Prometheus, OTLP, Graphite, InfluxDB and StatsD).
+	Buckets []*BucketsConfig `yaml:"buckets"`
+
+	// Samples represents the sample types of the histograms and timers created using the reporter's helper methods,
+	// by metric name pattern (the first matching pattern applies). If no pattern matches, an exponentially-decaying
+	// sample of 1028 values with an alpha of 0.015 is used.
+	Samples []*SampleConfig `yaml:"samples"`
+
+	// TTL represents the time in seconds after which the metrics created using the reporter's helper methods are
+	// unregistered if not updated, e.g. for metrics having dynamic names or labels (per tenant, per peer...). A metric
+	// is considered updated when requested using a helper method or when its value changes. Expired metrics are
+	// checked at the flush interval and counted by the "metrics.expired" counter. If not specified, metrics never
+	// expire.
+	TTL int `yaml:"ttl"`
+
+	// Pinned represents a list of metric names patterns (shell globbing, matched against the names without labels)
+	// which never expire.
+	Pinned []string `yaml:"pinned"`
+
 	// FlushInterval represents the time interval in seconds at which to flush metrics to the internal registry.
 	FlushInterval int `yaml:"flush_interval"`
 
 	// WithRuntimeMetrics represents a flag indicating whether Go runtime metrics should be included to the registered
-	// metrics.
+	// metrics. It is equivalent to enabling RuntimeMetrics.MemStats.
 	WithRuntimeMetrics bool `yaml:"runtime_metrics"`
 
+	// RuntimeMetrics represents the Go runtime and process metrics groups to collect.
+	RuntimeMetrics *RuntimeMetricsConfig `yaml:"runtime"`
+
 	// Debug represents a flags indicating whether to enable internal reporter activity logging.
 	// This is mainly for debug purposes.
 	Debug bool `yaml:"debug"`
@@ -30,5 +341,46 @@ func (c *Config) validate() error {
 		c.FlushInterval = defaultFlushIntervalSec
 	}
 
+	if c.MaxLabelSets <= 0 {
+		c.MaxLabelSets = defaultMaxLabelSets
+	}
+
+	if c.SeriesOverflow == "" {
+		c.SeriesOverflow = SeriesOverflowReject
+	}
+
+	if err := validation.ValidateStruct(c,
+		validation.Field(&c.MaxSeries, validation.Min(0)),
+		validation.Field(&c.TTL, validation.Min(0)),
+		validation.Field(&c.SeriesOverflow, validation.In(SeriesOverflowReject, SeriesOverflowAlias)),
+	); err != nil {
+		return err
+	}
+
+	for _, b := range c.Buckets {
+		if err := b.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.WithRuntimeMetrics {
+		if c.RuntimeMetrics == nil {
+			c.RuntimeMetrics = new(RuntimeMetricsConfig)
+		}
+		c.RuntimeMetrics.MemStats = true
+	}
+
+	for _, s := range c.Samples {
+		if err := s.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range c.Pinned {
+		if _, err := path.Match(p, ""); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -12,3 +12,69 @@ func TestConfig_Validate(t *testing.T) {
 	require.Equal(t, defaultFlushIntervalSec, testConfig.FlushInterval,
 		"should have been set to default value")
 }
+
+func TestConfig_Validate_Buckets(t *testing.T) {
+	testConfig := &Config{Buckets: []*BucketsConfig{
+		{Pattern: "*.latency", Bounds: []float64{0.1, 0.5, 1}},
+		{Pattern: "*.size", Linear: &LinearBucketsConfig{Start: 0, Width: 10, Count: 5}},
+		{Pattern: "*", Exponential: &ExponentialBucketsConfig{Start: 1, Factor: 2, Count: 10}},
+	}}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, []float64{0, 10, 20, 30, 40}, testConfig.Buckets[1].bounds())
+	require.Equal(t, float64(512), testConfig.Buckets[2].bounds()[9])
+
+	for _, invalid := range []*BucketsConfig{
+		{Bounds: []float64{1}},
+		{Pattern: "[", Bounds: []float64{1}},
+		{Pattern: "*"},
+		{Pattern: "*", Bounds: []float64{1, 0.5}},
+		{Pattern: "*", Bounds: []float64{1}, Linear: &LinearBucketsConfig{Width: 1, Count: 1}},
+		{Pattern: "*", Linear: &LinearBucketsConfig{Width: -1, Count: 1}},
+		{Pattern: "*", Exponential: &ExponentialBucketsConfig{Start: 1, Factor: 1, Count: 1}},
+		{Pattern: "*", Exponential: &ExponentialBucketsConfig{Start: 0, Factor: 2, Count: 1}},
+	} {
+		require.Error(t, (&Config{Buckets: []*BucketsConfig{invalid}}).validate(), "%+v", invalid)
+	}
+}
+
+func TestConfig_Validate_Samples(t *testing.T) {
+	testConfig := &Config{Samples: []*SampleConfig{
+		{Pattern: "*.latency", Type: "hdr"},
+		{Pattern: "*", Type: "window", Window: 10},
+	}}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultSamplePrecision, testConfig.Samples[0].Precision, "should have been set to default value")
+	require.Equal(t, defaultSampleSize, testConfig.Samples[1].Size, "should have been set to default value")
+	require.Equal(t, 10, testConfig.Samples[1].Window)
+
+	for _, invalid := range []*SampleConfig{
+		{Type: "uniform"},
+		{Pattern: "[", Type: "uniform"},
+		{Pattern: "*"},
+		{Pattern: "*", Type: "reservoir"},
+		{Pattern: "*", Type: "hdr", Precision: 6},
+	} {
+		require.Error(t, (&Config{Samples: []*SampleConfig{invalid}}).validate(), "%+v", invalid)
+	}
+}
+
+func TestConfig_Validate_RuntimeMetrics(t *testing.T) {
+	testConfig := &Config{WithRuntimeMetrics: true}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, &RuntimeMetricsConfig{MemStats: true}, testConfig.RuntimeMetrics)
+}
+
+func TestConfig_Validate_Pinned(t *testing.T) {
+	require.NoError(t, (&Config{TTL: 60, Pinned: []string{"app.*"}}).validate())
+	require.Error(t, (&Config{TTL: 60, Pinned: []string{"["}}).validate())
+	require.Error(t, (&Config{TTL: -1}).validate())
+}
+
+func TestConfig_Validate_Series(t *testing.T) {
+	testConfig := &Config{MaxSeries: 100}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, SeriesOverflowReject, testConfig.SeriesOverflow)
+	require.NoError(t, (&Config{MaxSeries: 100, SeriesOverflow: SeriesOverflowAlias}).validate())
+	require.Error(t, (&Config{MaxSeries: 100, SeriesOverflow: "drop"}).validate())
+	require.Error(t, (&Config{MaxSeries: -1}).validate())
+}
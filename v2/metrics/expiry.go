@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+)
+
+// expiredMetricName represents the name of the counter accounting for the metrics unregistered by the expirer.
+const expiredMetricName = "metrics.expired"
+
+// metricState represents the observable state of a metric, used to detect updates.
+type metricState struct {
+	count int64
+	value float64
+}
+
+// trackedMetric represents a metric tracked by the expirer.
+type trackedMetric struct {
+	state   metricState
+	updated time.Time
+	touched bool // Whether the metric has been requested using a helper method since the previous sweep
+}
+
+// expirer unregisters the metrics created using the reporter's helper methods which haven't been updated for a
+// given period of time. Since go-metrics metrics don't record their update time, a metric is considered updated when
+// it is requested using a helper method, or when its state (count, value or sum) changes between two sweeps: gauges
+// updated with an unchanged value are thus only kept if requested using a helper method.
+type expirer struct {
+	mu sync.Mutex
+
+	ttl      time.Duration
+	pinned   []string
+	tracked  map[string]*trackedMetric
+	registry metrics.Registry
+	limiter  *labelLimiter
+	namer    *namer
+	expired  metrics.Counter
+	now      func() time.Time
+
+	d *debug.D
+}
+
+func newExpirer(ttl time.Duration, pinned []string, registry metrics.Registry, limiter *labelLimiter, namer *namer,
+	d *debug.D) *expirer {
+	return &expirer{
+		ttl:      ttl,
+		pinned:   pinned,
+		tracked:  make(map[string]*trackedMetric),
+		registry: registry,
+		limiter:  limiter,
+		namer:    namer,
+		expired:  metrics.NewCounter(),
+		now:      time.Now,
+		d:        d,
+	}
+}
+
+// touch marks the metric registered under the specified name as updated, tracking it unless its name matches a
+// pinned pattern. It is a no-op on a nil expirer.
+func (e *expirer) touch(name string) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if t, ok := e.tracked[name]; ok {
+		t.touched = true
+		return
+	}
+
+	if e.isPinned(name) {
+		return
+	}
+
+	e.tracked[name] = &trackedMetric{updated: e.now(), touched: true}
+}
+
+// sweep unregisters the tracked metrics which haven't been updated for the expirer's TTL.
+func (e *expirer) sweep() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+
+	for name, t := range e.tracked {
+		metric := e.registry.Get(name)
+		if metric == nil {
+			// Unregistered by the user
+			delete(e.tracked, name)
+			continue
+		}
+
+		state, ok := stateOf(metric)
+		if !ok || t.touched || state != t.state {
+			t.state, t.updated, t.touched = state, now, false
+			continue
+		}
+
+		if now.Sub(t.updated) < e.ttl {
+			continue
+		}
+
+		e.d.Debug("unregistering expired metric", "metric", name, "updated", t.updated)
+		e.registry.Unregister(name)
+		e.limiter.forget(name)
+		e.namer.forget(name)
+		delete(e.tracked, name)
+		e.expired.Inc(1)
+	}
+}
+
+// isPinned returns true if the name (without labels) of a metric matches a pinned pattern.
+func (e *expirer) isPinned(name string) bool {
+	name, _ = labels.Decode(name)
+
+	for _, pattern := range e.pinned {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stateOf returns the observable state of a metric, or false if the metric type doesn't support expiry.
+func stateOf(metric interface{}) (metricState, bool) {
+	switch m := metric.(type) {
+	case metrics.Counter:
+		return metricState{count: m.Count()}, true
+	case metrics.Gauge:
+		return metricState{count: m.Value()}, true
+	case metrics.GaugeFloat64:
+		return metricState{value: m.Value()}, true
+	case metrics.Meter:
+		return metricState{count: m.Count()}, true
+	case metrics.Histogram:
+		return metricState{count: m.Count(), value: float64(m.Sum())}, true
+	case metrics.Timer:
+		return metricState{count: m.Count(), value: float64(m.Sum())}, true
+	}
+
+	return metricState{}, false
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReporter_Expiry(t *testing.T) {
+	reporter, err := New(&Config{
+		TTL:          60,
+		Pinned:       []string{"*.pinned"},
+		MaxLabelSets: 2,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	reporter.expirer.now = func() time.Time { return now }
+	sweep := func(after time.Duration) {
+		now = now.Add(after)
+		reporter.expirer.sweep()
+	}
+
+	counter := reporter.Counter(".requests", "peer", "a")
+	reporter.Counter(".requests", "peer", "b").Inc(1)
+	reporter.Gauge(".app.pinned").Update(1)
+	reporter.Histogram(".latency").Update(1)
+	sweep(0)
+
+	// Metrics updated or requested using a helper method are kept
+	counter.Inc(1)
+	reporter.Histogram(".latency")
+	sweep(time.Minute)
+	require.NotNil(t, reporter.registry.Get(`requests{peer="a"}`))
+	require.NotNil(t, reporter.registry.Get("latency"))
+	require.Nil(t, reporter.registry.Get(`requests{peer="b"}`))
+
+	sweep(time.Minute)
+	require.Nil(t, reporter.registry.Get(`requests{peer="a"}`))
+	require.Nil(t, reporter.registry.Get("latency"))
+	require.NotNil(t, reporter.registry.Get("app.pinned"), "pinned metrics should never expire")
+	require.Equal(t, int64(3), reporter.registry.Get(expiredMetricName).(gometrics.Counter).Count())
+
+	// Expired label sets no longer count towards the cardinality limit
+	reporter.Counter(".requests", "peer", "c").Inc(1)
+	reporter.Counter(".requests", "peer", "d").Inc(1)
+	require.NotNil(t, reporter.registry.Get(`requests{peer="d"}`))
+	require.Zero(t, reporter.labelLimiter.overflow.Count())
+
+	// Metrics unregistered by the user are no longer tracked
+	reporter.registry.Unregister(`requests{peer="c"}`)
+	sweep(0)
+	require.NotContains(t, reporter.expirer.tracked, `requests{peer="c"}`)
+}
+
+func TestReporter_Expiry_Disabled(t *testing.T) {
+	reporter, err := New(&Config{})
+	require.NoError(t, err)
+	require.Nil(t, reporter.expirer)
+	require.NotPanics(t, func() { reporter.Counter(".requests").Inc(1) })
+	require.Nil(t, reporter.registry.Get(expiredMetricName))
+}
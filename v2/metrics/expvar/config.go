@@ -0,0 +1,23 @@
+package expvar
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+// Config represents an expvar metrics export configuration.
+type Config struct {
+	// Listen represents a net.Dial compatible string indicating the network address to bind the expvar HTTP
+	// endpoint server.
+	Listen string `yaml:"listen"`
+
+	// Debug represents a flags indicating whether to enable internal exporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Listen, validation.Required, is.DialString),
+	)
+}
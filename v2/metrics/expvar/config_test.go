@@ -0,0 +1,13 @@
+package expvar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	require.Error(t, new(Config).validate())
+	require.Error(t, (&Config{Listen: "nope"}).validate())
+	require.NoError(t, (&Config{Listen: "127.0.0.1:8123"}).validate())
+}
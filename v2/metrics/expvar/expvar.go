@@ -0,0 +1,140 @@
+// expvar implements an expvar-compatible metrics exporter.
+package expvar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/rcrowley/go-metrics/exp"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+)
+
+// healthzFailStatus represents the HTTP status code returned by the healthchecks endpoint if a healthcheck fails.
+const healthzFailStatus = 542
+
+// Exporter represents a metrics exporter serving the go-metrics registry metrics over HTTP in the expvar format.
+type Exporter struct {
+	registry metrics.Registry
+	listener net.Listener
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new expvar metrics exporter based on provided configuration.
+func New(config *Config, registry metrics.Registry) (*Exporter, error) {
+	var exporter Exporter
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	exporter.config = config
+	exporter.registry = registry
+
+	exporter.D = debug.New("reporter/metrics/expvar")
+	if config.Debug {
+		exporter.D.On()
+	}
+
+	exporter.Debug("enabling exporter", "listen", config.Listen)
+
+	return &exporter, nil
+}
+
+// HTTPHandler returns an http.Handler serving the go-metrics registry metrics at the root path, and the status of
+// its healthchecks at the "/healthz" path.
+func (e *Exporter) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", exp.ExpHandler(e.registry))
+	mux.HandleFunc("/healthz", e.healthz)
+
+	return mux
+}
+
+// Start starts the metrics exporter. The HTTP endpoint network address is bound before returning: binding errors are
+// returned to the caller.
+func (e *Exporter) Start(ctx context.Context) error {
+	var err error
+
+	if e.listener, err = net.Listen("tcp", e.config.Listen); err != nil {
+		return fmt.Errorf("unable to bind expvar endpoint server: %s", err)
+	}
+
+	e.t, _ = tomb.WithContext(ctx)
+	e.t.Go(func() error {
+		return e.serveHTTP(e.listener, &http.Server{Handler: e.HTTPHandler()})
+	})
+
+	return nil
+}
+
+// Stop stops the metrics exporter.
+func (e *Exporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if e.t == nil {
+		return nil
+	}
+
+	e.t.Kill(nil)
+
+	return e.t.Wait()
+}
+
+// serveHTTP runs an HTTP server on the listener l to serve the expvar endpoint. This method blocks the caller until
+// the exporter's tomb dies.
+func (e *Exporter) serveHTTP(l net.Listener, server *http.Server) error {
+	e.Debug("starting endpoint server", "address", l.Addr())
+
+	e.t.Go(func() error {
+		if err := server.Serve(l); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+
+	_ = <-e.t.Dying()
+	e.Debug("terminating endpoint server")
+	return server.Shutdown(context.Background())
+}
+
+// healthz serves the status of the go-metrics registry healthchecks as a JSON object: its "status" key is either "ok"
+// or "fail", and its "details" key maps the healthchecks names to either "+ok" or their error prefixed with "!".
+func (e *Exporter) healthz(w http.ResponseWriter, _ *http.Request) {
+	details := struct {
+		Status  string            `json:"status"`
+		Details map[string]string `json:"details"`
+	}{
+		Status:  "ok",
+		Details: make(map[string]string),
+	}
+
+	e.registry.Each(func(name string, i interface{}) {
+		healthcheck, ok := i.(metrics.Healthcheck)
+		if !ok {
+			return
+		}
+
+		healthcheck.Check()
+		if err := healthcheck.Error(); err != nil {
+			details.Status = "fail"
+			details.Details[name] = "!" + err.Error()
+		} else {
+			details.Details[name] = "+ok"
+		}
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if details.Status == "fail" {
+		w.WriteHeader(healthzFailStatus)
+	}
+	_ = json.NewEncoder(w).Encode(details)
+}
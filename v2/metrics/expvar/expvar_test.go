@@ -0,0 +1,142 @@
+package expvar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var (
+		testConfig = &Config{Listen: "127.0.0.1:8123"}
+		metrics    = gometrics.NewRegistry()
+	)
+
+	exporter, err := New(testConfig, metrics)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	require.Equal(t, metrics, exporter.registry)
+	require.Equal(t, testConfig, exporter.config)
+}
+
+func TestExporter_Start(t *testing.T) {
+	exporter, err := New(&Config{Listen: testFreeAddr(t)}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	res, err := http.Get("http://" + exporter.listener.Addr().String())
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestExporter_Start_BindError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	exporter, err := New(&Config{Listen: listener.Addr().String()}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.Error(t, exporter.Start(context.Background()))
+	require.NoError(t, exporter.Stop(context.Background()))
+}
+
+func TestExporter_Stop(t *testing.T) {
+	var testCtx = context.Background()
+
+	exporter, err := New(&Config{Listen: testFreeAddr(t)}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(testCtx))
+	require.NotNil(t, exporter.t)
+	require.True(t, exporter.t.Alive())
+
+	require.Eventually(t,
+		func() bool { return exporter.Stop(testCtx) == nil },
+		time.Second*3,
+		500*time.Millisecond,
+	)
+
+	_, err = http.Get("http://" + exporter.listener.Addr().String())
+	require.Error(t, err, "endpoint server should have been shut down")
+}
+
+func TestExporter_HTTPHandler(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test", registry).Inc(42)
+
+	exporter, err := New(&Config{Listen: "127.0.0.1:8123"}, registry)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(exporter.HTTPHandler())
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var metrics struct {
+		Test int64 `json:"test"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&metrics))
+	require.Equal(t, int64(42), metrics.Test)
+}
+
+func TestExporter_healthz(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	require.NoError(t, registry.Register("ok", gometrics.NewHealthcheck(func(h gometrics.Healthcheck) {
+		h.Healthy()
+	})))
+
+	exporter, err := New(&Config{Listen: "127.0.0.1:8123"}, registry)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(exporter.HTTPHandler())
+	defer ts.Close()
+
+	var details struct {
+		Status  string            `json:"status"`
+		Details map[string]string `json:"details"`
+	}
+
+	res, err := ts.Client().Get(ts.URL + "/healthz")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&details))
+	res.Body.Close()
+	require.Equal(t, "ok", details.Status)
+	require.Equal(t, map[string]string{"ok": "+ok"}, details.Details)
+
+	require.NoError(t, registry.Register("fail", gometrics.NewHealthcheck(func(h gometrics.Healthcheck) {
+		h.Unhealthy(errors.New("oh noes!"))
+	})))
+
+	res, err = ts.Client().Get(ts.URL + "/healthz")
+	require.NoError(t, err)
+	require.Equal(t, healthzFailStatus, res.StatusCode)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&details))
+	res.Body.Close()
+	require.Equal(t, "fail", details.Status)
+	require.Equal(t, "!oh noes!", details.Details["fail"])
+}
+
+// testFreeAddr returns a local network address available for binding.
+func testFreeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	return l.Addr().String()
+}
@@ -0,0 +1,33 @@
+package file
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	defaultFlushIntervalSec = 10
+)
+
+// Config represents a file metrics export configuration.
+type Config struct {
+	// Path represents the filesystem path of the file to which the metrics are appended.
+	Path string `yaml:"path"`
+
+	// FlushInterval represents the time interval in seconds at which the metrics are written to the file. If not
+	// specified, defaults to 10 seconds.
+	FlushInterval int `yaml:"flush_interval"`
+
+	// Debug represents a flags indicating whether to enable internal exporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushIntervalSec
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Path, validation.Required),
+	)
+}
@@ -0,0 +1,15 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	require.Error(t, new(Config).validate())
+
+	testConfig := &Config{Path: "/tmp/metrics"}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultFlushIntervalSec, testConfig.FlushInterval, "should have been set to default value")
+}
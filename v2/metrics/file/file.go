@@ -0,0 +1,113 @@
+// file implements a file metrics exporter.
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+)
+
+// Exporter represents a metrics exporter periodically appending the go-metrics registry metrics to a file, as one-line
+// JSON objects.
+type Exporter struct {
+	registry metrics.Registry
+	file     *os.File
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new file metrics exporter based on provided configuration.
+func New(config *Config, registry metrics.Registry) (*Exporter, error) {
+	var exporter Exporter
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	exporter.config = config
+	exporter.registry = registry
+
+	exporter.D = debug.New("reporter/metrics/file")
+	if config.Debug {
+		exporter.D.On()
+	}
+
+	exporter.Debug("enabling exporter",
+		"path", config.Path,
+		"flush_interval", config.FlushInterval)
+
+	return &exporter, nil
+}
+
+// Start starts the metrics exporter.
+func (e *Exporter) Start(ctx context.Context) error {
+	var err error
+
+	if e.file, err = os.OpenFile(e.config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+		return err
+	}
+
+	e.t, _ = tomb.WithContext(ctx)
+	e.t.Go(e.flushLoop)
+
+	return nil
+}
+
+// Stop stops the metrics exporter.
+func (e *Exporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if e.t == nil {
+		return nil
+	}
+
+	e.t.Kill(nil)
+
+	return e.t.Wait()
+}
+
+// flushLoop periodically writes the go-metrics registry metrics to the file. This method blocks the caller until the
+// exporter's tomb dies.
+func (e *Exporter) flushLoop() error {
+	tick := time.NewTicker(time.Duration(e.config.FlushInterval) * time.Second)
+	defer tick.Stop()
+
+	e.Debug("starting flush loop")
+
+	for {
+		select {
+		case <-tick.C:
+			if err := writeJSON(e.registry, e.file); err != nil {
+				e.Error("unable to write metrics", "err", err)
+			}
+
+		case <-e.t.Dying():
+			e.Debug("terminating flush loop")
+			return e.file.Close()
+		}
+	}
+}
+
+// writeJSON writes the current go-metrics registry metrics to w as a one-line JSON object. Labeled metrics get
+// additional "name" and "tags" fields holding their name and labels.
+func writeJSON(registry metrics.Registry, w io.Writer) error {
+	all := registry.GetAll()
+	for name, values := range all {
+		if name, l := labels.Decode(name); l != nil {
+			values["name"] = name
+			values["tags"] = l
+		}
+	}
+
+	return json.NewEncoder(w).Encode(all)
+}
@@ -0,0 +1,115 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var (
+		testConfig = &Config{Path: "/tmp/metrics"}
+		metrics    = gometrics.NewRegistry()
+	)
+
+	exporter, err := New(testConfig, metrics)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	require.Equal(t, metrics, exporter.registry)
+	require.Equal(t, testConfig, exporter.config)
+}
+
+func TestExporter_Start(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test", registry).Inc(42)
+
+	testConfig := &Config{Path: filepath.Join(dir, "metrics.json"), FlushInterval: 1}
+	exporter, err := New(testConfig, registry)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	require.Eventually(t,
+		func() bool {
+			data, _ := ioutil.ReadFile(testConfig.Path)
+			return len(data) > 0
+		},
+		time.Second*3,
+		100*time.Millisecond)
+	require.NoError(t, exporter.Stop(context.Background()))
+
+	data, err := ioutil.ReadFile(testConfig.Path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	var metrics struct {
+		Test struct {
+			Count int64 `json:"count"`
+		} `json:"test"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &metrics))
+	require.Equal(t, int64(42), metrics.Test.Count)
+}
+
+func TestExporter_Start_Error(t *testing.T) {
+	exporter, err := New(&Config{Path: "/nonexistent/metrics.json"}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.Error(t, exporter.Start(context.Background()))
+	require.NoError(t, exporter.Stop(context.Background()))
+}
+
+func TestExporter_Stop(t *testing.T) {
+	var testCtx = context.Background()
+
+	dir, err := ioutil.TempDir("", "metrics")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exporter, err := New(&Config{Path: filepath.Join(dir, "metrics.json")}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(testCtx))
+	require.NotNil(t, exporter.t)
+	require.True(t, exporter.t.Alive())
+
+	require.Eventually(t,
+		func() bool { return exporter.Stop(testCtx) == nil },
+		time.Second*3,
+		500*time.Millisecond,
+	)
+}
+
+func TestWriteJSON(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test", registry).Inc(1)
+	gometrics.NewRegisteredCounter(`test{code="200"}`, registry).Inc(2)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeJSON(registry, &buf))
+	require.Equal(t, 1, strings.Count(buf.String(), "\n"), "should have been written on a single line")
+
+	var metrics map[string]struct {
+		Count int64             `json:"count"`
+		Name  string            `json:"name"`
+		Tags  map[string]string `json:"tags"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &metrics))
+	require.Equal(t, int64(1), metrics["test"].Count)
+	require.Empty(t, metrics["test"].Name)
+	require.Equal(t, int64(2), metrics[`test{code="200"}`].Count)
+	require.Equal(t, "test", metrics[`test{code="200"}`].Name)
+	require.Equal(t, map[string]string{"code": "200"}, metrics[`test{code="200"}`].Tags)
+}
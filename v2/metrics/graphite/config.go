@@ -0,0 +1,74 @@
+package graphite
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultProtocol         = "plaintext"
+	defaultPrefix           = "{fqdn}"
+	defaultFlushIntervalSec = 10
+	defaultTimeoutSec       = 5
+)
+
+// Config represents a Graphite metrics export configuration.
+type Config struct {
+	// Connect represents the network address ("host:port") of the Graphite (carbon) server to send the metrics to
+	// over TCP.
+	Connect string `yaml:"connect"`
+
+	// Protocol represents the carbon protocol used to send the metrics (plaintext|pickle). If not specified,
+	// defaults to "plaintext".
+	Protocol string `yaml:"protocol"`
+
+	// Prefix represents a prefix prepended to the paths of the sent metrics, in which the "{fqdn}" placeholder is
+	// replaced by the host name (dots being replaced by underscores). If not specified, defaults to "{fqdn}".
+	Prefix string `yaml:"prefix"`
+
+	// Hostname represents the host name substituted to the "{fqdn}" prefix placeholder. If not specified, defaults
+	// to the host FQDN.
+	Hostname string `yaml:"hostname"`
+
+	// FlushInterval represents the time interval in seconds at which the metrics are sent to the Graphite server.
+	// If not specified, defaults to 10 seconds.
+	FlushInterval int `yaml:"flush_interval"`
+
+	// Timeout represents the timeout in seconds of the connection to and writes to the Graphite server. If not
+	// specified, defaults to 5 seconds.
+	Timeout int `yaml:"timeout"`
+
+	// Exclude represents a list of metric names patterns (shell globbing) to exclude from the export.
+	Exclude []string `yaml:"exclude"`
+
+	// Debug represents a flags indicating whether to enable internal exporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	if c.Protocol == "" {
+		c.Protocol = defaultProtocol
+	}
+
+	if c.Prefix == "" {
+		c.Prefix = defaultPrefix
+	}
+
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushIntervalSec
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeoutSec
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Connect, validation.Required, is.DialString),
+		validation.Field(&c.Protocol,
+			validation.In(
+				"plaintext",
+				"pickle",
+			)),
+	)
+}
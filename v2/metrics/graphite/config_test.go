@@ -0,0 +1,20 @@
+package graphite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testConfig := &Config{Connect: "127.0.0.1:2003"}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultProtocol, testConfig.Protocol, "should have been set to default value")
+	require.Equal(t, defaultPrefix, testConfig.Prefix, "should have been set to default value")
+	require.Equal(t, defaultFlushIntervalSec, testConfig.FlushInterval, "should have been set to default value")
+	require.Equal(t, defaultTimeoutSec, testConfig.Timeout, "should have been set to default value")
+
+	require.Error(t, new(Config).validate())
+	require.Error(t, (&Config{Connect: "nope"}).validate())
+	require.Error(t, (&Config{Connect: "127.0.0.1:2003", Protocol: "nope"}).validate())
+}
@@ -0,0 +1,385 @@
+// graphite implements a Graphite metrics exporter.
+package graphite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/fqdn"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+	"github.com/exoscale/go-reporter/v2/internal/naming"
+)
+
+const (
+	// Bounds of the delay between reconnection attempts, doubled after each failed attempt.
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = time.Minute
+
+	// Maximum number of datapoints sent in a single pickle payload.
+	maxPickleDatapoints = 500
+)
+
+// Percentiles reported for histograms and timers.
+var (
+	percentiles      = []float64{0.5, 0.75, 0.95, 0.98, 0.99, 0.999}
+	percentilesNames = []string{"p50", "p75", "p95", "p98", "p99", "p999"}
+)
+
+var errDisconnected = errors.New("not connected to the Graphite server")
+
+// datapoint represents a value of a Graphite metric.
+type datapoint struct {
+	path  string
+	value float64
+}
+
+// Exporter represents a metrics exporter to a Graphite server.
+type Exporter struct {
+	registry metrics.Registry
+	prefix   string
+
+	conn    net.Conn
+	backoff time.Duration // Current delay between reconnection attempts
+	retryAt time.Time     // Time of the next reconnection attempt
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new Graphite metrics exporter based on provided configuration, periodically sending the metrics of
+// the go-metrics registry to the Graphite server. Meters, histograms and timers are flattened into one series per
+// value, suffixed with the value name (e.g. "count", "m1_rate", "p99"...).
+func New(config *Config, registry metrics.Registry) (*Exporter, error) {
+	var exporter Exporter
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	exporter.config = config
+	exporter.registry = registry
+
+	exporter.D = debug.New("reporter/metrics/graphite")
+	if config.Debug {
+		exporter.D.On()
+	}
+
+	exporter.prefix = config.Prefix
+	if strings.Contains(exporter.prefix, "{fqdn}") {
+		hostname := config.Hostname
+		if hostname == "" {
+			var err error
+			if hostname, err = fqdn.Get(); err != nil {
+				return nil, err
+			}
+		}
+
+		exporter.prefix = strings.Replace(exporter.prefix, "{fqdn}", strings.Replace(hostname, ".", "_", -1), -1)
+	}
+
+	exporter.Debug("enabling exporter",
+		"connect", config.Connect,
+		"protocol", config.Protocol,
+		"prefix", exporter.prefix,
+		"flush_interval", config.FlushInterval)
+
+	return &exporter, nil
+}
+
+// Start starts the metrics exporter. The connection to the Graphite server is established at the first flush, and
+// re-established with an exponential backoff whenever it fails.
+func (e *Exporter) Start(ctx context.Context) error {
+	e.t, _ = tomb.WithContext(ctx)
+	e.t.Go(e.flushLoop)
+
+	return nil
+}
+
+// Stop stops the metrics exporter.
+func (e *Exporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if e.t == nil {
+		return nil
+	}
+
+	e.t.Kill(nil)
+
+	return e.t.Wait()
+}
+
+// flushLoop periodically sends the go-metrics registry metrics to the Graphite server. This method blocks the caller
+// until the exporter's tomb dies.
+func (e *Exporter) flushLoop() error {
+	tick := time.NewTicker(time.Duration(e.config.FlushInterval) * time.Second)
+	defer tick.Stop()
+
+	e.Debug("starting flush loop")
+
+	for {
+		select {
+		case <-tick.C:
+			e.flush(time.Now())
+
+		case <-e.t.Dying():
+			e.Debug("terminating flush loop")
+			if e.conn != nil {
+				return e.conn.Close()
+			}
+			return nil
+		}
+	}
+}
+
+// flush sends the current go-metrics registry metrics to the Graphite server.
+func (e *Exporter) flush(now time.Time) {
+	var payloads [][]byte
+
+	datapoints := e.datapoints()
+	if len(datapoints) == 0 {
+		return
+	}
+
+	if e.config.Protocol == "pickle" {
+		payloads = pickle(datapoints, now)
+	} else {
+		payloads = [][]byte{plaintext(datapoints, now)}
+	}
+
+	for _, payload := range payloads {
+		if err := e.send(payload); err != nil {
+			e.Error("unable to send metrics", "err", err)
+			return
+		}
+	}
+}
+
+// send writes a payload to the Graphite server, (re-)connecting to it if needed. In case of connection failure,
+// the next attempt is delayed by an exponentially increasing backoff.
+func (e *Exporter) send(payload []byte) error {
+	timeout := time.Duration(e.config.Timeout) * time.Second
+
+	if e.conn == nil {
+		now := time.Now()
+		if now.Before(e.retryAt) {
+			return errDisconnected
+		}
+
+		conn, err := net.DialTimeout("tcp", e.config.Connect, timeout)
+		if err != nil {
+			e.backoff *= 2
+			if e.backoff < minReconnectBackoff {
+				e.backoff = minReconnectBackoff
+			} else if e.backoff > maxReconnectBackoff {
+				e.backoff = maxReconnectBackoff
+			}
+			e.retryAt = now.Add(e.backoff)
+
+			return err
+		}
+
+		e.Debug("connected to Graphite server", "connect", e.config.Connect)
+		e.conn = conn
+		e.backoff = 0
+	}
+
+	if err := e.conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	if _, err := e.conn.Write(payload); err != nil {
+		// The connection will be re-established at the next flush
+		e.conn.Close()
+		e.conn = nil
+
+		return err
+	}
+
+	return nil
+}
+
+// datapoints returns the Graphite datapoints of the go-metrics registry metrics.
+func (e *Exporter) datapoints() []datapoint {
+	var datapoints []datapoint
+
+	e.registry.Each(func(name string, i interface{}) {
+		// Any error parsing an exclusion pattern is silently ignored.
+		for _, pattern := range e.config.Exclude {
+			if matched, _ := path.Match(pattern, name); matched {
+				return
+			}
+		}
+
+		metricPath := e.path(name)
+		add := func(suffix string, value float64) {
+			if suffix != "" {
+				suffix = "." + suffix
+			}
+			datapoints = append(datapoints, datapoint{path: metricPath + suffix, value: value})
+		}
+
+		switch metric := i.(type) {
+		case metrics.Gauge:
+			add("", float64(metric.Value()))
+
+		case metrics.GaugeFloat64:
+			add("", metric.Value())
+
+		case metrics.Counter:
+			add("", float64(metric.Count()))
+
+		case metrics.Meter:
+			snapshot := metric.Snapshot()
+			add("count", float64(snapshot.Count()))
+			add("m1_rate", snapshot.Rate1())
+			add("m5_rate", snapshot.Rate5())
+			add("m15_rate", snapshot.Rate15())
+			add("mean_rate", snapshot.RateMean())
+
+		case metrics.Histogram:
+			snapshot := metric.Snapshot()
+			add("count", float64(snapshot.Count()))
+			add("max", float64(snapshot.Max()))
+			add("mean", snapshot.Mean())
+			add("min", float64(snapshot.Min()))
+			add("stddev", snapshot.StdDev())
+			for i, p := range snapshot.Percentiles(percentiles) {
+				add(percentilesNames[i], p)
+			}
+			addBuckets(add, snapshot)
+
+		case metrics.Timer:
+			snapshot := metric.Snapshot()
+			add("max", float64(snapshot.Max()))
+			add("mean", snapshot.Mean())
+			add("min", float64(snapshot.Min()))
+			add("stddev", snapshot.StdDev())
+			for i, p := range snapshot.Percentiles(percentiles) {
+				add(percentilesNames[i], p)
+			}
+			addBuckets(add, snapshot)
+		}
+	})
+
+	return datapoints
+}
+
+// addBuckets adds the bucket series of a histogram or timer snapshot counting its values in buckets (if any): the
+// cumulative count of the values less than or equal to each bucket upper bound, as "le_<bound>" (dots being replaced
+// by underscores).
+func addBuckets(add func(string, float64), snapshot interface{}) {
+	b, ok := snapshot.(buckets.Bucketed)
+	if !ok {
+		return
+	}
+
+	bounds, counts := b.Buckets()
+	for i, bound := range bounds {
+		add("le_"+strings.Replace(buckets.Format(bound), ".", "_", -1), float64(counts[i]))
+	}
+}
+
+// path returns the Graphite path of a metric: the label values (sorted by key) of a labeled metric are appended to
+// its name.
+func (e *Exporter) path(name string) string {
+	parts := make([]string, 0, 2)
+
+	if e.prefix != "" {
+		parts = append(parts, e.prefix)
+	}
+
+	name, l := labels.Decode(name)
+	parts = append(parts, name)
+	for _, v := range l.Values() {
+		parts = append(parts, strings.Replace(v, ".", "_", -1))
+	}
+
+	return naming.Graphite(strings.Join(parts, "."))
+}
+
+// plaintext returns the datapoints encoded using the carbon plaintext protocol.
+func plaintext(datapoints []datapoint, now time.Time) []byte {
+	var (
+		buf       bytes.Buffer
+		timestamp = strconv.FormatInt(now.Unix(), 10)
+	)
+
+	for _, dp := range datapoints {
+		buf.WriteString(dp.path)
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(dp.value, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(timestamp)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// pickle returns the datapoints encoded using the carbon pickle protocol, in payloads of at most
+// maxPickleDatapoints datapoints. Each payload is a length-prefixed pickle (protocol 2) of a list of
+// (path, (timestamp, value)) tuples.
+func pickle(datapoints []datapoint, now time.Time) [][]byte {
+	var payloads [][]byte
+
+	for len(datapoints) > 0 {
+		n := len(datapoints)
+		if n > maxPickleDatapoints {
+			n = maxPickleDatapoints
+		}
+
+		var buf bytes.Buffer
+		buf.Write([]byte{0, 0, 0, 0}) // Length header, set below
+		buf.Write([]byte{0x80, 2})    // PROTO 2
+		buf.WriteByte(']')            // EMPTY_LIST
+		buf.WriteByte('(')            // MARK
+		for _, dp := range datapoints[:n] {
+			pickleString(&buf, dp.path)
+			pickleFloat(&buf, float64(now.Unix()))
+			pickleFloat(&buf, dp.value)
+			buf.WriteByte(0x86) // TUPLE2: (timestamp, value)
+			buf.WriteByte(0x86) // TUPLE2: (path, (timestamp, value))
+		}
+		buf.WriteByte('e') // APPENDS
+		buf.WriteByte('.') // STOP
+
+		payload := buf.Bytes()
+		binary.BigEndian.PutUint32(payload, uint32(len(payload)-4))
+		payloads = append(payloads, payload)
+
+		datapoints = datapoints[n:]
+	}
+
+	return payloads
+}
+
+func pickleString(buf *bytes.Buffer, s string) {
+	var size [4]byte
+
+	binary.LittleEndian.PutUint32(size[:], uint32(len(s)))
+	buf.WriteByte('X') // BINUNICODE
+	buf.Write(size[:])
+	buf.WriteString(s)
+}
+
+func pickleFloat(buf *bytes.Buffer, f float64) {
+	var value [8]byte
+
+	binary.BigEndian.PutUint64(value[:], math.Float64bits(f))
+	buf.WriteByte('G') // BINFLOAT
+	buf.Write(value[:])
+}
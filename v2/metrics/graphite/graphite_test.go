@@ -0,0 +1,158 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+)
+
+func TestNew(t *testing.T) {
+	var (
+		testConfig = &Config{Connect: "127.0.0.1:2003", Prefix: "servers.{fqdn}.app", Hostname: "test.example.net"}
+		metrics    = gometrics.NewRegistry()
+	)
+
+	exporter, err := New(testConfig, metrics)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	require.Equal(t, metrics, exporter.registry)
+	require.Equal(t, "servers.test_example_net.app", exporter.prefix)
+	require.Equal(t, testConfig, exporter.config)
+}
+
+func TestExporter_Start(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test.counter", registry).Inc(42)
+	gometrics.NewRegisteredGauge("test.excluded", registry).Update(1)
+
+	exporter, err := New(&Config{
+		Connect:       l.Addr().String(),
+		Prefix:        "{fqdn}",
+		Hostname:      "test",
+		FlushInterval: 1,
+		Exclude:       []string{"*.excluded"},
+	}, registry)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Regexp(t, `^test\.test\.counter 42 \d+\n$`, line)
+}
+
+func TestExporter_datapoints(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredGaugeFloat64(`test.gauge{code="2.0",method="GET"}`, registry).Update(1.5)
+	gometrics.NewRegisteredMeter("test.meter", registry).Mark(3)
+
+	exporter, err := New(&Config{Connect: "127.0.0.1:2003", Prefix: "prefix"}, registry)
+	require.NoError(t, err)
+
+	datapoints := exporter.datapoints()
+	paths := make([]string, len(datapoints))
+	for i, dp := range datapoints {
+		paths[i] = dp.path
+	}
+	sort.Strings(paths)
+
+	require.Equal(t, []string{
+		"prefix.test.gauge.2_0.GET",
+		"prefix.test.meter.count",
+		"prefix.test.meter.m15_rate",
+		"prefix.test.meter.m1_rate",
+		"prefix.test.meter.m5_rate",
+		"prefix.test.meter.mean_rate",
+	}, paths)
+
+	// Bucket series of histograms counting their values in buckets
+	histogram := buckets.NewHistogram([]float64{0.5, 10}, gometrics.NewHistogram(gometrics.NewUniformSample(10)))
+	histogram.Update(3)
+	require.NoError(t, registry.Register("test.histogram", histogram))
+
+	exporter.config.Exclude = []string{"test.gauge*", "test.meter"}
+	values := make(map[string]float64)
+	for _, dp := range exporter.datapoints() {
+		values[dp.path] = dp.value
+	}
+	require.Len(t, values, 13)
+	require.Equal(t, float64(0), values["prefix.test.histogram.le_0_5"])
+	require.Equal(t, float64(1), values["prefix.test.histogram.le_10"])
+}
+
+func TestExporter_send(t *testing.T) {
+	// Reserve a free port, then release it so that the first connection attempt fails
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	exporter, err := New(&Config{Connect: addr, Prefix: "prefix"}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.Error(t, exporter.send([]byte("test 1 0\n")))
+	require.Equal(t, minReconnectBackoff, exporter.backoff)
+
+	// Reconnection attempts are delayed by the backoff
+	require.Equal(t, errDisconnected, exporter.send([]byte("test 1 0\n")))
+
+	l, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer l.Close()
+
+	exporter.retryAt = time.Time{}
+	require.NoError(t, exporter.send([]byte("test 1 0\n")))
+	require.Zero(t, exporter.backoff)
+	require.NoError(t, exporter.conn.Close())
+}
+
+func TestPickle(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	payloads := pickle([]datapoint{{path: "a.b", value: 2.5}}, now)
+	require.Len(t, payloads, 1)
+
+	payload := payloads[0]
+	require.Equal(t, uint32(len(payload)-4), binary.BigEndian.Uint32(payload))
+
+	float := func(f float64) []byte {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(f))
+		return b
+	}
+
+	expected := []byte{0x80, 2, ']', '(', 'X', 3, 0, 0, 0, 'a', '.', 'b', 'G'}
+	expected = append(expected, float(1600000000)...)
+	expected = append(expected, 'G')
+	expected = append(expected, float(2.5)...)
+	expected = append(expected, 0x86, 0x86, 'e', '.')
+	require.Equal(t, expected, payload[4:])
+
+	datapoints := make([]datapoint, maxPickleDatapoints+1)
+	require.Len(t, pickle(datapoints, now), 2)
+}
+
+func TestPlaintext(t *testing.T) {
+	require.Equal(t, "a.b 2.5 1600000000\nc 3 1600000000\n",
+		string(plaintext([]datapoint{{path: "a.b", value: 2.5}, {path: "c", value: 3}}, time.Unix(1600000000, 0))))
+}
@@ -0,0 +1,163 @@
+package influx
+
+import (
+	"errors"
+	"net/url"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultAPIVersion       = "v1"
+	defaultFlushIntervalSec = 10
+	defaultBatchSize        = 5000
+	defaultMaxPacketSize    = 1432 // Ethernet MTU minus IPv6 and UDP headers
+	defaultMaxRetries       = 3
+	defaultTimeoutSec       = 5
+)
+
+// Config represents an InfluxDB metrics export configuration.
+type Config struct {
+	// URL represents the URL of the InfluxDB server to write the metrics to: either an HTTP(S) URL (e.g.
+	// "https://influxdb.example.net:8086") to use the HTTP write API, or a "udp://host:port" URL to use the UDP
+	// service.
+	URL string `yaml:"url"`
+
+	// APIVersion represents the version of the InfluxDB HTTP write API (v1|v2). If not specified, defaults to "v1".
+	APIVersion string `yaml:"api_version"`
+
+	// Database represents the database to write the metrics to (v1 API, required).
+	Database string `yaml:"database"`
+
+	// RetentionPolicy represents the retention policy of the written metrics (v1 API). If not specified, the
+	// database default retention policy is used.
+	RetentionPolicy string `yaml:"retention_policy"`
+
+	// Org represents the organization to write the metrics to (v2 API, required).
+	Org string `yaml:"org"`
+
+	// Bucket represents the bucket to write the metrics to (v2 API, required).
+	Bucket string `yaml:"bucket"`
+
+	// Token represents the API token used to authenticate to the InfluxDB server. Mutually exclusive with
+	// Username/Password.
+	Token string `yaml:"token"`
+
+	// Username represents the user name used to authenticate to the InfluxDB server using HTTP basic
+	// authentication.
+	Username string `yaml:"username"`
+
+	// Password represents the password used to authenticate to the InfluxDB server using HTTP basic
+	// authentication.
+	Password string `yaml:"password"`
+
+	// Prefix represents the value of the "prefix" tag added to all the written metrics.
+	Prefix string `yaml:"prefix"`
+
+	// Hostname represents the value of the "host" tag added to all the written metrics. If not specified, defaults
+	// to the host FQDN.
+	Hostname string `yaml:"hostname"`
+
+	// Tags represents additional tags added to all the written metrics.
+	Tags map[string]string `yaml:"tags"`
+
+	// FlushInterval represents the time interval in seconds at which the metrics are written to the InfluxDB
+	// server. If not specified, defaults to 10 seconds.
+	FlushInterval int `yaml:"flush_interval"`
+
+	// BatchSize represents the maximum number of lines written in a single HTTP request. If not specified, defaults
+	// to 5000 lines.
+	BatchSize int `yaml:"batch_size"`
+
+	// MaxPacketSize represents the maximum size in bytes of the UDP packets, in which lines are batched. If not
+	// specified, defaults to 1432 bytes (fitting in a standard Ethernet MTU).
+	MaxPacketSize int `yaml:"max_packet_size"`
+
+	// Gzip represents a flag indicating whether to compress the HTTP requests bodies using gzip.
+	Gzip bool `yaml:"gzip"`
+
+	// MaxRetries represents the maximum number of times a failed HTTP request (network error, 429 or 5xx status)
+	// is retried, with an exponential backoff. If not specified, defaults to 3.
+	MaxRetries int `yaml:"max_retries"`
+
+	// Timeout represents the HTTP requests timeout in seconds. If not specified, defaults to 5 seconds.
+	Timeout int `yaml:"timeout"`
+
+	// Exclude represents a list of metric names patterns (shell globbing) to exclude from the export.
+	Exclude []string `yaml:"exclude"`
+
+	// Debug represents a flags indicating whether to enable internal exporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+// udp returns true if the metrics are written using the UDP service.
+func (c *Config) udp() bool {
+	u, err := url.Parse(c.URL)
+
+	return err == nil && u.Scheme == "udp"
+}
+
+func (c *Config) validate() error {
+	if c.APIVersion == "" {
+		c.APIVersion = defaultAPIVersion
+	}
+
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushIntervalSec
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+
+	if c.MaxPacketSize <= 0 {
+		c.MaxPacketSize = defaultMaxPacketSize
+	}
+
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeoutSec
+	}
+
+	http := !c.udp()
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.URL,
+			validation.Required,
+			validation.When(http, is.URL),
+			validation.By(func(v interface{}) error {
+				u, err := url.Parse(v.(string))
+				if err != nil {
+					return err
+				}
+				switch u.Scheme {
+				case "http", "https":
+					return nil
+				case "udp":
+					return is.DialString.Validate(u.Host)
+				}
+				return errors.New("unsupported scheme")
+			})),
+		validation.Field(&c.APIVersion,
+			validation.In(
+				"v1",
+				"v2",
+			)),
+		validation.Field(&c.Database, validation.When(http && c.APIVersion == "v1", validation.Required)),
+		validation.Field(&c.Org, validation.When(http && c.APIVersion == "v2", validation.Required)),
+		validation.Field(&c.Bucket, validation.When(http && c.APIVersion == "v2", validation.Required)),
+		validation.Field(&c.Password, validation.When(c.Username != "", validation.Required)),
+		validation.Field(&c.Token,
+			validation.By(func(v interface{}) error {
+				if v.(string) != "" && c.Username != "" {
+					return errors.New("mutually exclusive with username/password")
+				}
+				return nil
+			})),
+	)
+}
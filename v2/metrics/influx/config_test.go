@@ -0,0 +1,32 @@
+package influx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testConfig := &Config{URL: "http://127.0.0.1:8086", Database: "test"}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultAPIVersion, testConfig.APIVersion, "should have been set to default value")
+	require.Equal(t, defaultFlushIntervalSec, testConfig.FlushInterval, "should have been set to default value")
+	require.Equal(t, defaultBatchSize, testConfig.BatchSize, "should have been set to default value")
+	require.Equal(t, defaultMaxPacketSize, testConfig.MaxPacketSize, "should have been set to default value")
+	require.Equal(t, defaultMaxRetries, testConfig.MaxRetries, "should have been set to default value")
+	require.Equal(t, defaultTimeoutSec, testConfig.Timeout, "should have been set to default value")
+
+	require.NoError(t, (&Config{URL: "udp://127.0.0.1:8089"}).validate())
+	require.NoError(t, (&Config{URL: "http://127.0.0.1:8086", APIVersion: "v2", Org: "test", Bucket: "test",
+		Token: "test"}).validate())
+
+	require.Error(t, new(Config).validate())
+	require.Error(t, (&Config{URL: "tcp://127.0.0.1:8086", Database: "test"}).validate())
+	require.Error(t, (&Config{URL: "udp://nope"}).validate())
+	require.Error(t, (&Config{URL: "http://127.0.0.1:8086"}).validate())
+	require.Error(t, (&Config{URL: "http://127.0.0.1:8086", APIVersion: "v2", Org: "test"}).validate())
+	require.Error(t, (&Config{URL: "http://127.0.0.1:8086", APIVersion: "v3", Database: "test"}).validate())
+	require.Error(t, (&Config{URL: "http://127.0.0.1:8086", Database: "test", Username: "test"}).validate())
+	require.Error(t, (&Config{URL: "http://127.0.0.1:8086", Database: "test", Username: "test", Password: "test",
+		Token: "test"}).validate())
+}
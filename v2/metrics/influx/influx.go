@@ -0,0 +1,476 @@
+// influx implements an InfluxDB metrics exporter.
+package influx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/fqdn"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+)
+
+// Delay before the first retry of a failed HTTP request, doubled after each retry.
+const defaultRetryBackoff = time.Second
+
+// Percentiles reported for histograms and timers.
+var (
+	percentiles      = []float64{0.5, 0.75, 0.95, 0.98, 0.99, 0.999}
+	percentilesNames = []string{"p50", "p75", "p95", "p98", "p99", "p999"}
+)
+
+// Replacers escaping the characters having a special meaning in the line protocol.
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+// field represents a field of a line protocol point, whose value is already formatted.
+type field struct {
+	key   string
+	value string
+}
+
+// Exporter represents a metrics exporter to an InfluxDB server.
+type Exporter struct {
+	registry metrics.Registry
+	tags     labels.Labels // Tags added to all the points
+	writeURL string
+	client   *http.Client
+	conn     net.Conn
+
+	retryBackoff time.Duration
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new InfluxDB metrics exporter based on provided configuration, periodically writing the metrics of
+// the go-metrics registry to the InfluxDB server using the line protocol. Each metric is written as a single point,
+// whose measurement is the metric name, tagged with its labels: meters, histograms and timers values are written as
+// multiple fields (e.g. "count", "m1_rate", "p99"...) of the same point.
+func New(config *Config, registry metrics.Registry) (*Exporter, error) {
+	var exporter Exporter
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	exporter.config = config
+	exporter.registry = registry
+	exporter.retryBackoff = defaultRetryBackoff
+
+	exporter.D = debug.New("reporter/metrics/influx")
+	if config.Debug {
+		exporter.D.On()
+	}
+
+	hostname := config.Hostname
+	if hostname == "" {
+		var err error
+		if hostname, err = fqdn.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	tags := labels.Labels{"host": hostname}
+	if config.Prefix != "" {
+		tags["prefix"] = config.Prefix
+	}
+	for k, v := range config.Tags {
+		tags[k] = v
+	}
+	exporter.tags = tags
+
+	if !config.udp() {
+		query := url.Values{}
+		endpoint := "/write"
+		if config.APIVersion == "v2" {
+			endpoint = "/api/v2/write"
+			query.Set("org", config.Org)
+			query.Set("bucket", config.Bucket)
+		} else {
+			query.Set("db", config.Database)
+			if config.RetentionPolicy != "" {
+				query.Set("rp", config.RetentionPolicy)
+			}
+		}
+		exporter.writeURL = strings.TrimSuffix(config.URL, "/") + endpoint + "?" + query.Encode()
+		exporter.client = &http.Client{Timeout: time.Duration(config.Timeout) * time.Second}
+	}
+
+	exporter.Debug("enabling exporter",
+		"url", config.URL,
+		"api_version", config.APIVersion,
+		"host", hostname,
+		"flush_interval", config.FlushInterval)
+
+	return &exporter, nil
+}
+
+// Start starts the metrics exporter.
+func (e *Exporter) Start(ctx context.Context) error {
+	if e.config.udp() {
+		u, _ := url.Parse(e.config.URL)
+
+		var err error
+		if e.conn, err = net.Dial("udp", u.Host); err != nil {
+			return err
+		}
+	}
+
+	e.t, _ = tomb.WithContext(ctx)
+	e.t.Go(e.flushLoop)
+
+	return nil
+}
+
+// Stop stops the metrics exporter.
+func (e *Exporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if e.t == nil {
+		return nil
+	}
+
+	e.t.Kill(nil)
+
+	return e.t.Wait()
+}
+
+// flushLoop periodically writes the go-metrics registry metrics to the InfluxDB server. This method blocks the
+// caller until the exporter's tomb dies.
+func (e *Exporter) flushLoop() error {
+	tick := time.NewTicker(time.Duration(e.config.FlushInterval) * time.Second)
+	defer tick.Stop()
+
+	e.Debug("starting flush loop")
+
+	for {
+		select {
+		case <-tick.C:
+			e.flush(time.Now())
+
+		case <-e.t.Dying():
+			e.Debug("terminating flush loop")
+			if e.conn != nil {
+				return e.conn.Close()
+			}
+			return nil
+		}
+	}
+}
+
+// flush writes the current go-metrics registry metrics to the InfluxDB server.
+func (e *Exporter) flush(now time.Time) {
+	lines := e.lines(now)
+
+	if e.conn != nil {
+		var failed int
+
+		for _, packet := range batch(lines, e.config.MaxPacketSize) {
+			// Datagram sockets errors (e.g. server not listening) are not fatal
+			if _, err := e.conn.Write(packet); err != nil {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			e.Error("unable to send some metrics packets", "failed", failed)
+		}
+
+		return
+	}
+
+	for len(lines) > 0 {
+		n := len(lines)
+		if n > e.config.BatchSize {
+			n = e.config.BatchSize
+		}
+
+		if err := e.write(lines[:n]); err != nil {
+			e.Error("unable to write metrics", "err", err)
+		}
+
+		lines = lines[n:]
+	}
+}
+
+// write writes lines to the InfluxDB server HTTP write API, retrying failed requests with an exponential backoff.
+func (e *Exporter) write(lines []string) error {
+	var body bytes.Buffer
+
+	if e.config.Gzip {
+		gz := gzip.NewWriter(&body)
+		if _, err := io.WriteString(gz, strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	} else {
+		body.WriteString(strings.Join(lines, "\n"))
+	}
+
+	backoff := e.retryBackoff
+	for retry := 0; ; retry++ {
+		retryable, err := e.post(body.Bytes())
+		if err == nil || !retryable || retry >= e.config.MaxRetries {
+			return err
+		}
+
+		e.Debug("retrying failed write", "err", err, "backoff", backoff)
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+
+		case <-e.t.Dying():
+			return err
+		}
+	}
+}
+
+// post sends a write request to the InfluxDB server, and returns whether the request can be retried if it failed.
+func (e *Exporter) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, e.writeURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	if e.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+e.config.Token)
+	} else if e.config.Username != "" {
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 == 2 {
+		return false, nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500,
+		fmt.Errorf("unexpected response status %q: %s", res.Status, bytes.TrimSpace(msg))
+}
+
+// lines returns the line protocol lines of the go-metrics registry metrics.
+func (e *Exporter) lines(now time.Time) []string {
+	var (
+		lines     []string
+		timestamp = strconv.FormatInt(now.UnixNano(), 10)
+	)
+
+	e.registry.Each(func(name string, i interface{}) {
+		// Any error parsing an exclusion pattern is silently ignored.
+		for _, pattern := range e.config.Exclude {
+			if matched, _ := path.Match(pattern, name); matched {
+				return
+			}
+		}
+
+		var fields []field
+
+		switch metric := i.(type) {
+		case metrics.Gauge:
+			fields = []field{intField("value", metric.Value())}
+
+		case metrics.GaugeFloat64:
+			fields = []field{floatField("value", metric.Value())}
+
+		case metrics.Counter:
+			fields = []field{intField("count", metric.Count())}
+
+		case metrics.Meter:
+			snapshot := metric.Snapshot()
+			fields = []field{
+				intField("count", snapshot.Count()),
+				floatField("m1_rate", snapshot.Rate1()),
+				floatField("m5_rate", snapshot.Rate5()),
+				floatField("m15_rate", snapshot.Rate15()),
+				floatField("mean_rate", snapshot.RateMean()),
+			}
+
+		case metrics.Histogram:
+			snapshot := metric.Snapshot()
+			fields = distributionFields(snapshot.Count(), snapshot.Min(), snapshot.Max(), snapshot.Mean(),
+				snapshot.StdDev(), snapshot.Percentiles(percentiles))
+			fields = append(fields, bucketsFields(snapshot)...)
+
+		case metrics.Timer:
+			snapshot := metric.Snapshot()
+			fields = distributionFields(snapshot.Count(), snapshot.Min(), snapshot.Max(), snapshot.Mean(),
+				snapshot.StdDev(), snapshot.Percentiles(percentiles))
+			fields = append(fields, bucketsFields(snapshot)...)
+
+		default:
+			return
+		}
+
+		if line := e.line(name, fields, timestamp); line != "" {
+			lines = append(lines, line)
+		}
+	})
+
+	return lines
+}
+
+// line returns the line protocol line of a metric, or an empty string if it has no valid field.
+func (e *Exporter) line(name string, fields []field, timestamp string) string {
+	var buf strings.Builder
+
+	measurement, l := labels.Decode(name)
+	buf.WriteString(measurementEscaper.Replace(measurement))
+
+	// Duplicate tag keys are rejected by InfluxDB: metric labels take precedence over the global tags
+	tags := e.tags
+	if l != nil {
+		tags = make(labels.Labels, len(e.tags)+len(l))
+		for k, v := range e.tags {
+			tags[k] = v
+		}
+		for k, v := range l {
+			tags[k] = v
+		}
+	}
+	buf.WriteString(formatTags(tags))
+
+	sep := byte(' ')
+	for _, f := range fields {
+		// NaN and infinite values are not supported by InfluxDB
+		if f.value == "" {
+			continue
+		}
+
+		buf.WriteByte(sep)
+		buf.WriteString(keyEscaper.Replace(f.key))
+		buf.WriteByte('=')
+		buf.WriteString(f.value)
+		sep = ','
+	}
+	if sep == ' ' {
+		return ""
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(timestamp)
+
+	return buf.String()
+}
+
+// formatTags returns the line protocol representation of tags, sorted by key as recommended by InfluxDB.
+func formatTags(l labels.Labels) string {
+	var buf strings.Builder
+
+	for _, k := range l.Keys() {
+		if l[k] == "" {
+			continue
+		}
+
+		buf.WriteByte(',')
+		buf.WriteString(keyEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(keyEscaper.Replace(l[k]))
+	}
+
+	return buf.String()
+}
+
+func distributionFields(count, min, max int64, mean, stddev float64, percentiles []float64) []field {
+	fields := []field{
+		intField("count", count),
+		intField("min", min),
+		intField("max", max),
+		floatField("mean", mean),
+		floatField("stddev", stddev),
+	}
+
+	for i, p := range percentiles {
+		fields = append(fields, floatField(percentilesNames[i], p))
+	}
+
+	return fields
+}
+
+// bucketsFields returns the fields of a histogram or timer snapshot counting its values in buckets (if any): the
+// cumulative count of the values less than or equal to each bucket upper bound, as "le_<bound>".
+func bucketsFields(snapshot interface{}) []field {
+	b, ok := snapshot.(buckets.Bucketed)
+	if !ok {
+		return nil
+	}
+
+	bounds, counts := b.Buckets()
+	fields := make([]field, len(bounds))
+	for i, bound := range bounds {
+		fields[i] = intField("le_"+buckets.Format(bound), int64(counts[i]))
+	}
+
+	return fields
+}
+
+func intField(key string, value int64) field {
+	return field{key: key, value: strconv.FormatInt(value, 10) + "i"}
+}
+
+func floatField(key string, value float64) field {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return field{key: key}
+	}
+
+	return field{key: key, value: strconv.FormatFloat(value, 'f', -1, 64)}
+}
+
+// batch returns the lines batched in newline-separated packets of at most max bytes. Lines larger than max are sent
+// in their own packet.
+func batch(lines []string, max int) [][]byte {
+	var (
+		packets [][]byte
+		buf     bytes.Buffer
+	)
+
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > max {
+			packets = append(packets, append([]byte(nil), buf.Bytes()...))
+			buf.Reset()
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+
+	if buf.Len() > 0 {
+		packets = append(packets, buf.Bytes())
+	}
+
+	return packets
+}
@@ -0,0 +1,217 @@
+package influx
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+)
+
+func TestNew(t *testing.T) {
+	var (
+		testConfig = &Config{
+			URL:             "http://127.0.0.1:8086/",
+			Database:        "test",
+			RetentionPolicy: "weekly",
+			Hostname:        "test",
+			Prefix:          "project",
+			Tags:            map[string]string{"env": "prod"},
+		}
+		metrics = gometrics.NewRegistry()
+	)
+
+	exporter, err := New(testConfig, metrics)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	require.Equal(t, metrics, exporter.registry)
+	require.Equal(t, "http://127.0.0.1:8086/write?db=test&rp=weekly", exporter.writeURL)
+	require.Equal(t, labels.Labels{"env": "prod", "host": "test", "prefix": "project"}, exporter.tags)
+	require.Equal(t, testConfig, exporter.config)
+
+	exporter, err = New(&Config{
+		URL:        "http://127.0.0.1:8086",
+		APIVersion: "v2",
+		Org:        "test",
+		Bucket:     "metrics",
+		Hostname:   "test",
+	}, metrics)
+	require.NoError(t, err)
+	require.Equal(t, "http://127.0.0.1:8086/api/v2/write?bucket=metrics&org=test", exporter.writeURL)
+}
+
+func TestExporter_Start(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+		bodies   []string
+		done     = make(chan struct{})
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		gz, err := gzip.NewReader(r.Body)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, err := ioutil.ReadAll(gz)
+		assert.NoError(t, err)
+
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+
+		// Fail the first request to test retries
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		if len(requests) == 2 {
+			close(done)
+		}
+	}))
+	defer server.Close()
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test.counter", registry).Inc(42)
+	gometrics.NewRegisteredGauge("test.excluded", registry).Update(1)
+
+	exporter, err := New(&Config{
+		URL:           server.URL,
+		APIVersion:    "v2",
+		Org:           "test",
+		Bucket:        "test",
+		Token:         "secret",
+		Hostname:      "test",
+		FlushInterval: 1,
+		Gzip:          true,
+		Exclude:       []string{"*.excluded"},
+	}, registry)
+	require.NoError(t, err)
+	exporter.retryBackoff = 10 * time.Millisecond
+
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for metrics")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.True(t, len(requests) >= 2)
+	require.Equal(t, bodies[0], bodies[1])
+	require.Equal(t, "/api/v2/write", requests[1].URL.Path)
+	require.Equal(t, "Token secret", requests[1].Header.Get("Authorization"))
+	require.Equal(t, "gzip", requests[1].Header.Get("Content-Encoding"))
+	require.Regexp(t, `^test\.counter,host=test count=42i \d+$`, bodies[1])
+}
+
+func TestExporter_write(t *testing.T) {
+	var calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", username)
+		assert.Equal(t, "pass", password)
+		assert.Equal(t, "test", r.URL.Query().Get("db"))
+
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	exporter, err := New(&Config{
+		URL:      server.URL,
+		Database: "test",
+		Username: "user",
+		Password: "pass",
+		Hostname: "test",
+	}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	// Client errors are not retried
+	require.Error(t, exporter.write([]string{"test value=1i 0"}))
+	require.Equal(t, 1, calls)
+}
+
+func TestExporter_StartUDP(t *testing.T) {
+	sock, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer sock.Close()
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredGaugeFloat64(`test.gauge{code="200"}`, registry).Update(1.5)
+
+	exporter, err := New(&Config{
+		URL:           "udp://" + sock.LocalAddr().String(),
+		Hostname:      "test",
+		FlushInterval: 1,
+	}, registry)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	require.NoError(t, sock.SetReadDeadline(time.Now().Add(3*time.Second)))
+	buf := make([]byte, defaultMaxPacketSize)
+	n, err := sock.Read(buf)
+	require.NoError(t, err)
+	require.Regexp(t, `^test\.gauge,code=200,host=test value=1.5 \d+$`, string(buf[:n]))
+}
+
+func TestExporter_lines(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredMeter(`test.meter{host="other",path="/a b"}`, registry).Mark(1)
+	gometrics.NewRegisteredHistogram("test.histogram", registry, gometrics.NewUniformSample(10)).Update(3)
+
+	exporter, err := New(&Config{URL: "udp://127.0.0.1:8089", Hostname: "test"}, registry)
+	require.NoError(t, err)
+
+	lines := exporter.lines(time.Unix(0, 42))
+	sort.Strings(lines)
+	require.Len(t, lines, 2)
+	require.Equal(t, "test.histogram,host=test count=1i,min=3i,max=3i,mean=3,stddev=0,"+
+		"p50=3,p75=3,p95=3,p98=3,p99=3,p999=3 42", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], `test.meter,host=other,path=/a\ b count=1i,m1_rate=`), lines[1])
+	require.True(t, strings.HasSuffix(lines[1], " 42"), lines[1])
+
+	// Bucket counts of histograms counting their values in buckets
+	registry.UnregisterAll()
+	histogram := buckets.NewHistogram([]float64{0.5, 10}, gometrics.NewHistogram(gometrics.NewUniformSample(10)))
+	histogram.Update(3)
+	require.NoError(t, registry.Register("test.histogram", histogram))
+
+	lines = exporter.lines(time.Unix(0, 42))
+	require.Len(t, lines, 1)
+	require.True(t, strings.HasSuffix(lines[0], ",le_0.5=0i,le_10=1i 42"), lines[0])
+}
+
+func TestBatch(t *testing.T) {
+	packets := batch([]string{"a value=1i", "b value=1i", "looooooooooong value=1i"}, 22)
+	require.Equal(t, [][]byte{
+		[]byte("a value=1i\nb value=1i"),
+		[]byte("looooooooooong value=1i"),
+	}, packets)
+}
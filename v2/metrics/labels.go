@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+)
+
+const (
+	// labelsOverflowValue represents the label value of the series into which the label sets exceeding the
+	// cardinality limit of a metric are folded.
+	labelsOverflowValue = "_overflow"
+
+	// labelsOverflowMetricName represents the name of the counter accounting for the label sets folded into
+	// overflow series.
+	labelsOverflowMetricName = "metrics.labels.overflow"
+)
+
+// labelSets represents the label sets registered for a labeled metric name.
+type labelSets struct {
+	keys   []string
+	series map[string]struct{}
+	warned bool
+}
+
+// labelLimiter enforces the cardinality safeguards of the labeled metrics: all the label sets of a metric name must
+// share the same label keys, and the number of distinct label sets per metric name is bounded.
+type labelLimiter struct {
+	mu sync.Mutex
+
+	max      int
+	sets     map[string]*labelSets
+	overflow metrics.Counter
+	logger   Logger
+}
+
+func newLabelLimiter(max int, logger Logger) *labelLimiter {
+	return &labelLimiter{
+		max:      max,
+		sets:     make(map[string]*labelSets),
+		overflow: metrics.NewCounter(),
+		logger:   logger,
+	}
+}
+
+// limit returns the label set to use for the metric name: either l itself if it complies with the cardinality
+// safeguards, or the overflow label set of the metric name otherwise.
+func (ll *labelLimiter) limit(name string, l labels.Labels) labels.Labels {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+
+	encoded := l.String()
+
+	sets, ok := ll.sets[name]
+	if !ok {
+		ll.sets[name] = &labelSets{
+			keys:   l.Keys(),
+			series: map[string]struct{}{encoded: {}},
+		}
+		return l
+	}
+
+	if _, ok := sets.series[encoded]; ok {
+		return l
+	}
+
+	sameKeys := strings.Join(sets.keys, ",") == strings.Join(l.Keys(), ",")
+	if sameKeys && len(sets.series) < ll.max {
+		sets.series[encoded] = struct{}{}
+		return l
+	}
+
+	ll.overflow.Inc(1)
+	if !sets.warned {
+		ll.logger.Warn("labeled metric cardinality limit reached, folding new label sets into overflow series",
+			"metric", name,
+			"labels", encoded,
+			"max_label_sets", ll.max,
+			"inconsistent_keys", !sameKeys)
+		sets.warned = true
+	}
+
+	overflow := make(labels.Labels, len(sets.keys))
+	for _, k := range sets.keys {
+		overflow[k] = labelsOverflowValue
+	}
+
+	return overflow
+}
+
+// forget removes the label set of an unregistered metric from the label sets of its name, freeing room for new ones.
+func (ll *labelLimiter) forget(name string) {
+	name, l := labels.Decode(name)
+	if l == nil {
+		return
+	}
+
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+
+	if sets, ok := ll.sets[name]; ok {
+		delete(sets.series, l.String())
+		if len(sets.series) == 0 {
+			delete(ll.sets, name)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+	"github.com/exoscale/go-reporter/v2/internal/samples"
+)
+
+const (
+	// nameSeparator represents the separator of the metrics names components.
+	nameSeparator = "."
+)
+
+// reporterPackages represents the list of the reporter's own packages, which are skipped when looking for the
+// package creating a metric.
+var reporterPackages = []string{
+	"github.com/exoscale/go-reporter/v2",
+	"github.com/exoscale/go-reporter/v2/metrics",
+}
+
+// Counter returns the counter registered under the specified name, registering a new one if needed.
+//
+// All the helper methods accept optional labels, specified as a list of alternating keys and values, e.g.
+// Counter("requests", "method", "GET", "code", "200"). All the label sets of a metric must have the same keys, and
+// the number of label sets per metric is bounded (see Config.MaxLabelSets): offending label sets are folded into an
+// overflow series.
+//
+// Invalid metric names and label keys are sanitized, and the number of distinct series can be bounded (see
+// Config.MaxSeries): rejected series are returned as metrics discarding their updates, which are never registered.
+func (r *Reporter) Counter(name string, labels ...string) metrics.Counter {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilCounter{}
+	}
+
+	return metrics.GetOrRegisterCounter(name, r.registry)
+}
+
+// Gauge returns the gauge registered under the specified name, registering a new one if needed.
+func (r *Reporter) Gauge(name string, labels ...string) metrics.Gauge {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilGauge{}
+	}
+
+	return metrics.GetOrRegisterGauge(name, r.registry)
+}
+
+// GaugeFloat64 returns the 64-bit float gauge registered under the specified name, registering a new one if needed.
+func (r *Reporter) GaugeFloat64(name string, labels ...string) metrics.GaugeFloat64 {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilGaugeFloat64{}
+	}
+
+	return metrics.GetOrRegisterGaugeFloat64(name, r.registry)
+}
+
+// Histogram returns the histogram registered under the specified name, registering a new one if needed. Its sample
+// type is the one of the first sample configuration matching the name (see Config.Samples). If the name matches a
+// buckets layout (see Config.Buckets), the histogram additionally counts its values in buckets.
+func (r *Reporter) Histogram(name string, labels ...string) metrics.Histogram {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilHistogram{}
+	}
+
+	return r.registry.GetOrRegister(name, func() metrics.Histogram {
+		histogram := samples.NewHistogram(r.sample(name))
+		if bounds := r.bucketsBounds(name); bounds != nil {
+			return buckets.NewHistogram(bounds, histogram)
+		}
+		return histogram
+	}).(metrics.Histogram)
+}
+
+// Meter returns the meter registered under the specified name, registering a new one if needed.
+func (r *Reporter) Meter(name string, labels ...string) metrics.Meter {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilMeter{}
+	}
+
+	return metrics.GetOrRegisterMeter(name, r.registry)
+}
+
+// Timer returns the timer registered under the specified name, registering a new one if needed. Its sample type is
+// the one of the first sample configuration matching the name (see Config.Samples). If the name matches a buckets
+// layout (see Config.Buckets), the timer additionally counts its values in buckets.
+func (r *Reporter) Timer(name string, labels ...string) metrics.Timer {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilTimer{}
+	}
+
+	return r.registry.GetOrRegister(name, func() metrics.Timer {
+		timer := samples.NewTimer(r.sample(name))
+		if bounds := r.bucketsBounds(name); bounds != nil {
+			return buckets.NewTimer(bounds, timer)
+		}
+		return timer
+	}).(metrics.Timer)
+}
+
+// Healthcheck returns the healthcheck registered under the specified name, registering a new one executing the
+// function fn if needed.
+func (r *Reporter) Healthcheck(name string, fn func(metrics.Healthcheck), labels ...string) metrics.Healthcheck {
+	name, ok := r.metricName(name, labels)
+	if !ok {
+		return metrics.NilHealthcheck{}
+	}
+
+	return r.registry.GetOrRegister(name, func() metrics.Healthcheck {
+		return metrics.NewHealthcheck(fn)
+	}).(metrics.Healthcheck)
+}
+
+// metricName returns the registry name of a metric created using the reporter's helper methods, i.e. prefixed with
+// the configured prefix and the calling package (if enabled), followed by its labels (if any) specified as a list of
+// alternating keys and values. Invalid names and label keys are sanitized. It returns false if the series is rejected
+// by the series limit (see Config.MaxSeries), otherwise the metric is marked as updated for the expiry of stale
+// metrics (see Config.TTL).
+func (r *Reporter) metricName(name string, pairs []string) (string, bool) {
+	parts := make([]string, 0, 3)
+
+	if r.config.Prefix != "" {
+		parts = append(parts, r.config.Prefix)
+	}
+
+	if strings.HasPrefix(name, nameSeparator) {
+		name = name[1:]
+	} else if r.config.PackagePrefix != "" {
+		if pkg := callerPackage(r.config.PackagePrefix); pkg != "" {
+			parts = append(parts, pkg)
+		}
+	}
+
+	name, l := r.namer.validate(strings.Join(append(parts, name), nameSeparator), labels.FromPairs(pairs...))
+
+	if len(l) > 0 {
+		name = labels.Encode(name, r.labelLimiter.limit(name, l))
+	}
+
+	name, ok := r.namer.limit(name)
+	if !ok {
+		return "", false
+	}
+
+	r.expirer.touch(name)
+
+	return name, true
+}
+
+// bucketsBounds returns the buckets upper bounds of the first buckets layout whose pattern matches the registry name
+// of a histogram or timer, or nil if none matches.
+func (r *Reporter) bucketsBounds(name string) []float64 {
+	name, _ = labels.Decode(name)
+
+	for _, b := range r.config.Buckets {
+		if matched, _ := path.Match(b.Pattern, name); matched {
+			return b.bounds()
+		}
+	}
+
+	return nil
+}
+
+// sample returns a new sample of the type of the first sample configuration whose pattern matches the registry name
+// of a histogram or timer, or an exponentially-decaying sample with the default size and alpha if none matches.
+func (r *Reporter) sample(name string) metrics.Sample {
+	name, _ = labels.Decode(name)
+
+	for _, s := range r.config.Samples {
+		if matched, _ := path.Match(s.Pattern, name); matched {
+			return s.sample()
+		}
+	}
+
+	return metrics.NewExpDecaySample(defaultSampleSize, defaultSampleAlpha)
+}
+
+// callerPackage returns the path, relative to prefix and using nameSeparator as separator, of the first package
+// found in the call stack belonging to the project identified by the import path prefix. The reporter's own
+// packages are only considered if no other package is found, in which case the outermost one is used.
+func callerPackage(prefix string) string {
+	var (
+		pcs  = make([]uintptr, 64)
+		best string
+	)
+
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+
+		pkg := funcPackage(frame.Function)
+		if rel := strings.TrimPrefix(pkg, prefix); rel != pkg && strings.HasPrefix(rel, "/") &&
+			!strings.HasPrefix(rel, "/vendor/") {
+			rel = strings.Replace(rel[1:], "/", nameSeparator, -1)
+
+			if !isReporterPackage(pkg) {
+				return rel
+			}
+			best = rel
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return best
+}
+
+// funcPackage returns the import path of the package of a fully-qualified function name as reported by the Go
+// runtime (e.g. "github.com/exoscale/project/api.(*Server).Start").
+func funcPackage(function string) string {
+	pkg := function
+
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		pkg = function[:slash+1+dot]
+	}
+
+	// The Go runtime encodes dots in the last element of packages' import path into "%2e".
+	return strings.Replace(pkg, "%2e", ".", -1)
+}
+
+func isReporterPackage(pkg string) bool {
+	for _, p := range reporterPackages {
+		if pkg == p {
+			return true
+		}
+	}
+
+	return false
+}
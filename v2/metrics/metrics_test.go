@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+)
+
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Warn(msg string, _ ...interface{}) {
+	l.messages = append(l.messages, msg)
+}
+
+func TestReporter_Helpers(t *testing.T) {
+	reporter, err := New(&Config{})
+	require.NoError(t, err)
+
+	counter := reporter.Counter("counter")
+	counter.Inc(1)
+	require.Equal(t, counter, reporter.Counter("counter"), "should have returned the existing metric")
+	require.Equal(t, int64(1), reporter.registry.Get("counter").(gometrics.Counter).Count())
+
+	reporter.Gauge("gauge")
+	require.IsType(t, &gometrics.StandardGauge{}, reporter.registry.Get("gauge"))
+	reporter.GaugeFloat64("gauge_float64")
+	require.IsType(t, &gometrics.StandardGaugeFloat64{}, reporter.registry.Get("gauge_float64"))
+	reporter.Histogram("histogram")
+	require.IsType(t, &gometrics.StandardHistogram{}, reporter.registry.Get("histogram"))
+	reporter.Meter("meter").Stop()
+	require.IsType(t, &gometrics.StandardMeter{}, reporter.registry.Get("meter"))
+	reporter.Timer("timer").Stop()
+	require.IsType(t, &gometrics.StandardTimer{}, reporter.registry.Get("timer"))
+
+	healthcheck := reporter.Healthcheck("healthcheck", func(h gometrics.Healthcheck) {
+		h.Unhealthy(errors.New("oh noes!"))
+	})
+	healthcheck.Check()
+	require.EqualError(t, reporter.registry.Get("healthcheck").(gometrics.Healthcheck).Error(), "oh noes!")
+}
+
+func TestReporter_metricName(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		config   Config
+		metric   string
+		expected string
+	}{
+		{name: "no prefix", metric: "requests", expected: "requests"},
+		{name: "prefix", config: Config{Prefix: "app"}, metric: "requests", expected: "app.requests"},
+		{
+			name:     "package prefix",
+			config:   Config{PackagePrefix: "github.com/exoscale/go-reporter"},
+			metric:   "requests",
+			expected: "v2.metrics.requests",
+		},
+		{
+			name:     "prefix and package prefix",
+			config:   Config{Prefix: "app", PackagePrefix: "github.com/exoscale/go-reporter/v2"},
+			metric:   "requests",
+			expected: "app.metrics.requests",
+		},
+		{
+			name:     "package prefix disabled",
+			config:   Config{Prefix: "app", PackagePrefix: "github.com/exoscale/go-reporter/v2"},
+			metric:   ".requests",
+			expected: "app.requests",
+		},
+		{
+			name:     "caller outside of project",
+			config:   Config{PackagePrefix: "github.com/exoscale/project"},
+			metric:   "requests",
+			expected: "requests",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter, err := New(&tc.config)
+			require.NoError(t, err)
+
+			reporter.Counter(tc.metric)
+			require.NotNil(t, reporter.registry.Get(tc.expected), reporter.registry.GetAll())
+		})
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	require.Equal(t, "github.com/exoscale/project/api",
+		funcPackage("github.com/exoscale/project/api.(*Server).Start"))
+	require.Equal(t, "gopkg.in/tomb.v2", funcPackage("gopkg.in/tomb%2ev2.(*Tomb).run"))
+	require.Equal(t, "main", funcPackage("main.main"))
+}
+
+func TestReporter_Labels(t *testing.T) {
+	reporter, err := New(&Config{Prefix: "app", MaxLabelSets: 2})
+	require.NoError(t, err)
+
+	logger := new(testLogger)
+	reporter.SetLogger(logger)
+
+	reporter.Counter(".requests", "method", "GET", "code", "200").Inc(1)
+	reporter.Counter(".requests", "code", "200", "method", "GET").Inc(1)
+	reporter.Counter(".requests", "method", "POST", "code", "201").Inc(1)
+	require.Equal(t, int64(2),
+		reporter.registry.Get(`app.requests{code="200",method="GET"}`).(gometrics.Counter).Count())
+	require.Equal(t, int64(1),
+		reporter.registry.Get(`app.requests{code="201",method="POST"}`).(gometrics.Counter).Count())
+
+	// Exceeding the label sets limit
+	reporter.Counter(".requests", "method", "PUT", "code", "204").Inc(1)
+	// Inconsistent label keys
+	reporter.Counter(".requests", "method", "GET").Inc(1)
+	require.Nil(t, reporter.registry.Get(`app.requests{code="204",method="PUT"}`))
+	require.Nil(t, reporter.registry.Get(`app.requests{method="GET"}`))
+	require.Equal(t, int64(2),
+		reporter.registry.Get(`app.requests{code="_overflow",method="_overflow"}`).(gometrics.Counter).Count())
+	require.Equal(t, int64(2), reporter.registry.Get(labelsOverflowMetricName).(gometrics.Counter).Count())
+	require.Equal(t, []string{"labeled metric cardinality limit reached, folding new label sets into overflow series"},
+		logger.messages, "offending metric names should be logged once")
+
+	// Existing label sets are still usable
+	reporter.Counter(".requests", "method", "GET", "code", "200").Inc(1)
+	require.Equal(t, int64(3),
+		reporter.registry.Get(`app.requests{code="200",method="GET"}`).(gometrics.Counter).Count())
+}
+
+func TestReporter_Naming(t *testing.T) {
+	reporter, err := New(&Config{Prefix: "app"})
+	require.NoError(t, err)
+
+	logger := new(testLogger)
+	reporter.SetLogger(logger)
+
+	reporter.Counter(".http requests", "status-code", "200").Inc(1)
+	reporter.Counter("..http requests.", "status-code", "200").Inc(1)
+	require.Equal(t, int64(2),
+		reporter.registry.Get(`app.http_requests{status_code="200"}`).(gometrics.Counter).Count())
+	require.Len(t, reporter.namer.warned, 3, "offending names should be logged once")
+	require.Equal(t, []string{
+		"invalid metric name, sanitizing",
+		"invalid metric label key, sanitizing",
+		"invalid metric name, sanitizing",
+	}, logger.messages)
+}
+
+func TestReporter_Series(t *testing.T) {
+	reporter, err := New(&Config{MaxSeries: 2})
+	require.NoError(t, err)
+
+	logger := new(testLogger)
+	reporter.SetLogger(logger)
+
+	reporter.Counter(".jobs").Inc(1)
+	reporter.Counter(".requests", "method", "GET").Inc(1)
+	reporter.Counter(".requests", "method", "POST").Inc(1)
+	reporter.Timer(".latency").Update(time.Second)
+	require.Nil(t, reporter.registry.Get(`requests{method="POST"}`))
+	require.Nil(t, reporter.registry.Get("latency"))
+	require.Equal(t, int64(0), reporter.Counter(".requests", "method", "POST").Count())
+	require.Equal(t, int64(3), reporter.registry.Get(seriesOverflowMetricName).(gometrics.Counter).Count())
+	require.Equal(t, []string{
+		"metric series limit reached, rejecting new series",
+		"metric series limit reached, rejecting new series",
+	}, logger.messages)
+
+	// Existing series are still usable
+	reporter.Counter(".jobs").Inc(1)
+	require.Equal(t, int64(2), reporter.registry.Get("jobs").(gometrics.Counter).Count())
+
+	// Forgotten series free room for new ones
+	reporter.namer.forget("jobs")
+	reporter.Timer(".latency").Update(time.Second)
+	require.NotNil(t, reporter.registry.Get("latency"))
+
+	reporter, err = New(&Config{MaxSeries: 2, SeriesOverflow: SeriesOverflowAlias})
+	require.NoError(t, err)
+
+	reporter.Counter(".jobs").Inc(1)
+	reporter.Counter(".requests", "method", "GET").Inc(1)
+	reporter.Counter(".requests", "method", "POST").Inc(1)
+	reporter.Counter(".requests", "method", "PUT").Inc(1)
+	reporter.Counter(".latency").Inc(1)
+	require.Equal(t, int64(2),
+		reporter.registry.Get(`requests{method="_overflow"}`).(gometrics.Counter).Count())
+	require.Nil(t, reporter.registry.Get("latency"))
+}
+
+func TestReporter_Buckets(t *testing.T) {
+	reporter, err := New(&Config{
+		Prefix: "app",
+		Buckets: []*BucketsConfig{
+			{Pattern: "app.*.size", Bounds: []float64{10, 100}},
+			{Pattern: "app.*.latency", Exponential: &ExponentialBucketsConfig{Start: 1e6, Factor: 10, Count: 3}},
+		},
+	})
+	require.NoError(t, err)
+
+	histogram := reporter.Histogram(".request.size", "method", "GET")
+	histogram.Update(42)
+	require.Equal(t, histogram, reporter.Histogram(".request.size", "method", "GET"),
+		"should have returned the existing metric")
+	_, counts := histogram.(buckets.Bucketed).Buckets()
+	require.Equal(t, []uint64{0, 1}, counts)
+
+	timer := reporter.Timer(".request.latency")
+	defer timer.Stop()
+	bounds, _ := timer.(buckets.Bucketed).Buckets()
+	require.Equal(t, []float64{1e6, 1e7, 1e8}, bounds)
+
+	// Unmatched names use regular histograms and timers
+	require.IsType(t, &gometrics.StandardHistogram{}, reporter.Histogram(".response.bytes"))
+	reporter.Timer(".request.duration").Stop()
+	require.IsType(t, &gometrics.StandardTimer{}, reporter.registry.Get("app.request.duration"))
+}
+
+func TestReporter_Samples(t *testing.T) {
+	reporter, err := New(&Config{
+		Prefix: "app",
+		Samples: []*SampleConfig{
+			{Pattern: "app.*.latency", Type: "hdr"},
+			{Pattern: "app.*.size", Type: "uniform", Size: 10},
+		},
+		Buckets: []*BucketsConfig{
+			{Pattern: "app.*.latency", Bounds: []float64{1e6}},
+		},
+	})
+	require.NoError(t, err)
+
+	timer := reporter.Timer(".request.latency", "method", "GET")
+	defer timer.Stop()
+	for i := 1; i <= 1000; i++ {
+		timer.Update(time.Duration(i) * time.Microsecond)
+	}
+	snapshot := timer.Snapshot()
+	require.Equal(t, int64(1000), snapshot.Count())
+	require.InDelta(t, float64(999*time.Microsecond), snapshot.Percentile(0.999), float64(time.Microsecond))
+	_, counts := snapshot.(buckets.Bucketed).Buckets()
+	require.Equal(t, []uint64{1000}, counts)
+
+	histogram := reporter.Histogram(".request.size")
+	for i := int64(0); i < 100; i++ {
+		histogram.Update(i)
+	}
+	require.Equal(t, 10, histogram.Sample().Size())
+
+	// Unmatched names use the default sample
+	require.IsType(t, &gometrics.ExpDecaySample{}, reporter.Histogram(".response.bytes").Sample())
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+	"github.com/exoscale/go-reporter/v2/internal/naming"
+)
+
+const (
+	// SeriesOverflowReject represents the series overflow policy rejecting the series exceeding the limit.
+	SeriesOverflowReject = "reject"
+
+	// SeriesOverflowAlias represents the series overflow policy folding the labeled series exceeding the limit into
+	// the overflow series of their name.
+	SeriesOverflowAlias = "alias"
+
+	// seriesOverflowMetricName represents the name of the counter accounting for the series exceeding the limit.
+	seriesOverflowMetricName = "metrics.series.overflow"
+
+	// maxOffenders represents the maximum number of offending metric names logged. Further offenders are only
+	// counted.
+	maxOffenders = 1000
+)
+
+// namer enforces the naming rules of the metrics created using the reporter's helper methods: invalid names and
+// label keys are sanitized, and the number of distinct series is bounded if a limit is set. Offending names are
+// logged once.
+type namer struct {
+	mu sync.Mutex
+
+	max      int
+	alias    bool
+	series   map[string]struct{}
+	warned   map[string]struct{}
+	overflow metrics.Counter
+	logger   Logger
+}
+
+func newNamer(max int, overflow string, logger Logger) *namer {
+	return &namer{
+		max:      max,
+		alias:    overflow == SeriesOverflowAlias,
+		series:   make(map[string]struct{}),
+		warned:   make(map[string]struct{}),
+		overflow: metrics.NewCounter(),
+		logger:   logger,
+	}
+}
+
+// validate returns the metric name and labels l with invalid name and label keys sanitized.
+func (n *namer) validate(name string, l labels.Labels) (string, labels.Labels) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if valid := naming.Name(name); valid != name {
+		n.warnOnce(name, "invalid metric name, sanitizing", "sanitized", valid)
+		name = valid
+	}
+
+	for k, v := range l {
+		if valid := naming.LabelKey(k); valid != k {
+			n.warnOnce(name, "invalid metric label key, sanitizing", "key", k, "sanitized", valid)
+			delete(l, k)
+			l[valid] = v
+		}
+	}
+
+	return name, l
+}
+
+// limit returns the registry name to use for a series: either name itself if it complies with the series limit, or
+// the name of the overflow series of its metric name if aliasing is enabled. It returns false if the series is
+// rejected.
+func (n *namer) limit(name string) (string, bool) {
+	if n.max <= 0 {
+		return name, true
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.series[name]; ok {
+		return name, true
+	}
+
+	if len(n.series) < n.max {
+		n.series[name] = struct{}{}
+		return name, true
+	}
+
+	n.overflow.Inc(1)
+
+	base, l := labels.Decode(name)
+	if n.alias && l != nil {
+		for k := range l {
+			l[k] = labelsOverflowValue
+		}
+		alias := labels.Encode(base, l)
+		n.warnOnce(base, "metric series limit reached, folding new series into overflow series",
+			"max_series", n.max,
+			"alias", alias)
+		return alias, true
+	}
+
+	n.warnOnce(base, "metric series limit reached, rejecting new series", "max_series", n.max)
+
+	return "", false
+}
+
+// forget removes an unregistered metric from the series accounted for by the series limit, freeing room for new ones.
+// It is a no-op on a nil namer.
+func (n *namer) forget(name string) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.series, name)
+}
+
+// warnOnce logs an offending metric name the first time only. The caller must hold the namer lock.
+func (n *namer) warnOnce(name, msg string, ctx ...interface{}) {
+	if _, ok := n.warned[name]; ok || len(n.warned) >= maxOffenders {
+		return
+	}
+	n.warned[name] = struct{}{}
+
+	n.logger.Warn(msg, append([]interface{}{"metric", name}, ctx...)...)
+}
@@ -0,0 +1,116 @@
+package otlp
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultURLPath          = "/v1/metrics"
+	defaultHistograms       = "summary"
+	defaultFlushIntervalSec = 10
+	defaultTimeoutSec       = 10
+)
+
+// TLSConfig represents an OTLP receiver client TLS configuration.
+type TLSConfig struct {
+	// CAFile represents the path to a CA certificates bundle file (PEM-encoded) used to verify the OTLP receiver
+	// certificate. If not specified, the system CA certificates are used.
+	CAFile string `yaml:"ca_file"`
+
+	// CertFile represents the path to a client certificate file (PEM-encoded), for mutual TLS.
+	CertFile string `yaml:"cert_file"`
+
+	// KeyFile represents the path to the client certificate private key file (PEM-encoded), for mutual TLS.
+	KeyFile string `yaml:"key_file"`
+
+	// InsecureSkipVerify represents a flag indicating whether to skip the OTLP receiver certificate verification.
+	// This is mainly for testing purposes.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+func (c *TLSConfig) validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.CertFile, validation.When(c.KeyFile != "", validation.Required)),
+		validation.Field(&c.KeyFile, validation.When(c.CertFile != "", validation.Required)))
+}
+
+// Config represents an OTLP/HTTP metrics export configuration.
+type Config struct {
+	// Endpoint represents the OTLP receiver endpoint as "host:port".
+	Endpoint string `yaml:"endpoint"`
+
+	// URLPath represents the OTLP receiver metrics URL path. If not specified, defaults to "/v1/metrics".
+	URLPath string `yaml:"url_path"`
+
+	// Insecure represents a flag indicating whether to use plain HTTP instead of HTTPS.
+	Insecure bool `yaml:"insecure"`
+
+	// TLS represents the OTLP receiver client TLS configuration.
+	TLS *TLSConfig `yaml:"tls"`
+
+	// Headers represents user-defined HTTP headers to add to the export requests (e.g. for authentication).
+	Headers map[string]string `yaml:"headers"`
+
+	// Compression represents a flag indicating whether to gzip-compress the export requests.
+	Compression bool `yaml:"compression"`
+
+	// ServiceName represents the name of the service, reported as the "service.name" resource attribute. If not
+	// specified, defaults to the executable name.
+	ServiceName string `yaml:"service_name"`
+
+	// ResourceAttributes represents additional attributes of the resource reporting the metrics.
+	ResourceAttributes map[string]string `yaml:"resource_attributes"`
+
+	// Histograms represents the way histograms and timers are exported (summary|exponential): either as summaries
+	// (count, sum and quantiles), or as exponential histograms built from the histograms samples. If not specified,
+	// defaults to "summary".
+	Histograms string `yaml:"histograms"`
+
+	// FlushInterval represents the time interval in seconds at which the metrics are exported to the OTLP
+	// receiver. If not specified, defaults to 10 seconds.
+	FlushInterval int `yaml:"flush_interval"`
+
+	// Timeout represents the export requests timeout in seconds. If not specified, defaults to 10 seconds.
+	Timeout int `yaml:"timeout"`
+
+	// Exclude represents a list of metric names patterns (shell globbing) to exclude from the export.
+	Exclude []string `yaml:"exclude"`
+
+	// Debug represents a flags indicating whether to enable internal exporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	if c.URLPath == "" {
+		c.URLPath = defaultURLPath
+	}
+
+	if c.Histograms == "" {
+		c.Histograms = defaultHistograms
+	}
+
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushIntervalSec
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeoutSec
+	}
+
+	if c.TLS != nil {
+		if err := c.TLS.validate(); err != nil {
+			return err
+		}
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Endpoint, validation.Required, is.DialString),
+		validation.Field(&c.Histograms,
+			validation.In(
+				"summary",
+				"exponential",
+			)),
+	)
+}
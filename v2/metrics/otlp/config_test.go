@@ -0,0 +1,21 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testConfig := &Config{Endpoint: "127.0.0.1:4318"}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultURLPath, testConfig.URLPath, "should have been set to default value")
+	require.Equal(t, defaultHistograms, testConfig.Histograms, "should have been set to default value")
+	require.Equal(t, defaultFlushIntervalSec, testConfig.FlushInterval, "should have been set to default value")
+	require.Equal(t, defaultTimeoutSec, testConfig.Timeout, "should have been set to default value")
+
+	require.Error(t, new(Config).validate())
+	require.Error(t, (&Config{Endpoint: "nope"}).validate())
+	require.Error(t, (&Config{Endpoint: "127.0.0.1:4318", Histograms: "nope"}).validate())
+	require.Error(t, (&Config{Endpoint: "127.0.0.1:4318", TLS: &TLSConfig{CertFile: "/tmp/cert.pem"}}).validate())
+}
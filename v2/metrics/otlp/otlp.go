@@ -0,0 +1,751 @@
+// otlp implements an OTLP/HTTP metrics exporter.
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/fqdn"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+)
+
+const (
+	// scopeName represents the name of the instrumentation scope of the exported metrics.
+	scopeName = "github.com/exoscale/go-reporter/v2/metrics"
+
+	// Bounds of the scale of the exponential histograms, and maximum number of buckets per sign.
+	maxScale   = 20
+	minScale   = -10
+	maxBuckets = 160
+
+	// Number of quantiles used to approximate the distribution of histograms and timers values as exponential
+	// histograms, since go-metrics timers don't expose their samples.
+	distributionQuantiles = 100
+
+	// Maximum number of conflicting metric names logged, to bound the memory used to log them once.
+	maxConflicts = 1000
+)
+
+// Quantiles reported for histograms and timers summaries (0 and 1 being the min and max values).
+var summaryQuantiles = []float64{0, 0.5, 0.75, 0.95, 0.99, 1}
+
+// Logger represents the interface of the logger used by the exporter to log the data points skipped because their
+// metric name is already used by a metric of another type.
+type Logger interface {
+	Warn(msg string, ctx ...interface{})
+}
+
+// Exporter represents a metrics exporter to an OTLP receiver (e.g. an OpenTelemetry collector).
+type Exporter struct {
+	registry metrics.Registry
+	resource *resourcepb.Resource
+	client   *http.Client
+	url      string
+
+	series    map[string]series // Registry metrics exported during the latest collection
+	startTime time.Time         // Start time of the cumulative metrics of the series new to the next collection
+	seriesMu  sync.Mutex
+
+	logger   Logger
+	warned   map[string]struct{} // Conflicting metric names already logged
+	warnedMu sync.Mutex
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// series represents a registry metric exported, along with the start time of its cumulative values.
+type series struct {
+	metric interface{}
+	start  uint64
+}
+
+// New returns a new OTLP metrics exporter based on provided configuration, periodically exporting the metrics of the
+// go-metrics registry to the OTLP receiver over HTTP/protobuf. Counters and meters counts are exported as cumulative
+// monotonic sums, gauges as gauges, and histograms and timers as summaries or exponential histograms (timers values
+// being in nanoseconds). The labels of the labeled metrics are exported as data points attributes.
+func New(config *Config, registry metrics.Registry) (*Exporter, error) {
+	var (
+		exporter Exporter
+		err      error
+	)
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	exporter.config = config
+	exporter.registry = registry
+	exporter.startTime = time.Now()
+
+	exporter.D = debug.New("reporter/metrics/otlp")
+	if config.Debug {
+		exporter.D.On()
+	}
+
+	exporter.logger = exporter.D
+	exporter.warned = make(map[string]struct{})
+
+	if exporter.resource, err = newResource(config); err != nil {
+		return nil, err
+	}
+
+	if exporter.client, err = newHTTPClient(config); err != nil {
+		return nil, err
+	}
+
+	scheme := "https"
+	if config.Insecure {
+		scheme = "http"
+	}
+	exporter.url = scheme + "://" + config.Endpoint + config.URLPath
+
+	exporter.Debug("enabling exporter",
+		"url", exporter.url,
+		"histograms", config.Histograms,
+		"flush_interval", config.FlushInterval)
+
+	return &exporter, nil
+}
+
+// SetLogger sets the logger used to log the data points skipped because their metric name is already used by a metric
+// of another type. By default, they are logged in debug mode only.
+func (e *Exporter) SetLogger(logger Logger) {
+	e.warnedMu.Lock()
+	e.logger = logger
+	e.warnedMu.Unlock()
+}
+
+// Start starts the metrics exporter.
+func (e *Exporter) Start(ctx context.Context) error {
+	e.t, _ = tomb.WithContext(ctx)
+	e.t.Go(e.flushLoop)
+
+	return nil
+}
+
+// Stop stops the metrics exporter, exporting the metrics a last time.
+func (e *Exporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if e.t == nil {
+		return nil
+	}
+
+	e.t.Kill(nil)
+
+	return e.t.Wait()
+}
+
+// Export exports the current go-metrics registry metrics to the OTLP receiver.
+func (e *Exporter) Export(ctx context.Context) error {
+	body, err := proto.Marshal(&colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: e.resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: scopeName},
+				Metrics: e.metrics(time.Now()),
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if e.config.Compression {
+		var buf bytes.Buffer
+
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if e.config.Compression {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("unexpected response status %q: %s", res.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// flushLoop periodically exports the go-metrics registry metrics to the OTLP receiver. This method blocks the caller
+// until the exporter's tomb dies.
+func (e *Exporter) flushLoop() error {
+	tick := time.NewTicker(time.Duration(e.config.FlushInterval) * time.Second)
+	defer tick.Stop()
+
+	e.Debug("starting flush loop")
+
+	for {
+		select {
+		case <-tick.C:
+			e.flush()
+
+		case <-e.t.Dying():
+			e.Debug("terminating flush loop")
+			e.flush()
+			return nil
+		}
+	}
+}
+
+func (e *Exporter) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.config.Timeout)*time.Second)
+	defer cancel()
+
+	if err := e.Export(ctx); err != nil {
+		e.Error("unable to export metrics", "err", err)
+	}
+}
+
+// metrics returns the OTLP metrics of the go-metrics registry metrics. The labeled metrics sharing the same name are
+// exported as data points of a single metric. The registry metrics are read in the order of their registry names, so
+// that the data points skipped because their metric name is already used by a metric of another type are always the
+// same ones. The start time of the cumulative metrics is reset when their registry metric is replaced (e.g. when
+// re-created after having expired).
+func (e *Exporter) metrics(now time.Time) []*metricspb.Metric {
+	e.seriesMu.Lock()
+	defer e.seriesMu.Unlock()
+
+	var (
+		byName    = make(map[string]*metricspb.Metric)
+		collected = make(map[string]series)
+		keys      []string
+		timestamp = uint64(now.UnixNano())
+	)
+
+	e.registry.Each(func(key string, i interface{}) {
+		// Any error parsing an exclusion pattern is silently ignored.
+		for _, pattern := range e.config.Exclude {
+			if matched, _ := path.Match(pattern, key); matched {
+				return
+			}
+		}
+
+		s, ok := e.series[key]
+		if !ok || replaced(s.metric, i) {
+			s = series{metric: i, start: uint64(e.startTime.UnixNano())}
+		}
+		collected[key] = s
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+
+	e.series = collected
+	e.startTime = now
+
+	for _, key := range keys {
+		i, start := collected[key].metric, collected[key].start
+
+		name, l := labels.Decode(key)
+		attributes := make([]*commonpb.KeyValue, 0, len(l))
+		for _, k := range l.Keys() {
+			attributes = append(attributes, &commonpb.KeyValue{
+				Key:   k,
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: l[k]}},
+			})
+		}
+
+		var metric *metricspb.Metric
+
+		switch m := i.(type) {
+		case metrics.Counter:
+			metric = e.sum(byName, name, &metricspb.NumberDataPoint{
+				Attributes:        attributes,
+				StartTimeUnixNano: start,
+				TimeUnixNano:      timestamp,
+				Value:             &metricspb.NumberDataPoint_AsInt{AsInt: m.Count()},
+			})
+
+		case metrics.Meter:
+			metric = e.sum(byName, name, &metricspb.NumberDataPoint{
+				Attributes:        attributes,
+				StartTimeUnixNano: start,
+				TimeUnixNano:      timestamp,
+				Value:             &metricspb.NumberDataPoint_AsInt{AsInt: m.Count()},
+			})
+
+		case metrics.Gauge:
+			metric = e.gauge(byName, name, &metricspb.NumberDataPoint{
+				Attributes:   attributes,
+				TimeUnixNano: timestamp,
+				Value:        &metricspb.NumberDataPoint_AsInt{AsInt: m.Value()},
+			})
+
+		case metrics.GaugeFloat64:
+			metric = e.gauge(byName, name, &metricspb.NumberDataPoint{
+				Attributes:   attributes,
+				TimeUnixNano: timestamp,
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: m.Value()},
+			})
+
+		case metrics.Histogram:
+			s := m.Snapshot()
+			if b, ok := s.(buckets.Bucketed); ok {
+				metric = e.histogram(byName, name, attributes, start, timestamp,
+					s.Count(), s.Sum(), s.Min(), s.Max(), b)
+			} else {
+				metric = e.distribution(byName, name, attributes, start, timestamp,
+					s.Count(), s.Sum(), s.Min(), s.Max(), s.Percentiles)
+			}
+
+		case metrics.Timer:
+			s := m.Snapshot()
+			if b, ok := s.(buckets.Bucketed); ok {
+				metric = e.histogram(byName, name, attributes, start, timestamp,
+					s.Count(), s.Sum(), s.Min(), s.Max(), b)
+			} else {
+				metric = e.distribution(byName, name, attributes, start, timestamp,
+					s.Count(), s.Sum(), s.Min(), s.Max(), s.Percentiles)
+			}
+			metric.Unit = "ns"
+		}
+
+		if metric != nil {
+			byName[name] = metric
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]*metricspb.Metric, len(names))
+	for i, name := range names {
+		list[i] = byName[name]
+	}
+
+	return list
+}
+
+// sum adds a data point to the cumulative monotonic sum metric of the specified name, creating it if needed.
+func (e *Exporter) sum(byName map[string]*metricspb.Metric, name string,
+	dp *metricspb.NumberDataPoint) *metricspb.Metric {
+	metric, ok := byName[name]
+	if !ok {
+		metric = &metricspb.Metric{Name: name, Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}}
+	}
+
+	if sum, ok := metric.Data.(*metricspb.Metric_Sum); ok {
+		sum.Sum.DataPoints = append(sum.Sum.DataPoints, dp)
+	} else {
+		e.conflict(metric, "sum")
+	}
+
+	return metric
+}
+
+// gauge adds a data point to the gauge metric of the specified name, creating it if needed.
+func (e *Exporter) gauge(byName map[string]*metricspb.Metric, name string,
+	dp *metricspb.NumberDataPoint) *metricspb.Metric {
+	metric, ok := byName[name]
+	if !ok {
+		metric = &metricspb.Metric{Name: name, Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}}
+	}
+
+	if gauge, ok := metric.Data.(*metricspb.Metric_Gauge); ok {
+		gauge.Gauge.DataPoints = append(gauge.Gauge.DataPoints, dp)
+	} else {
+		e.conflict(metric, "gauge")
+	}
+
+	return metric
+}
+
+// histogram adds a data point of a histogram or timer counting its values in buckets to the explicit buckets
+// histogram metric of the specified name, creating it if needed.
+func (e *Exporter) histogram(byName map[string]*metricspb.Metric, name string, attributes []*commonpb.KeyValue,
+	start, timestamp uint64, count, sum, min, max int64, b buckets.Bucketed) *metricspb.Metric {
+	metric, ok := byName[name]
+	if !ok {
+		metric = &metricspb.Metric{Name: name, Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}}}
+	}
+
+	if h, ok := metric.Data.(*metricspb.Metric_Histogram); ok {
+		bounds, counts := b.Buckets()
+		dp := &metricspb.HistogramDataPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      timestamp,
+			Count:             uint64(count),
+			ExplicitBounds:    bounds,
+			BucketCounts:      make([]uint64, len(bounds)+1),
+		}
+
+		// OTLP bucket counts are not cumulative, the last one being the count of the values above the highest bound
+		var previous uint64
+		for i, c := range append(counts, uint64(count)) {
+			dp.BucketCounts[i] = c - previous
+			previous = c
+		}
+
+		if count > 0 {
+			s, mn, mx := float64(sum), float64(min), float64(max)
+			dp.Sum, dp.Min, dp.Max = &s, &mn, &mx
+		}
+
+		h.Histogram.DataPoints = append(h.Histogram.DataPoints, dp)
+	} else {
+		e.conflict(metric, "histogram")
+	}
+
+	return metric
+}
+
+// distribution adds a data point of a histogram or timer to the summary or exponential histogram metric of the
+// specified name, creating it if needed.
+func (e *Exporter) distribution(byName map[string]*metricspb.Metric, name string, attributes []*commonpb.KeyValue,
+	start, timestamp uint64, count, sum, min, max int64, percentiles func([]float64) []float64) *metricspb.Metric {
+	metric, ok := byName[name]
+
+	if e.config.Histograms == "exponential" {
+		if !ok {
+			metric = &metricspb.Metric{Name: name, Data: &metricspb.Metric_ExponentialHistogram{
+				ExponentialHistogram: &metricspb.ExponentialHistogram{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				},
+			}}
+		}
+
+		if h, ok := metric.Data.(*metricspb.Metric_ExponentialHistogram); ok {
+			dp := exponentialHistogram(count, percentiles(distributionPoints()))
+			dp.Attributes = attributes
+			dp.StartTimeUnixNano = start
+			dp.TimeUnixNano = timestamp
+			if count > 0 {
+				s, mn, mx := float64(sum), float64(min), float64(max)
+				dp.Sum, dp.Min, dp.Max = &s, &mn, &mx
+			}
+			h.ExponentialHistogram.DataPoints = append(h.ExponentialHistogram.DataPoints, dp)
+		} else {
+			e.conflict(metric, "exponential_histogram")
+		}
+
+		return metric
+	}
+
+	if !ok {
+		metric = &metricspb.Metric{Name: name, Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{}}}
+	}
+
+	if summary, ok := metric.Data.(*metricspb.Metric_Summary); ok {
+		dp := &metricspb.SummaryDataPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      timestamp,
+			Count:             uint64(count),
+			Sum:               float64(sum),
+		}
+
+		if count > 0 {
+			values := percentiles(summaryQuantiles)
+			values[0], values[len(values)-1] = float64(min), float64(max)
+			for i, q := range summaryQuantiles {
+				dp.QuantileValues = append(dp.QuantileValues,
+					&metricspb.SummaryDataPoint_ValueAtQuantile{Quantile: q, Value: values[i]})
+			}
+		}
+
+		summary.Summary.DataPoints = append(summary.Summary.DataPoints, dp)
+	} else {
+		e.conflict(metric, "summary")
+	}
+
+	return metric
+}
+
+// conflict logs, the first time only, a data point of the specified metric type skipped because its metric name is
+// already used by a metric of another type.
+func (e *Exporter) conflict(metric *metricspb.Metric, kind string) {
+	e.warnedMu.Lock()
+	defer e.warnedMu.Unlock()
+
+	if _, ok := e.warned[metric.Name]; ok || len(e.warned) >= maxConflicts {
+		return
+	}
+	e.warned[metric.Name] = struct{}{}
+
+	e.logger.Warn("skipping metric data point conflicting with a metric of another type",
+		"metric", metric.Name,
+		"type", kind,
+		"conflicting", metricKind(metric))
+}
+
+// metricKind returns the type of an OTLP metric.
+func metricKind(metric *metricspb.Metric) string {
+	switch metric.Data.(type) {
+	case *metricspb.Metric_Sum:
+		return "sum"
+	case *metricspb.Metric_Gauge:
+		return "gauge"
+	case *metricspb.Metric_Histogram:
+		return "histogram"
+	case *metricspb.Metric_ExponentialHistogram:
+		return "exponential_histogram"
+	case *metricspb.Metric_Summary:
+		return "summary"
+	default:
+		return "unknown"
+	}
+}
+
+// replaced returns true if the registry metric of a series is not the one previously exported. Metrics of
+// non-comparable types can't be told apart, and are considered unchanged.
+func replaced(previous, current interface{}) bool {
+	if reflect.TypeOf(previous) != reflect.TypeOf(current) {
+		return true
+	}
+
+	return reflect.TypeOf(current).Comparable() && previous != current
+}
+
+// distributionPoints returns the quantiles approximating the distribution of the values of histograms and timers,
+// each accounting for the same share of the values.
+func distributionPoints() []float64 {
+	points := make([]float64, distributionQuantiles)
+	for i := range points {
+		points[i] = (float64(i) + 0.5) / distributionQuantiles
+	}
+
+	return points
+}
+
+// exponentialHistogram returns an exponential histogram data point of count values, whose distribution is
+// approximated by the values at evenly spaced quantiles (sorted in ascending order). The scale is the highest one
+// allowing the values to fit in maxBuckets buckets per sign.
+func exponentialHistogram(count int64, values []float64) *metricspb.ExponentialHistogramDataPoint {
+	dp := &metricspb.ExponentialHistogramDataPoint{Count: uint64(count)}
+	if count <= 0 || len(values) == 0 {
+		return dp
+	}
+
+	scale := int32(maxScale)
+	for ; scale > minScale; scale-- {
+		if fits(values, scale, 1) && fits(values, scale, -1) {
+			break
+		}
+	}
+	dp.Scale = scale
+
+	positive := make(map[int32]uint64)
+	negative := make(map[int32]uint64)
+
+	// Each value accounts for count/len(values) values: cumulative rounding ensures the buckets counts add up to count.
+	var previous int64
+	for i, v := range values {
+		cumulative := int64(math.Round(float64(i+1) * float64(count) / float64(len(values))))
+		n := uint64(cumulative - previous)
+		previous = cumulative
+
+		switch {
+		case v > 0:
+			positive[bucketIndex(v, scale)] += n
+		case v < 0:
+			negative[bucketIndex(-v, scale)] += n
+		default:
+			dp.ZeroCount += n
+		}
+	}
+
+	dp.Positive = exponentialBuckets(positive)
+	dp.Negative = exponentialBuckets(negative)
+
+	return dp
+}
+
+// fits returns true if the values of the specified sign fit in maxBuckets buckets at the specified scale.
+func fits(values []float64, scale int32, sign float64) bool {
+	var (
+		min, max int32
+		found    bool
+	)
+
+	for _, v := range values {
+		if v*sign <= 0 {
+			continue
+		}
+
+		i := bucketIndex(v*sign, scale)
+		if !found || i < min {
+			min = i
+		}
+		if !found || i > max {
+			max = i
+		}
+		found = true
+	}
+
+	return !found || max-min < maxBuckets
+}
+
+// bucketIndex returns the index of the bucket of a positive value at the specified scale, i.e. the bucket whose
+// upper bound (inclusive) is base^(index+1) with base = 2^(2^-scale).
+func bucketIndex(v float64, scale int32) int32 {
+	// Powers of two are bucket boundaries at all scales: their index is computed exactly from their exponent.
+	if frac, exp := math.Frexp(v); frac == 0.5 {
+		if scale >= 0 {
+			return int32(exp-1)<<uint(scale) - 1
+		}
+		return int32(exp-1)>>uint(-scale) - 1
+	}
+
+	return int32(math.Ceil(math.Log(v)*math.Ldexp(math.Log2E, int(scale)))) - 1
+}
+
+// exponentialBuckets returns the OTLP representation of the buckets counts indexed by bucket index.
+func exponentialBuckets(counts map[int32]uint64) *metricspb.ExponentialHistogramDataPoint_Buckets {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	var min, max int32
+	first := true
+	for i := range counts {
+		if first || i < min {
+			min = i
+		}
+		if first || i > max {
+			max = i
+		}
+		first = false
+	}
+
+	b := &metricspb.ExponentialHistogramDataPoint_Buckets{
+		Offset:       min,
+		BucketCounts: make([]uint64, max-min+1),
+	}
+	for i, n := range counts {
+		b.BucketCounts[i-min] = n
+	}
+
+	return b
+}
+
+// newResource returns the resource reporting the metrics.
+func newResource(config *Config) (*resourcepb.Resource, error) {
+	attributes := map[string]string{
+		"service.name": config.ServiceName,
+	}
+	if config.ServiceName == "" {
+		attributes["service.name"] = filepath.Base(os.Args[0])
+	}
+
+	for k, v := range config.ResourceAttributes {
+		attributes[k] = v
+	}
+
+	if _, ok := attributes["host.name"]; !ok {
+		hostname, err := fqdn.Get()
+		if err != nil {
+			return nil, err
+		}
+		attributes["host.name"] = hostname
+	}
+
+	var resource resourcepb.Resource
+	for _, k := range labels.Labels(attributes).Keys() {
+		resource.Attributes = append(resource.Attributes, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: attributes[k]}},
+		})
+	}
+
+	return &resource, nil
+}
+
+// newHTTPClient returns the HTTP client used to send requests to the OTLP receiver.
+func newHTTPClient(config *Config) (*http.Client, error) {
+	client := &http.Client{Timeout: time.Duration(config.Timeout) * time.Second}
+
+	if config.TLS == nil {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.TLS.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if config.TLS.CAFile != "" {
+		caCerts, err := ioutil.ReadFile(config.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read TLS CA file: %s", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCerts) {
+			return nil, errors.New("no valid certificate found in TLS CA file")
+		}
+	}
+
+	if config.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLS.CertFile, config.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load TLS client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+
+	return client, nil
+}
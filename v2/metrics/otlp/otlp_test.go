@@ -0,0 +1,293 @@
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+)
+
+// fakeCollector returns a fake OTLP/HTTP receiver, sending the received export requests to the returned channel.
+func fakeCollector(t *testing.T, tls bool) (*httptest.Server, chan *colmetricspb.ExportMetricsServiceRequest) {
+	requests := make(chan *colmetricspb.ExportMetricsServiceRequest, 10)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultURLPath || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var body = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if !assert.NoError(t, err) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+
+		data, err := ioutil.ReadAll(body)
+		assert.NoError(t, err)
+
+		var req colmetricspb.ExportMetricsServiceRequest
+		assert.NoError(t, proto.Unmarshal(data, &req))
+		requests <- &req
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	})
+
+	if tls {
+		return httptest.NewTLSServer(handler), requests
+	}
+	return httptest.NewServer(handler), requests
+}
+
+func TestNew(t *testing.T) {
+	var (
+		testConfig = &Config{
+			Endpoint:           "127.0.0.1:4318",
+			ServiceName:        "test",
+			ResourceAttributes: map[string]string{"host.name": "test", "env": "prod"},
+		}
+		metrics = gometrics.NewRegistry()
+	)
+
+	exporter, err := New(testConfig, metrics)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	require.Equal(t, metrics, exporter.registry)
+	require.Equal(t, "https://127.0.0.1:4318/v1/metrics", exporter.url)
+	require.Len(t, exporter.resource.Attributes, 3)
+	require.Equal(t, "env", exporter.resource.Attributes[0].Key)
+	require.Equal(t, "service.name", exporter.resource.Attributes[2].Key)
+	require.Equal(t, "test", exporter.resource.Attributes[2].Value.GetStringValue())
+	require.Equal(t, testConfig, exporter.config)
+}
+
+func TestExporter_Start(t *testing.T) {
+	server, requests := fakeCollector(t, true)
+	defer server.Close()
+
+	// Trust the fake collector certificate
+	dir, err := ioutil.TempDir("", "otlp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test.counter", registry).Inc(42)
+	gometrics.NewRegisteredGauge("test.excluded", registry).Update(1)
+
+	exporter, err := New(&Config{
+		Endpoint:           strings.TrimPrefix(server.URL, "https://"),
+		TLS:                &TLSConfig{CAFile: caFile},
+		Headers:            map[string]string{"Authorization": "Bearer secret"},
+		Compression:        true,
+		ResourceAttributes: map[string]string{"host.name": "test"},
+		FlushInterval:      1,
+		Exclude:            []string{"*.excluded"},
+	}, registry)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	var req *colmetricspb.ExportMetricsServiceRequest
+	select {
+	case req = <-requests:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for metrics")
+	}
+
+	require.Len(t, req.ResourceMetrics, 1)
+	require.Len(t, req.ResourceMetrics[0].ScopeMetrics, 1)
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 1)
+	require.Equal(t, "test.counter", metrics[0].Name)
+	require.True(t, metrics[0].GetSum().IsMonotonic)
+	require.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		metrics[0].GetSum().AggregationTemporality)
+	require.Equal(t, int64(42), metrics[0].GetSum().DataPoints[0].GetAsInt())
+}
+
+func TestExporter_Export(t *testing.T) {
+	server, requests := fakeCollector(t, false)
+	defer server.Close()
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredGaugeFloat64(`test.gauge{code="200"}`, registry).Update(1.5)
+	gometrics.NewRegisteredGaugeFloat64(`test.gauge{code="500"}`, registry).Update(2.5)
+	histogram := gometrics.NewRegisteredHistogram("test.histogram", registry, gometrics.NewUniformSample(100))
+	for i := int64(1); i <= 10; i++ {
+		histogram.Update(i)
+	}
+
+	config := &Config{
+		Endpoint:           strings.TrimPrefix(server.URL, "http://"),
+		Insecure:           true,
+		Headers:            map[string]string{"Authorization": "Bearer secret"},
+		ResourceAttributes: map[string]string{"host.name": "test"},
+	}
+	exporter, err := New(config, registry)
+	require.NoError(t, err)
+	require.NoError(t, exporter.Export(context.Background()))
+
+	metrics := (<-requests).ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+
+	require.Equal(t, "test.gauge", metrics[0].Name)
+	require.Len(t, metrics[0].GetGauge().DataPoints, 2)
+	for _, dp := range metrics[0].GetGauge().DataPoints {
+		require.Equal(t, "code", dp.Attributes[0].Key)
+	}
+
+	require.Equal(t, "test.histogram", metrics[1].Name)
+	summary := metrics[1].GetSummary().DataPoints[0]
+	require.Equal(t, uint64(10), summary.Count)
+	require.Equal(t, float64(55), summary.Sum)
+	require.Equal(t, float64(1), summary.QuantileValues[0].Value)
+	require.Equal(t, float64(10), summary.QuantileValues[len(summary.QuantileValues)-1].Value)
+
+	config.Histograms = "exponential"
+	require.NoError(t, exporter.Export(context.Background()))
+
+	metrics = (<-requests).ResourceMetrics[0].ScopeMetrics[0].Metrics
+	dp := metrics[1].GetExponentialHistogram().DataPoints[0]
+	require.Equal(t, uint64(10), dp.Count)
+	require.Equal(t, float64(55), dp.GetSum())
+	require.Equal(t, float64(1), dp.GetMin())
+	require.Equal(t, float64(10), dp.GetMax())
+
+	var total uint64
+	for _, n := range dp.Positive.BucketCounts {
+		total += n
+	}
+	require.Equal(t, dp.Count, total+dp.ZeroCount)
+
+	// Histograms counting their values in buckets are exported as explicit buckets histograms
+	bucketed := buckets.NewTimer([]float64{1e6, 1e9}, gometrics.NewTimer())
+	defer bucketed.Stop()
+	require.NoError(t, registry.Register("test.timer", bucketed))
+	bucketed.Update(time.Millisecond)
+	bucketed.Update(time.Second)
+	bucketed.Update(time.Minute)
+	require.NoError(t, exporter.Export(context.Background()))
+
+	metrics = (<-requests).ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Equal(t, "test.timer", metrics[2].Name)
+	require.Equal(t, "ns", metrics[2].Unit)
+	hdp := metrics[2].GetHistogram().DataPoints[0]
+	require.Equal(t, uint64(3), hdp.Count)
+	require.Equal(t, []float64{1e6, 1e9}, hdp.ExplicitBounds)
+	require.Equal(t, []uint64{1, 1, 1}, hdp.BucketCounts)
+	require.Equal(t, float64(time.Minute), hdp.GetMax())
+
+	// Authentication errors are reported
+	config.Headers = nil
+	require.Error(t, exporter.Export(context.Background()))
+}
+
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Warn(msg string, _ ...interface{}) {
+	l.messages = append(l.messages, msg)
+}
+
+func TestExporter_Metrics_Conflict(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter(`test{code="200"}`, registry).Inc(1)
+	gometrics.NewRegisteredGauge(`test{code="500"}`, registry).Update(1)
+	gometrics.NewRegisteredGauge(`test{code="503"}`, registry).Update(1)
+
+	exporter, err := New(&Config{Endpoint: "127.0.0.1:4318"}, registry)
+	require.NoError(t, err)
+	logger := new(testLogger)
+	exporter.SetLogger(logger)
+
+	// The data points of the first registry name win, the conflicting ones are skipped and logged once
+	for i := 0; i < 2; i++ {
+		metrics := exporter.metrics(time.Now())
+		require.Len(t, metrics, 1)
+		require.Len(t, metrics[0].GetSum().DataPoints, 1)
+	}
+	require.Len(t, logger.messages, 1)
+}
+
+func TestExporter_Metrics_StartTime(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	counter := gometrics.NewRegisteredCounter("test.counter", registry)
+
+	exporter, err := New(&Config{Endpoint: "127.0.0.1:4318"}, registry)
+	require.NoError(t, err)
+	start := uint64(exporter.startTime.UnixNano())
+
+	first := time.Now()
+	require.Equal(t, start, exporter.metrics(first)[0].GetSum().DataPoints[0].StartTimeUnixNano)
+
+	// The start time of a series is kept as long as its registry metric is the same
+	counter.Inc(1)
+	second := first.Add(time.Second)
+	require.Equal(t, start, exporter.metrics(second)[0].GetSum().DataPoints[0].StartTimeUnixNano)
+
+	// A re-created series starts at the previous collection
+	registry.Unregister("test.counter")
+	gometrics.NewRegisteredCounter("test.counter", registry)
+	third := second.Add(time.Second)
+	require.Equal(t, uint64(second.UnixNano()),
+		exporter.metrics(third)[0].GetSum().DataPoints[0].StartTimeUnixNano)
+	require.Equal(t, uint64(second.UnixNano()),
+		exporter.metrics(third.Add(time.Second))[0].GetSum().DataPoints[0].StartTimeUnixNano)
+}
+
+func TestExponentialHistogram(t *testing.T) {
+	// 1 and 4 are 128 buckets apart at scale 6, 256 at scale 7
+	dp := exponentialHistogram(7, []float64{-2, 0, 1, 2, 4})
+	require.Equal(t, int32(6), dp.Scale)
+	require.Equal(t, uint64(7), dp.ZeroCount+sum(dp.Positive.BucketCounts)+sum(dp.Negative.BucketCounts))
+	require.Equal(t, bucketIndex(1, dp.Scale), dp.Positive.Offset)
+
+	// Values too far apart for the maximum scale are downscaled
+	dp = exponentialHistogram(2, []float64{1, 1e9})
+	require.Less(t, dp.Scale, int32(maxScale))
+	require.LessOrEqual(t, len(dp.Positive.BucketCounts), maxBuckets)
+	require.Equal(t, uint64(1), dp.Positive.BucketCounts[0])
+	require.Equal(t, uint64(1), dp.Positive.BucketCounts[len(dp.Positive.BucketCounts)-1])
+
+	// Buckets are (base^index, base^(index+1)]
+	require.Equal(t, int32(-2), bucketIndex(0.5, 0))
+	require.Equal(t, int32(-1), bucketIndex(1, 0))
+	require.Equal(t, int32(1), bucketIndex(3, 0))
+	require.Equal(t, int32(3), bucketIndex(3, 1))
+	require.Equal(t, int32(255), bucketIndex(4, 7))
+	require.Equal(t, int32(0), bucketIndex(4, -1))
+}
+
+func sum(counts []uint64) uint64 {
+	var total uint64
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
@@ -0,0 +1,242 @@
+package prometheus
+
+import (
+	"sort"
+	"sync"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+	"github.com/exoscale/go-reporter/v2/internal/naming"
+)
+
+var (
+	// Percentiles exported as buckets of the histograms and timers.
+	histogramBuckets = []float64{0.05, 0.1, 0.25, 0.50, 0.75, 0.9, 0.95, 0.99}
+	timerBuckets     = []float64{0.50, 0.95, 0.99, 0.999}
+)
+
+// maxCollisions represents the maximum number of colliding metric names logged. Further collisions are only skipped.
+const maxCollisions = 1000
+
+// registryCollector represents a Prometheus collector exporting the metrics of a go-metrics registry, reading them
+// at scrape time. Counters and gauges are exported as gauges, meters as gauges of their 1-minute rate, histograms as
+// a gauge of their last sample and a "<name>_histogram" histogram, timers as a gauge of their 1-minute rate and a
+// "<name>_timer" histogram. Labeled metrics are exported as Prometheus metrics with labels.
+//
+// The buckets of the histograms and timers histograms are the percentiles of their samples, unless they count their
+// values in buckets (see the buckets package), in which case they are native Prometheus histograms.
+//
+// Registry names are sanitized to comply with the Prometheus naming rules, so distinct registry names may end up as
+// the same Prometheus metric name (e.g. "a.b" and "a_b"). Since the Prometheus registry rejects the whole gathering
+// of inconsistent metric families, the metrics colliding with a metric of another registry name are skipped instead
+// (the first registry name in lexical order wins), and logged once.
+//
+// If the collector is periodically flushed, Collect returns the metrics read during the latest flush instead of
+// reading the registry.
+type registryCollector struct {
+	registry  metrics.Registry
+	namespace string
+	subsystem string
+
+	flushed   []prom.Metric // Metrics read during the latest flush
+	flushedMu sync.Mutex
+	periodic  bool
+
+	logger   Logger
+	warned   map[string]struct{} // Colliding metric names already logged
+	warnedMu sync.Mutex
+}
+
+// collection represents the state of a single collection of the registry metrics, used to detect the metrics
+// colliding once their name sanitized.
+type collection struct {
+	ch     chan<- prom.Metric
+	owners map[string]string   // Registry name of the metrics sent, per Prometheus metric name
+	series map[string]struct{} // Prometheus series sent
+}
+
+// Describe implements the prometheus.Collector interface. It doesn't send any descriptor since the registry metrics
+// are not known in advance, making registryCollector an unchecked collector.
+func (c *registryCollector) Describe(_ chan<- *prom.Desc) {}
+
+// Collect implements the prometheus.Collector interface.
+func (c *registryCollector) Collect(ch chan<- prom.Metric) {
+	if !c.periodic {
+		c.collect(ch)
+		return
+	}
+
+	c.flushedMu.Lock()
+	defer c.flushedMu.Unlock()
+
+	for _, m := range c.flushed {
+		ch <- m
+	}
+}
+
+// flush reads the metrics of the registry, to be returned by subsequent Collect calls.
+func (c *registryCollector) flush() {
+	ch := make(chan prom.Metric)
+	go func() {
+		c.collect(ch)
+		close(ch)
+	}()
+
+	var flushed []prom.Metric
+	for m := range ch {
+		flushed = append(flushed, m)
+	}
+
+	c.flushedMu.Lock()
+	c.flushed = flushed
+	c.flushedMu.Unlock()
+}
+
+// collect reads the metrics of the registry and sends them to ch.
+func (c *registryCollector) collect(ch chan<- prom.Metric) {
+	registered := make(map[string]interface{})
+	c.registry.Each(func(name string, i interface{}) {
+		registered[name] = i
+	})
+
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	col := &collection{
+		ch:     ch,
+		owners: make(map[string]string),
+		series: make(map[string]struct{}),
+	}
+
+	for _, key := range names {
+		name, l := labels.Decode(key)
+
+		switch metric := registered[key].(type) {
+		case metrics.Counter:
+			c.gauge(col, name, l, float64(metric.Count()))
+
+		case metrics.Gauge:
+			c.gauge(col, name, l, float64(metric.Value()))
+
+		case metrics.GaugeFloat64:
+			c.gauge(col, name, l, metric.Value())
+
+		case metrics.Histogram:
+			snapshot := metric.Snapshot()
+			if samples := snapshot.Sample().Values(); len(samples) > 0 {
+				c.gauge(col, name, l, float64(samples[len(samples)-1]))
+			}
+			c.histogram(col, name+"_histogram", l, snapshot.Count(), snapshot.Sum(),
+				bucketValues(snapshot, histogramBuckets, snapshot.Percentiles))
+
+		case metrics.Meter:
+			c.gauge(col, name, l, metric.Snapshot().Rate1())
+
+		case metrics.Timer:
+			snapshot := metric.Snapshot()
+			c.gauge(col, name, l, snapshot.Rate1())
+			c.histogram(col, name+"_timer", l, snapshot.Count(), snapshot.Sum(),
+				bucketValues(snapshot, timerBuckets, snapshot.Percentiles))
+		}
+	}
+}
+
+func (c *registryCollector) gauge(col *collection, name string, l labels.Labels, value float64) {
+	desc, ok := c.desc(col, name, l)
+	if !ok {
+		return
+	}
+
+	m, err := prom.NewConstMetric(desc, prom.GaugeValue, value, l.Values()...)
+	if err != nil {
+		m = prom.NewInvalidMetric(desc, err)
+	}
+
+	col.ch <- m
+}
+
+func (c *registryCollector) histogram(col *collection, name string, l labels.Labels, count, sum int64,
+	bucketValues map[float64]uint64) {
+	desc, ok := c.desc(col, name, l)
+	if !ok {
+		return
+	}
+
+	m, err := prom.NewConstHistogram(desc, uint64(count), float64(sum), bucketValues, l.Values()...)
+	if err != nil {
+		m = prom.NewInvalidMetric(desc, err)
+	}
+
+	col.ch <- m
+}
+
+// bucketValues returns the buckets of the histogram of a go-metrics histogram or timer snapshot: its bucket counts if
+// it counts its values in buckets, otherwise the percentiles ps of its samples.
+func bucketValues(snapshot interface{}, ps []float64, percentiles func([]float64) []float64) map[float64]uint64 {
+	if b, ok := snapshot.(buckets.Bucketed); ok {
+		bounds, counts := b.Buckets()
+		values := make(map[float64]uint64, len(bounds))
+		for i, bound := range bounds {
+			values[bound] = counts[i]
+		}
+		return values
+	}
+
+	values := make(map[float64]uint64, len(ps))
+	for i, v := range percentiles(ps) {
+		values[ps[i]] = uint64(v)
+	}
+
+	return values
+}
+
+// desc returns the descriptor of the Prometheus metric name with labels l. It returns false if the metric collides
+// with a metric already sent during the collection col once their name and label keys sanitized, in which case it
+// must be skipped. The help string is the sanitized metric name, so that it is the same for all the metrics of a
+// family.
+func (c *registryCollector) desc(col *collection, name string, l labels.Labels) (*prom.Desc, bool) {
+	keys := l.Keys()
+	sanitized := make(labels.Labels, len(keys))
+	for i, k := range keys {
+		keys[i] = naming.PrometheusLabel(k)
+		sanitized[keys[i]] = l[k]
+	}
+
+	fqName := naming.Prometheus(prom.BuildFQName(c.namespace, c.subsystem, name))
+	seriesKey := labels.Encode(fqName, sanitized)
+
+	if owner, ok := col.owners[fqName]; ok && owner != name {
+		c.warnOnce(fqName, "skipping metric colliding with another metric once sanitized",
+			"metric", name,
+			"colliding", owner)
+		return nil, false
+	}
+	if _, ok := col.series[seriesKey]; ok {
+		c.warnOnce(seriesKey, "skipping metric series colliding with another series once sanitized",
+			"metric", labels.Encode(name, l))
+		return nil, false
+	}
+	col.owners[fqName] = name
+	col.series[seriesKey] = struct{}{}
+
+	return prom.NewDesc(fqName, fqName, keys, nil), true
+}
+
+// warnOnce logs a colliding metric the first time only.
+func (c *registryCollector) warnOnce(key, msg string, ctx ...interface{}) {
+	c.warnedMu.Lock()
+	defer c.warnedMu.Unlock()
+
+	if _, ok := c.warned[key]; ok || len(c.warned) >= maxCollisions {
+		return
+	}
+	c.warned[key] = struct{}{}
+
+	c.logger.Warn(msg, append([]interface{}{"prometheus_name", key}, ctx...)...)
+}
@@ -1,22 +1,76 @@
 package prometheus
 
 import (
+	"errors"
+	"strings"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
 )
 
 const (
-	defaultFlushIntervalSec = 5
+	defaultPath = "/"
 )
 
+// TLSConfig represents a Prometheus metrics scraping endpoint TLS configuration.
+type TLSConfig struct {
+	// CertFile represents the path to the server certificate file (PEM-encoded).
+	CertFile string `yaml:"cert_file"`
+
+	// KeyFile represents the path to the server certificate private key file (PEM-encoded).
+	KeyFile string `yaml:"key_file"`
+
+	// ClientCAFile represents the path to a CA certificates bundle file (PEM-encoded). If specified, clients must
+	// present a certificate signed by one of these CAs (mutual TLS).
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+func (c *TLSConfig) validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.CertFile, validation.Required),
+		validation.Field(&c.KeyFile, validation.Required))
+}
+
+// BasicAuthConfig represents a Prometheus metrics scraping endpoint HTTP basic authentication configuration.
+type BasicAuthConfig struct {
+	// Username represents the username expected from the clients.
+	Username string `yaml:"username"`
+
+	// Password represents the password expected from the clients.
+	Password string `yaml:"password"`
+}
+
+func (c *BasicAuthConfig) validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Username, validation.Required),
+		validation.Field(&c.Password, validation.Required))
+}
+
 // Config represents a prometheus metrics export configuration.
 type Config struct {
 	// Listen represents a net.Dial compatible string indicating the network address to bind the Prometheus metrics
 	// scraping endpoint server. If not specified, the server won't be started.
 	Listen string `yaml:"listen"`
 
+	// Path represents the URL path of the Prometheus metrics scraping endpoint. If not specified, the metrics are
+	// served on any path.
+	Path string `yaml:"path"`
+
+	// TLS represents the scraping endpoint TLS configuration. If not specified, the endpoint is served over plain
+	// HTTP.
+	TLS *TLSConfig `yaml:"tls"`
+
+	// BearerToken represents a token clients must present in an "Authorization: Bearer <token>" request header.
+	// Mutually exclusive with BasicAuth.
+	BearerToken string `yaml:"bearer_token"`
+
+	// BasicAuth represents the credentials clients must present using HTTP basic authentication. Mutually exclusive
+	// with BearerToken.
+	BasicAuth *BasicAuthConfig `yaml:"basic_auth"`
+
 	// FlushInterval represents the time interval in seconds at which the metrics reporter's registry metrics are
-	// flushed to the Prometheus registry.
+	// flushed to the Prometheus registry. If not specified, the metrics are read from the metrics reporter's
+	// registry at scrape time.
 	FlushInterval int `yaml:"flush_interval"`
 
 	// Namespace represents the namespace to apply to registered Prometheus metrics.
@@ -31,12 +85,39 @@ type Config struct {
 }
 
 func (c *Config) validate() error {
-	if c.FlushInterval <= 0 {
-		c.FlushInterval = defaultFlushIntervalSec
+	if c.Path == "" {
+		c.Path = defaultPath
+	}
+
+	if c.TLS != nil {
+		if err := c.TLS.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.BasicAuth != nil {
+		if err := c.BasicAuth.validate(); err != nil {
+			return err
+		}
 	}
 
 	return validation.ValidateStruct(c,
 		validation.Field(&c.Listen,
 			validation.When(c.Listen != "", is.DialString)),
+		validation.Field(&c.FlushInterval, validation.Min(0)),
+		validation.Field(&c.Path,
+			validation.By(func(v interface{}) error {
+				if !strings.HasPrefix(v.(string), "/") {
+					return errors.New("must be an absolute URL path")
+				}
+				return nil
+			})),
+		validation.Field(&c.BearerToken,
+			validation.By(func(v interface{}) error {
+				if v.(string) != "" && c.BasicAuth != nil {
+					return errors.New("mutually exclusive with basic_auth")
+				}
+				return nil
+			})),
 	)
 }
@@ -9,6 +9,21 @@ import (
 func TestConfig_Validate(t *testing.T) {
 	testConfig := new(Config)
 	require.NoError(t, testConfig.validate())
-	require.Equal(t, defaultFlushIntervalSec, testConfig.FlushInterval,
-		"should have been set to default value")
+	require.Zero(t, testConfig.FlushInterval, "should have been left unset")
+
+	require.Error(t, (&Config{FlushInterval: -1}).validate())
+}
+
+func TestConfig_Validate_Endpoint(t *testing.T) {
+	testConfig := new(Config)
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultPath, testConfig.Path, "should have been set to default value")
+
+	require.Error(t, (&Config{Path: "metrics"}).validate())
+	require.Error(t, (&Config{TLS: &TLSConfig{CertFile: "cert.pem"}}).validate())
+	require.Error(t, (&Config{BasicAuth: &BasicAuthConfig{Username: "prom"}}).validate())
+	require.Error(t, (&Config{
+		BearerToken: "s3cr3t",
+		BasicAuth:   &BasicAuthConfig{Username: "prom", Password: "s3cr3t"},
+	}).validate())
 }
@@ -3,22 +3,36 @@ package prometheus
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"time"
 
-	prometheusmetrics "github.com/deathowl/go-metrics-prometheus"
 	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/rcrowley/go-metrics"
 	"gopkg.in/tomb.v2"
 
 	"github.com/exoscale/go-reporter/v2/internal/debug"
 )
 
+// Logger represents the interface of the logger used by the exporter to log the go-metrics registry metrics skipped
+// because their name collides with another one once sanitized.
+type Logger interface {
+	Warn(msg string, ctx ...interface{})
+}
+
 // Exporter represents a metrics exporter to a Prometheus server.
 type Exporter struct {
-	registry *prom.Registry
-	pm       *prometheusmetrics.PrometheusConfig
+	registry  *prom.Registry
+	collector *registryCollector
+	listener  net.Listener
 
 	t      *tomb.Tomb // Goroutines manager
 	config *Config
@@ -28,7 +42,8 @@ type Exporter struct {
 
 // New returns a new Prometheus metrics exporter based on provided configuration.
 // If a go-metrics registry is provided, it is hooked into the exporter's internal Prometheus registry and its
-// metrics will be flushed periodically to the Prometheus endpoint (flush interval to be specified in the config).
+// metrics are read when the Prometheus registry is gathered, or flushed periodically if a flush interval is
+// specified in the config.
 func New(config *Config, registry metrics.Registry) (*Exporter, error) {
 	var exporter Exporter
 
@@ -52,12 +67,18 @@ func New(config *Config, registry metrics.Registry) (*Exporter, error) {
 	if registry != nil {
 		exporter.Debug("enabling go-metrics registry export to Prometheus")
 
-		exporter.pm = prometheusmetrics.NewPrometheusProvider(
-			registry,
-			exporter.config.Namespace,
-			exporter.config.Subsystem,
-			exporter.registry,
-			time.Duration(exporter.config.FlushInterval)*time.Second)
+		exporter.collector = &registryCollector{
+			registry:  registry,
+			namespace: config.Namespace,
+			subsystem: config.Subsystem,
+			periodic:  config.FlushInterval > 0,
+			logger:    exporter.D,
+			warned:    make(map[string]struct{}),
+		}
+
+		if err := exporter.registry.Register(exporter.collector); err != nil {
+			return nil, err
+		}
 	}
 
 	return &exporter, nil
@@ -68,6 +89,27 @@ func (e *Exporter) HTTPHandler() http.Handler {
 	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
 }
 
+// Gather implements the prometheus.Gatherer interface: it returns the metrics of the Prometheus registry, including
+// the current metrics of the go-metrics registry provided during exporter initialization (if any). This allows to
+// expose the exporter's metrics by other means than the scraping endpoint (e.g. pushing them to a Pushgateway).
+func (e *Exporter) Gather() ([]*dto.MetricFamily, error) {
+	if e.flushing() {
+		e.collector.flush()
+	}
+
+	return e.registry.Gather()
+}
+
+// SetLogger sets the logger used to log the go-metrics registry metrics skipped because their name collides with
+// another one once sanitized. By default, they are logged in debug mode only.
+func (e *Exporter) SetLogger(logger Logger) {
+	if e.collector != nil {
+		e.collector.warnedMu.Lock()
+		e.collector.logger = logger
+		e.collector.warnedMu.Unlock()
+	}
+}
+
 // Register registers the provided metric to the Prometheus exporter registry.
 func (e *Exporter) Register(m prom.Collector) error {
 	return e.registry.Register(m)
@@ -78,23 +120,36 @@ func (e *Exporter) MustRegister(m prom.Collector) {
 	e.registry.MustRegister(m)
 }
 
-// Start starts the metrics exporter.
+// Start starts the metrics exporter. If the scraping endpoint server is enabled, its network address is bound before
+// returning: binding errors are returned to the caller.
 func (e *Exporter) Start(ctx context.Context) error {
 	// Before initializing the goroutines management tomb we have to check that we actually have goroutines to
 	// handle with it, otherwise it'll get stuck during shutdown (see Stop() method).
-	if e.pm == nil && e.config.Listen == "" {
+	if !e.flushing() && e.config.Listen == "" {
 		return nil
 	}
 
+	var server *http.Server
+	if e.config.Listen != "" {
+		var err error
+		if server, err = e.newServer(); err != nil {
+			return err
+		}
+
+		if e.listener, err = net.Listen("tcp", e.config.Listen); err != nil {
+			return fmt.Errorf("unable to bind scraping endpoint server: %s", err)
+		}
+	}
+
 	e.t, _ = tomb.WithContext(ctx)
 
-	if e.pm != nil {
+	if e.flushing() {
 		e.t.Go(e.registryFlushLoop)
 	}
 
-	if e.config.Listen != "" {
+	if server != nil {
 		e.t.Go(func() error {
-			return e.serveHTTP(nil)
+			return e.serveHTTP(e.listener, server)
 		})
 	}
 
@@ -114,6 +169,12 @@ func (e *Exporter) Stop(_ context.Context) error {
 	return e.t.Wait()
 }
 
+// flushing returns true if the go-metrics registry provided during exporter initialization is flushed periodically
+// instead of being read at scrape time.
+func (e *Exporter) flushing() bool {
+	return e.collector != nil && e.collector.periodic
+}
+
 // registryFlushLoop periodically flushes the go-metrics registry provided during exporter initialization to the
 // Prometheus registry. This method blocks the caller until the exporter's tomb dies.
 func (e *Exporter) registryFlushLoop() error {
@@ -125,9 +186,7 @@ func (e *Exporter) registryFlushLoop() error {
 	for {
 		select {
 		case <-tick.C:
-			if err := e.pm.UpdatePrometheusMetricsOnce(); err != nil {
-				e.Error("unable to flush go-metrics registry", "err", err)
-			}
+			e.collector.flush()
 
 		case <-e.t.Dying():
 			e.Debug("terminating go-metrics flush loop")
@@ -136,19 +195,95 @@ func (e *Exporter) registryFlushLoop() error {
 	}
 }
 
-// serveHTTP runs an HTTP server to serve the Prometheus metrics scraping endpoint. This method blocks the caller
-// until the exporter's tomb dies.
-func (e *Exporter) serveHTTP(server *http.Server) error {
-	if server == nil {
-		server = &http.Server{
-			Addr:    e.config.Listen,
-			Handler: e.HTTPHandler(),
+// newServer returns the HTTP server of the Prometheus metrics scraping endpoint.
+func (e *Exporter) newServer() (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle(e.config.Path, e.authenticate(e.HTTPHandler()))
+
+	server := &http.Server{
+		Addr:    e.config.Listen,
+		Handler: mux,
+	}
+
+	if e.config.TLS != nil {
+		cert, err := tls.LoadX509KeyPair(e.config.TLS.CertFile, e.config.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load TLS certificate: %s", err)
+		}
+
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+
+		if e.config.TLS.ClientCAFile != "" {
+			caCerts, err := ioutil.ReadFile(e.config.TLS.ClientCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read TLS client CA file: %s", err)
+			}
+
+			clientCAs := x509.NewCertPool()
+			if !clientCAs.AppendCertsFromPEM(caCerts) {
+				return nil, errors.New("no valid certificate found in TLS client CA file")
+			}
+
+			server.TLSConfig.ClientCAs = clientCAs
+			server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
 		}
 	}
 
-	e.Debug("starting scraping endpoint server")
+	return server, nil
+}
 
-	go server.ListenAndServe()
+// authenticate returns an HTTP handler enforcing the configured client authentication (if any) before passing the
+// requests to the handler h.
+func (e *Exporter) authenticate(h http.Handler) http.Handler {
+	switch {
+	case e.config.BearerToken != "":
+		expected := []byte("Bearer " + e.config.BearerToken)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+
+	case e.config.BasicAuth != nil:
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok ||
+				subtle.ConstantTimeCompare([]byte(username), []byte(e.config.BasicAuth.Username)) != 1 ||
+				subtle.ConstantTimeCompare([]byte(password), []byte(e.config.BasicAuth.Password)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	return h
+}
+
+// serveHTTP runs an HTTP server on the listener l to serve the Prometheus metrics scraping endpoint. This method
+// blocks the caller until the exporter's tomb dies.
+func (e *Exporter) serveHTTP(l net.Listener, server *http.Server) error {
+	e.Debug("starting scraping endpoint server", "address", l.Addr(), "tls", server.TLSConfig != nil)
+
+	e.t.Go(func() error {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ServeTLS(l, "", "")
+		} else {
+			err = server.Serve(l)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
 
 	_ = <-e.t.Dying()
 	e.Debug("terminating scraping endpoint server")
@@ -2,10 +2,21 @@ package prometheus
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +24,8 @@ import (
 	gometrics "github.com/rcrowley/go-metrics"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
 )
 
 func TestNew(t *testing.T) {
@@ -25,7 +38,8 @@ func TestNew(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, exporter)
 	require.NotNil(t, exporter.registry)
-	require.NotNil(t, exporter.pm)
+	require.NotNil(t, exporter.collector)
+	require.False(t, exporter.collector.periodic)
 	require.Equal(t, testConfig, exporter.config)
 }
 
@@ -52,17 +66,79 @@ func TestExporter_Register(t *testing.T) {
 	require.Equal(t, testMetricHelp, registeredMetrics[0].GetHelp())
 }
 
+func TestExporter_Gather(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test", registry).Inc(42)
+
+	exporter, err := New(new(Config), registry)
+	require.NoError(t, err)
+
+	families, err := exporter.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Equal(t, "test", families[0].GetName())
+	require.Equal(t, 42.0, families[0].GetMetric()[0].GetGauge().GetValue())
+}
+
 func TestExporter_Start(t *testing.T) {
 	exporter, err := New(&Config{}, nil)
 	require.NoError(t, err)
 
 	require.NoError(t, exporter.Start(context.Background()))
+
+	// No flush loop is started if the registry is read at scrape time
+	exporter, err = New(&Config{}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	require.Nil(t, exporter.t)
+}
+
+func TestExporter_scrapeTime(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	counter := gometrics.NewRegisteredCounter("test.counter", registry)
+	histogram := gometrics.NewRegisteredHistogram("test.histogram", registry, gometrics.NewUniformSample(10))
+	histogram.Update(3)
+
+	exporter, err := New(&Config{Namespace: "app"}, registry)
+	require.NoError(t, err)
+
+	values := func() map[string]float64 {
+		families, err := exporter.registry.Gather()
+		require.NoError(t, err)
+
+		values := make(map[string]float64)
+		for _, f := range families {
+			m := f.GetMetric()[0]
+			if m.GetHistogram() != nil {
+				values[f.GetName()] = float64(m.GetHistogram().GetSampleCount())
+			} else {
+				values[f.GetName()] = m.GetGauge().GetValue()
+			}
+		}
+		return values
+	}
+
+	require.Equal(t, map[string]float64{
+		"app_test_counter":             0,
+		"app_test_histogram":           3,
+		"app_test_histogram_histogram": 1,
+	}, values())
+
+	// Updates are visible to the next scrape without any flush
+	counter.Inc(42)
+	histogram.Update(5)
+	require.Equal(t, map[string]float64{
+		"app_test_counter":             42,
+		"app_test_histogram":           5,
+		"app_test_histogram_histogram": 2,
+	}, values())
 }
 
 func TestExporter_Stop(t *testing.T) {
 	var (
 		testCtx    = context.Background()
-		testConfig = new(Config)
+		testConfig = &Config{FlushInterval: 1}
 		metrics    = gometrics.NewRegistry()
 	)
 
@@ -130,12 +206,16 @@ func TestExporter_registryFlushLoop(t *testing.T) {
 			"exporter tomb failed to be killed")
 	}()
 
-	time.Sleep(time.Duration(testConfig.FlushInterval) * time.Second * 2)
+	// Scrapes return the metrics of the latest flush
 	registeredMetrics, err := exporter.registry.Gather()
 	require.NoError(t, err)
+	require.Len(t, registeredMetrics, 0)
+
+	time.Sleep(time.Duration(testConfig.FlushInterval) * time.Second * 2)
+	registeredMetrics, err = exporter.registry.Gather()
+	require.NoError(t, err)
 	require.Len(t, registeredMetrics, 1)
 	require.Equal(t, testMetricName, registeredMetrics[0].GetName())
-
 }
 
 func TestExporter_serveHTTP(t *testing.T) {
@@ -156,10 +236,11 @@ func TestExporter_serveHTTP(t *testing.T) {
 	require.NoError(t, exporter.Register(testMetric))
 
 	testMetric.Set(testMetricValue)
-	ts := httptest.NewServer(exporter.HTTPHandler())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
 	exporter.t, _ = tomb.WithContext(context.Background())
 	exporter.t.Go(func() error {
-		return exporter.serveHTTP(ts.Config)
+		return exporter.serveHTTP(listener, &http.Server{Handler: exporter.HTTPHandler()})
 	})
 	defer func() {
 		exporter.t.Kill(nil)
@@ -170,7 +251,7 @@ func TestExporter_serveHTTP(t *testing.T) {
 			"exporter tomb failed to be killed")
 	}()
 
-	res, err := ts.Client().Get(ts.URL)
+	res, err := http.Get("http://" + listener.Addr().String())
 	require.NoError(t, err)
 	testCheckHTTPResponse(t, res, testMetricName, testMetricHelp, testMetricValue)
 }
@@ -190,3 +271,294 @@ func testCheckHTTPResponse(t *testing.T, res *http.Response, metricName, metricH
 		int64(metricValue),
 	), string(body))
 }
+
+func TestExporter_labeledMetrics(t *testing.T) {
+	var testConfig = &Config{Namespace: "app"}
+
+	registry := gometrics.NewRegistry()
+	require.NoError(t, registry.Register("requests.total", gometrics.NewCounter()))
+	labeled := gometrics.NewCounter()
+	labeled.Inc(3)
+	require.NoError(t, registry.Register(`requests.total{code="200",http.method="GET"}`, labeled))
+	require.NoError(t, registry.Register(`latency{code="200"}`, gometrics.NewTimer()))
+
+	exporter, err := New(testConfig, registry)
+	require.NoError(t, err)
+	families, err := exporter.registry.Gather()
+	require.NoError(t, err)
+
+	exported := make(map[string]int)
+	for _, f := range families {
+		exported[f.GetName()] = len(f.GetMetric())
+	}
+	require.Equal(t, map[string]int{
+		"app_requests_total": 2,
+		"app_latency":        1,
+		"app_latency_timer":  1,
+	}, exported)
+
+	for _, f := range families {
+		if f.GetName() != "app_requests_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			if len(m.GetLabel()) == 0 {
+				continue
+			}
+			require.Len(t, m.GetLabel(), 2)
+			require.Equal(t, "code", m.GetLabel()[0].GetName())
+			require.Equal(t, "200", m.GetLabel()[0].GetValue())
+			require.Equal(t, "http_method", m.GetLabel()[1].GetName())
+			require.Equal(t, "GET", m.GetLabel()[1].GetValue())
+			require.Equal(t, 3.0, m.GetGauge().GetValue())
+		}
+	}
+}
+
+func TestExporter_bucketedMetrics(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	histogram := buckets.NewHistogram([]float64{1, 10}, gometrics.NewHistogram(gometrics.NewUniformSample(10)))
+	require.NoError(t, registry.Register(`size{code="200"}`, histogram))
+	for _, v := range []int64{1, 5, 5, 50} {
+		histogram.Update(v)
+	}
+
+	exporter, err := New(&Config{}, registry)
+	require.NoError(t, err)
+
+	families, err := exporter.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 2)
+	require.Equal(t, "size_histogram", families[1].GetName())
+
+	h := families[1].GetMetric()[0].GetHistogram()
+	require.Equal(t, uint64(4), h.GetSampleCount())
+	require.Equal(t, float64(61), h.GetSampleSum())
+	require.Len(t, h.GetBucket(), 2)
+	require.Equal(t, float64(1), h.GetBucket()[0].GetUpperBound())
+	require.Equal(t, uint64(1), h.GetBucket()[0].GetCumulativeCount())
+	require.Equal(t, float64(10), h.GetBucket()[1].GetUpperBound())
+	require.Equal(t, uint64(3), h.GetBucket()[1].GetCumulativeCount())
+}
+
+func TestExporter_Start_BindError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	exporter, err := New(&Config{Listen: listener.Addr().String()}, nil)
+	require.NoError(t, err)
+
+	require.Error(t, exporter.Start(context.Background()))
+	require.Nil(t, exporter.t)
+	require.NoError(t, exporter.Stop(context.Background()))
+}
+
+func TestExporter_Start_Endpoint(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		config  Config
+		request func(*http.Request)
+		path    string
+		status  int
+	}{
+		{name: "default path", path: "/anything", status: http.StatusOK},
+		{name: "custom path", config: Config{Path: "/metrics"}, path: "/metrics", status: http.StatusOK},
+		{name: "custom path not found", config: Config{Path: "/metrics"}, path: "/", status: http.StatusNotFound},
+		{
+			name:    "bearer token",
+			config:  Config{BearerToken: "s3cr3t"},
+			request: func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cr3t") },
+			status:  http.StatusOK,
+		},
+		{
+			name:    "bearer token invalid",
+			config:  Config{BearerToken: "s3cr3t"},
+			request: func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") },
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name:    "basic auth",
+			config:  Config{BasicAuth: &BasicAuthConfig{Username: "prom", Password: "s3cr3t"}},
+			request: func(r *http.Request) { r.SetBasicAuth("prom", "s3cr3t") },
+			status:  http.StatusOK,
+		},
+		{
+			name:   "basic auth missing",
+			config: Config{BasicAuth: &BasicAuthConfig{Username: "prom", Password: "s3cr3t"}},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config.Listen = testFreeAddr(t)
+			exporter, err := New(&tc.config, nil)
+			require.NoError(t, err)
+			require.NoError(t, exporter.Start(context.Background()))
+			defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+			req, err := http.NewRequest(http.MethodGet, "http://"+exporter.listener.Addr().String()+tc.path, nil)
+			require.NoError(t, err)
+			if tc.request != nil {
+				tc.request(req)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			res.Body.Close()
+			require.Equal(t, tc.status, res.StatusCode)
+		})
+	}
+}
+
+func TestExporter_Start_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prometheus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, caKey := testCertificate(t, nil, nil, "ca")
+	serverCert, serverKey := testCertificate(t, ca, caKey, "127.0.0.1")
+	clientCert, clientKey := testCertificate(t, ca, caKey, "client")
+
+	tlsConfig := &TLSConfig{
+		CertFile:     testWritePEM(t, dir, "server.crt", "CERTIFICATE", serverCert.Raw),
+		KeyFile:      testWritePEM(t, dir, "server.key", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(serverKey)),
+		ClientCAFile: testWritePEM(t, dir, "ca.crt", "CERTIFICATE", ca.Raw),
+	}
+
+	exporter, err := New(&Config{Listen: testFreeAddr(t), TLS: tlsConfig}, nil)
+	require.NoError(t, err)
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca)
+	url := "https://" + exporter.listener.Addr().String()
+
+	// Without client certificate
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}}}
+	_, err = client.Get(url)
+	require.Error(t, err)
+
+	// With client certificate
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs: rootCAs,
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{clientCert.Raw},
+			PrivateKey:  clientKey,
+		}},
+	}}}
+	res, err := client.Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestExporter_Start_TLSInvalidCertificate(t *testing.T) {
+	exporter, err := New(&Config{
+		Listen: testFreeAddr(t),
+		TLS:    &TLSConfig{CertFile: "/nonexistent.crt", KeyFile: "/nonexistent.key"},
+	}, nil)
+	require.NoError(t, err)
+	require.Error(t, exporter.Start(context.Background()))
+}
+
+// testCertificate returns a new certificate for the specified common name, signed by the parent certificate or
+// self-signed if parent is nil.
+func testCertificate(t *testing.T, parent *x509.Certificate, parentKey *rsa.PrivateKey,
+	cn string) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if ip := net.ParseIP(cn); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+func testWritePEM(t *testing.T, dir, name, blockType string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600))
+
+	return path
+}
+
+// testFreeAddr returns a local network address available for binding.
+func testFreeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+type testLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *testLogger) Warn(msg string, _ ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages = append(l.messages, msg)
+}
+
+func TestExporter_collidingMetrics(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("a.b", registry).Inc(1)
+	gometrics.NewRegisteredCounter("a_b", registry).Inc(2)
+	gometrics.NewRegisteredHistogram("c", registry, gometrics.NewUniformSample(10)).Update(3)
+	gometrics.NewRegisteredCounter("c_histogram", registry).Inc(4)
+
+	exporter, err := New(new(Config), registry)
+	require.NoError(t, err)
+
+	logger := new(testLogger)
+	exporter.SetLogger(logger)
+
+	ts := httptest.NewServer(exporter.HTTPHandler())
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		res, err := ts.Client().Get(ts.URL)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	families, err := exporter.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, f := range families {
+		require.Equal(t, f.GetName(), f.GetHelp())
+		require.Len(t, f.GetMetric(), 1)
+		values[f.GetName()] = f.GetMetric()[0].GetGauge().GetValue()
+	}
+	require.Equal(t, map[string]float64{"a_b": 1, "c": 3, "c_histogram": 0}, values)
+
+	// Collisions are logged once
+	require.Len(t, logger.messages, 2)
+}
@@ -0,0 +1,94 @@
+package pushgateway
+
+import (
+	"errors"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultTimeoutSec = 10
+)
+
+// TLSConfig represents a Pushgateway client TLS configuration.
+type TLSConfig struct {
+	// CAFile represents the path to a CA certificates bundle file (PEM-encoded) used to verify the Pushgateway
+	// server certificate. If not specified, the system CA certificates are used.
+	CAFile string `yaml:"ca_file"`
+
+	// CertFile represents the path to a client certificate file (PEM-encoded), for mutual TLS.
+	CertFile string `yaml:"cert_file"`
+
+	// KeyFile represents the path to the client certificate private key file (PEM-encoded), for mutual TLS.
+	KeyFile string `yaml:"key_file"`
+
+	// InsecureSkipVerify represents a flag indicating whether to skip the Pushgateway server certificate
+	// verification. This is mainly for testing purposes.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+func (c *TLSConfig) validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.CertFile, validation.When(c.KeyFile != "", validation.Required)),
+		validation.Field(&c.KeyFile, validation.When(c.CertFile != "", validation.Required)))
+}
+
+// Config represents a Prometheus Pushgateway metrics export configuration.
+type Config struct {
+	// URL represents the base URL of the Pushgateway (e.g. "https://pushgateway.example.net:9091").
+	URL string `yaml:"url"`
+
+	// Job represents the value of the "job" grouping label of the pushed metrics.
+	Job string `yaml:"job"`
+
+	// Grouping represents additional grouping labels of the pushed metrics (e.g. "instance").
+	Grouping map[string]string `yaml:"grouping"`
+
+	// PushInterval represents the time interval in seconds at which to push metrics to the Pushgateway. If not
+	// specified, metrics are not pushed periodically.
+	PushInterval int `yaml:"push_interval"`
+
+	// PushOnStop represents a flag indicating whether to push metrics a last time when the exporter is stopped.
+	// Mutually exclusive with DeleteOnStop.
+	PushOnStop bool `yaml:"push_on_stop"`
+
+	// DeleteOnStop represents a flag indicating whether to delete the pushed metrics from the Pushgateway when the
+	// exporter is stopped. Mutually exclusive with PushOnStop.
+	DeleteOnStop bool `yaml:"delete_on_stop"`
+
+	// TLS represents the Pushgateway client TLS configuration.
+	TLS *TLSConfig `yaml:"tls"`
+
+	// Timeout represents the Pushgateway requests timeout in seconds. If not specified, defaults to 10 seconds.
+	Timeout int `yaml:"timeout"`
+
+	// Debug represents a flags indicating whether to enable internal exporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeoutSec
+	}
+
+	if c.TLS != nil {
+		if err := c.TLS.validate(); err != nil {
+			return err
+		}
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.URL, validation.Required, is.URL),
+		validation.Field(&c.Job, validation.Required),
+		validation.Field(&c.PushInterval, validation.Min(0)),
+		validation.Field(&c.DeleteOnStop,
+			validation.By(func(v interface{}) error {
+				if v.(bool) && c.PushOnStop {
+					return errors.New("mutually exclusive with push_on_stop")
+				}
+				return nil
+			})),
+	)
+}
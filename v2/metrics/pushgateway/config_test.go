@@ -0,0 +1,27 @@
+package pushgateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testConfig := &Config{URL: "http://127.0.0.1:9091", Job: "test"}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultTimeoutSec, testConfig.Timeout, "should have been set to default value")
+
+	require.Error(t, (&Config{Job: "test"}).validate())
+	require.Error(t, (&Config{URL: "http://127.0.0.1:9091"}).validate())
+	require.Error(t, (&Config{
+		URL:          "http://127.0.0.1:9091",
+		Job:          "test",
+		PushOnStop:   true,
+		DeleteOnStop: true,
+	}).validate())
+	require.Error(t, (&Config{
+		URL: "http://127.0.0.1:9091",
+		Job: "test",
+		TLS: &TLSConfig{CertFile: "cert.pem"},
+	}).validate())
+}
@@ -0,0 +1,177 @@
+// pushgateway implements a Prometheus Pushgateway metrics exporter.
+package pushgateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+)
+
+// Exporter represents a metrics exporter to a Prometheus Pushgateway.
+type Exporter struct {
+	pusher *push.Pusher
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new Pushgateway metrics exporter based on provided configuration, pushing the metrics returned by
+// the gatherer (typically the Prometheus metrics exporter, to push the same metrics as the ones exposed by its
+// scraping endpoint).
+func New(config *Config, gatherer prom.Gatherer) (*Exporter, error) {
+	var exporter Exporter
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	exporter.config = config
+
+	exporter.D = debug.New("reporter/metrics/pushgateway")
+	if config.Debug {
+		exporter.D.On()
+	}
+
+	exporter.Debug("enabling exporter",
+		"url", config.URL,
+		"job", config.Job,
+		"push_interval", config.PushInterval)
+
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter.pusher = push.New(config.URL, config.Job).
+		Gatherer(gatherer).
+		Client(client)
+	for k, v := range config.Grouping {
+		exporter.pusher = exporter.pusher.Grouping(k, v)
+	}
+
+	return &exporter, nil
+}
+
+// Push pushes the metrics to the Pushgateway, replacing all the metrics previously pushed with the same grouping
+// labels.
+func (e *Exporter) Push() error {
+	e.Debug("pushing metrics")
+
+	return e.pusher.Push()
+}
+
+// Delete deletes all the metrics previously pushed to the Pushgateway with the same grouping labels.
+func (e *Exporter) Delete() error {
+	e.Debug("deleting metrics")
+
+	return e.pusher.Delete()
+}
+
+// Start starts the metrics exporter.
+func (e *Exporter) Start(ctx context.Context) error {
+	// Before initializing the goroutines management tomb we have to check that we actually have goroutines to
+	// handle with it, otherwise it'll get stuck during shutdown (see Stop() method).
+	if e.config.PushInterval <= 0 {
+		return nil
+	}
+
+	e.t, _ = tomb.WithContext(ctx)
+	e.t.Go(e.pushLoop)
+
+	return nil
+}
+
+// Stop stops the metrics exporter, pushing the metrics a last time or deleting them from the Pushgateway if
+// configured to do so.
+func (e *Exporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if e.t != nil {
+		e.t.Kill(nil)
+		if err := e.t.Wait(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case e.config.PushOnStop:
+		return e.Push()
+
+	case e.config.DeleteOnStop:
+		return e.Delete()
+	}
+
+	return nil
+}
+
+// pushLoop periodically pushes the metrics to the Pushgateway. This method blocks the caller until the exporter's
+// tomb dies.
+func (e *Exporter) pushLoop() error {
+	tick := time.NewTicker(time.Duration(e.config.PushInterval) * time.Second)
+	defer tick.Stop()
+
+	e.Debug("starting push loop")
+
+	for {
+		select {
+		case <-tick.C:
+			if err := e.Push(); err != nil {
+				e.Error("unable to push metrics", "err", err)
+			}
+
+		case <-e.t.Dying():
+			e.Debug("terminating push loop")
+			return nil
+		}
+	}
+}
+
+// newHTTPClient returns the HTTP client used to send requests to the Pushgateway.
+func newHTTPClient(config *Config) (*http.Client, error) {
+	client := &http.Client{Timeout: time.Duration(config.Timeout) * time.Second}
+
+	if config.TLS == nil {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.TLS.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if config.TLS.CAFile != "" {
+		caCerts, err := ioutil.ReadFile(config.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read TLS CA file: %s", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCerts) {
+			return nil, errors.New("no valid certificate found in TLS CA file")
+		}
+	}
+
+	if config.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLS.CertFile, config.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load TLS client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+
+	return client, nil
+}
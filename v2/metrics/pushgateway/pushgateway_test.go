@@ -0,0 +1,162 @@
+package pushgateway
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+// testPushgateway represents a fake Pushgateway recording the requests it receives.
+type testPushgateway struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []testRequest
+}
+
+func newTestPushgateway(t *testing.T, tls bool) *testPushgateway {
+	pg := new(testPushgateway)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		pg.mu.Lock()
+		pg.requests = append(pg.requests, testRequest{method: r.Method, path: r.URL.Path, body: string(body)})
+		pg.mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	if tls {
+		pg.Server = httptest.NewTLSServer(handler)
+	} else {
+		pg.Server = httptest.NewServer(handler)
+	}
+
+	return pg
+}
+
+func (pg *testPushgateway) Requests() []testRequest {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	return append([]testRequest(nil), pg.requests...)
+}
+
+func newTestGatherer(t *testing.T) prom.Gatherer {
+	registry := prom.NewRegistry()
+	gauge := prom.NewGauge(prom.GaugeOpts{Name: "test", Help: "Test Prometheus metric"})
+	gauge.Set(42)
+	require.NoError(t, registry.Register(gauge))
+
+	return registry
+}
+
+func TestExporter_Push(t *testing.T) {
+	pg := newTestPushgateway(t, false)
+	defer pg.Close()
+
+	exporter, err := New(&Config{
+		URL:      pg.URL,
+		Job:      "batch",
+		Grouping: map[string]string{"instance": "worker1"},
+	}, newTestGatherer(t))
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Push())
+	require.NoError(t, exporter.Delete())
+
+	requests := pg.Requests()
+	require.Len(t, requests, 2)
+	require.Equal(t, http.MethodPut, requests[0].method)
+	require.Equal(t, "/metrics/job/batch/instance/worker1", requests[0].path)
+	require.NotEmpty(t, requests[0].body)
+	require.Equal(t, http.MethodDelete, requests[1].method)
+	require.Equal(t, "/metrics/job/batch/instance/worker1", requests[1].path)
+}
+
+func TestExporter_Start(t *testing.T) {
+	pg := newTestPushgateway(t, false)
+	defer pg.Close()
+
+	exporter, err := New(&Config{URL: pg.URL, Job: "batch", PushInterval: 1}, newTestGatherer(t))
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	require.Eventually(t,
+		func() bool { return len(pg.Requests()) > 0 },
+		time.Second*3,
+		100*time.Millisecond)
+	require.NoError(t, exporter.Stop(context.Background()))
+
+	require.Equal(t, http.MethodPut, pg.Requests()[0].method)
+}
+
+func TestExporter_Stop(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{name: "nothing on stop"},
+		{name: "push on stop", config: Config{PushOnStop: true}, expected: []string{http.MethodPut}},
+		{name: "delete on stop", config: Config{DeleteOnStop: true}, expected: []string{http.MethodDelete}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pg := newTestPushgateway(t, false)
+			defer pg.Close()
+
+			tc.config.URL = pg.URL
+			tc.config.Job = "batch"
+			exporter, err := New(&tc.config, newTestGatherer(t))
+			require.NoError(t, err)
+
+			require.NoError(t, exporter.Start(context.Background()))
+			require.Nil(t, exporter.t, "no push loop should have been started")
+			require.NoError(t, exporter.Stop(context.Background()))
+
+			var methods []string
+			for _, r := range pg.Requests() {
+				methods = append(methods, r.method)
+			}
+			require.Equal(t, tc.expected, methods)
+		})
+	}
+}
+
+func TestExporter_TLS(t *testing.T) {
+	pg := newTestPushgateway(t, true)
+	defer pg.Close()
+
+	exporter, err := New(&Config{URL: pg.URL, Job: "batch"}, newTestGatherer(t))
+	require.NoError(t, err)
+	err = exporter.Push()
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "certificate"), err.Error())
+
+	exporter, err = New(&Config{
+		URL: pg.URL,
+		Job: "batch",
+		TLS: &TLSConfig{InsecureSkipVerify: true},
+	}, newTestGatherer(t))
+	require.NoError(t, err)
+	require.NoError(t, exporter.Push())
+}
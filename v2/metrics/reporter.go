@@ -9,15 +9,42 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+	"github.com/exoscale/go-reporter/v2/metrics/collectd"
+	"github.com/exoscale/go-reporter/v2/metrics/expvar"
+	"github.com/exoscale/go-reporter/v2/metrics/file"
+	"github.com/exoscale/go-reporter/v2/metrics/graphite"
+	"github.com/exoscale/go-reporter/v2/metrics/influx"
+	"github.com/exoscale/go-reporter/v2/metrics/otlp"
 	"github.com/exoscale/go-reporter/v2/metrics/prometheus"
+	"github.com/exoscale/go-reporter/v2/metrics/pushgateway"
+	"github.com/exoscale/go-reporter/v2/metrics/statsd"
 )
 
+// Logger represents the interface of the logger used by the metrics reporter to log the offending metrics: invalid
+// names sanitized, series exceeding the series limit and label sets exceeding the cardinality limit.
+type Logger interface {
+	Warn(msg string, ctx ...interface{})
+}
+
 // Reporter represents a metrics reporter instance.
 type Reporter struct {
-	Prometheus *prometheus.Exporter
+	Prometheus  *prometheus.Exporter
+	Pushgateway *pushgateway.Exporter
+	Collectd    *collectd.Exporter
+	Expvar      *expvar.Exporter
+	File        *file.Exporter
+	Statsd      *statsd.Exporter
+	Graphite    *graphite.Exporter
+	Influx      *influx.Exporter
+	OTLP        *otlp.Exporter
 
-	registry        metrics.Registry
-	runtimeRegistry metrics.Registry
+	registry     metrics.Registry
+	gatherer     *prometheus.Exporter // Standalone Prometheus exporter gathered by the Pushgateway exporter, if any
+	runtime      *runtimeCollector
+	labelLimiter *labelLimiter
+	namer        *namer
+	expirer      *expirer
 
 	t      *tomb.Tomb // Goroutines manager
 	config *Config
@@ -48,10 +75,33 @@ func New(config *Config) (*Reporter, error) {
 
 	reporter.registry = metrics.NewRegistry()
 
-	if config.WithRuntimeMetrics {
+	// Offending metrics are logged in debug mode only, until a logger is set using SetLogger().
+	reporter.labelLimiter = newLabelLimiter(config.MaxLabelSets, reporter.D)
+	if err := reporter.registry.Register(labelsOverflowMetricName, reporter.labelLimiter.overflow); err != nil {
+		return nil, err
+	}
+
+	reporter.namer = newNamer(config.MaxSeries, config.SeriesOverflow, reporter.D)
+	if config.MaxSeries > 0 {
+		reporter.Debug("enabling metric series limit", "max_series", config.MaxSeries,
+			"overflow", config.SeriesOverflow)
+		if err := reporter.registry.Register(seriesOverflowMetricName, reporter.namer.overflow); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.TTL > 0 {
+		reporter.Debug("enabling stale metrics expiry", "ttl", config.TTL)
+		reporter.expirer = newExpirer(time.Duration(config.TTL)*time.Second, config.Pinned, reporter.registry,
+			reporter.labelLimiter, reporter.namer, reporter.D)
+		if err := reporter.registry.Register(expiredMetricName, reporter.expirer.expired); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.RuntimeMetrics != nil {
 		reporter.Debug("enabling Go runtime metrics collection")
-		reporter.runtimeRegistry = metrics.NewPrefixedChildRegistry(reporter.registry, "go.")
-		metrics.RegisterRuntimeMemStats(reporter.runtimeRegistry)
+		reporter.runtime = newRuntimeCollector(reporter.registry, config.RuntimeMetrics)
 	}
 
 	if config.Prometheus != nil {
@@ -61,17 +111,111 @@ func New(config *Config) (*Reporter, error) {
 		}
 	}
 
+	if config.Pushgateway != nil {
+		config.Pushgateway.Debug = config.Debug
+
+		// The Pushgateway exporter pushes the metrics exposed by the Prometheus exporter: if not configured, we use
+		// a standalone one (which doesn't need to be started since it reads the registry when gathered).
+		gatherer := reporter.Prometheus
+		if gatherer == nil {
+			if gatherer, err = prometheus.New(new(prometheus.Config), reporter.registry); err != nil {
+				return nil, err
+			}
+			reporter.gatherer = gatherer
+		}
+
+		if reporter.Pushgateway, err = pushgateway.New(config.Pushgateway, gatherer); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Collectd != nil {
+		config.Collectd.Debug = config.Debug
+		if reporter.Collectd, err = collectd.New(config.Collectd, reporter.registry); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Expvar != nil {
+		config.Expvar.Debug = config.Debug
+		if reporter.Expvar, err = expvar.New(config.Expvar, reporter.registry); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.File != nil {
+		config.File.Debug = config.Debug
+		if reporter.File, err = file.New(config.File, reporter.registry); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Statsd != nil {
+		config.Statsd.Debug = config.Debug
+		if reporter.Statsd, err = statsd.New(config.Statsd, reporter.registry); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Graphite != nil {
+		config.Graphite.Debug = config.Debug
+		if reporter.Graphite, err = graphite.New(config.Graphite, reporter.registry); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Influx != nil {
+		config.Influx.Debug = config.Debug
+		if reporter.Influx, err = influx.New(config.Influx, reporter.registry); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.OTLP != nil {
+		config.OTLP.Debug = config.Debug
+		if reporter.OTLP, err = otlp.New(config.OTLP, reporter.registry); err != nil {
+			return nil, err
+		}
+	}
+
 	return &reporter, nil
 }
 
-// Register registers a metric in the internal registry.
+// Register registers a metric in the internal registry. Like with the helper methods, invalid metric name and label
+// keys (if any) are sanitized, and logged once.
 func (r *Reporter) Register(name string, metric interface{}) error {
-	return r.registry.Register(name, metric)
+	name, l := r.namer.validate(labels.Decode(name))
+
+	return r.registry.Register(labels.Encode(name, l), metric)
+}
+
+// SetLogger sets the logger used to log the offending metrics, once per metric name: invalid names sanitized, series
+// exceeding the series limit, label sets exceeding the cardinality limit and metrics skipped by the Prometheus
+// exporter because their name collides with another one once sanitized, and data points skipped by the OTLP exporter
+// because their name is already used by a metric of another type. By default, they are logged in debug mode only.
+func (r *Reporter) SetLogger(logger Logger) {
+	r.namer.mu.Lock()
+	r.namer.logger = logger
+	r.namer.mu.Unlock()
+
+	r.labelLimiter.mu.Lock()
+	r.labelLimiter.logger = logger
+	r.labelLimiter.mu.Unlock()
+
+	if r.Prometheus != nil {
+		r.Prometheus.SetLogger(logger)
+	}
+	if r.gatherer != nil {
+		r.gatherer.SetLogger(logger)
+	}
+	if r.OTLP != nil {
+		r.OTLP.SetLogger(logger)
+	}
 }
 
 // Start starts the metrics reporter.
 func (r *Reporter) Start(ctx context.Context) error {
-	if r.runtimeRegistry != nil {
+	if r.runtime != nil || r.expirer != nil {
 		r.t, _ = tomb.WithContext(ctx)
 
 		r.t.Go(func() error {
@@ -80,8 +224,16 @@ func (r *Reporter) Start(ctx context.Context) error {
 			for {
 				select {
 				case <-ticker.C:
-					r.Debug("flushing runtime metrics to registry")
-					metrics.CaptureRuntimeMemStatsOnce(r.runtimeRegistry)
+					if r.runtime != nil {
+						r.Debug("flushing runtime metrics to registry")
+						if err := r.runtime.capture(); err != nil {
+							r.Error("unable to collect runtime metrics", "err", err)
+						}
+					}
+
+					if r.expirer != nil {
+						r.expirer.sweep()
+					}
 
 				case <-r.t.Dying():
 					ticker.Stop()
@@ -99,11 +251,139 @@ func (r *Reporter) Start(ctx context.Context) error {
 		r.Debug("Prometheus exporter started")
 	}
 
+	if r.Pushgateway != nil {
+		r.Debug("starting Pushgateway exporter")
+		if err := r.Pushgateway.Start(ctx); err != nil {
+			return err
+		}
+		r.Debug("Pushgateway exporter started")
+	}
+
+	if r.Collectd != nil {
+		r.Debug("starting collectd exporter")
+		if err := r.Collectd.Start(ctx); err != nil {
+			return err
+		}
+		r.Debug("collectd exporter started")
+	}
+
+	if r.Expvar != nil {
+		r.Debug("starting expvar exporter")
+		if err := r.Expvar.Start(ctx); err != nil {
+			return err
+		}
+		r.Debug("expvar exporter started")
+	}
+
+	if r.File != nil {
+		r.Debug("starting file exporter")
+		if err := r.File.Start(ctx); err != nil {
+			return err
+		}
+		r.Debug("file exporter started")
+	}
+
+	if r.Statsd != nil {
+		r.Debug("starting StatsD exporter")
+		if err := r.Statsd.Start(ctx); err != nil {
+			return err
+		}
+		r.Debug("StatsD exporter started")
+	}
+
+	if r.Graphite != nil {
+		r.Debug("starting Graphite exporter")
+		if err := r.Graphite.Start(ctx); err != nil {
+			return err
+		}
+		r.Debug("Graphite exporter started")
+	}
+
+	if r.Influx != nil {
+		r.Debug("starting InfluxDB exporter")
+		if err := r.Influx.Start(ctx); err != nil {
+			return err
+		}
+		r.Debug("InfluxDB exporter started")
+	}
+
+	if r.OTLP != nil {
+		r.Debug("starting OTLP exporter")
+		if err := r.OTLP.Start(ctx); err != nil {
+			return err
+		}
+		r.Debug("OTLP exporter started")
+	}
+
 	return nil
 }
 
 // Stop stops the metrics reporter.
 func (r *Reporter) Stop(ctx context.Context) error {
+	if r.OTLP != nil {
+		r.Debug("stopping OTLP exporter")
+		if err := r.OTLP.Stop(ctx); err != nil {
+			return err
+		}
+		r.Debug("OTLP exporter stopped")
+	}
+
+	if r.Influx != nil {
+		r.Debug("stopping InfluxDB exporter")
+		if err := r.Influx.Stop(ctx); err != nil {
+			return err
+		}
+		r.Debug("InfluxDB exporter stopped")
+	}
+
+	if r.Graphite != nil {
+		r.Debug("stopping Graphite exporter")
+		if err := r.Graphite.Stop(ctx); err != nil {
+			return err
+		}
+		r.Debug("Graphite exporter stopped")
+	}
+
+	if r.Statsd != nil {
+		r.Debug("stopping StatsD exporter")
+		if err := r.Statsd.Stop(ctx); err != nil {
+			return err
+		}
+		r.Debug("StatsD exporter stopped")
+	}
+
+	if r.File != nil {
+		r.Debug("stopping file exporter")
+		if err := r.File.Stop(ctx); err != nil {
+			return err
+		}
+		r.Debug("file exporter stopped")
+	}
+
+	if r.Expvar != nil {
+		r.Debug("stopping expvar exporter")
+		if err := r.Expvar.Stop(ctx); err != nil {
+			return err
+		}
+		r.Debug("expvar exporter stopped")
+	}
+
+	if r.Collectd != nil {
+		r.Debug("stopping collectd exporter")
+		if err := r.Collectd.Stop(ctx); err != nil {
+			return err
+		}
+		r.Debug("collectd exporter stopped")
+	}
+
+	if r.Pushgateway != nil {
+		r.Debug("stopping Pushgateway exporter")
+		if err := r.Pushgateway.Stop(ctx); err != nil {
+			return err
+		}
+		r.Debug("Pushgateway exporter stopped")
+	}
+
 	if r.Prometheus != nil {
 		r.Debug("stopping Prometheus exporter")
 		if err := r.Prometheus.Stop(ctx); err != nil {
@@ -2,13 +2,24 @@ package metrics
 
 import (
 	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	gometrics "github.com/rcrowley/go-metrics"
 	"github.com/stretchr/testify/require"
 
+	"github.com/exoscale/go-reporter/v2/metrics/collectd"
+	"github.com/exoscale/go-reporter/v2/metrics/expvar"
+	"github.com/exoscale/go-reporter/v2/metrics/file"
+	"github.com/exoscale/go-reporter/v2/metrics/graphite"
+	"github.com/exoscale/go-reporter/v2/metrics/influx"
+	"github.com/exoscale/go-reporter/v2/metrics/otlp"
 	"github.com/exoscale/go-reporter/v2/metrics/prometheus"
+	"github.com/exoscale/go-reporter/v2/metrics/pushgateway"
+	"github.com/exoscale/go-reporter/v2/metrics/statsd"
 )
 
 /*
@@ -30,6 +41,14 @@ func TestNew(t *testing.T) {
 	var (
 		testConfig = &Config{
 			Prometheus: new(prometheus.Config),
+			Collectd:   &collectd.Config{Hostname: "test"},
+			Expvar:     &expvar.Config{Listen: "127.0.0.1:8123"},
+			File:       &file.Config{Path: "/tmp/metrics"},
+			Statsd:     &statsd.Config{Address: "127.0.0.1:8125"},
+			Graphite:   &graphite.Config{Connect: "127.0.0.1:2003", Hostname: "test"},
+			Influx:     &influx.Config{URL: "http://127.0.0.1:8086", Database: "test", Hostname: "test"},
+			OTLP: &otlp.Config{Endpoint: "127.0.0.1:4318",
+				ResourceAttributes: map[string]string{"host.name": "test"}},
 		}
 	)
 
@@ -42,6 +61,13 @@ func TestNew(t *testing.T) {
 	require.NotNil(t, reporter)
 	require.NotNil(t, reporter.registry)
 	require.NotNil(t, reporter.Prometheus)
+	require.NotNil(t, reporter.Collectd)
+	require.NotNil(t, reporter.Expvar)
+	require.NotNil(t, reporter.File)
+	require.NotNil(t, reporter.Statsd)
+	require.NotNil(t, reporter.Graphite)
+	require.NotNil(t, reporter.Influx)
+	require.NotNil(t, reporter.OTLP)
 }
 
 func TestReporter_Register(t *testing.T) {
@@ -53,6 +79,16 @@ func TestReporter_Register(t *testing.T) {
 	metric := gometrics.NewGauge()
 	require.NoError(t, reporter.Register(testMetricName, metric))
 	require.Equal(t, metric, reporter.registry.Get(testMetricName))
+
+	logger := new(testLogger)
+	reporter.SetLogger(logger)
+
+	require.NoError(t, reporter.Register(`requests{method="GET"}`, gometrics.NewCounter()))
+	require.NoError(t, reporter.Register("invalid name", gometrics.NewCounter()))
+	require.NotNil(t, reporter.registry.Get("invalid_name"))
+	require.NoError(t, reporter.Register(`requests{http-method="GET"}`, gometrics.NewCounter()))
+	require.NotNil(t, reporter.registry.Get(`requests{http_method="GET"}`))
+	require.Len(t, logger.messages, 2)
 }
 
 func TestReporter_Start(t *testing.T) {
@@ -97,3 +133,26 @@ func TestReporter_Stop(t *testing.T) {
 		500*time.Millisecond,
 	)
 }
+
+func TestReporter_Pushgateway(t *testing.T) {
+	var pushed []byte
+
+	pg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer pg.Close()
+
+	reporter, err := New(&Config{
+		Pushgateway: &pushgateway.Config{URL: pg.URL, Job: "batch", PushOnStop: true},
+	})
+	require.NoError(t, err)
+	require.Nil(t, reporter.Prometheus)
+	require.NotNil(t, reporter.Pushgateway)
+
+	reporter.Counter(".jobs").Inc(1)
+
+	require.NoError(t, reporter.Start(context.Background()))
+	require.NoError(t, reporter.Stop(context.Background()))
+	require.NotEmpty(t, pushed, "metrics should have been pushed on stop")
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+const (
+	runtimeMetricsPrefix = "go."
+	processMetricsPrefix = "process."
+)
+
+// runtimeCollector represents a collector of the Go runtime and process metrics groups enabled in the configuration,
+// updating the registered metrics every time capture() is called.
+type runtimeCollector struct {
+	config *RuntimeMetricsConfig
+
+	runtimeRegistry metrics.Registry
+	processRegistry metrics.Registry
+
+	goroutines metrics.Gauge
+	threads    metrics.Gauge
+	threadsPrf *pprof.Profile
+
+	gcCount  metrics.Gauge
+	gcPauses metrics.Histogram
+	gcStats  debug.GCStats
+
+	sched   *schedLatency
+	process *processStats
+}
+
+// newRuntimeCollector returns a new runtime metrics collector, registering the metrics of the enabled groups in the
+// registry under the "go." (Go runtime) and "process." (process) prefixes.
+func newRuntimeCollector(registry metrics.Registry, config *RuntimeMetricsConfig) *runtimeCollector {
+	c := runtimeCollector{
+		config:          config,
+		runtimeRegistry: metrics.NewPrefixedChildRegistry(registry, runtimeMetricsPrefix),
+		processRegistry: metrics.NewPrefixedChildRegistry(registry, processMetricsPrefix),
+	}
+
+	if config.MemStats {
+		metrics.RegisterRuntimeMemStats(c.runtimeRegistry)
+	}
+
+	if config.Goroutines {
+		c.goroutines = metrics.NewRegisteredGauge("runtime.goroutines", c.runtimeRegistry)
+		c.threads = metrics.NewRegisteredGauge("runtime.threads", c.runtimeRegistry)
+		c.threadsPrf = pprof.Lookup("threadcreate")
+	}
+
+	if config.GC {
+		c.gcCount = metrics.NewRegisteredGauge("gc.count", c.runtimeRegistry)
+		c.gcPauses = metrics.NewRegisteredHistogram("gc.pause", c.runtimeRegistry,
+			metrics.NewExpDecaySample(defaultSampleSize, defaultSampleAlpha))
+	}
+
+	if config.Scheduler {
+		c.sched = newSchedLatency(c.runtimeRegistry)
+	}
+
+	if config.Process {
+		c.process = newProcessStats(c.processRegistry)
+	}
+
+	return &c
+}
+
+// capture updates the metrics of the enabled groups. Groups unsupported by the Go version or the platform are
+// ignored.
+func (c *runtimeCollector) capture() error {
+	if c.config.MemStats {
+		metrics.CaptureRuntimeMemStatsOnce(c.runtimeRegistry)
+	}
+
+	if c.config.Goroutines {
+		c.goroutines.Update(int64(runtime.NumGoroutine()))
+		c.threads.Update(int64(c.threadsPrf.Count()))
+	}
+
+	if c.config.GC {
+		c.captureGC()
+	}
+
+	if c.sched != nil {
+		c.sched.capture()
+	}
+
+	if c.process != nil {
+		return c.process.capture()
+	}
+
+	return nil
+}
+
+// captureGC records the pauses of the garbage collections that occurred since the previous capture, as far as they
+// are still part of the runtime's pauses history.
+func (c *runtimeCollector) captureGC() {
+	lastNumGC := c.gcStats.NumGC
+	debug.ReadGCStats(&c.gcStats)
+
+	c.gcCount.Update(c.gcStats.NumGC)
+
+	// The pauses are sorted from the most recent to the oldest one.
+	pauses := c.gcStats.Pause
+	if n := c.gcStats.NumGC - lastNumGC; n < int64(len(pauses)) {
+		pauses = pauses[:n]
+	}
+	for i := len(pauses) - 1; i >= 0; i-- {
+		c.gcPauses.Update(int64(pauses[i]))
+	}
+}
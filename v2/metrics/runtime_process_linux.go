@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// procClockTicks represents the number of clock ticks per second (USER_HZ) used by the /proc files, which is 100 on
+// all the supported architectures.
+const procClockTicks = 100
+
+// processStats represents a collector of the process metrics read from /proc/self.
+type processStats struct {
+	root string // procfs mount point
+
+	rss                 metrics.Gauge
+	openFDs             metrics.Gauge
+	maxFDs              metrics.Gauge
+	cpuSeconds          metrics.GaugeFloat64
+	startTime           metrics.GaugeFloat64
+	voluntarySwitches   metrics.Gauge
+	involuntarySwitches metrics.Gauge
+}
+
+// newProcessStats returns a new process metrics collector registering its metrics in the registry.
+func newProcessStats(registry metrics.Registry) *processStats {
+	return &processStats{
+		root:                "/proc",
+		rss:                 metrics.NewRegisteredGauge("memory.rss", registry),
+		openFDs:             metrics.NewRegisteredGauge("fds.open", registry),
+		maxFDs:              metrics.NewRegisteredGauge("fds.max", registry),
+		cpuSeconds:          metrics.NewRegisteredGaugeFloat64("cpu.seconds", registry),
+		startTime:           metrics.NewRegisteredGaugeFloat64("start_time", registry),
+		voluntarySwitches:   metrics.NewRegisteredGauge("context_switches.voluntary", registry),
+		involuntarySwitches: metrics.NewRegisteredGauge("context_switches.involuntary", registry),
+	}
+}
+
+// capture updates the process metrics.
+func (p *processStats) capture() error {
+	if err := p.captureStat(); err != nil {
+		return err
+	}
+
+	if err := p.captureFDs(); err != nil {
+		return err
+	}
+
+	return p.readFields("self/status", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return
+		}
+
+		v, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return
+		}
+
+		switch fields[0] {
+		case "voluntary_ctxt_switches:":
+			p.voluntarySwitches.Update(v)
+		case "nonvoluntary_ctxt_switches:":
+			p.involuntarySwitches.Update(v)
+		}
+	})
+}
+
+// captureStat updates the metrics read from /proc/self/stat (see proc(5)).
+func (p *processStats) captureStat() error {
+	data, err := ioutil.ReadFile(filepath.Join(p.root, "self", "stat"))
+	if err != nil {
+		return err
+	}
+
+	// The executable name (2nd field) may contain spaces and parentheses.
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return errors.New("invalid /proc/self/stat format")
+	}
+
+	// The fields following the executable name start with the 3rd one (state).
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 22 {
+		return errors.New("invalid /proc/self/stat format")
+	}
+
+	field := func(n int) float64 {
+		v, _ := strconv.ParseFloat(fields[n-3], 64)
+		return v
+	}
+
+	p.cpuSeconds.Update((field(14) + field(15)) / procClockTicks)
+	p.rss.Update(int64(field(24)) * int64(os.Getpagesize()))
+
+	var bootTime float64
+	if err := p.readFields("stat", func(line string) {
+		if strings.HasPrefix(line, "btime ") {
+			bootTime, _ = strconv.ParseFloat(strings.TrimPrefix(line, "btime "), 64)
+		}
+	}); err != nil {
+		return err
+	}
+	p.startTime.Update(bootTime + field(22)/procClockTicks)
+
+	return nil
+}
+
+// captureFDs updates the number of open file descriptors and their limit (-1 if unlimited).
+func (p *processStats) captureFDs() error {
+	d, err := os.Open(filepath.Join(p.root, "self", "fd"))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	fds, err := d.Readdirnames(-1)
+	if err != nil {
+		return err
+	}
+	p.openFDs.Update(int64(len(fds)))
+
+	return p.readFields("self/limits", func(line string) {
+		// Limit                     Soft Limit           Hard Limit           Units
+		// Max open files            1024                 1048576              files
+		if !strings.HasPrefix(line, "Max open files") {
+			return
+		}
+
+		soft := strings.Fields(strings.TrimPrefix(line, "Max open files"))[0]
+		if soft == "unlimited" {
+			p.maxFDs.Update(-1)
+		} else if v, err := strconv.ParseInt(soft, 10, 64); err == nil {
+			p.maxFDs.Update(v)
+		}
+	})
+}
+
+// readFields calls fn for each line of a file relative to the procfs mount point.
+func (p *processStats) readFields(name string, fn func(line string)) error {
+	f, err := os.Open(filepath.Join(p.root, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessStats(t *testing.T) {
+	root, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	for name, content := range map[string]string{
+		"stat": "cpu  1 2 3\nbtime 1600000000\n",
+		"self/stat": "42 (my (app)) S 1 42 42 0 -1 4194560 1000 0 0 0 250 150 0 0 20 0 12 0 5000 " +
+			"1000000 25 18446744073709551615\n",
+		"self/limits": "Limit                     Soft Limit           Hard Limit           Units\n" +
+			"Max open files            1024                 1048576              files\n",
+		"self/status": "Name:\tapp\nvoluntary_ctxt_switches:\t120\nnonvoluntary_ctxt_switches:\t7\n",
+		"self/fd/0":   "",
+		"self/fd/1":   "",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0700))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0600))
+	}
+
+	registry := gometrics.NewRegistry()
+	process := newProcessStats(registry)
+	process.root = root
+	require.NoError(t, process.capture())
+
+	require.Equal(t, int64(25*os.Getpagesize()), registry.Get("memory.rss").(gometrics.Gauge).Value())
+	require.Equal(t, int64(2), registry.Get("fds.open").(gometrics.Gauge).Value())
+	require.Equal(t, int64(1024), registry.Get("fds.max").(gometrics.Gauge).Value())
+	require.Equal(t, float64(4), registry.Get("cpu.seconds").(gometrics.GaugeFloat64).Value())
+	require.Equal(t, float64(1600000050), registry.Get("start_time").(gometrics.GaugeFloat64).Value())
+	require.Equal(t, int64(120), registry.Get("context_switches.voluntary").(gometrics.Gauge).Value())
+	require.Equal(t, int64(7), registry.Get("context_switches.involuntary").(gometrics.Gauge).Value())
+
+	process.root = filepath.Join(root, "missing")
+	require.Error(t, process.capture())
+}
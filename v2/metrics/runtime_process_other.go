@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package metrics
+
+import (
+	"github.com/rcrowley/go-metrics"
+)
+
+// processStats represents a collector of the process metrics, which are only supported on Linux.
+type processStats struct{}
+
+// newProcessStats returns nil, since process metrics aren't supported on this platform.
+func newProcessStats(metrics.Registry) *processStats { return nil }
+
+func (*processStats) capture() error { return nil }
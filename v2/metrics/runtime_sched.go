@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"math"
+	runtimemetrics "runtime/metrics"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// schedLatenciesMetric represents the runtime/metrics name of the distribution of the time goroutines have spent in
+// the scheduler in a runnable state before actually running.
+const schedLatenciesMetric = "/sched/latencies:seconds"
+
+// schedLatencyQuantiles represents the quantiles of the scheduler latency reported, by metric name.
+var schedLatencyQuantiles = []struct {
+	name string
+	q    float64
+}{
+	{"sched.latency.p50", 0.5},
+	{"sched.latency.p90", 0.9},
+	{"sched.latency.p99", 0.99},
+	{"sched.latency.max", 1},
+}
+
+// schedLatency represents a collector of the quantiles (in seconds) of the scheduler latency observed since the
+// previous capture.
+type schedLatency struct {
+	samples   []runtimemetrics.Sample
+	previous  []uint64
+	quantiles []metrics.GaugeFloat64
+}
+
+// newSchedLatency returns a new scheduler latency collector registering its metrics in the registry, or nil if the
+// Go runtime doesn't support the scheduler latency metric.
+func newSchedLatency(registry metrics.Registry) *schedLatency {
+	s := schedLatency{samples: []runtimemetrics.Sample{{Name: schedLatenciesMetric}}}
+
+	runtimemetrics.Read(s.samples)
+	if s.samples[0].Value.Kind() != runtimemetrics.KindFloat64Histogram {
+		return nil
+	}
+
+	for _, q := range schedLatencyQuantiles {
+		s.quantiles = append(s.quantiles, metrics.NewRegisteredGaugeFloat64(q.name, registry))
+	}
+
+	return &s
+}
+
+// capture updates the scheduler latency quantiles.
+func (s *schedLatency) capture() {
+	runtimemetrics.Read(s.samples)
+	histogram := s.samples[0].Value.Float64Histogram()
+
+	counts := make([]uint64, len(histogram.Counts))
+	var total uint64
+	for i, c := range histogram.Counts {
+		counts[i] = c
+		if i < len(s.previous) {
+			counts[i] -= s.previous[i]
+		}
+		total += counts[i]
+	}
+	s.previous = append(s.previous[:0], histogram.Counts...)
+
+	for i, q := range schedLatencyQuantiles {
+		s.quantiles[i].Update(histogramQuantile(histogram.Buckets, counts, total, q.q))
+	}
+}
+
+// histogramQuantile returns an estimation of a quantile of the values counted in a runtime/metrics histogram, i.e.
+// the upper bound of the bucket containing it (or its lower bound for the highest, unbounded bucket).
+func histogramQuantile(buckets []float64, counts []uint64, total uint64, q float64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var cumulative uint64
+	for i, c := range counts {
+		if cumulative += c; cumulative >= rank {
+			if math.IsInf(buckets[i+1], 1) {
+				return buckets[i]
+			}
+			return buckets[i+1]
+		}
+	}
+
+	return 0
+}
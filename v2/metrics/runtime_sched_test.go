@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	var (
+		buckets = []float64{0, 1, 2, 4, math.Inf(1)}
+		counts  = []uint64{50, 40, 9, 1}
+	)
+
+	require.Equal(t, float64(1), histogramQuantile(buckets, counts, 100, 0.5))
+	require.Equal(t, float64(2), histogramQuantile(buckets, counts, 100, 0.9))
+	require.Equal(t, float64(4), histogramQuantile(buckets, counts, 100, 0.99))
+	require.Equal(t, float64(4), histogramQuantile(buckets, counts, 100, 1))
+	require.Zero(t, histogramQuantile(buckets, make([]uint64, 4), 0, 0.5))
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"runtime"
+	"testing"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeCollector(t *testing.T) {
+	registry := gometrics.NewRegistry()
+
+	// MemStats can only be registered once per package (see reporter_test.go)
+	collector := newRuntimeCollector(registry, &RuntimeMetricsConfig{
+		Goroutines: true,
+		GC:         true,
+		Scheduler:  true,
+		Process:    true,
+	})
+	require.NoError(t, collector.capture())
+
+	runtime.GC()
+	runtime.GC()
+	require.NoError(t, collector.capture())
+
+	require.Nil(t, registry.Get("go.runtime.MemStats.Alloc"))
+	require.Greater(t, registry.Get("go.runtime.goroutines").(gometrics.Gauge).Value(), int64(0))
+	require.Greater(t, registry.Get("go.runtime.threads").(gometrics.Gauge).Value(), int64(0))
+	require.GreaterOrEqual(t, registry.Get("go.gc.count").(gometrics.Gauge).Value(), int64(2))
+	require.GreaterOrEqual(t, registry.Get("go.gc.pause").(gometrics.Histogram).Count(), int64(2))
+
+	if collector.sched != nil {
+		require.NotNil(t, registry.Get("go.sched.latency.p99"))
+	}
+	if collector.process != nil {
+		require.Greater(t, registry.Get("process.memory.rss").(gometrics.Gauge).Value(), int64(0))
+		require.Greater(t, registry.Get("process.fds.open").(gometrics.Gauge).Value(), int64(0))
+	}
+}
@@ -0,0 +1,108 @@
+package statsd
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultNetwork          = "udp"
+	defaultFlavor           = "statsd"
+	defaultHistograms       = "summary"
+	defaultFlushIntervalSec = 10
+	defaultMaxPacketSize    = 1432 // Ethernet MTU minus IPv6 and UDP headers
+)
+
+// Config represents a StatsD metrics export configuration.
+type Config struct {
+	// Network represents the network used to send the metrics to the StatsD agent (udp|unixgram). If not
+	// specified, defaults to "udp".
+	Network string `yaml:"network"`
+
+	// Address represents the address of the StatsD agent: "host:port" for the "udp" network, or the path to the
+	// agent socket for the "unixgram" network.
+	Address string `yaml:"address"`
+
+	// Flavor represents the StatsD protocol flavor (statsd|dogstatsd). Tags are only supported by the "dogstatsd"
+	// flavor: with the "statsd" flavor, the values of the labeled metrics labels are appended to their name. If not
+	// specified, defaults to "statsd".
+	Flavor string `yaml:"flavor"`
+
+	// Prefix represents a prefix prepended to the names of the sent metrics.
+	Prefix string `yaml:"prefix"`
+
+	// Tags represents tags added to all the sent metrics (only supported by the "dogstatsd" flavor).
+	Tags map[string]string `yaml:"tags"`
+
+	// Histograms represents the way histograms and timers are sent (summary|mean): either as a counter of the
+	// recorded values and gauges of their statistics (min, max, mean, standard deviation and percentiles), or as a
+	// single timing/histogram value per flush, the mean of the values recorded since the last flush, with a sample
+	// rate letting the agent account for all of them. In "mean" mode the agent computes its own statistics over
+	// those means, so that the intra-interval distribution (e.g. the true min, max and percentiles) is lost: the
+	// go-metrics samples don't retain the individual values recorded during an interval. If not specified, defaults
+	// to "summary" ("samples" is accepted as a deprecated alias of "mean").
+	Histograms string `yaml:"histograms"`
+
+	// FlushInterval represents the time interval in seconds at which the metrics are sent to the StatsD agent. If
+	// not specified, defaults to 10 seconds.
+	FlushInterval int `yaml:"flush_interval"`
+
+	// MaxPacketSize represents the maximum size in bytes of the packets sent to the StatsD agent, in which metrics
+	// are batched. If not specified, defaults to 1432 bytes (fitting in a standard Ethernet MTU).
+	MaxPacketSize int `yaml:"max_packet_size"`
+
+	// Exclude represents a list of metric names patterns (shell globbing) to exclude from the export.
+	Exclude []string `yaml:"exclude"`
+
+	// Debug represents a flags indicating whether to enable internal exporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	if c.Network == "" {
+		c.Network = defaultNetwork
+	}
+
+	if c.Flavor == "" {
+		c.Flavor = defaultFlavor
+	}
+
+	if c.Histograms == "" {
+		c.Histograms = defaultHistograms
+	}
+
+	// "samples" is a deprecated alias of "mean"
+	if c.Histograms == "samples" {
+		c.Histograms = "mean"
+	}
+
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushIntervalSec
+	}
+
+	if c.MaxPacketSize <= 0 {
+		c.MaxPacketSize = defaultMaxPacketSize
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Network,
+			validation.In(
+				"udp",
+				"unixgram",
+			)),
+		validation.Field(&c.Address,
+			validation.Required,
+			validation.When(c.Network == "udp", is.DialString)),
+		validation.Field(&c.Flavor,
+			validation.In(
+				"statsd",
+				"dogstatsd",
+			)),
+		validation.Field(&c.Histograms,
+			validation.In(
+				"summary",
+				"mean",
+			)),
+	)
+}
@@ -0,0 +1,29 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testConfig := &Config{Address: "127.0.0.1:8125"}
+	require.NoError(t, testConfig.validate())
+	require.Equal(t, defaultNetwork, testConfig.Network, "should have been set to default value")
+	require.Equal(t, defaultFlavor, testConfig.Flavor, "should have been set to default value")
+	require.Equal(t, defaultHistograms, testConfig.Histograms, "should have been set to default value")
+	require.Equal(t, defaultFlushIntervalSec, testConfig.FlushInterval, "should have been set to default value")
+	require.Equal(t, defaultMaxPacketSize, testConfig.MaxPacketSize, "should have been set to default value")
+
+	require.NoError(t, (&Config{Network: "unixgram", Address: "/var/run/statsd.sock"}).validate())
+	require.Error(t, new(Config).validate())
+	require.Error(t, (&Config{Address: "/var/run/statsd.sock"}).validate())
+	require.Error(t, (&Config{Network: "tcp", Address: "127.0.0.1:8125"}).validate())
+	require.Error(t, (&Config{Address: "127.0.0.1:8125", Flavor: "nope"}).validate())
+	require.NoError(t, (&Config{Address: "127.0.0.1:8125", Histograms: "mean"}).validate())
+	require.Error(t, (&Config{Address: "127.0.0.1:8125", Histograms: "nope"}).validate())
+
+	deprecated := &Config{Address: "127.0.0.1:8125", Histograms: "samples"}
+	require.NoError(t, deprecated.validate())
+	require.Equal(t, "mean", deprecated.Histograms)
+}
@@ -0,0 +1,333 @@
+// statsd implements a StatsD/DogStatsD metrics exporter.
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"gopkg.in/tomb.v2"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+	"github.com/exoscale/go-reporter/v2/internal/labels"
+	"github.com/exoscale/go-reporter/v2/internal/naming"
+)
+
+// Percentiles sent as gauges for histograms and timers in "summary" mode.
+var (
+	summaryPercentiles      = []float64{0.5, 0.75, 0.95, 0.99}
+	summaryPercentilesNames = []string{"p50", "p75", "p95", "p99"}
+)
+
+// Exporter represents a metrics exporter to a StatsD agent.
+type Exporter struct {
+	registry metrics.Registry
+	conn     net.Conn
+	tags     []string
+
+	// Values of the counters, meters, histograms and timers counts and sums at the previous and current flushes, to
+	// compute deltas. Values of the metrics unregistered since the previous flush are discarded.
+	previous map[string]int64
+	current  map[string]int64
+
+	t      *tomb.Tomb // Goroutines manager
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new StatsD metrics exporter based on provided configuration, periodically sending the metrics of the
+// go-metrics registry to the StatsD agent.
+func New(config *Config, registry metrics.Registry) (*Exporter, error) {
+	var exporter Exporter
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	exporter.config = config
+	exporter.registry = registry
+	exporter.previous = make(map[string]int64)
+
+	exporter.D = debug.New("reporter/metrics/statsd")
+	if config.Debug {
+		exporter.D.On()
+	}
+
+	for k, v := range config.Tags {
+		exporter.tags = append(exporter.tags, naming.StatsdTag(k, v))
+	}
+	sort.Strings(exporter.tags)
+
+	exporter.Debug("enabling exporter",
+		"network", config.Network,
+		"address", config.Address,
+		"flavor", config.Flavor,
+		"flush_interval", config.FlushInterval)
+
+	return &exporter, nil
+}
+
+// Start starts the metrics exporter.
+func (e *Exporter) Start(ctx context.Context) error {
+	var err error
+
+	if e.conn, err = net.Dial(e.config.Network, e.config.Address); err != nil {
+		return err
+	}
+
+	e.t, _ = tomb.WithContext(ctx)
+	e.t.Go(e.flushLoop)
+
+	return nil
+}
+
+// Stop stops the metrics exporter.
+func (e *Exporter) Stop(_ context.Context) error {
+	// Since tomb activation is conditional, we have to check if it has actually been activated
+	// before trying to kill it otherwise we'll get stuck: https://github.com/go-tomb/tomb/issues/21
+	if e.t == nil {
+		return nil
+	}
+
+	e.t.Kill(nil)
+
+	return e.t.Wait()
+}
+
+// flushLoop periodically sends the go-metrics registry metrics to the StatsD agent. This method blocks the caller
+// until the exporter's tomb dies.
+func (e *Exporter) flushLoop() error {
+	tick := time.NewTicker(time.Duration(e.config.FlushInterval) * time.Second)
+	defer tick.Stop()
+
+	e.Debug("starting flush loop")
+
+	for {
+		select {
+		case <-tick.C:
+			e.flush()
+
+		case <-e.t.Dying():
+			e.Debug("terminating flush loop")
+			return e.conn.Close()
+		}
+	}
+}
+
+// flush sends the current go-metrics registry metrics to the StatsD agent.
+func (e *Exporter) flush() {
+	var failed int
+
+	for _, packet := range batch(e.lines(), e.config.MaxPacketSize) {
+		// Datagram sockets errors (e.g. agent not listening) are not fatal
+		if _, err := e.conn.Write(packet); err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		e.Error("unable to send some metrics packets", "failed", failed)
+	}
+}
+
+// lines returns the StatsD protocol lines of the go-metrics registry metrics.
+func (e *Exporter) lines() []string {
+	var lines []string
+
+	e.current = make(map[string]int64, len(e.previous))
+	defer func() { e.previous = e.current }()
+
+	e.registry.Each(func(key string, i interface{}) {
+		// Any error parsing an exclusion pattern is silently ignored.
+		for _, pattern := range e.config.Exclude {
+			if matched, _ := path.Match(pattern, key); matched {
+				return
+			}
+		}
+
+		name, tags := e.nameAndTags(key)
+		line := func(suffix, value, typ string) {
+			lines = append(lines, e.line(name+suffix, value, typ, tags))
+		}
+
+		switch metric := i.(type) {
+		case metrics.Counter:
+			if delta := e.delta(key, metric.Count()); delta != 0 {
+				line("", strconv.FormatInt(delta, 10), "c")
+			}
+
+		case metrics.Gauge:
+			e.gauge(line, float64(metric.Value()))
+
+		case metrics.GaugeFloat64:
+			e.gauge(line, metric.Value())
+
+		case metrics.Meter:
+			if delta := e.delta(key, metric.Count()); delta != 0 {
+				line("", strconv.FormatInt(delta, 10), "c")
+			}
+
+		case metrics.Histogram:
+			s := metric.Snapshot()
+			e.distribution(key, line, s.Count(), s.Sum(), s.Min(), s.Max(), s.Mean(), s.StdDev(),
+				s.Percentiles(summaryPercentiles), 1)
+			e.buckets(key, line, s)
+
+		case metrics.Timer:
+			// Timers values are durations in nanoseconds, StatsD expects milliseconds
+			s := metric.Snapshot()
+			e.distribution(key, line, s.Count(), s.Sum(), s.Min(), s.Max(), s.Mean(), s.StdDev(),
+				s.Percentiles(summaryPercentiles), float64(time.Millisecond))
+			e.buckets(key, line, s)
+		}
+	})
+
+	return lines
+}
+
+// distribution adds the lines of a histogram or timer, whose values are divided by unit.
+func (e *Exporter) distribution(key string, line func(string, string, string), count, sum, min, max int64,
+	mean, stddev float64, percentiles []float64, unit float64) {
+	countDelta := e.delta(key+".count", count)
+	sumDelta := e.delta(key+".sum", sum)
+
+	if e.config.Histograms == "mean" {
+		if countDelta <= 0 {
+			return
+		}
+
+		typ := "ms"
+		if e.config.Flavor == "dogstatsd" && unit == 1 {
+			typ = "h"
+		}
+
+		// Only the mean of the values recorded since the last flush is sent, the sample rate making the agent account
+		// for all of them
+		if countDelta > 1 {
+			typ += "|@" + formatFloat(1/float64(countDelta))
+		}
+		line("", formatFloat(float64(sumDelta)/float64(countDelta)/unit), typ)
+
+		return
+	}
+
+	line(".count", strconv.FormatInt(countDelta, 10), "c")
+	line(".min", formatFloat(float64(min)/unit), "g")
+	line(".max", formatFloat(float64(max)/unit), "g")
+	line(".mean", formatFloat(mean/unit), "g")
+	line(".stddev", formatFloat(stddev/unit), "g")
+	for i, p := range percentiles {
+		line("."+summaryPercentilesNames[i], formatFloat(p/unit), "g")
+	}
+}
+
+// buckets adds the lines of a histogram or timer snapshot counting its values in buckets (if any): the number of
+// values less than or equal to each bucket upper bound since the previous flush, as "le_<bound>" counters (dots
+// being replaced by underscores).
+func (e *Exporter) buckets(key string, line func(string, string, string), snapshot interface{}) {
+	b, ok := snapshot.(buckets.Bucketed)
+	if !ok {
+		return
+	}
+
+	bounds, counts := b.Buckets()
+	for i, bound := range bounds {
+		suffix := ".le_" + strings.Replace(buckets.Format(bound), ".", "_", -1)
+		if delta := e.delta(key+suffix, int64(counts[i])); delta != 0 {
+			line(suffix, strconv.FormatInt(delta, 10), "c")
+		}
+	}
+}
+
+// gauge adds the lines of a gauge. Since a signed value is interpreted by StatsD agents as a relative change of the
+// gauge, negative values are sent after resetting the gauge to zero.
+func (e *Exporter) gauge(line func(string, string, string), value float64) {
+	if value < 0 {
+		line("", "0", "g")
+	}
+
+	line("", formatFloat(value), "g")
+}
+
+// delta returns the difference between the current value of a cumulative metric and its value at the previous flush.
+func (e *Exporter) delta(key string, value int64) int64 {
+	delta := value - e.previous[key]
+	e.current[key] = value
+
+	return delta
+}
+
+// nameAndTags returns the name and tags of a metric, based on its registry key.
+func (e *Exporter) nameAndTags(key string) (string, []string) {
+	name, l := labels.Decode(key)
+
+	if e.config.Prefix != "" {
+		name = e.config.Prefix + "." + name
+	}
+
+	if e.config.Flavor != "dogstatsd" {
+		if l != nil {
+			name = strings.Join(append([]string{name}, l.Values()...), ".")
+		}
+
+		return naming.Statsd(name), nil
+	}
+
+	tags := e.tags
+	if l != nil {
+		tags = append([]string(nil), e.tags...)
+		for _, k := range l.Keys() {
+			tags = append(tags, naming.StatsdTag(k, l[k]))
+		}
+	}
+
+	return naming.Statsd(name), tags
+}
+
+// line returns a StatsD protocol line.
+func (e *Exporter) line(name, value, typ string, tags []string) string {
+	line := name + ":" + value + "|" + typ
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+
+	return line
+}
+
+// batch returns the lines batched in newline-separated packets of at most max bytes. Lines larger than max are sent
+// in their own packet.
+func batch(lines []string, max int) [][]byte {
+	var (
+		packets [][]byte
+		buf     bytes.Buffer
+	)
+
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > max {
+			packets = append(packets, append([]byte(nil), buf.Bytes()...))
+			buf.Reset()
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+
+	if buf.Len() > 0 {
+		packets = append(packets, buf.Bytes())
+	}
+
+	return packets
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,222 @@
+package statsd
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+
+	"github.com/exoscale/go-reporter/v2/internal/buckets"
+)
+
+func TestNew(t *testing.T) {
+	var (
+		testConfig = &Config{Address: "127.0.0.1:8125", Tags: map[string]string{"env": "test", "az": "a"}}
+		metrics    = gometrics.NewRegistry()
+	)
+
+	exporter, err := New(testConfig, metrics)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	require.Equal(t, metrics, exporter.registry)
+	require.Equal(t, []string{"az:a", "env:test"}, exporter.tags)
+	require.Equal(t, testConfig, exporter.config)
+}
+
+func TestExporter_Start(t *testing.T) {
+	sock, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer sock.Close()
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter("test.counter", registry).Inc(42)
+
+	exporter, err := New(&Config{Address: sock.LocalAddr().String(), FlushInterval: 1}, registry)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	require.Equal(t, "test.counter:42|c", testReadPacket(t, sock))
+}
+
+func TestExporter_Start_Unixgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statsd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "statsd.sock")
+	sock, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	require.NoError(t, err)
+	defer sock.Close()
+
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredGauge("test.gauge", registry).Update(42)
+
+	exporter, err := New(&Config{Network: "unixgram", Address: socketPath, FlushInterval: 1}, registry)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(context.Background()))
+	defer func() { require.NoError(t, exporter.Stop(context.Background())) }()
+
+	require.Equal(t, "test.gauge:42|g", testReadPacket(t, sock))
+}
+
+func TestExporter_Start_Error(t *testing.T) {
+	exporter, err := New(&Config{Network: "unixgram", Address: "/nonexistent/statsd.sock"}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.Error(t, exporter.Start(context.Background()))
+	require.NoError(t, exporter.Stop(context.Background()))
+}
+
+func TestExporter_Stop(t *testing.T) {
+	var testCtx = context.Background()
+
+	exporter, err := New(&Config{Address: "127.0.0.1:8125"}, gometrics.NewRegistry())
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Start(testCtx))
+	require.NotNil(t, exporter.t)
+	require.True(t, exporter.t.Alive())
+
+	require.Eventually(t,
+		func() bool { return exporter.Stop(testCtx) == nil },
+		time.Second*3,
+		500*time.Millisecond,
+	)
+}
+
+func TestExporter_lines(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	counter := gometrics.NewRegisteredCounter("counter", registry)
+	counter.Inc(5)
+	gometrics.NewRegisteredGauge("gauge", registry).Update(-3)
+	gometrics.NewRegisteredGaugeFloat64(`gauge_float64{code="200",method="GET"}`, registry).Update(1.5)
+	gometrics.NewRegisteredGauge("excluded.gauge", registry).Update(1)
+
+	exporter, err := New(&Config{Address: "127.0.0.1:8125", Prefix: "app", Exclude: []string{"excluded.*"}},
+		registry)
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []string{
+		"app.counter:5|c",
+		"app.gauge:0|g",
+		"app.gauge:-3|g",
+		"app.gauge_float64.200.GET:1.5|g",
+	}, exporter.lines())
+
+	// Counters are sent as deltas, and not sent at all if unchanged
+	counter.Inc(2)
+	require.Contains(t, exporter.lines(), "app.counter:2|c")
+	require.NotContains(t, strings.Join(exporter.lines(), "\n"), "app.counter:")
+
+	// Counters unregistered then registered again are sent as new counters
+	registry.Unregister("counter")
+	exporter.lines()
+	gometrics.NewRegisteredCounter("counter", registry).Inc(3)
+	require.Contains(t, exporter.lines(), "app.counter:3|c")
+}
+
+func TestExporter_lines_DogStatsD(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	gometrics.NewRegisteredCounter(`requests{code="200",method="GET"}`, registry).Inc(1)
+
+	exporter, err := New(&Config{
+		Address: "127.0.0.1:8125",
+		Flavor:  "dogstatsd",
+		Tags:    map[string]string{"env": "test"},
+	}, registry)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"requests:1|c|#env:test,code:200,method:GET"}, exporter.lines())
+}
+
+func TestExporter_lines_Histograms(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	histogram := gometrics.NewRegisteredHistogram("histogram", registry, gometrics.NewUniformSample(100))
+	timer := gometrics.NewRegisteredTimer("timer", registry)
+	for _, v := range []int64{10, 20, 30, 40} {
+		histogram.Update(v)
+		timer.Update(time.Duration(v) * time.Millisecond)
+	}
+
+	exporter, err := New(&Config{Address: "127.0.0.1:8125"}, registry)
+	require.NoError(t, err)
+
+	lines := exporter.lines()
+	require.Subset(t, lines, []string{
+		"histogram.count:4|c",
+		"histogram.min:10|g",
+		"histogram.max:40|g",
+		"histogram.mean:25|g",
+		"timer.count:4|c",
+		"timer.min:10|g",
+		"timer.max:40|g",
+		"timer.mean:25|g",
+	})
+
+	exporter, err = New(&Config{Address: "127.0.0.1:8125", Flavor: "dogstatsd", Histograms: "mean"}, registry)
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []string{
+		"histogram:25|h|@0.25",
+		"timer:25|ms|@0.25",
+	}, exporter.lines())
+
+	histogram.Update(50)
+	require.Equal(t, []string{"histogram:50|h"}, exporter.lines())
+}
+
+func TestExporter_lines_Buckets(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	histogram := buckets.NewHistogram([]float64{0.5, 20}, gometrics.NewHistogram(gometrics.NewUniformSample(100)))
+	require.NoError(t, registry.Register("histogram", histogram))
+	histogram.Update(10)
+	histogram.Update(20)
+
+	exporter, err := New(&Config{Address: "127.0.0.1:8125", Histograms: "mean"}, registry)
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []string{
+		"histogram:15|ms|@0.5",
+		"histogram.le_20:2|c",
+	}, exporter.lines())
+
+	histogram.Update(1)
+	histogram.Update(30)
+	require.ElementsMatch(t, []string{
+		"histogram:15.5|ms|@0.5",
+		"histogram.le_20:1|c",
+	}, exporter.lines())
+}
+
+func TestBatch(t *testing.T) {
+	lines := []string{"a:1|c", "b:1|c", "c:1|c", "looooooooong:1|c"}
+
+	packets := batch(lines, 12)
+	require.Len(t, packets, 3)
+	require.Equal(t, "a:1|c\nb:1|c", string(packets[0]))
+	require.Equal(t, "c:1|c", string(packets[1]))
+	require.Equal(t, "looooooooong:1|c", string(packets[2]))
+
+	require.Len(t, batch(lines, 1432), 1)
+	require.Empty(t, batch(nil, 1432))
+}
+
+func testReadPacket(t *testing.T, conn net.Conn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+
+	buf := make([]byte, defaultMaxPacketSize)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
@@ -8,16 +8,20 @@ import (
 	"gopkg.in/inconshreveable/log15.v2"
 
 	"github.com/exoscale/go-reporter/v2/errors"
+	"github.com/exoscale/go-reporter/v2/health"
 	"github.com/exoscale/go-reporter/v2/internal/debug"
 	"github.com/exoscale/go-reporter/v2/logging"
 	"github.com/exoscale/go-reporter/v2/metrics"
+	"github.com/exoscale/go-reporter/v2/tracing"
 )
 
 // Reporter represents a reporter instance.
 type Reporter struct {
 	Errors  *errors.Reporter
+	Health  *health.Reporter
 	Logging *logging.Reporter
 	Metrics *metrics.Reporter
+	Tracing *tracing.Reporter
 
 	config *Config
 
@@ -64,6 +68,10 @@ func New(config *Config) (*Reporter, error) {
 			return nil, err
 		}
 
+		if reporter.Errors != nil {
+			reporter.Errors.SetLogger(reporter.Logging)
+		}
+
 		// Hook the errors reporter's log handler to the logging reporter's logger
 		if config.Logging.ReportErrors {
 			if reporter.Errors == nil {
@@ -83,13 +91,55 @@ func New(config *Config) (*Reporter, error) {
 		if reporter.Metrics, err = metrics.New(config.Metrics); err != nil {
 			return nil, err
 		}
+
+		if reporter.Logging != nil {
+			reporter.Metrics.SetLogger(reporter.Logging)
+		}
+
+		if reporter.Errors != nil {
+			if err = reporter.Errors.RegisterMetrics(reporter.Metrics.Register); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if config.Health != nil {
+		reporter.D.Debug("initializing health reporter")
+		config.Health.Debug = config.Debug
+
+		if reporter.Health, err = health.New(config.Health); err != nil {
+			return nil, err
+		}
+
+		if reporter.Metrics != nil {
+			if err = reporter.Health.RegisterMetrics(reporter.Metrics.Register); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if config.Tracing != nil {
+		reporter.D.Debug("initializing tracing reporter")
+		config.Tracing.Debug = config.Debug
+
+		if reporter.Tracing, err = tracing.New(config.Tracing); err != nil {
+			return nil, err
+		}
 	}
 
 	return &reporter, nil
 }
 
-// Start starts the configured reporters.
+// Start starts the configured reporters, the tracing reporter first so that the others can use it.
 func (r *Reporter) Start(ctx context.Context) error {
+	if r.Tracing != nil {
+		r.D.Debug("starting tracing reporter")
+		if err := r.Tracing.Start(ctx); err != nil {
+			return err
+		}
+		r.D.Debug("tracing reporter started")
+	}
+
 	if r.Errors != nil {
 		r.D.Debug("starting errors reporter")
 		if err := r.Errors.Start(ctx); err != nil {
@@ -114,17 +164,32 @@ func (r *Reporter) Start(ctx context.Context) error {
 		r.D.Debug("metrics reporter started")
 	}
 
+	if r.Health != nil {
+		r.D.Debug("starting health reporter")
+		if err := r.Health.Start(ctx); err != nil {
+			return err
+		}
+		r.D.Debug("health reporter started")
+	}
 	return nil
 }
 
-// Stop stops the configured reporters.
+// Stop stops the configured reporters, in the reverse order of Start().
 func (r *Reporter) Stop(ctx context.Context) error {
-	if r.Errors != nil {
-		r.D.Debug("stopping errors reporter")
-		if err := r.Errors.Stop(ctx); err != nil {
+	if r.Health != nil {
+		r.D.Debug("stopping health reporter")
+		if err := r.Health.Stop(ctx); err != nil {
 			return err
 		}
-		r.D.Debug("errors reporter stopped")
+		r.D.Debug("health reporter stopped")
+	}
+
+	if r.Metrics != nil {
+		r.D.Debug("stopping metrics reporter")
+		if err := r.Metrics.Stop(ctx); err != nil {
+			return err
+		}
+		r.D.Debug("metrics reporter stopped")
 	}
 
 	if r.Logging != nil {
@@ -135,12 +200,20 @@ func (r *Reporter) Stop(ctx context.Context) error {
 		r.D.Debug("logging reporter stopped")
 	}
 
-	if r.Metrics != nil {
-		r.D.Debug("stopping metrics reporter")
-		if err := r.Metrics.Stop(ctx); err != nil {
+	if r.Errors != nil {
+		r.D.Debug("stopping errors reporter")
+		if err := r.Errors.Stop(ctx); err != nil {
 			return err
 		}
-		r.D.Debug("metrics reporter stopped")
+		r.D.Debug("errors reporter stopped")
+	}
+
+	if r.Tracing != nil {
+		r.D.Debug("stopping tracing reporter")
+		if err := r.Tracing.Stop(ctx); err != nil {
+			return err
+		}
+		r.D.Debug("tracing reporter stopped")
 	}
 
 	return nil
@@ -0,0 +1,321 @@
+package testing
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	// SentryServerPublicKey represents the public key of the DSN accepted by the fake Sentry server.
+	SentryServerPublicKey = "public"
+
+	// SentryServerProjectID represents the project ID of the DSN accepted by the fake Sentry server.
+	SentryServerProjectID = "42"
+)
+
+// sentryAPIPath matches the Sentry ingestion API endpoints paths.
+var sentryAPIPath = regexp.MustCompile(`^/api/([^/]+)/(store|envelope)/?$`)
+
+// SentryServer represents a fake Sentry ingestion server, accepting events sent to the store and envelope API
+// endpoints and recording them for later assertions.
+type SentryServer struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu         sync.Mutex
+	events     []*sentry.Event
+	requests   int
+	failures   int
+	failStatus int
+	rateLimits int
+	retryAfter time.Duration
+	newEvent   chan struct{}
+}
+
+// NewSentryServer returns a new started fake Sentry server. The server must be closed by the caller using the
+// Close() method once done.
+func NewSentryServer(t *testing.T) *SentryServer {
+	s := &SentryServer{
+		t:        t,
+		newEvent: make(chan struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Close shuts the fake Sentry server down.
+func (s *SentryServer) Close() {
+	s.server.Close()
+}
+
+// URL returns the base URL of the fake Sentry server.
+func (s *SentryServer) URL() string {
+	return s.server.URL
+}
+
+// DSN returns a Sentry DSN pointing to the fake Sentry server.
+func (s *SentryServer) DSN() string {
+	return strings.Replace(s.server.URL, "://", "://"+SentryServerPublicKey+"@", 1) + "/" + SentryServerProjectID
+}
+
+// FailRequests makes the fake Sentry server reply to the next n requests with the specified HTTP status code,
+// without recording the events they contain.
+func (s *SentryServer) FailRequests(n, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = n
+	s.failStatus = status
+}
+
+// RateLimitRequests makes the fake Sentry server reply to the next n requests with a "429 Too Many Requests"
+// status code and a Retry-After header set to retryAfter, without recording the events they contain.
+func (s *SentryServer) RateLimitRequests(n int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimits = n
+	s.retryAfter = retryAfter
+}
+
+// Requests returns the number of requests received by the fake Sentry server, including the failed ones.
+func (s *SentryServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+// Events returns the events received by the fake Sentry server.
+func (s *SentryServer) Events() []*sentry.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]*sentry.Event, len(s.events))
+	copy(events, s.events)
+
+	return events
+}
+
+// WaitForEvents waits until the fake Sentry server has received at least n events, and returns them. If the timeout
+// is reached, it makes the associated test fail.
+func (s *SentryServer) WaitForEvents(n int, timeout time.Duration) []*sentry.Event {
+	s.t.Helper()
+
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		received, newEvent := len(s.events), s.newEvent
+		s.mu.Unlock()
+
+		if received >= n {
+			return s.Events()
+		}
+
+		select {
+		case <-newEvent:
+		case <-deadline:
+			s.t.Fatalf("timeout waiting for %d Sentry events (received %d)", n, received)
+			return nil
+		}
+	}
+}
+
+// ExpectEvent waits until the fake Sentry server has received an event for which the match function returns true,
+// and returns it. If the timeout is reached, it makes the associated test fail.
+func (s *SentryServer) ExpectEvent(match func(*sentry.Event) bool, timeout time.Duration) *sentry.Event {
+	s.t.Helper()
+
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		events, newEvent := s.events, s.newEvent
+		s.mu.Unlock()
+
+		for _, e := range events {
+			if match(e) {
+				return e
+			}
+		}
+
+		select {
+		case <-newEvent:
+		case <-deadline:
+			s.t.Fatalf("timeout waiting for expected Sentry event (received %d non-matching)", len(events))
+			return nil
+		}
+	}
+}
+
+func (s *SentryServer) handle(w http.ResponseWriter, r *http.Request) {
+	m := sentryAPIPath.FindStringSubmatch(r.URL.Path)
+	if r.Method != http.MethodPost || m == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if m[1] != SentryServerProjectID {
+		http.Error(w, "unknown project", http.StatusNotFound)
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("X-Sentry-Auth"), "sentry_key="+SentryServerPublicKey) &&
+		r.URL.Query().Get("sentry_key") != SentryServerPublicKey {
+		http.Error(w, "invalid authentication", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests++
+	switch {
+	case s.rateLimits > 0:
+		s.rateLimits--
+		retryAfter := int(s.retryAfter.Seconds())
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.Header().Set("X-Sentry-Rate-Limits", fmt.Sprintf("%d::organization", retryAfter))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+
+	case s.failures > 0:
+		s.failures--
+		status := s.failStatus
+		s.mu.Unlock()
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	s.mu.Unlock()
+
+	body, err := readSentryRequestBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var events []*sentry.Event
+	if m[2] == "envelope" {
+		events, err = decodeSentryEnvelope(body)
+	} else {
+		event := new(sentry.Event)
+		err = json.Unmarshal(body, event)
+		events = []*sentry.Event{event}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.events = append(s.events, events...)
+	close(s.newEvent)
+	s.newEvent = make(chan struct{})
+	s.mu.Unlock()
+
+	var id string
+	if len(events) > 0 {
+		id = string(events[0].EventID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+// readSentryRequestBody returns the body of a Sentry ingestion request, uncompressed if needed.
+func readSentryRequestBody(r *http.Request) ([]byte, error) {
+	var (
+		body io.Reader = r.Body
+		err  error
+	)
+
+	switch r.Header.Get("Content-Encoding") {
+	case "gzip":
+		if body, err = gzip.NewReader(r.Body); err != nil {
+			return nil, err
+		}
+	case "deflate":
+		if body, err = zlib.NewReader(r.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	return ioutil.ReadAll(body)
+}
+
+// decodeSentryEnvelope returns the events (and transactions) contained in a Sentry envelope. Other item types are
+// ignored.
+func decodeSentryEnvelope(data []byte) ([]*sentry.Event, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+
+	// Envelope header, unused
+	if _, err := readEnvelopeLine(r); err != nil {
+		return nil, fmt.Errorf("invalid envelope header: %s", err)
+	}
+
+	var events []*sentry.Event
+	for {
+		line, err := readEnvelopeLine(r)
+		if err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var header struct {
+			Type   string `json:"type"`
+			Length *int   `json:"length"`
+		}
+		if err := json.Unmarshal(line, &header); err != nil {
+			return nil, fmt.Errorf("invalid envelope item header: %s", err)
+		}
+
+		var payload []byte
+		if header.Length != nil {
+			payload = make([]byte, *header.Length)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return nil, fmt.Errorf("invalid envelope item payload: %s", err)
+			}
+		} else if payload, err = readEnvelopeLine(r); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("invalid envelope item payload: %s", err)
+		}
+
+		if header.Type != "event" && header.Type != "transaction" {
+			continue
+		}
+
+		event := new(sentry.Event)
+		if err := json.Unmarshal(payload, event); err != nil {
+			return nil, fmt.Errorf("invalid envelope %s item: %s", header.Type, err)
+		}
+		events = append(events, event)
+	}
+}
+
+// readEnvelopeLine reads a newline-terminated line from an envelope, stripping the trailing newline. It returns
+// io.EOF only if there is no more data to read.
+func readEnvelopeLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+
+	return bytes.TrimSuffix(line, []byte("\n")), err
+}
@@ -0,0 +1,109 @@
+package testing
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSentryClient(t *testing.T, dsn string) *sentry.Client {
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:       dsn,
+		Transport: &sentry.HTTPSyncTransport{Timeout: time.Second},
+	})
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestSentryServer_Store(t *testing.T) {
+	server := NewSentryServer(t)
+	defer server.Close()
+
+	client := newTestSentryClient(t, server.DSN())
+	client.CaptureException(errors.New("oh noes!"), nil, nil)
+	client.CaptureMessage("hello", nil, nil)
+
+	events := server.WaitForEvents(2, time.Second)
+	require.Len(t, events, 2)
+	require.Equal(t, "oh noes!", events[0].Exception[0].Value)
+
+	event := server.ExpectEvent(func(e *sentry.Event) bool { return e.Message == "hello" }, time.Second)
+	require.Equal(t, "hello", event.Message)
+}
+
+func TestSentryServer_Envelope(t *testing.T) {
+	server := NewSentryServer(t)
+	defer server.Close()
+
+	envelope := []byte(`{"event_id":"abc","dsn":"` + server.DSN() + `"}
+{"type":"attachment","length":5}
+hello
+{"type":"event","length":39}
+{"event_id":"abc","message":"oh noes!"}
+{"type":"transaction"}
+{"event_id":"def","transaction":"GET /"}
+`)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL()+"/api/"+SentryServerProjectID+"/envelope/",
+		bytes.NewReader(envelope))
+	require.NoError(t, err)
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_key="+SentryServerPublicKey)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	events := server.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, "oh noes!", events[0].Message)
+	require.Equal(t, "GET /", events[1].Transaction)
+}
+
+func TestSentryServer_Authentication(t *testing.T) {
+	server := NewSentryServer(t)
+	defer server.Close()
+
+	res, err := http.Post(server.URL()+"/api/"+SentryServerProjectID+"/store/", "application/json",
+		bytes.NewReader([]byte(`{}`)))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Empty(t, server.Events())
+}
+
+func TestSentryServer_FailRequests(t *testing.T) {
+	server := NewSentryServer(t)
+	defer server.Close()
+
+	server.FailRequests(2, http.StatusServiceUnavailable)
+
+	client := newTestSentryClient(t, server.DSN())
+	for i := 0; i < 3; i++ {
+		client.CaptureMessage("hello", nil, nil)
+	}
+
+	require.Len(t, server.WaitForEvents(1, time.Second), 1)
+	require.Equal(t, 3, server.Requests())
+}
+
+func TestSentryServer_RateLimitRequests(t *testing.T) {
+	server := NewSentryServer(t)
+	defer server.Close()
+
+	server.RateLimitRequests(1, time.Minute)
+
+	client := newTestSentryClient(t, server.DSN())
+	client.CaptureMessage("hello", nil, nil)
+	client.CaptureMessage("hello", nil, nil)
+
+	// The Sentry SDK transport must back off after being rate limited
+	require.Equal(t, 1, server.Requests())
+	require.Empty(t, server.Events())
+}
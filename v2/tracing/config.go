@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const (
+	defaultSampler = "always_on"
+
+	defaultOTLPURLPath    = "/v1/traces"
+	defaultOTLPTimeoutSec = 10
+)
+
+// OTLPExporterConfig represents an OTLP/HTTP spans exporter configuration.
+type OTLPExporterConfig struct {
+	// Endpoint represents the OTLP receiver endpoint as "host:port".
+	Endpoint string `yaml:"endpoint"`
+
+	// URLPath represents the OTLP receiver traces URL path. If not specified, defaults to "/v1/traces".
+	URLPath string `yaml:"url_path"`
+
+	// Insecure represents a flag indicating whether to use plain HTTP instead of HTTPS.
+	Insecure bool `yaml:"insecure"`
+
+	// Headers represents user-defined HTTP headers to add to the export requests (e.g. for authentication).
+	Headers map[string]string `yaml:"headers"`
+
+	// Compression represents a flag indicating whether to gzip-compress the export requests.
+	Compression bool `yaml:"compression"`
+
+	// Timeout represents the export requests timeout in seconds. If not specified, defaults to 10 seconds.
+	Timeout int `yaml:"timeout"`
+}
+
+func (c *OTLPExporterConfig) validate() error {
+	if c.URLPath == "" {
+		c.URLPath = defaultOTLPURLPath
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = defaultOTLPTimeoutSec
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Endpoint, validation.Required, is.DialString))
+}
+
+// FileExporterConfig represents a file spans exporter configuration.
+type FileExporterConfig struct {
+	// Path represents the filesystem path of the file to which the spans are appended as JSON documents.
+	Path string `yaml:"path"`
+
+	// PrettyPrint represents a flag indicating whether to indent the JSON documents.
+	PrettyPrint bool `yaml:"pretty_print"`
+}
+
+func (c *FileExporterConfig) validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Path, validation.Required))
+}
+
+// Config represents a tracing reporter configuration.
+type Config struct {
+	// ServiceName represents the name of the traced service, reported as the "service.name" resource attribute.
+	// If not specified, defaults to the executable name.
+	ServiceName string `yaml:"service_name"`
+
+	// ServiceVersion represents the version of the traced service, reported as the "service.version" resource
+	// attribute.
+	ServiceVersion string `yaml:"service_version"`
+
+	// Exporter represents the destination of the recorded spans (otlp|stdout|file).
+	Exporter string `yaml:"exporter"`
+
+	// OTLP represents the OTLP/HTTP exporter configuration. Required if the exporter is "otlp".
+	OTLP *OTLPExporterConfig `yaml:"otlp"`
+
+	// File represents the file exporter configuration. Required if the exporter is "file".
+	File *FileExporterConfig `yaml:"file"`
+
+	// Sampler represents the sampling strategy of the root spans (always_on|always_off|ratio). Child spans always
+	// follow the sampling decision of their parent, including remote parents. If not specified, defaults to
+	// "always_on".
+	Sampler string `yaml:"sampler"`
+
+	// SamplingRatio represents the fraction of root spans sampled, between 0 and 1, if the sampler is "ratio".
+	SamplingRatio float64 `yaml:"sampling_ratio"`
+
+	// Debug represents a flags indicating whether to enable internal reporter activity logging.
+	// This is mainly for debug purposes.
+	Debug bool `yaml:"debug"`
+}
+
+func (c *Config) validate() error {
+	if c.Sampler == "" {
+		c.Sampler = defaultSampler
+	}
+
+	if c.OTLP != nil {
+		if err := c.OTLP.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.File != nil {
+		if err := c.File.validate(); err != nil {
+			return err
+		}
+	}
+
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Exporter,
+			validation.Required,
+			validation.In(
+				"otlp",
+				"stdout",
+				"file",
+			)),
+		validation.Field(&c.OTLP,
+			validation.When(c.Exporter == "otlp", validation.Required)),
+		validation.Field(&c.File,
+			validation.When(c.Exporter == "file", validation.Required)),
+		validation.Field(&c.Sampler,
+			validation.In(
+				"always_on",
+				"always_off",
+				"ratio",
+			)),
+		validation.Field(&c.SamplingRatio, validation.Min(0.0), validation.Max(1.0)))
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	var config *Config
+
+	config = &Config{}
+	require.Error(t, config.validate())
+
+	config = &Config{Exporter: "lolnope"}
+	require.Error(t, config.validate())
+
+	config = &Config{Exporter: "stdout"}
+	require.NoError(t, config.validate())
+	require.Equal(t, defaultSampler, config.Sampler, "should have been set to default value")
+
+	config = &Config{Exporter: "otlp"}
+	require.Error(t, config.validate())
+
+	config = &Config{Exporter: "otlp", OTLP: &OTLPExporterConfig{Endpoint: "lolnope"}}
+	require.Error(t, config.validate())
+
+	config = &Config{Exporter: "otlp", OTLP: &OTLPExporterConfig{Endpoint: "localhost:4318"}}
+	require.NoError(t, config.validate())
+	require.Equal(t, defaultOTLPURLPath, config.OTLP.URLPath, "should have been set to default value")
+	require.Equal(t, defaultOTLPTimeoutSec, config.OTLP.Timeout, "should have been set to default value")
+
+	config = &Config{Exporter: "file"}
+	require.Error(t, config.validate())
+
+	config = &Config{Exporter: "file", File: &FileExporterConfig{Path: "/tmp/spans.json"}}
+	require.NoError(t, config.validate())
+
+	config = &Config{Exporter: "stdout", Sampler: "lolnope"}
+	require.Error(t, config.validate())
+
+	config = &Config{Exporter: "stdout", Sampler: "ratio", SamplingRatio: 1.5}
+	require.Error(t, config.validate())
+
+	config = &Config{Exporter: "stdout", Sampler: "ratio", SamplingRatio: 0.1}
+	require.NoError(t, config.validate())
+}
@@ -0,0 +1,204 @@
+// tracing implements a distributed tracing reporter based on OpenTelemetry. The log records and error events reported
+// with a context carrying a span carry its trace context (see the reporters' *Context() methods).
+package tracing
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/exoscale/go-reporter/v2/internal/debug"
+)
+
+// defaultTracerName represents the name of the tracer used by the StartSpan() method.
+const defaultTracerName = "github.com/exoscale/go-reporter/v2/tracing"
+
+// Reporter represents a tracing reporter instance.
+type Reporter struct {
+	provider *sdktrace.TracerProvider
+	closer   io.Closer
+
+	config *Config
+
+	*debug.D
+}
+
+// New returns a new tracing reporter instance.
+func New(config *Config) (*Reporter, error) {
+	var (
+		reporter Reporter
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	if config == nil {
+		return nil, nil
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	reporter.config = config
+
+	reporter.D = debug.New("reporter/tracing")
+	if config.Debug {
+		reporter.D.On()
+	}
+
+	reporter.Debug("initializing spans exporter", "exporter", config.Exporter, "sampler", config.Sampler)
+
+	// Close the spans file if the initialization fails after it has been opened
+	defer func() {
+		if err != nil && reporter.closer != nil {
+			_ = reporter.closer.Close()
+		}
+	}()
+
+	switch config.Exporter {
+	case "otlp":
+		exporter, err = newOTLPExporter(config.OTLP)
+
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+
+	case "file":
+		var f *os.File
+		if f, err = os.OpenFile(config.File.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+			return nil, err
+		}
+		reporter.closer = f
+
+		opts := []stdouttrace.Option{stdouttrace.WithWriter(f)}
+		if config.File.PrettyPrint {
+			opts = append(opts, stdouttrace.WithPrettyPrint())
+		}
+		exporter, err = stdouttrace.New(opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(serviceAttributes(config)...))
+	if err != nil {
+		return nil, err
+	}
+
+	reporter.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(newSampler(config)))
+
+	return &reporter, nil
+}
+
+// Start registers the tracing reporter as the global OpenTelemetry tracer provider, and installs the W3C Trace
+// Context and Baggage propagators as global propagators.
+func (r *Reporter) Start(_ context.Context) error {
+	otel.SetTracerProvider(r.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{}))
+
+	return nil
+}
+
+// Stop exports the pending spans and shuts the tracing reporter down.
+func (r *Reporter) Stop(ctx context.Context) error {
+	r.Debug("flushing pending spans")
+	if err := r.provider.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+
+	return nil
+}
+
+// TracerProvider returns the tracing reporter's OpenTelemetry tracer provider.
+func (r *Reporter) TracerProvider() trace.TracerProvider {
+	return r.provider
+}
+
+// Tracer returns a named tracer, typically named after the instrumented package import path.
+func (r *Reporter) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return r.provider.Tracer(name, opts...)
+}
+
+// StartSpan starts a new span, child of the span carried by ctx (if any), and returns a copy of ctx carrying the
+// new span. The span must be ended by the caller.
+func (r *Reporter) StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context,
+	trace.Span) {
+	return r.provider.Tracer(defaultTracerName).Start(ctx, name, opts...)
+}
+
+// newOTLPExporter returns a new OTLP/HTTP spans exporter.
+func newOTLPExporter(config *OTLPExporterConfig) (sdktrace.SpanExporter, error) {
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(config.Endpoint),
+		otlptracehttp.WithURLPath(config.URLPath),
+		otlptracehttp.WithTimeout(time.Duration(config.Timeout) * time.Second),
+	}
+
+	if config.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	if len(config.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(config.Headers))
+	}
+
+	if config.Compression {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	} else {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.NoCompression))
+	}
+
+	// The OTLP/HTTP exporter doesn't perform any network operation until spans are exported.
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+// newSampler returns the sampler specified in the tracing reporter configuration.
+func newSampler(config *Config) sdktrace.Sampler {
+	var root sdktrace.Sampler
+
+	switch config.Sampler {
+	case "always_off":
+		root = sdktrace.NeverSample()
+
+	case "ratio":
+		root = sdktrace.TraceIDRatioBased(config.SamplingRatio)
+
+	default:
+		root = sdktrace.AlwaysSample()
+	}
+
+	return sdktrace.ParentBased(root)
+}
+
+// serviceAttributes returns the resource attributes identifying the traced service.
+func serviceAttributes(config *Config) []attribute.KeyValue {
+	name := config.ServiceName
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("service.name", name)}
+	if config.ServiceVersion != "" {
+		attrs = append(attrs, attribute.String("service.version", config.ServiceVersion))
+	}
+
+	return attrs
+}
@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// testOTLPReceiver represents a local OTLP/HTTP traces receiver.
+type testOTLPReceiver struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	spans map[string]string // span name => service name
+}
+
+func newTestOTLPReceiver(t *testing.T) *testOTLPReceiver {
+	r := &testOTLPReceiver{spans: make(map[string]string)}
+
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, defaultOTLPURLPath, req.URL.Path)
+		assert.Equal(t, "secret", req.Header.Get("Authorization"))
+
+		data, err := ioutil.ReadAll(req.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var export coltracepb.ExportTraceServiceRequest
+		if !assert.NoError(t, proto.Unmarshal(data, &export)) {
+			return
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, rs := range export.ResourceSpans {
+			var service string
+			for _, attr := range rs.Resource.Attributes {
+				if attr.Key == "service.name" {
+					service = attr.Value.GetStringValue()
+				}
+			}
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					r.spans[span.Name] = service
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+
+	return r
+}
+
+func (r *testOTLPReceiver) Spans() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.spans
+}
+
+func TestNew(t *testing.T) {
+	reporter, err := New(nil)
+	require.NoError(t, err)
+	require.Nil(t, reporter)
+
+	_, err = New(&Config{})
+	require.Error(t, err)
+
+	reporter, err = New(&Config{Exporter: "stdout"})
+	require.NoError(t, err)
+	require.NotNil(t, reporter)
+	require.NotNil(t, reporter.provider)
+	require.NoError(t, reporter.Stop(context.Background()))
+}
+
+func TestReporter_OTLP(t *testing.T) {
+	receiver := newTestOTLPReceiver(t)
+	defer receiver.Close()
+
+	u, err := url.Parse(receiver.URL)
+	require.NoError(t, err)
+
+	reporter, err := New(&Config{
+		ServiceName: "test",
+		Exporter:    "otlp",
+		OTLP: &OTLPExporterConfig{
+			Endpoint: u.Host,
+			Insecure: true,
+			Headers:  map[string]string{"Authorization": "secret"},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, reporter.Start(context.Background()))
+	require.Equal(t, reporter.TracerProvider(), otel.GetTracerProvider())
+
+	ctx, parent := reporter.StartSpan(context.Background(), "parent")
+	_, child := otel.Tracer("test").Start(ctx, "child")
+	require.Equal(t, parent.SpanContext().TraceID(), child.SpanContext().TraceID())
+	child.End()
+	parent.End()
+
+	// Stopping the reporter must export the pending spans
+	require.NoError(t, reporter.Stop(context.Background()))
+	require.Equal(t, map[string]string{"parent": "test", "child": "test"}, receiver.Spans())
+}
+
+func TestReporter_File(t *testing.T) {
+	var testFile = path.Join(os.TempDir(), "go-reporter-spans.json")
+	defer os.Remove(testFile)
+
+	reporter, err := New(&Config{
+		Exporter: "file",
+		File:     &FileExporterConfig{Path: testFile},
+	})
+	require.NoError(t, err)
+	require.NoError(t, reporter.Start(context.Background()))
+
+	_, span := reporter.StartSpan(context.Background(), "test")
+	span.End()
+	require.NoError(t, reporter.Stop(context.Background()))
+
+	f, err := os.Open(testFile)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var spans []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var span struct{ Name string }
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		spans = append(spans, span.Name)
+	}
+	require.Equal(t, []string{"test"}, spans)
+}
+
+func TestReporter_Sampler(t *testing.T) {
+	reporter, err := New(&Config{Exporter: "stdout", Sampler: "always_off"})
+	require.NoError(t, err)
+
+	ctx, parent := reporter.StartSpan(context.Background(), "parent")
+	require.False(t, parent.SpanContext().IsSampled())
+	parent.End()
+
+	// Child spans follow their remote parent's sampling decision
+	remote := trace.ContextWithRemoteSpanContext(ctx, parent.SpanContext().WithTraceFlags(trace.FlagsSampled))
+	_, child := reporter.StartSpan(remote, "child")
+	require.True(t, child.SpanContext().IsSampled())
+	child.End()
+
+	require.NoError(t, reporter.Stop(context.Background()))
+}